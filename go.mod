@@ -1,19 +1,22 @@
 module github.com/lpcalisi/otelcompare
 
-go 1.23
+go 1.23.0
 
 require (
 	github.com/google/go-github/v60 v60.0.0
 	github.com/spf13/cobra v1.8.0
+	github.com/spf13/pflag v1.0.5
+	go.opentelemetry.io/proto/otlp v1.7.0
 	golang.org/x/oauth2 v0.17.0
+	golang.org/x/term v0.32.0
+	google.golang.org/protobuf v1.36.6
 )
 
 require (
 	github.com/golang/protobuf v1.5.3 // indirect
 	github.com/google/go-querystring v1.1.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/spf13/pflag v1.0.5 // indirect
-	golang.org/x/net v0.21.0 // indirect
+	golang.org/x/net v0.40.0 // indirect
+	golang.org/x/sys v0.33.0 // indirect
 	google.golang.org/appengine v1.6.7 // indirect
-	google.golang.org/protobuf v1.31.0 // indirect
 )
@@ -1,13 +1,15 @@
 package main
 
 import (
-	"log"
+	"fmt"
+	"os"
 
 	"github.com/lpcalisi/otelcompare/pkg/cli"
 )
 
 func main() {
 	if err := cli.Execute(); err != nil {
-		log.Fatal(err)
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(cli.ExitCode(err))
 	}
 }
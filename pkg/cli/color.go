@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"os"
+	"regexp"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// ANSI escape sequences for terminal colors
+const (
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+	ansiReset = "\x1b[0m"
+)
+
+// slowerDiffLine and fasterDiffLine match diff lines of durations that grew
+// or shrank
+var (
+	slowerDiffLine = regexp.MustCompile(`duration .* \(\+[^)]*%\)$`)
+	fasterDiffLine = regexp.MustCompile(`duration .* \(-[^)]*%\)$`)
+)
+
+// useColor reports whether output to stdout should be colored: it must be a
+// terminal, and neither --no-color nor the NO_COLOR environment variable
+// may be set
+func useColor(noColor bool) bool {
+	return !noColor && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout)
+}
+
+// isTerminal reports whether f is a terminal rather than a pipe, file or
+// other device such as /dev/null
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// colorizeComparison colors the lines of a markdown comparison report that
+// show a regression red and those that show an improvement green, by their
+// emoji or --no-emoji markers. Legends explaining the symbols are left
+// alone. It is only applied to terminal output, never to posted comments.
+func colorizeComparison(report string) string {
+	return colorizeLines(report, func(line string) string {
+		switch {
+		case strings.HasPrefix(line, "_") || strings.HasPrefix(line, "<sub>"):
+			return ""
+		case containsAny(line, "🔴", "❌", "[SLOW]", "[FAIL]"):
+			return ansiRed
+		case containsAny(line, "🟢", "✅", "[FAST]", "[OK]"):
+			return ansiGreen
+		}
+		return ""
+	})
+}
+
+// colorizeDiff colors the lines of a text diff whose duration grew red and
+// those whose duration shrank green
+func colorizeDiff(diff string) string {
+	return colorizeLines(diff, func(line string) string {
+		switch {
+		case slowerDiffLine.MatchString(line):
+			return ansiRed
+		case fasterDiffLine.MatchString(line):
+			return ansiGreen
+		}
+		return ""
+	})
+}
+
+// colorizeLines wraps every line in the color returned for it, if any
+func colorizeLines(text string, color func(line string) string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if c := color(line); c != "" {
+			lines[i] = c + line + ansiReset
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// containsAny reports whether s contains any of the substrings
+func containsAny(s string, substrs ...string) bool {
+	for _, substr := range substrs {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+	return false
+}
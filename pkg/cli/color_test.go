@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestColorizeComparison(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected string
+	}{
+		{name: "regression", line: "| db | 1.00s | 2.00s | 🔴 1.00s | |", expected: ansiRed + "| db | 1.00s | 2.00s | 🔴 1.00s | |" + ansiReset},
+		{name: "improvement", line: "| db | 2.00s | 1.00s | 🟢 1.00s | |", expected: ansiGreen + "| db | 2.00s | 1.00s | 🟢 1.00s | |" + ansiReset},
+		{name: "failing verdict", line: "**❌ 1 trace slower than threshold (10.0%)**", expected: ansiRed + "**❌ 1 trace slower than threshold (10.0%)**" + ansiReset},
+		{name: "plain regression", line: "| db | 1.00s | 2.00s | [SLOW] 1.00s | |", expected: ansiRed + "| db | 1.00s | 2.00s | [SLOW] 1.00s | |" + ansiReset},
+		{name: "plain improvement", line: "| db | 2.00s | 1.00s | [FAST] 1.00s | |", expected: ansiGreen + "| db | 2.00s | 1.00s | [FAST] 1.00s | |" + ansiReset},
+		{name: "unchanged", line: "| db | 1.00s | 1.00s | - | |", expected: "| db | 1.00s | 1.00s | - | |"},
+		{name: "duration legend", line: "_🔴 slower than the baseline `base` · 🟢 faster than the baseline_", expected: "_🔴 slower than the baseline `base` · 🟢 faster than the baseline_"},
+		{name: "footer", line: "<sub>🔴 slower · 🟢 faster<br>Generated by otelcompare dev</sub>", expected: "<sub>🔴 slower · 🟢 faster<br>Generated by otelcompare dev</sub>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := colorizeComparison(tt.line + "\n"); got != tt.expected+"\n" {
+				t.Errorf("colorizeComparison() = %q, want %q", got, tt.expected+"\n")
+			}
+		})
+	}
+}
+
+func TestColorizeDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected string
+	}{
+		{name: "slower", line: "~ t: duration 1.00s → 2.00s (+100.0%)", expected: ansiRed + "~ t: duration 1.00s → 2.00s (+100.0%)" + ansiReset},
+		{name: "faster", line: "~ t: duration 2.00s → 1.00s (-50.0%)", expected: ansiGreen + "~ t: duration 2.00s → 1.00s (-50.0%)" + ansiReset},
+		{name: "attribute", line: "~ t: attribute k added: (+1)", expected: "~ t: attribute k added: (+1)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := colorizeDiff(tt.line); got != tt.expected {
+				t.Errorf("colorizeDiff() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if isTerminal(file) {
+		t.Error("isTerminal() = true for a regular file")
+	}
+	if null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0); err == nil {
+		defer null.Close()
+		if isTerminal(null) {
+			t.Errorf("isTerminal() = true for %s, a character device but not a terminal", os.DevNull)
+		}
+	}
+
+	stdout := os.Stdout
+	os.Stdout = file
+	defer func() { os.Stdout = stdout }()
+	if useColor(false) {
+		t.Error("useColor() = true when stdout is a file")
+	}
+}
@@ -1,16 +1,47 @@
 package cli
 
 import (
+	"errors"
+
 	"github.com/spf13/cobra"
 )
 
+// strictInput turns warnings about the shape of the input into errors
+var strictInput bool
+
 var rootCmd = &cobra.Command{
 	Use:   "otelcompare",
 	Short: "Generate and compare OpenTelemetry traces",
 	Long: `A tool that reads JSON files with OpenTelemetry traces,
 generates visualizations and compares them in GitHub Pull Requests.`,
+	// Errors are printed once by main, which also picks the exit code
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(cmd, configFile); err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.Version = toolVersion()
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Config file with flag defaults (default: "+defaultConfigFile+" if present)")
+	rootCmd.PersistentFlags().BoolVar(&strictInput, "strict", false, "Fail instead of warning when the input does not look like traces (no spans, missing IDs or timestamps)")
 }
 
+// Execute runs the command line. Errors are returned as an *ExitError
+// wrapping the command's error, with the code to exit with; printing them
+// is left to the caller.
 func Execute() error {
-	return rootCmd.Execute()
+	err := rootCmd.Execute()
+	if err == nil {
+		return nil
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return err
+	}
+	return &ExitError{Code: ExitUsage, Err: err}
 }
@@ -1,21 +1,64 @@
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/lpcalisi/otelcompare/pkg/github"
+	"github.com/lpcalisi/otelcompare/pkg/slack"
 	"github.com/lpcalisi/otelcompare/pkg/trace"
 	"github.com/spf13/cobra"
 )
 
 var (
-	compareInputFiles []string
-	comparePrNumber   int
-	compareOwner      string
-	compareRepo       string
-	compareAttribute  string
-	compareDryRun     bool
+	compareInputFiles       []string
+	comparePrNumber         int
+	compareOwner            string
+	compareRepo             string
+	compareAttribute        string
+	compareDryRun           bool
+	compareSectionBy        string
+	compareApdex            time.Duration
+	compareApdexOps         map[string]string
+	compareOutput           string
+	compareFuzzy            float64
+	compareNormalizeNames   bool
+	compareAgainstBaseline  string
+	compareAttrDiff         string
+	compareNormalizePattern string
+	compareFormat           string
+	compareFailOnRegression bool
+	compareThreshold        float64
+	compareNewComment       bool
+	compareAggregate        bool
+	compareService          string
+	compareIncludeSpans     []string
+	compareExcludeSpans     []string
+	compareBaseRef          string
+	compareHeadRef          string
+	comparePath             string
+	compareProvider         string
+	compareChangesOnly      bool
+	compareTop              int
+	compareGitHubURL        string
+	compareNoColor          bool
+	compareNoEmoji          bool
+	compareBaseline         string
+	compareGroupBy          string
+	compareRegressionLabels []string
+	compareCheckRun         bool
+	compareSHA              string
+	compareMinDuration      time.Duration
+	compareCollapseSpans    bool
+	compareQuiet            bool
+	compareScope            string
+	compareSlackWebhook     string
+	compareMinMatch         float64
 )
 
 var compareCmd = &cobra.Command{
@@ -24,66 +67,394 @@ var compareCmd = &cobra.Command{
 	Long: `Compare traces between different files and generate a markdown report.
 For example:
   otelcompare compare -i file1.json -i file2.json -i file3.json
-  otelcompare compare -i file1.json -i file2.json -a http.url`,
+  otelcompare compare -i file1.json -i file2.json -a http.url
+  otelcompare compare --baseline main.json -i 'branches/*.json'
+  otelcompare compare --against-baseline baseline.json -i head.json
+  otelcompare compare --base main --head HEAD --path traces.json`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if len(compareInputFiles) < 2 {
-			return fmt.Errorf("at least two input files are required for comparison")
+		// Flags are valid at this point, so errors are not usage mistakes
+		cmd.SilenceUsage = true
+		if err := expandEnvFlags(cmd, "owner", "repo", "attribute"); err != nil {
+			return err
 		}
+		return runCompare()
+	},
+}
+
+func init() {
+	compareCmd.Flags().StringArrayVarP(&compareInputFiles, "input", "i", []string{}, "Input JSON files, http(s) URLs, directories, glob patterns or .tar.gz/.zip archives of JSON files to compare; append =Label to name a file in reports")
+	compareCmd.Flags().StringVar(&compareAgainstBaseline, "against-baseline", "", "Baseline artifact written by export --as-baseline, file or http(s) URL, to compare the inputs against as the first file")
+	compareCmd.Flags().IntVarP(&comparePrNumber, "pr", "p", 0, "Pull request (or GitLab merge request) number to comment on")
+	compareCmd.Flags().StringVar(&compareOwner, "owner", "", "Repository owner (GitLab group path)")
+	compareCmd.Flags().StringVar(&compareRepo, "repo", "", "Repository name")
+	compareCmd.Flags().StringVar(&compareProvider, "provider", "github", "Code host to comment on: github or gitlab")
+	compareCmd.Flags().StringVar(&compareGitHubURL, "github-url", "", "GitHub Enterprise URL (default: $GITHUB_API_URL, or github.com)")
+	compareCmd.Flags().StringVarP(&compareAttribute, "attribute", "a", "trace_id", "Attribute to use for trace identification (name, structure, trace_id or a trace attribute), or a comma-separated list tried in order before falling back to the trace ID")
+	compareCmd.Flags().BoolVar(&compareDryRun, "dry-run", false, "Print comment to stdout without posting it")
+	compareCmd.Flags().BoolVar(&compareNoColor, "no-color", false, "Do not color regressions and improvements when printing to a terminal")
+	compareCmd.Flags().BoolVar(&compareNoEmoji, "no-emoji", false, "Use plain ASCII markers such as [SLOW] and [FAST] instead of emoji")
+	compareCmd.Flags().BoolVar(&compareNewComment, "new-comment", false, "Always post a new comment instead of updating the previous otelcompare comment")
+	compareCmd.Flags().StringVar(&compareSectionBy, "section-by", "", "Span attribute used to group comparison rows under subheaders (e.g. team)")
+
+	compareCmd.Flags().DurationVar(&compareApdex, "apdex-target", 0, "Default Apdex target T for every operation (0 disables Apdex scores)")
+	compareCmd.Flags().StringToStringVar(&compareApdexOps, "apdex-operation-target", nil, "Apdex target T for a single operation, as name=duration (repeatable)")
+
+	compareCmd.Flags().IntVar(&compareTop, "top", 0, "Only report the N slowest traces (0 reports all)")
+	compareCmd.Flags().BoolVar(&compareChangesOnly, "changes-only", false, "Only show details for traces whose duration changed by more than the threshold or whose attributes changed")
+	compareCmd.Flags().BoolVar(&compareAggregate, "aggregate", false, "Group traces by the identifier attribute and compare p90 trace durations and p50/p90 span durations across files")
+	compareCmd.Flags().StringVar(&compareGroupBy, "group-by", "", "Compare total and average span durations per value of this span attribute instead of per trace (\"service\" is short for service.name)")
+	compareCmd.Flags().Float64Var(&compareMinMatch, "min-match", 0, "Warn when less than this fraction (0..1) of the traces match across all files; no matches at all are always warned about")
+	compareCmd.Flags().Float64Var(&compareFuzzy, "fuzzy-match", 0, "Similarity threshold (0..1) for approximately matching renamed spans (0 disables)")
+	compareCmd.Flags().StringVar(&compareAttrDiff, "attr-diff", trace.AttrDiffTable, "How to show the trace attributes of matching traces: table (side by side) or unified (a diff of the changes)")
+	compareCmd.Flags().BoolVar(&compareNormalizeNames, "normalize-names", false, "Compare spans by their name without volatile suffixes such as IDs, matched by --normalize-pattern")
+	compareCmd.Flags().StringVar(&compareNormalizePattern, "normalize-pattern", trace.DefaultNormalizePattern, "Regular expression removed from span names with --normalize-names")
+	compareCmd.Flags().StringVar(&compareService, "service", "", "Only compare spans whose service.name is this service")
+	compareCmd.Flags().StringVar(&compareScope, "scope", "", "Only compare spans created by the instrumentation scope with this name")
+	compareCmd.Flags().StringArrayVar(&compareIncludeSpans, "include-span", nil, "Only compare spans whose name matches this regular expression (repeatable)")
+	compareCmd.Flags().StringArrayVar(&compareExcludeSpans, "exclude-span", nil, "Skip spans whose name matches this regular expression (repeatable)")
+	compareCmd.Flags().DurationVar(&compareMinDuration, "min-duration", 0, "Drop spans shorter than this (e.g. 1ms); their children move up to the nearest kept ancestor")
+	compareCmd.Flags().BoolVar(&compareCollapseSpans, "collapse-spans", false, "Merge the spans of each name within a trace into one span lasting the sum of their durations")
+	compareCmd.Flags().StringVar(&compareFormat, "format", "json", "Input trace format: json, ndjson, zipkin or otlp-proto")
+	compareCmd.Flags().StringVarP(&compareOutput, "output", "o", "markdown", "Output format: markdown, prometheus, json, junit or csv")
+
+	compareCmd.Flags().BoolVar(&compareFailOnRegression, "fail-on-regression", false, "Exit with a non-zero status if a matching trace got slower than the threshold")
+	compareCmd.Flags().Float64Var(&compareThreshold, "threshold", 10, "Regression threshold as a percentage of the first file's duration, used by the verdict, --fail-on-regression and --regression-label")
+	compareCmd.Flags().BoolVarP(&compareQuiet, "quiet", "q", false, "Do not print the report to stdout; only warnings and errors are printed, to stderr")
+	compareCmd.Flags().StringArrayVar(&compareRegressionLabels, "regression-label", nil, "Label to add to the pull or merge request when a matching trace got slower than the threshold (repeatable)")
+
+	compareCmd.Flags().BoolVar(&compareCheckRun, "check-run", false, "Report regressions as a GitHub check run on --sha instead of commenting")
+	compareCmd.Flags().StringVar(&compareSHA, "sha", "", "Commit SHA to report the --check-run on")
+	compareCmd.Flags().StringVar(&compareSlackWebhook, "slack-webhook", "", "Slack incoming webhook URL to post the report to instead of commenting")
+
+	compareCmd.Flags().StringVar(&compareBaseline, "baseline", "", "Trace file every other input is compared against; it becomes the first column")
+	compareCmd.Flags().StringVar(&compareBaseRef, "base", "", "Git ref to read the base version of --path from")
+	compareCmd.Flags().StringVar(&compareHeadRef, "head", "", "Git ref to read the head version of --path from")
+	compareCmd.Flags().StringVar(&comparePath, "path", "", "Path of the trace file to compare between --base and --head")
+
+	compareCmd.MarkFlagsRequiredTogether("base", "head", "path")
+	compareCmd.MarkFlagsRequiredTogether("check-run", "sha")
+	compareCmd.MarkFlagsMutuallyExclusive("input", "base")
+	compareCmd.MarkFlagsMutuallyExclusive("baseline", "base")
+	compareCmd.MarkFlagsMutuallyExclusive("against-baseline", "base")
+	compareCmd.MarkFlagsMutuallyExclusive("against-baseline", "baseline")
+	compareCmd.MarkFlagsMutuallyExclusive("group-by", "aggregate")
+	compareCmd.MarkFlagsMutuallyExclusive("slack-webhook", "check-run")
+	compareCmd.MarkFlagsOneRequired("input", "base")
+
+	rootCmd.AddCommand(compareCmd)
+}
+
+func runCompare() error {
+	includeSpans, err := compilePatterns("include-span", compareIncludeSpans)
+	if err != nil {
+		return err
+	}
+	excludeSpans, err := compilePatterns("exclude-span", compareExcludeSpans)
+	if err != nil {
+		return err
+	}
+
+	// Validate every flag up front, so machine-readable outputs reject the
+	// same values as markdown
+	if compareFuzzy < 0 || compareFuzzy > 1 {
+		return fmt.Errorf("--fuzzy-match must be between 0 and 1")
+	}
+	if compareMinMatch < 0 || compareMinMatch > 1 {
+		return fmt.Errorf("--min-match must be between 0 and 1")
+	}
+	if compareAttrDiff != trace.AttrDiffTable && compareAttrDiff != trace.AttrDiffUnified {
+		return fmt.Errorf("unsupported --attr-diff %q: must be table or unified", compareAttrDiff)
+	}
+	var normalizeNames *regexp.Regexp
+	if compareNormalizeNames {
+		normalizeNames, err = regexp.Compile(compareNormalizePattern)
+		if err != nil {
+			return fmt.Errorf("invalid --normalize-pattern %q: %w", compareNormalizePattern, err)
+		}
+	}
 
-		// Read and parse all files
-		var traceSets []trace.TraceSet
-		for _, file := range compareInputFiles {
-			data, err := os.ReadFile(file)
-			if err != nil {
-				return fmt.Errorf("error reading file %s: %w", file, err)
-			}
-
-			traces, err := trace.ParseTraces(data)
-			if err != nil {
-				return fmt.Errorf("error parsing traces from %s: %w", file, err)
-			}
-
-			traceSets = append(traceSets, trace.TraceSet{
-				Name:   file,
-				Traces: traces,
-			})
+	// Parse per-operation Apdex targets
+	apdexTargets := make(map[string]time.Duration)
+	for operation, value := range compareApdexOps {
+		target, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid Apdex target for %s: %w", operation, err)
 		}
+		apdexTargets[operation] = target
+	}
 
-		// Compare traces using the specified attribute
-		markdown := trace.CompareMultipleTraces(traceSets, compareAttribute)
+	// Expand globs and directories so each matched file is its own TraceSet
+	inputs, err := expandInputPaths(compareInputFiles)
+	if err != nil {
+		return err
+	}
+	if compareBaseline != "" {
+		// Durations are always compared with the first TraceSet
+		inputs, err = withBaseline(inputs, compareBaseline)
+		if err != nil {
+			return err
+		}
+	}
 
-		// If dry-run, just print to stdout
-		if compareDryRun {
-			fmt.Print(markdown)
-			return nil
+	// Read and parse all files, either from disk or from two git refs
+	inputOpts := inputOptions{
+		format:        compareFormat,
+		service:       compareService,
+		scope:         compareScope,
+		includeSpans:  includeSpans,
+		excludeSpans:  excludeSpans,
+		minDuration:   compareMinDuration,
+		collapseSpans: compareCollapseSpans,
+		strict:        strictInput,
+	}
+	var traceSets []trace.TraceSet
+	if compareBaseRef != "" {
+		traceSets, err = loadGitTraceSets([]string{compareBaseRef, compareHeadRef}, comparePath, inputOpts)
+	} else {
+		traceSets, err = loadTraceSets(inputs, inputOpts)
+	}
+	if err != nil {
+		return err
+	}
+	if compareAgainstBaseline != "" {
+		baseline, err := loadBaseline(compareAgainstBaseline, inputOpts)
+		if err != nil {
+			return err
 		}
+		traceSets = append([]trace.TraceSet{baseline}, traceSets...)
+	}
+	if len(traceSets) < 2 {
+		return fmt.Errorf("at least two input files are required for comparison")
+	}
+
+	// Detect regressions up front so every output mode can fail on them.
+	// Aggregated reports compare group p90s, so regressions do too.
+	var regressions []trace.DurationChange
+	switch {
+	case !compareFailOnRegression && len(compareRegressionLabels) == 0 && !compareCheckRun:
+	case compareAggregate:
+		regressions = trace.DetectGroupRegressions(traceSets, compareAttribute, compareThreshold)
+	default:
+		regressions = trace.DetectRegressions(traceSets, compareAttribute, compareThreshold)
+	}
 
-		// Validate GitHub flags if not dry-run
-		if compareOwner == "" || compareRepo == "" {
-			return fmt.Errorf("--owner and --repo are required when not using --dry-run")
+	// Reports are still generated with --quiet, so it only changes the output
+	var stdout io.Writer = os.Stdout
+	if compareQuiet {
+		stdout = io.Discard
+	}
+
+	// Markdown and JSON compare traces with the same options
+	opts := trace.Options{
+		SectionBy:        compareSectionBy,
+		ApdexTarget:      compareApdex,
+		ApdexTargets:     apdexTargets,
+		FuzzyMatch:       compareFuzzy,
+		NormalizeNames:   normalizeNames,
+		AttrDiff:         compareAttrDiff,
+		MinMatchFraction: compareMinMatch,
+		Verdict:          &trace.Verdict{Threshold: compareThreshold},
+		Top:              compareTop,
+		Footer:           &trace.Footer{Version: toolVersion()},
+		NoEmoji:          compareNoEmoji,
+	}
+	if compareAggregate {
+		opts.AggregateBy = compareAttribute
+	}
+	opts.GroupBy = groupByAttribute(compareGroupBy)
+	if compareChangesOnly {
+		opts.ChangesOnly = &trace.ChangesOnly{Threshold: compareThreshold}
+	}
+
+	// Machine-readable outputs are printed to stdout instead of commenting
+	switch compareOutput {
+	case "markdown":
+	case "prometheus":
+		fmt.Fprint(stdout, trace.GeneratePrometheus(trace.BuildReport(traceSets)))
+		return regressionError(regressions)
+	case "json":
+		if err := printJSONComparison(stdout, traceSets, opts); err != nil {
+			return err
+		}
+		return regressionError(regressions)
+	case "junit":
+		report, err := trace.GenerateJUnit(traceSets, compareAttribute, compareThreshold)
+		if err != nil {
+			return err
 		}
+		fmt.Fprint(stdout, report)
+		return regressionError(regressions)
+	case "csv":
+		report, err := trace.GenerateCSV(traceSets, compareAttribute)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(stdout, report)
+		return regressionError(regressions)
+	default:
+		return fmt.Errorf("unsupported output %q: must be markdown, prometheus, json, junit or csv", compareOutput)
+	}
+
+	markdown := trace.CompareMultipleTraces(traceSets, compareAttribute, opts)
 
-		// Get GitHub token from environment
-		token := os.Getenv("GITHUB_TOKEN")
-		if token == "" {
-			return fmt.Errorf("GITHUB_TOKEN environment variable is required when not using --dry-run")
+	// If dry-run, just print to stdout, colored when it is a terminal
+	if compareDryRun {
+		if useColor(compareNoColor) {
+			fmt.Fprint(stdout, colorizeComparison(markdown))
+		} else {
+			fmt.Fprint(stdout, markdown)
 		}
+		return regressionError(regressions)
+	}
 
-		// Comment on GitHub
-		client := github.NewClient(token)
-		return client.CommentPR(compareOwner, compareRepo, comparePrNumber, markdown)
-	},
+	// Post to Slack instead of commenting, which needs no repository
+	if compareSlackWebhook != "" {
+		if err := slack.PostMessage(compareSlackWebhook, markdown); err != nil {
+			return apiError(fmt.Errorf("error posting to Slack: %w", err))
+		}
+		return regressionError(regressions)
+	}
+
+	// Validate comment flags if not dry-run
+	if compareOwner == "" || compareRepo == "" {
+		return fmt.Errorf("--owner and --repo are required when not using --dry-run")
+	}
+
+	// Report a check run on the commit instead of commenting
+	if compareCheckRun {
+		if err := postCheckRun(markdown, regressions); err != nil {
+			return err
+		}
+		return regressionError(regressions)
+	}
+
+	// Comment on the pull or merge request
+	target := commentTarget{
+		provider:  compareProvider,
+		githubURL: compareGitHubURL,
+		owner:     compareOwner,
+		repo:      compareRepo,
+		number:    comparePrNumber,
+	}
+	if err := postComment(target, "compare", markdown, compareNewComment); err != nil {
+		return err
+	}
+
+	// Label the pull or merge request when it regressed
+	if len(regressions) > 0 && len(compareRegressionLabels) > 0 {
+		poster, err := newCommentPoster(target)
+		if err != nil {
+			return err
+		}
+		if err := addRegressionLabels(poster, target, compareRegressionLabels, regressions); err != nil {
+			return err
+		}
+	}
+
+	return regressionError(regressions)
 }
 
-func init() {
-	compareCmd.Flags().StringArrayVarP(&compareInputFiles, "input", "i", []string{}, "Input JSON files to compare")
-	compareCmd.Flags().IntVarP(&comparePrNumber, "pr", "p", 0, "Pull request number to comment on")
-	compareCmd.Flags().StringVar(&compareOwner, "owner", "", "GitHub repository owner")
-	compareCmd.Flags().StringVar(&compareRepo, "repo", "", "GitHub repository name")
-	compareCmd.Flags().StringVarP(&compareAttribute, "attribute", "a", "trace_id", "Attribute to use for trace identification (default: span name)")
-	compareCmd.Flags().BoolVar(&compareDryRun, "dry-run", false, "Print comment to stdout without posting to GitHub")
+// addRegressionLabels adds labels to the target pull or merge request when
+// there are regressions
+func addRegressionLabels(poster CommentPoster, target commentTarget, labels []string, regressions []trace.DurationChange) error {
+	if len(labels) == 0 || len(regressions) == 0 {
+		return nil
+	}
+	if err := poster.AddLabels(target.owner+"/"+target.repo, target.number, labels); err != nil {
+		return apiError(fmt.Errorf("error labeling regressions: %w", err))
+	}
+	return nil
+}
+
+// groupByAttribute returns the span attribute named by --group-by, where
+// "service" is short for service.name
+func groupByAttribute(groupBy string) string {
+	if groupBy == "service" {
+		return "service.name"
+	}
+	return groupBy
+}
 
-	compareCmd.MarkFlagRequired("input")
+// postCheckRun reports the regressions of a comparison as a check run on
+// the --sha commit, with the markdown report as its details
+func postCheckRun(markdown string, regressions []trace.DurationChange) error {
+	if compareProvider != "github" {
+		return fmt.Errorf("--check-run is only supported with --provider github")
+	}
+	client, err := newGitHubClient(compareGitHubURL)
+	if err != nil {
+		return err
+	}
+	run := regressionCheckRun(compareSHA, regressions, compareThreshold, markdown)
+	if err := client.CreateCheckRun(compareOwner, compareRepo, run); err != nil {
+		return apiError(fmt.Errorf("error creating check run: %w", err))
+	}
+	return nil
+}
 
-	rootCmd.AddCommand(compareCmd)
+// regressionCheckRun returns the check run reporting the regressions of a
+// comparison, which fails when there is any
+func regressionCheckRun(sha string, regressions []trace.DurationChange, threshold float64, report string) github.CheckRun {
+	run := github.CheckRun{
+		HeadSHA: sha,
+		Success: len(regressions) == 0,
+		Title:   "No regressions",
+		Summary: fmt.Sprintf("No trace got more than %.1f%% slower.", threshold),
+		Text:    report,
+	}
+	if len(regressions) > 0 {
+		run.Title = fmt.Sprintf("%d regression(s) over %.1f%%", len(regressions), threshold)
+		var sb strings.Builder
+		for _, r := range regressions {
+			sb.WriteString(fmt.Sprintf("- %s in %s took %s, %.1f%% slower than %s\n",
+				r.Name, r.File, r.Current, r.Percent(), r.Baseline))
+		}
+		run.Summary = sb.String()
+	}
+	return run
+}
+
+// jsonComparison is the JSON output comparing one file against the first
+type jsonComparison struct {
+	Base string `json:"base"`
+	Head string `json:"head"`
+	trace.ComparisonResult
+}
+
+// printJSONComparison prints the comparison of every file against the first
+// one as a JSON array, matching traces and spans in the same pass as the
+// markdown report
+func printJSONComparison(w io.Writer, traceSets []trace.TraceSet, opts trace.Options) error {
+	comparisons := make([]jsonComparison, 0, len(traceSets)-1)
+	for i, result := range trace.CompareSets(traceSets, compareAttribute, opts) {
+		comparisons = append(comparisons, jsonComparison{
+			Base:             traceSets[0].Name,
+			Head:             traceSets[i+1].Name,
+			ComparisonResult: result,
+		})
+	}
+
+	data, err := json.MarshalIndent(comparisons, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling comparison: %w", err)
+	}
+	fmt.Fprintln(w, string(data))
+	return nil
+}
+
+// regressionError returns an error listing the regressions, if any, with
+// --fail-on-regression
+func regressionError(regressions []trace.DurationChange) error {
+	if !compareFailOnRegression || len(regressions) == 0 {
+		return nil
+	}
+
+	for _, r := range regressions {
+		fmt.Fprintf(os.Stderr, "Regression: %s in %s took %s, %.1f%% slower than %s\n",
+			r.Name, r.File, r.Current, r.Percent(), r.Baseline)
+	}
+	return &ExitError{
+		Code: ExitRegression,
+		Err:  fmt.Errorf("%d trace(s) regressed by more than %.1f%%", len(regressions), compareThreshold),
+	}
 }
@@ -0,0 +1,233 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lpcalisi/otelcompare/pkg/trace"
+)
+
+// mockPoster records the labels added through it
+type mockPoster struct {
+	CommentPoster
+	project string
+	number  int
+	labels  []string
+	err     error
+}
+
+func (p *mockPoster) AddLabels(project string, number int, labels []string) error {
+	p.project, p.number, p.labels = project, number, labels
+	return p.err
+}
+
+func TestAddRegressionLabels(t *testing.T) {
+	target := commentTarget{owner: "owner", repo: "repo", number: 42}
+	regressions := []trace.DurationChange{{Name: "checkout"}}
+
+	tests := []struct {
+		name        string
+		labels      []string
+		regressions []trace.DurationChange
+		wantLabels  []string
+	}{
+		{name: "regressed", labels: []string{"perf-regression"}, regressions: regressions, wantLabels: []string{"perf-regression"}},
+		{name: "no regressions", labels: []string{"perf-regression"}},
+		{name: "no labels", regressions: regressions},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poster := &mockPoster{}
+			if err := addRegressionLabels(poster, target, tt.labels, tt.regressions); err != nil {
+				t.Fatalf("addRegressionLabels() error = %v", err)
+			}
+			if !reflect.DeepEqual(poster.labels, tt.wantLabels) {
+				t.Errorf("addRegressionLabels() added %v, want %v", poster.labels, tt.wantLabels)
+			}
+			if tt.wantLabels != nil && (poster.project != "owner/repo" || poster.number != 42) {
+				t.Errorf("addRegressionLabels() labeled %s#%d, want owner/repo#42", poster.project, poster.number)
+			}
+		})
+	}
+
+	poster := &mockPoster{err: errors.New("forbidden")}
+	err := addRegressionLabels(poster, target, []string{"perf-regression"}, regressions)
+	if err == nil || !strings.Contains(err.Error(), "forbidden") {
+		t.Errorf("addRegressionLabels() error = %v, want the poster error", err)
+	}
+	if code := ExitCode(err); code != ExitAPI {
+		t.Errorf("ExitCode(addRegressionLabels()) = %d, want %d", code, ExitAPI)
+	}
+}
+
+func TestRegressionCheckRun(t *testing.T) {
+	run := regressionCheckRun("abc123", nil, 10, "report")
+	if !run.Success || run.HeadSHA != "abc123" || run.Title != "No regressions" || run.Text != "report" {
+		t.Errorf("regressionCheckRun() without regressions = %+v, want a successful run", run)
+	}
+
+	regressions := []trace.DurationChange{{Name: "checkout", File: "head.json", Baseline: time.Second, Current: 1500 * time.Millisecond}}
+	run = regressionCheckRun("abc123", regressions, 10, "report")
+	if run.Success || run.Title != "1 regression(s) over 10.0%" {
+		t.Errorf("regressionCheckRun() with a regression = %+v, want a failed run", run)
+	}
+	if want := "- checkout in head.json took 1.5s, 50.0% slower than 1s\n"; run.Summary != want {
+		t.Errorf("regressionCheckRun() summary = %q, want %q", run.Summary, want)
+	}
+}
+
+func TestRunCompareValidatesFlagsForEveryOutput(t *testing.T) {
+	defer func(output string, fuzzy float64, inputs []string) {
+		compareOutput, compareFuzzy, compareInputFiles = output, fuzzy, inputs
+	}(compareOutput, compareFuzzy, compareInputFiles)
+
+	// The inputs do not exist, so only validation that runs before reading
+	// them can produce the expected error
+	compareInputFiles = []string{"missing-base.json", "missing-head.json"}
+	compareFuzzy = 2
+	for _, output := range []string{"markdown", "prometheus", "json", "junit", "csv"} {
+		t.Run(output, func(t *testing.T) {
+			compareOutput = output
+			err := runCompare()
+			if err == nil || !strings.Contains(err.Error(), "--fuzzy-match must be between 0 and 1") {
+				t.Errorf("runCompare() error = %v, want the --fuzzy-match error", err)
+			}
+		})
+	}
+}
+
+func TestPrintJSONComparisonOptions(t *testing.T) {
+	now := time.Now()
+	newSet := func(name string, d time.Duration) trace.TraceSet {
+		return trace.TraceSet{Name: name, Traces: []trace.Trace{{
+			TraceID:       "t1",
+			ResourceAttrs: trace.Attributes{"service.name": "api"},
+			Spans:         []trace.Span{{SpanID: "a", Name: "GET /users", StartTime: now, EndTime: now.Add(d)}},
+		}}}
+	}
+
+	var sb strings.Builder
+	sets := []trace.TraceSet{newSet("base.json", time.Second), newSet("head.json", 2*time.Second)}
+	if err := printJSONComparison(&sb, sets, trace.Options{GroupBy: "service.name"}); err != nil {
+		t.Fatalf("printJSONComparison() error = %v", err)
+	}
+	// Grouped like the markdown report, by service rather than by trace
+	if !strings.Contains(sb.String(), `"name": "api"`) || strings.Contains(sb.String(), `"name": "t1"`) {
+		t.Errorf("printJSONComparison() did not group by service.name:\n%s", sb.String())
+	}
+}
+
+func TestPrintJSONComparisonMatchesMarkdown(t *testing.T) {
+	now := time.Now()
+	newTrace := func(id string, d time.Duration) trace.Trace {
+		return trace.Trace{TraceID: id, Spans: []trace.Span{
+			{SpanID: id + "-root", Name: "checkout", StartTime: now, EndTime: now.Add(d), Attributes: trace.Attributes{"service.name": "api"}},
+			{SpanID: id + "-db", ParentSpanID: id + "-root", Name: "SELECT orders", StartTime: now, EndTime: now.Add(d / 2), Attributes: trace.Attributes{"service.name": "postgres"}},
+		}}
+	}
+	// t4 falls outside the top 3, and t1 and t3 did not change
+	sets := []trace.TraceSet{
+		{Name: "base.json", Traces: []trace.Trace{newTrace("t1", 1500*time.Millisecond), newTrace("t2", 2*time.Second), newTrace("t3", 3*time.Second), newTrace("t4", 500*time.Millisecond)}},
+		{Name: "head.json", Traces: []trace.Trace{newTrace("t1", 1500*time.Millisecond), newTrace("t2", 3*time.Second), newTrace("t3", 3*time.Second), newTrace("t4", time.Second)}},
+	}
+	opts := trace.Options{Top: 3, ChangesOnly: &trace.ChangesOnly{Threshold: 10}, SectionBy: "service.name"}
+
+	var sb strings.Builder
+	if err := printJSONComparison(&sb, sets, opts); err != nil {
+		t.Fatalf("printJSONComparison() error = %v", err)
+	}
+	var comparisons []jsonComparison
+	if err := json.Unmarshal([]byte(sb.String()), &comparisons); err != nil {
+		t.Fatalf("error parsing JSON output: %v", err)
+	}
+	var fromJSON []string
+	for _, c := range comparisons[0].Matching {
+		for _, span := range c.Spans {
+			fromJSON = append(fromJSON, c.Name+"/"+span.Section+"/"+span.Name)
+		}
+	}
+
+	// Span rows of the detailed comparison, by trace and section
+	var fromMarkdown []string
+	var traceName, section string
+	for _, line := range strings.Split(trace.CompareMultipleTraces(sets, "trace_id", opts), "\n") {
+		switch {
+		case strings.HasPrefix(line, "<summary>"):
+			traceName = strings.TrimSuffix(strings.TrimPrefix(line, "<summary>"), "</summary>")
+		case line == "</details>":
+			traceName = ""
+		case strings.HasPrefix(line, "#### "):
+			section = strings.TrimPrefix(line, "#### ")
+		case traceName != "" && strings.HasPrefix(line, "| ") && !strings.HasPrefix(line, "| Span Name |") && !strings.HasPrefix(line, "| Attributes |"):
+			if name := strings.TrimSpace(strings.Split(line, "|")[1]); name != "Attribute" {
+				fromMarkdown = append(fromMarkdown, traceName+"/"+section+"/"+name)
+			}
+		}
+	}
+
+	// Markdown orders spans by section first
+	sort.Strings(fromJSON)
+	sort.Strings(fromMarkdown)
+	want := []string{"t2/api/checkout", "t2/postgres/SELECT orders"}
+	if !reflect.DeepEqual(fromJSON, want) {
+		t.Errorf("JSON spans = %q, want %q", fromJSON, want)
+	}
+	if !reflect.DeepEqual(fromMarkdown, fromJSON) {
+		t.Errorf("markdown spans = %q, JSON spans = %q", fromMarkdown, fromJSON)
+	}
+	if comparisons[0].Hidden != 3 {
+		t.Errorf("JSON hidden = %d, want 3", comparisons[0].Hidden)
+	}
+}
+
+func TestRunCompareQuiet(t *testing.T) {
+	defer func(inputs []string, output string, quiet, failOnRegression, dryRun bool, threshold float64) {
+		compareInputFiles, compareOutput, compareQuiet, compareFailOnRegression, compareDryRun, compareThreshold = inputs, output, quiet, failOnRegression, dryRun, threshold
+	}(compareInputFiles, compareOutput, compareQuiet, compareFailOnRegression, compareDryRun, compareThreshold)
+
+	// head takes twice as long as base
+	dir := t.TempDir()
+	for name, end := range map[string]string{"base.json": "10:00:01Z", "head.json": "10:00:02Z"} {
+		data := `[{"trace_id": "t1", "spans": [{"span_id": "a", "name": "checkout", "start_time": "2024-03-06T10:00:00Z", "end_time": "2024-03-06T` + end + `"}]}]`
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatalf("error writing fixture: %v", err)
+		}
+	}
+	compareInputFiles = []string{filepath.Join(dir, "base.json"), filepath.Join(dir, "head.json")}
+	compareQuiet, compareFailOnRegression, compareDryRun, compareThreshold = true, true, true, 10
+
+	for _, output := range []string{"markdown", "json"} {
+		t.Run(output, func(t *testing.T) {
+			compareOutput = output
+			out, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer out.Close()
+			stdout := os.Stdout
+			os.Stdout = out
+			runErr := runCompare()
+			os.Stdout = stdout
+
+			var exitErr *ExitError
+			if !errors.As(runErr, &exitErr) || exitErr.Code != ExitRegression {
+				t.Errorf("runCompare() error = %v, want an ExitError with ExitRegression", runErr)
+			}
+			written, err := os.ReadFile(out.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(written) > 0 {
+				t.Errorf("runCompare() wrote to stdout with --quiet:\n%s", written)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// defaultConfigFile is read from the working directory when --config is
+// not given
+const defaultConfigFile = ".otelcompare.yaml"
+
+var configFile string
+
+// mutuallyExclusiveAnnotation is the flag annotation cobra stores the groups
+// of MarkFlagsMutuallyExclusive in, as space-separated flag names
+const mutuallyExclusiveAnnotation = "cobra_annotation_mutually_exclusive"
+
+// config holds flag defaults read from a config file. Values are kept as
+// strings and lists of strings and parsed by the flags they are applied to.
+type config struct {
+	// global holds the top-level keys, applied to every command that has
+	// a flag of the same name
+	global map[string][]string
+
+	// commands holds the keys of each command's section
+	commands map[string]map[string][]string
+}
+
+// loadConfig reads the config file and applies it to the command's flags.
+// A missing default config file is not an error.
+func loadConfig(cmd *cobra.Command, path string) error {
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigFile
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+
+	cfg, err := parseConfig(data)
+	if err != nil {
+		return fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+	return cfg.apply(cmd)
+}
+
+// apply sets every flag of cmd that was not given on the command line from
+// the config. Keys in the command's section take precedence over top-level
+// keys. Unknown keys in the command's section are an error, while
+// top-level keys are skipped by commands without a matching flag. Keys
+// whose flag is mutually exclusive with one given on the command line are
+// skipped too, so the command line can replace e.g. a configured input.
+func (c config) apply(cmd *cobra.Command) error {
+	values := make(map[string][]string)
+	for key, value := range c.global {
+		if cmd.Flags().Lookup(key) != nil {
+			values[key] = value
+		}
+	}
+	for key, value := range c.commands[cmd.Name()] {
+		if cmd.Flags().Lookup(key) == nil {
+			return fmt.Errorf("unknown %s option %q in config file", cmd.Name(), key)
+		}
+		values[key] = value
+	}
+
+	// Flags set from the config are marked as changed too, so collect the
+	// command line ones before applying anything
+	given := make(map[string]bool)
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		given[f.Name] = true
+	})
+
+	for key, value := range values {
+		if given[key] || excludedBy(cmd.Flags().Lookup(key), given) {
+			continue
+		}
+		for _, v := range value {
+			if err := cmd.Flags().Set(key, v); err != nil {
+				return fmt.Errorf("invalid value %q for %s in config file: %w", v, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// excludedBy reports whether f is mutually exclusive with any of the given
+// flags
+func excludedBy(f *pflag.Flag, given map[string]bool) bool {
+	for _, group := range f.Annotations[mutuallyExclusiveAnnotation] {
+		for _, name := range strings.Fields(group) {
+			if name != f.Name && given[name] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseConfig parses the YAML subset used by config files: top-level
+// "key: value" pairs, one level of command sections holding their own
+// pairs, and lists written as "- item" lines below a key. Comments start
+// with #.
+func parseConfig(data []byte) (config, error) {
+	cfg := config{
+		global:   make(map[string][]string),
+		commands: make(map[string]map[string][]string),
+	}
+
+	// section is the command whose keys are being read, and listKey the
+	// key that "- item" lines are appended to
+	var section string
+	var listKey string
+	var listTarget map[string][]string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		raw := stripComment(scanner.Text())
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			continue
+		}
+		indented := raw[0] == ' ' || raw[0] == '\t'
+
+		if item, ok := strings.CutPrefix(line, "- "); ok || line == "-" {
+			if listTarget == nil {
+				return config{}, fmt.Errorf("line %d: list item without a key", lineNumber)
+			}
+			listTarget[listKey] = append(listTarget[listKey], unquote(strings.TrimSpace(item)))
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return config{}, fmt.Errorf("line %d: expected key: value", lineNumber)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		target := cfg.global
+		if indented {
+			if section == "" {
+				return config{}, fmt.Errorf("line %d: unexpected indentation", lineNumber)
+			}
+			target = cfg.commands[section]
+		} else {
+			section = ""
+		}
+
+		if value != "" {
+			target[key] = []string{unquote(value)}
+			listTarget = nil
+			continue
+		}
+
+		// A key without a value starts a list, or a command section when
+		// it is not indented and followed by keys
+		listKey, listTarget = key, target
+		if !indented {
+			section = key
+			cfg.commands[key] = make(map[string][]string)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return config{}, err
+	}
+	return cfg, nil
+}
+
+// stripComment removes a # comment outside of quotes from a line
+func stripComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// unquote removes matching single or double quotes around a value
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
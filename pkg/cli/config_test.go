@@ -0,0 +1,221 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const testConfig = `# shared defaults
+attribute: service.name
+threshold: 5
+
+compare:
+  threshold: 20 # overrides the top-level value
+  output: json
+  exclude-span:
+    - "^health"
+    - '^ping'
+
+info:
+  top: 3
+`
+
+// newConfigTestCommand returns a command with a few of the compare flags
+func newConfigTestCommand(name string) *cobra.Command {
+	cmd := &cobra.Command{Use: name}
+	cmd.Flags().StringP("attribute", "a", "trace_id", "")
+	cmd.Flags().Float64("threshold", 10, "")
+	cmd.Flags().StringP("output", "o", "markdown", "")
+	cmd.Flags().StringArray("exclude-span", nil, "")
+	return cmd
+}
+
+func TestConfigOverrideOrder(t *testing.T) {
+	cfg, err := parseConfig([]byte(testConfig))
+	if err != nil {
+		t.Fatalf("parseConfig() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want map[string]string
+	}{
+		{
+			name: "config values replace built-in defaults",
+			args: nil,
+			want: map[string]string{
+				"attribute":    "service.name",
+				"threshold":    "20",
+				"output":       "json",
+				"exclude-span": "[^health,^ping]",
+			},
+		},
+		{
+			name: "command line flags override the config",
+			args: []string{"--threshold", "1", "-a", "trace_id", "--exclude-span", "^db"},
+			want: map[string]string{
+				"attribute":    "trace_id",
+				"threshold":    "1",
+				"output":       "json",
+				"exclude-span": "[^db]",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := newConfigTestCommand("compare")
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatalf("ParseFlags() error = %v", err)
+			}
+			if err := cfg.apply(cmd); err != nil {
+				t.Fatalf("apply() error = %v", err)
+			}
+			got := make(map[string]string)
+			for name := range tt.want {
+				got[name] = cmd.Flags().Lookup(name).Value.String()
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("flags = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigTopLevelKeysApplyToCommandsWithTheFlag(t *testing.T) {
+	cfg, err := parseConfig([]byte(testConfig))
+	if err != nil {
+		t.Fatalf("parseConfig() error = %v", err)
+	}
+
+	cmd := &cobra.Command{Use: "info"}
+	cmd.Flags().StringP("attribute", "a", "trace_id", "")
+	cmd.Flags().Int("top", 0, "")
+	if err := cfg.apply(cmd); err != nil {
+		t.Fatalf("apply() error = %v", err)
+	}
+	if got := cmd.Flags().Lookup("attribute").Value.String(); got != "service.name" {
+		t.Errorf("attribute = %q, want %q", got, "service.name")
+	}
+	if got := cmd.Flags().Lookup("top").Value.String(); got != "3" {
+		t.Errorf("top = %q, want %q", got, "3")
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{name: "unknown command option", config: "compare:\n  colour: red\n", wantErr: `unknown compare option "colour"`},
+		{name: "invalid value", config: "compare:\n  threshold: high\n", wantErr: `invalid value "high" for threshold`},
+		{name: "missing colon", config: "threshold 5\n", wantErr: "line 1"},
+		{name: "list item without key", config: "- foo\n", wantErr: "line 1: list item without a key"},
+		{name: "unexpected indentation", config: "  threshold: 5\n", wantErr: "line 1: unexpected indentation"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parseConfig([]byte(tt.config))
+			if err == nil {
+				err = cfg.apply(newConfigTestCommand("compare"))
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.yaml")
+	if err := loadConfig(newConfigTestCommand("compare"), missing); err == nil {
+		t.Error("loadConfig() with a missing explicit file should fail")
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(testConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := newConfigTestCommand("compare")
+	if err := loadConfig(cmd, path); err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if got := cmd.Flags().Lookup("output").Value.String(); got != "json" {
+		t.Errorf("output = %q, want %q", got, "json")
+	}
+}
+
+func TestConfigSkipsFlagsExcludedByTheCommandLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		args   []string
+		want   map[string]string
+	}{
+		{
+			name:   "git refs replace a configured input",
+			config: "compare:\n  input:\n    - base.json\n    - head.json\n",
+			args:   []string{"--base", "main", "--head", "HEAD", "--path", "traces.json"},
+			want:   map[string]string{"input": "[]", "base": "main"},
+		},
+		{
+			name:   "against-baseline replaces a configured baseline",
+			config: "compare:\n  baseline: main.json\n",
+			args:   []string{"-i", "head.json", "--against-baseline", "main.baseline.json"},
+			want:   map[string]string{"baseline": "", "against-baseline": "main.baseline.json"},
+		},
+		{
+			name:   "configured input satisfies the required group",
+			config: "compare:\n  input: head.json\n",
+			args:   nil,
+			want:   map[string]string{"input": "[head.json]", "base": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parseConfig([]byte(tt.config))
+			if err != nil {
+				t.Fatalf("parseConfig() error = %v", err)
+			}
+
+			cmd := &cobra.Command{
+				Use:               "compare",
+				PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return cfg.apply(cmd) },
+				RunE:              func(cmd *cobra.Command, args []string) error { return nil },
+			}
+			cmd.Flags().StringArrayP("input", "i", []string{}, "")
+			cmd.Flags().String("against-baseline", "", "")
+			cmd.Flags().String("baseline", "", "")
+			cmd.Flags().String("base", "", "")
+			cmd.Flags().String("head", "", "")
+			cmd.Flags().String("path", "", "")
+			cmd.MarkFlagsRequiredTogether("base", "head", "path")
+			cmd.MarkFlagsMutuallyExclusive("input", "base")
+			cmd.MarkFlagsMutuallyExclusive("baseline", "base")
+			cmd.MarkFlagsMutuallyExclusive("against-baseline", "base")
+			cmd.MarkFlagsMutuallyExclusive("against-baseline", "baseline")
+			cmd.MarkFlagsOneRequired("input", "base")
+			cmd.SetArgs(tt.args)
+
+			if err := cmd.Execute(); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			got := make(map[string]string)
+			for name := range tt.want {
+				got[name] = cmd.Flags().Lookup(name).Value.String()
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("flags = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lpcalisi/otelcompare/pkg/trace"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffAttribute string
+	diffThreshold float64
+	diffFormat    string
+	diffNoColor   bool
+)
+
+// errTracesDiffer makes diff exit with ExitRegression when the inputs
+// differ
+var errTracesDiffer error = &ExitError{Code: ExitRegression, Err: errors.New("traces differ")}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <file1> <file2>",
+	Short: "Print the differences between two trace files",
+	Long: `Print a terse diff of two trace files and exit with a non-zero status if
+they differ in structure, attributes or durations beyond the threshold.
+For example:
+  otelcompare diff baseline.json current.json
+  otelcompare diff baseline.json current.json --threshold 5`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Arguments are valid at this point, so errors are not usage mistakes
+		cmd.SilenceUsage = true
+		if err := expandEnvFlags(cmd, "attribute"); err != nil {
+			return err
+		}
+		return runDiff(args[0], args[1])
+	},
+}
+
+func init() {
+	diffCmd.Flags().StringVarP(&diffAttribute, "attribute", "a", "trace_id", "Attribute to use for trace identification (name, structure, trace_id or a trace attribute)")
+	diffCmd.Flags().Float64Var(&diffThreshold, "threshold", 10, "Duration change, as a percentage of the first file's duration, that counts as a difference")
+	diffCmd.Flags().BoolVar(&diffNoColor, "no-color", false, "Do not color slower and faster durations when printing to a terminal")
+	diffCmd.Flags().StringVar(&diffFormat, "format", "json", "Input trace format: json, ndjson, zipkin or otlp-proto")
+
+	rootCmd.AddCommand(diffCmd)
+}
+
+func runDiff(file1, file2 string) error {
+	traceSets, err := loadTraceSets([]string{file1, file2}, inputOptions{format: diffFormat, strict: strictInput})
+	if err != nil {
+		return err
+	}
+	if len(traceSets) != 2 {
+		return fmt.Errorf("diff needs exactly two trace files, got %d inputs", len(traceSets))
+	}
+
+	diff, differ := trace.GenerateDiff(traceSets[0], traceSets[1], diffAttribute, diffThreshold)
+	if !differ {
+		return nil
+	}
+	if useColor(diffNoColor) {
+		diff = colorizeDiff(diff)
+	}
+	fmt.Print(diff)
+	return errTracesDiffer
+}
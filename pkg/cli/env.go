@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// expandEnvFlags expands ${VAR} and $VAR references to environment
+// variables in the values of the named flags, so CI pipelines can pass
+// e.g. --owner ${GITHUB_REPOSITORY_OWNER}
+func expandEnvFlags(cmd *cobra.Command, names ...string) error {
+	for _, name := range names {
+		flag := cmd.Flags().Lookup(name)
+		value, err := expandEnv(flag.Value.String())
+		if err != nil {
+			return fmt.Errorf("invalid --%s: %w", name, err)
+		}
+		if err := flag.Value.Set(value); err != nil {
+			return fmt.Errorf("invalid --%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// expandEnv expands references to environment variables like os.ExpandEnv,
+// but fails when a variable is not set instead of leaving it empty
+func expandEnv(s string) (string, error) {
+	var missing []string
+	expanded := os.Expand(s, func(name string) string {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
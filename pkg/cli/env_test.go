@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("OTELCOMPARE_TEST_OWNER", "my-org")
+	t.Setenv("OTELCOMPARE_TEST_EMPTY", "")
+
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+		wantErr  string
+	}{
+		{name: "literal", value: "my-org", expected: "my-org"},
+		{name: "braces", value: "${OTELCOMPARE_TEST_OWNER}", expected: "my-org"},
+		{name: "bare", value: "$OTELCOMPARE_TEST_OWNER/api", expected: "my-org/api"},
+		{name: "set but empty", value: "${OTELCOMPARE_TEST_EMPTY}", expected: ""},
+		{name: "unset", value: "${OTELCOMPARE_TEST_UNSET}", wantErr: "OTELCOMPARE_TEST_UNSET is not set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandEnv(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expandEnv() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expandEnv() error = %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expandEnv() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExpandEnvFlags(t *testing.T) {
+	t.Setenv("OTELCOMPARE_TEST_OWNER", "my-org")
+
+	var owner, repo string
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().StringVar(&owner, "owner", "", "")
+	cmd.Flags().StringVar(&repo, "repo", "", "")
+	if err := cmd.ParseFlags([]string{"--owner", "${OTELCOMPARE_TEST_OWNER}", "--repo", "${OTELCOMPARE_TEST_UNSET}"}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := expandEnvFlags(cmd, "owner", "repo")
+	if owner != "my-org" {
+		t.Errorf("--owner = %q, want it expanded to my-org", owner)
+	}
+	if err == nil || !strings.Contains(err.Error(), "--repo") {
+		t.Errorf("expandEnvFlags() error = %v, want it to name --repo", err)
+	}
+}
@@ -0,0 +1,56 @@
+package cli
+
+import "errors"
+
+// Exit codes of otelcompare, which CI scripts can rely on
+const (
+	// ExitOK means the command succeeded without finding a regression
+	ExitOK = 0
+
+	// ExitRegression means a regression was found with
+	// --fail-on-regression, the diff command found a difference or the
+	// validate command found an error
+	ExitRegression = 1
+
+	// ExitUsage means invalid flags or arguments, or input that could not
+	// be read or parsed
+	ExitUsage = 2
+
+	// ExitAPI means a GitHub, GitLab or Slack API call failed
+	ExitAPI = 3
+)
+
+// ExitError is an error that ends the command with a specific exit code
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// apiError marks err as a failed API call
+func apiError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ExitError{Code: ExitAPI, Err: err}
+}
+
+// ExitCode returns the exit code for an error returned by a command. Errors
+// that are not an ExitError are usage or parse errors.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return ExitUsage
+}
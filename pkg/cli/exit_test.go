@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/lpcalisi/otelcompare/pkg/trace"
+)
+
+func TestExitCode(t *testing.T) {
+	compareFailOnRegression = true
+	defer func() { compareFailOnRegression = false }()
+	regressions := []trace.DurationChange{{Name: "GET /users", File: "head"}}
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "success", err: nil, want: ExitOK},
+		{name: "regression", err: regressionError(regressions), want: ExitRegression},
+		{name: "no regression", err: regressionError(nil), want: ExitOK},
+		{name: "traces differ", err: errTracesDiffer, want: ExitRegression},
+		{name: "usage error", err: errors.New("unknown flag: --foo"), want: ExitUsage},
+		{name: "api error", err: apiError(errors.New("401 Bad credentials")), want: ExitAPI},
+		{name: "wrapped api error", err: fmt.Errorf("error commenting on PR: %w", apiError(errors.New("404 Not Found"))), want: ExitAPI},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.want {
+				t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteReturnsExitError(t *testing.T) {
+	rootCmd.SetArgs([]string{"diff", "only-one.json"})
+	rootCmd.SetOut(io.Discard)
+	rootCmd.SetErr(io.Discard)
+	defer func() {
+		rootCmd.SetArgs(nil)
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+	}()
+
+	err := Execute()
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("Execute() error = %v, want an *ExitError", err)
+	}
+	if exitErr.Code != ExitUsage {
+		t.Errorf("Execute() exit code = %d, want %d", exitErr.Code, ExitUsage)
+	}
+	if !strings.Contains(err.Error(), "accepts 2 arg(s)") {
+		t.Errorf("Execute() error = %q, want cobra's message", err)
+	}
+}
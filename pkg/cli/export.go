@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lpcalisi/otelcompare/pkg/trace"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportInputFile  string
+	exportFormat     string
+	exportOutputFile string
+	exportPretty     bool
+	exportBaseline   bool
+	exportName       string
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Convert traces to the tool's own JSON trace format",
+	Long: `Parse a trace file in any supported format and write its traces in the
+tool's own JSON trace format, for example to archive a normalized copy.
+With --as-baseline the traces are written as a baseline artifact that
+compare --against-baseline reads back.
+For example:
+  otelcompare export -i spans.json --format zipkin -o traces.json
+  otelcompare export -i traces.pb --format otlp-proto --pretty
+  otelcompare export -i main.json --as-baseline --baseline-name main -o baseline.json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Flags are valid at this point, so errors are not usage mistakes
+		cmd.SilenceUsage = true
+		return runExport(exportInputFile)
+	},
+}
+
+func init() {
+	exportCmd.Flags().StringVarP(&exportInputFile, "input", "i", "", "Input JSON file or http(s) URL containing traces")
+	exportCmd.Flags().StringVar(&exportFormat, "format", "json", "Input trace format: json, ndjson, zipkin or otlp-proto")
+	exportCmd.Flags().StringVarP(&exportOutputFile, "output", "o", "", "File to write the traces to (default: stdout)")
+	exportCmd.Flags().BoolVar(&exportPretty, "pretty", false, "Indent the JSON for reading")
+	exportCmd.Flags().BoolVar(&exportBaseline, "as-baseline", false, "Write a baseline artifact with the traces and metadata, for compare --against-baseline")
+	exportCmd.Flags().StringVar(&exportName, "baseline-name", "", "Name of the baseline in reports (default: the input file name)")
+
+	exportCmd.MarkFlagRequired("input")
+
+	rootCmd.AddCommand(exportCmd)
+}
+
+func runExport(inputFile string) error {
+	traces, err := loadTraces(inputFile, inputOptions{format: exportFormat, strict: strictInput})
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	if exportBaseline {
+		name := exportName
+		if name == "" {
+			name = strings.TrimSuffix(filepath.Base(inputFile), filepath.Ext(inputFile))
+		}
+		data, err = trace.MarshalBaseline(trace.Baseline{
+			Name:        name,
+			CreatedAt:   time.Now().UTC(),
+			ToolVersion: toolVersion(),
+			Source:      inputFile,
+			Traces:      traces,
+		}, exportPretty)
+	} else {
+		data, err = trace.MarshalTraces(traces, exportPretty)
+	}
+	if err != nil {
+		return fmt.Errorf("error marshaling traces: %w", err)
+	}
+	data = append(data, '\n')
+
+	if exportOutputFile == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(exportOutputFile, data, 0o644); err != nil {
+		return fmt.Errorf("error writing %s: %w", exportOutputFile, err)
+	}
+	return nil
+}
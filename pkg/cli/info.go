@@ -2,36 +2,88 @@ package cli
 
 import (
 	"fmt"
-	"io/ioutil"
-	"os"
+	"time"
 
-	"github.com/lpcalisi/otelcompare/pkg/github"
 	"github.com/lpcalisi/otelcompare/pkg/trace"
 	"github.com/spf13/cobra"
 )
 
 var (
-	infoInputFile string
-	infoPrNumber  int
-	infoOwner     string
-	infoRepo      string
-	infoDryRun    bool
+	infoInputFile   string
+	infoPrNumber    int
+	infoOwner       string
+	infoRepo        string
+	infoDryRun      bool
+	infoSectionBy   string
+	infoFold        bool
+	infoFormat      string
+	infoNewComment  bool
+	infoOutput      string
+	infoMermaid     bool
+	infoAggregate   bool
+	infoAttribute   string
+	infoService     string
+	infoIDLength    int
+	infoFullIDs     bool
+	infoProvider    string
+	infoTop         int
+	infoGitHubURL   string
+	infoCardinality int
+	infoSortBy      string
+	infoHistogram   bool
+	infoBuckets     int
+	infoMinDuration time.Duration
+	infoTimestamps  bool
+	infoScope       string
+	infoOutliers    bool
+	infoMaxDepth    int
+	infoNoEmoji     bool
 )
 
 var infoCmd = &cobra.Command{
 	Use:   "info",
 	Short: "Generate trace information for a GitHub PR",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		// Flags are valid at this point, so errors are not usage mistakes
+		cmd.SilenceUsage = true
+		if err := expandEnvFlags(cmd, "owner", "repo", "attribute"); err != nil {
+			return err
+		}
 		return runInfo(infoInputFile)
 	},
 }
 
 func init() {
-	infoCmd.Flags().StringVarP(&infoInputFile, "input", "i", "", "Input JSON file containing traces")
-	infoCmd.Flags().IntVarP(&infoPrNumber, "pr", "p", 0, "Pull request number to comment on")
-	infoCmd.Flags().StringVar(&infoOwner, "owner", "", "GitHub repository owner")
-	infoCmd.Flags().StringVar(&infoRepo, "repo", "", "GitHub repository name")
-	infoCmd.Flags().BoolVar(&infoDryRun, "dry-run", false, "Print comment to stdout without posting to GitHub")
+	infoCmd.Flags().StringVarP(&infoInputFile, "input", "i", "", "Input JSON file or http(s) URL containing traces")
+	infoCmd.Flags().IntVarP(&infoPrNumber, "pr", "p", 0, "Pull request (or GitLab merge request) number to comment on")
+	infoCmd.Flags().StringVar(&infoOwner, "owner", "", "Repository owner (GitLab group path)")
+	infoCmd.Flags().StringVar(&infoRepo, "repo", "", "Repository name")
+	infoCmd.Flags().StringVar(&infoProvider, "provider", "github", "Code host to comment on: github or gitlab")
+	infoCmd.Flags().StringVar(&infoGitHubURL, "github-url", "", "GitHub Enterprise URL (default: $GITHUB_API_URL, or github.com)")
+	infoCmd.Flags().BoolVar(&infoDryRun, "dry-run", false, "Print comment to stdout without posting it")
+	infoCmd.Flags().BoolVar(&infoNewComment, "new-comment", false, "Always post a new comment instead of updating the previous otelcompare comment")
+	infoCmd.Flags().StringVar(&infoSectionBy, "section-by", "", "Span attribute used to group span details under subheaders (e.g. team)")
+
+	infoCmd.Flags().StringVar(&infoService, "service", "", "Only report spans whose service.name is this service")
+	infoCmd.Flags().StringVar(&infoScope, "scope", "", "Only report spans created by the instrumentation scope with this name")
+	infoCmd.Flags().DurationVar(&infoMinDuration, "min-duration", 0, "Drop spans shorter than this (e.g. 1ms); their children move up to the nearest kept ancestor")
+	infoCmd.Flags().StringVar(&infoFormat, "format", "json", "Input trace format: json, ndjson, zipkin or otlp-proto")
+	infoCmd.Flags().StringVarP(&infoOutput, "output", "o", "markdown", "Output format: markdown or html")
+	infoCmd.Flags().BoolVar(&infoAggregate, "aggregate", false, "Report count and p50/p90/p99/max durations per trace group instead of a row per trace")
+	infoCmd.Flags().StringVarP(&infoAttribute, "attribute", "a", "name", "Attribute used to group traces with --aggregate and --outliers")
+	infoCmd.Flags().StringVar(&infoSortBy, "sort-by", trace.SortByDuration, "Order of the traces in the report: duration, name or spancount")
+	infoCmd.Flags().IntVar(&infoTop, "top", 0, "Only report the N slowest traces (0 reports all)")
+	infoCmd.Flags().IntVar(&infoIDLength, "id-length", 8, "Number of characters span IDs are truncated to")
+	infoCmd.Flags().BoolVar(&infoFullIDs, "full-ids", false, "Print span IDs without truncating them")
+	infoCmd.Flags().BoolVar(&infoTimestamps, "show-timestamps", false, "Add the UTC start and end time of every span to the span details")
+	infoCmd.Flags().BoolVar(&infoMermaid, "mermaid", false, "Add a Mermaid gantt diagram of each trace to its details")
+	infoCmd.Flags().IntVar(&infoCardinality, "cardinality-threshold", 50, "Warn about span attributes with more distinct values than this across all traces (0 disables)")
+	infoCmd.Flags().BoolVar(&infoOutliers, "outliers", false, "List traces more than 3 standard deviations slower than the other traces of their operation")
+	infoCmd.Flags().BoolVar(&infoHistogram, "histogram", false, "Add a histogram of the span durations of every operation")
+	infoCmd.Flags().IntVar(&infoBuckets, "histogram-buckets", 10, "Number of buckets of each --histogram")
+	infoCmd.Flags().BoolVar(&infoFold, "fold-repeats", false, "Fold consecutive sibling spans with the same name into a single line")
+	infoCmd.Flags().BoolVar(&infoNoEmoji, "no-emoji", false, "Use plain ASCII markers such as [WARN] instead of emoji")
+	infoCmd.Flags().IntVar(&infoMaxDepth, "max-depth", 0, "Show this many levels of the span hierarchy and only count deeper spans (0 shows every level)")
 
 	infoCmd.MarkFlagRequired("input")
 
@@ -39,20 +91,72 @@ func init() {
 }
 
 func runInfo(inputFile string) error {
-	// Read input file
-	data, err := ioutil.ReadFile(inputFile)
+	// Read and parse the input file
+	traces, err := loadTraces(inputFile, inputOptions{
+		format:      infoFormat,
+		service:     infoService,
+		scope:       infoScope,
+		minDuration: infoMinDuration,
+		strict:      strictInput,
+	})
 	if err != nil {
-		return fmt.Errorf("error reading input file: %w", err)
+		return err
 	}
 
-	// Parse traces
-	traces, err := trace.ParseTraces(data)
-	if err != nil {
-		return fmt.Errorf("error parsing traces: %w", err)
+	// HTML pages are meant for local viewing, so they are printed to stdout
+	switch infoOutput {
+	case "markdown":
+	case "html":
+		fmt.Print(trace.GenerateHTML(traces))
+		return nil
+	default:
+		return fmt.Errorf("unsupported output %q: must be markdown or html", infoOutput)
 	}
 
 	// Generate Markdown for the PR comment
-	markdown := trace.GenerateMarkdown(traces)
+	opts := trace.Options{
+		SectionBy:      infoSectionBy,
+		FoldRepeats:    infoFold,
+		MaxSpanDepth:   infoMaxDepth,
+		Mermaid:        infoMermaid,
+		Top:            infoTop,
+		ShowTimestamps: infoTimestamps,
+		NoEmoji:        infoNoEmoji,
+		Footer:         &trace.Footer{Version: toolVersion()},
+	}
+	switch infoSortBy {
+	case trace.SortByDuration, trace.SortByName, trace.SortBySpanCount:
+		opts.SortBy = infoSortBy
+	default:
+		return fmt.Errorf("unsupported --sort-by %q: must be duration, name or spancount", infoSortBy)
+	}
+	if infoHistogram {
+		if infoBuckets <= 0 {
+			return fmt.Errorf("--histogram-buckets must be positive")
+		}
+		opts.Histogram = infoBuckets
+	}
+	if infoMaxDepth < 0 {
+		return fmt.Errorf("--max-depth must not be negative")
+	}
+	if infoCardinality < 0 {
+		return fmt.Errorf("--cardinality-threshold must not be negative")
+	}
+	opts.CardinalityThreshold = infoCardinality
+	if infoAggregate {
+		opts.AggregateBy = infoAttribute
+	}
+	if infoOutliers {
+		opts.OutliersBy = infoAttribute
+	}
+	if infoIDLength <= 0 {
+		return fmt.Errorf("--id-length must be positive, use --full-ids to disable truncation")
+	}
+	opts.IDLength = infoIDLength
+	if infoFullIDs {
+		opts.IDLength = -1
+	}
+	markdown := trace.GenerateMarkdown(traces, opts)
 	comment := fmt.Sprintf("### OpenTelemetry Traces Analysis\n\n%s", markdown)
 
 	// If dry-run, just print to stdout
@@ -61,7 +165,7 @@ func runInfo(inputFile string) error {
 		return nil
 	}
 
-	// Validate GitHub flags if not dry-run
+	// Validate comment flags if not dry-run
 	if infoPrNumber == 0 {
 		return fmt.Errorf("--pr is required when not using --dry-run")
 	}
@@ -69,15 +173,14 @@ func runInfo(inputFile string) error {
 		return fmt.Errorf("--owner and --repo are required when not using --dry-run")
 	}
 
-	// Get GitHub token from environment
-	token := os.Getenv("GITHUB_TOKEN")
-	if token == "" {
-		return fmt.Errorf("GITHUB_TOKEN environment variable is required when not using --dry-run")
-	}
-
 	// Comment on the PR
-	client := github.NewClient(token)
-	if err := client.CommentPR(infoOwner, infoRepo, infoPrNumber, comment); err != nil {
+	if err := postComment(commentTarget{
+		provider:  infoProvider,
+		githubURL: infoGitHubURL,
+		owner:     infoOwner,
+		repo:      infoRepo,
+		number:    infoPrNumber,
+	}, "info", comment, infoNewComment); err != nil {
 		return fmt.Errorf("error commenting on PR: %w", err)
 	}
 
@@ -0,0 +1,687 @@
+package cli
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lpcalisi/otelcompare/pkg/trace"
+)
+
+// inputOptions controls how input files are parsed
+type inputOptions struct {
+	// format is the trace format of the input files: json, ndjson, zipkin
+	// or otlp-proto
+	format string
+
+	// service keeps only the spans of this service when set
+	service string
+
+	// scope keeps only the spans of this instrumentation scope when set
+	scope string
+
+	// includeSpans and excludeSpans filter spans by name
+	includeSpans []*regexp.Regexp
+	excludeSpans []*regexp.Regexp
+
+	// minDuration drops spans shorter than this when positive
+	minDuration time.Duration
+
+	// collapseSpans merges the spans of each name within a trace
+	collapseSpans bool
+
+	// strict fails on input that does not look like traces instead of
+	// warning about it
+	strict bool
+}
+
+// loadTraceSets reads and parses every input. Regular files become a single
+// TraceSet named after the file, while archives contribute one TraceSet per
+// JSON entry, named by the entry's path inside the archive. Inputs given as
+// path=Label are named by their label instead.
+func loadTraceSets(inputs []string, opts inputOptions) ([]trace.TraceSet, error) {
+	var files []inputFile
+	for _, input := range inputs {
+		input, label := splitInputLabel(input)
+		inputFiles, err := readInputFiles(input)
+		if err != nil {
+			return nil, err
+		}
+		if label != "" {
+			if len(inputFiles) != 1 {
+				return nil, fmt.Errorf("label %q needs %s to hold a single trace file, but it holds %d", label, input, len(inputFiles))
+			}
+			inputFiles[0].name = label
+		}
+		files = append(files, inputFiles...)
+	}
+	return parseTraceSets(files, opts)
+}
+
+// splitInputLabel splits an input given as path=Label into its path and
+// label. Inputs naming an existing file are never split, and neither is an
+// = that gives a value to the last query parameter of a URL.
+func splitInputLabel(input string) (string, string) {
+	i := strings.LastIndex(input, "=")
+	if i <= 0 || i == len(input)-1 {
+		return input, ""
+	}
+	if _, err := os.Stat(input); err == nil {
+		return input, ""
+	}
+
+	path, label := input[:i], input[i+1:]
+	if isURL(path) {
+		if _, query, ok := strings.Cut(path, "?"); ok {
+			for _, param := range strings.Split(query, "&") {
+				if !strings.Contains(param, "=") {
+					return input, ""
+				}
+			}
+		}
+	}
+	return path, label
+}
+
+// expandInputPaths expands glob patterns and directories among the inputs
+// into the files they name. Matches are sorted, and directories contribute
+// the .json files they directly contain. Other inputs are kept as given. A
+// labelled input keeps its label and must expand to a single file.
+func expandInputPaths(inputs []string) ([]string, error) {
+	var paths []string
+	for _, input := range inputs {
+		input, label := splitInputLabel(input)
+		expanded, err := expandInputPath(input)
+		if err != nil {
+			return nil, err
+		}
+		if label == "" {
+			paths = append(paths, expanded...)
+			continue
+		}
+		if len(expanded) != 1 {
+			return nil, fmt.Errorf("label %q needs %s to name a single file, but it matches %d", label, input, len(expanded))
+		}
+		paths = append(paths, expanded[0]+"="+label)
+	}
+	return paths, nil
+}
+
+// expandInputPath expands a single glob pattern or directory into the files
+// it names
+func expandInputPath(input string) ([]string, error) {
+	var paths []string
+	matches := []string{input}
+	if isGlob(input) {
+		var err error
+		matches, err = filepath.Glob(input)
+		if err != nil {
+			return nil, fmt.Errorf("invalid input pattern %q: %w", input, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("input pattern %q matches no files", input)
+		}
+		sort.Strings(matches)
+	}
+
+	for _, match := range matches {
+		if isURL(match) {
+			paths = append(paths, match)
+			continue
+		}
+		info, err := os.Stat(match)
+		if err != nil || !info.IsDir() {
+			// Missing files are reported when they are read
+			paths = append(paths, match)
+			continue
+		}
+		files, err := jsonFilesInDir(match)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, files...)
+	}
+	return paths, nil
+}
+
+// withBaseline moves the baseline to the front of the inputs, adding it
+// when it is not among them. The baseline must name a single trace file.
+// An unlabelled baseline keeps the label it was given among the inputs.
+func withBaseline(inputs []string, baseline string) ([]string, error) {
+	path, label := splitInputLabel(baseline)
+	if isArchive(path) || isGlob(path) {
+		return nil, fmt.Errorf("baseline %s must be a single trace file", path)
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return nil, fmt.Errorf("baseline %s must be a single trace file", path)
+	}
+
+	ordered := []string{baseline}
+	for _, input := range inputs {
+		inputPath, inputLabel := splitInputLabel(input)
+		if filepath.Clean(inputPath) != filepath.Clean(path) {
+			ordered = append(ordered, input)
+		} else if label == "" && inputLabel != "" {
+			ordered[0] = input
+		}
+	}
+	return ordered, nil
+}
+
+// isGlob reports whether an input contains glob metacharacters. URLs are
+// never globs, as their query strings start with a question mark.
+func isGlob(input string) bool {
+	return !isURL(input) && strings.ContainsAny(input, "*?[")
+}
+
+// jsonFilesInDir returns the sorted paths of the .json files in a directory
+func jsonFilesInDir(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading directory %s: %w", dir, err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && isJSONEntry(entry.Name()) {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("directory %s contains no JSON files", dir)
+	}
+	return files, nil
+}
+
+// loadGitTraceSets reads and parses the file at path as of each git ref.
+// Each TraceSet is named ref:path.
+func loadGitTraceSets(refs []string, path string, opts inputOptions) ([]trace.TraceSet, error) {
+	var files []inputFile
+	for _, ref := range refs {
+		data, err := readGitFile(ref, path)
+		if err != nil {
+			return nil, err
+		}
+		inputFiles, err := expandInputFile(ref+":"+path, data)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, inputFiles...)
+	}
+	return parseTraceSets(files, opts)
+}
+
+// parseTraceSets parses and filters every input file into a TraceSet
+func parseTraceSets(files []inputFile, opts inputOptions) ([]trace.TraceSet, error) {
+	parsed, err := parseInputFiles(files, opts.format, runtime.GOMAXPROCS(0))
+	if err != nil {
+		return nil, err
+	}
+
+	var traceSets []trace.TraceSet
+	for i, file := range files {
+		traces := parsed[i]
+		if err := checkShape(file.name, traces, opts.strict); err != nil {
+			return nil, err
+		}
+
+		traceSets = append(traceSets, trace.TraceSet{
+			Name:   file.name,
+			Traces: filterTraces(traces, opts),
+		})
+	}
+
+	if opts.service != "" && !hasTraces(traceSets) {
+		return nil, fmt.Errorf("no spans found for service %q in the input files", opts.service)
+	}
+	if opts.scope != "" && !hasTraces(traceSets) {
+		return nil, fmt.Errorf("no spans found for instrumentation scope %q in the input files", opts.scope)
+	}
+	return traceSets, nil
+}
+
+// filterTraces applies the service, scope, span name and duration filters
+// of the options, then collapses spans by name if requested
+func filterTraces(traces []trace.Trace, opts inputOptions) []trace.Trace {
+	if opts.service != "" {
+		traces = trace.FilterByService(traces, opts.service)
+	}
+	if opts.scope != "" {
+		traces = trace.FilterByScope(traces, opts.scope)
+	}
+	traces = trace.FilterSpans(traces, opts.includeSpans, opts.excludeSpans)
+	traces = trace.FilterByMinDuration(traces, opts.minDuration)
+	if opts.collapseSpans {
+		traces = trace.CollapseSpans(traces)
+	}
+	return traces
+}
+
+// loadBaseline reads a baseline artifact from a file or http(s) URL and
+// filters its traces like those of the other inputs
+func loadBaseline(input string, opts inputOptions) (trace.TraceSet, error) {
+	var data []byte
+	if isURL(input) {
+		var err error
+		if data, err = fetchURL(input); err != nil {
+			return trace.TraceSet{}, err
+		}
+		if isGzip(data) {
+			if data, err = gunzip(data); err != nil {
+				return trace.TraceSet{}, fmt.Errorf("error decompressing %s: %w", input, err)
+			}
+		}
+	} else {
+		r, err := openInputFile(input)
+		if err != nil {
+			return trace.TraceSet{}, err
+		}
+		data, err = io.ReadAll(r)
+		if closeErr := r.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return trace.TraceSet{}, fmt.Errorf("error reading file %s: %w", input, err)
+		}
+	}
+
+	baseline, err := trace.ParseBaseline(data)
+	if errors.Is(err, trace.ErrEmptyInput) {
+		return trace.TraceSet{}, fmt.Errorf("no baseline found in %s: the file is empty", input)
+	}
+	if err != nil {
+		return trace.TraceSet{}, fmt.Errorf("error reading baseline from %s: %w", input, err)
+	}
+	set := baseline.TraceSet()
+	set.Traces = filterTraces(set.Traces, opts)
+	return set, nil
+}
+
+// parseInputFiles parses input files concurrently with up to workers
+// files at a time, returning the traces of each file in the order of the
+// files. The first file that fails to parse cancels the others.
+func parseInputFiles(files []inputFile, format string, workers int) ([][]trace.Trace, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	jobs := make(chan int)
+	parsed := make([][]trace.Trace, len(files))
+	errs := make([]error, len(files))
+	var wg sync.WaitGroup
+	for range min(max(workers, 1), len(files)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				parsed[i], errs[i] = parseInputFile(ctx, files[i], format)
+				if errs[i] != nil {
+					cancel()
+				}
+			}
+		}()
+	}
+
+	// Stop handing out files once one of them fails
+	for i := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Report the first file that failed on its own rather than one that
+	// was cancelled because of it
+	for i, err := range errs {
+		if errors.Is(err, trace.ErrEmptyInput) {
+			return nil, fmt.Errorf("no traces found in %s: the file is empty", files[i].name)
+		}
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return nil, fmt.Errorf("error parsing traces from %s: %w", files[i].name, err)
+		}
+	}
+	return parsed, nil
+}
+
+// checkShape warns about parsed traces that do not look like traces, or
+// fails on them when strict
+func checkShape(name string, traces []trace.Trace, strict bool) error {
+	warnings := trace.CheckShape(traces)
+	if len(warnings) == 0 {
+		return nil
+	}
+	if strict {
+		return fmt.Errorf("invalid traces in %s: %s", name, strings.Join(warnings, "; "))
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", name, warning)
+	}
+	return nil
+}
+
+// compilePatterns compiles the regular expressions given to a flag
+func compilePatterns(flag string, patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --%s pattern %q: %w", flag, pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// hasTraces reports whether any trace set contains a trace
+func hasTraces(traceSets []trace.TraceSet) bool {
+	for _, set := range traceSets {
+		if len(set.Traces) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// loadTraces reads and parses a single input and returns all of its traces
+func loadTraces(input string, opts inputOptions) ([]trace.Trace, error) {
+	traceSets, err := loadTraceSets([]string{input}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var traces []trace.Trace
+	for _, set := range traceSets {
+		traces = append(traces, set.Traces...)
+	}
+	return traces, nil
+}
+
+// parseInputFile opens an input file and parses its traces. Reading stops
+// once the context is cancelled.
+func parseInputFile(ctx context.Context, file inputFile, format string) ([]trace.Trace, error) {
+	r, err := file.open()
+	if err != nil {
+		return nil, err
+	}
+	traces, err := parseTraces(contextReader{ctx: ctx, r: r}, format)
+	if closeErr := r.Close(); err == nil && closeErr != nil {
+		return nil, fmt.Errorf("error reading file %s: %w", file.name, closeErr)
+	}
+	return traces, err
+}
+
+// contextReader is a reader that fails once its context is cancelled
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// parseTraces parses trace data in the given format. JSON and NDJSON are
+// decoded as they are read; Zipkin and OTLP protobuf spans are grouped
+// after reading them all.
+func parseTraces(r io.Reader, format string) ([]trace.Trace, error) {
+	switch format {
+	case "", "json", "ndjson":
+		return trace.ParseTracesStream(r)
+	case "zipkin":
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return trace.ParseZipkin(data)
+	case "otlp-proto":
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return trace.ParseOTLPProto(data)
+	default:
+		return nil, fmt.Errorf("unsupported format %q: must be json, ndjson, zipkin or otlp-proto", format)
+	}
+}
+
+// inputFile is a single trace file, opened only when it is parsed
+type inputFile struct {
+	name string
+	open func() (io.ReadCloser, error)
+}
+
+// memoryFile returns an inputFile backed by data already in memory
+func memoryFile(name string, data []byte) inputFile {
+	return inputFile{name: name, open: func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}}
+}
+
+// readInputFiles reads an input path or URL, expanding archives into their
+// JSON entries. Other files are streamed from disk when they are parsed.
+func readInputFiles(input string) ([]inputFile, error) {
+	if isURL(input) {
+		data, err := fetchURL(input)
+		if err != nil {
+			return nil, err
+		}
+		return expandInputFile(input, data)
+	}
+	if !isArchive(input) {
+		if _, err := os.Stat(input); err != nil {
+			return nil, fmt.Errorf("error reading file %s: %w", input, err)
+		}
+		return []inputFile{{name: input, open: func() (io.ReadCloser, error) {
+			return openInputFile(input)
+		}}}, nil
+	}
+
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file %s: %w", input, err)
+	}
+	return expandInputFile(input, data)
+}
+
+// openInputFile opens a file for reading, decompressing it on the fly when
+// it is gzip-compressed
+func openInputFile(input string) (io.ReadCloser, error) {
+	f, err := os.Open(input)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file %s: %w", input, err)
+	}
+
+	br := bufio.NewReader(f)
+	magic, _ := br.Peek(2)
+	if !strings.HasSuffix(input, ".gz") && !isGzip(magic) {
+		return readCloser{Reader: br, Closer: f}, nil
+	}
+
+	gz, err := gzip.NewReader(br)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("error decompressing %s: %w", input, err)
+	}
+	return gzipReadCloser{Reader: gz, file: f}, nil
+}
+
+// readCloser pairs a reader with the closer of its underlying file
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// gzipReadCloser decompresses a gzip file as it is read. Closing it closes
+// the gzip reader and then the file.
+type gzipReadCloser struct {
+	*gzip.Reader
+	file *os.File
+}
+
+// Close closes the gzip reader and the file, returning the error of the
+// gzip reader first
+func (r gzipReadCloser) Close() error {
+	err := r.Reader.Close()
+	if fileErr := r.file.Close(); err == nil {
+		err = fileErr
+	}
+	return err
+}
+
+// isArchive reports whether an input path names a tar.gz or zip archive
+func isArchive(input string) bool {
+	return strings.HasSuffix(input, ".tar.gz") || strings.HasSuffix(input, ".tgz") || strings.HasSuffix(input, ".zip")
+}
+
+// readGitFile returns the content of a file as of a git ref
+func readGitFile(ref, path string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", "show", ref+":"+path)
+	cmd.Stderr = &stderr
+	data, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s at git ref %s: %s", path, ref, strings.TrimSpace(stderr.String()))
+	}
+	return data, nil
+}
+
+// expandInputFile decompresses gzip data and expands archives into their
+// JSON entries. The name of the input decides how it is read.
+func expandInputFile(input string, data []byte) ([]inputFile, error) {
+	var err error
+	var files []inputFile
+	var skipped int
+	switch {
+	case strings.HasSuffix(input, ".tar.gz") || strings.HasSuffix(input, ".tgz"):
+		files, skipped, err = readTarGz(data)
+	case strings.HasSuffix(input, ".zip"):
+		files, skipped, err = readZip(data)
+	case strings.HasSuffix(input, ".gz") || isGzip(data):
+		data, err = gunzip(data)
+		if err != nil {
+			return nil, fmt.Errorf("error decompressing %s: %w", input, err)
+		}
+		return []inputFile{memoryFile(input, data)}, nil
+	default:
+		return []inputFile{memoryFile(input, data)}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading archive %s: %w", input, err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("archive %s contains no JSON files", input)
+	}
+
+	fmt.Fprintf(os.Stderr, "Read %d JSON entries from %s (skipped %d)\n", len(files), input, skipped)
+	return files, nil
+}
+
+// isGzip reports whether data starts with the gzip magic bytes
+func isGzip(data []byte) bool {
+	return len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b
+}
+
+// gunzip decompresses gzip data
+func gunzip(data []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	return io.ReadAll(gz)
+}
+
+// readTarGz returns the JSON entries of a gzip-compressed tar archive and
+// the number of skipped entries
+func readTarGz(data []byte) ([]inputFile, int, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer gz.Close()
+
+	var files []inputFile
+	var skipped int
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if !isJSONEntry(header.Name) {
+			skipped++
+			continue
+		}
+
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error reading %s: %w", header.Name, err)
+		}
+		files = append(files, memoryFile(header.Name, content))
+	}
+	return files, skipped, nil
+}
+
+// readZip returns the JSON entries of a zip archive and the number of
+// skipped entries
+func readZip(data []byte) ([]inputFile, int, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var files []inputFile
+	var skipped int
+	for _, entry := range zr.File {
+		if entry.FileInfo().IsDir() {
+			continue
+		}
+		if !isJSONEntry(entry.Name) {
+			skipped++
+			continue
+		}
+
+		rc, err := entry.Open()
+		if err != nil {
+			return nil, 0, fmt.Errorf("error opening %s: %w", entry.Name, err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, 0, fmt.Errorf("error reading %s: %w", entry.Name, err)
+		}
+		files = append(files, memoryFile(entry.Name, content))
+	}
+	return files, skipped, nil
+}
+
+// isJSONEntry reports whether an archive entry holds JSON traces
+func isJSONEntry(name string) bool {
+	return strings.EqualFold(path.Ext(name), ".json")
+}
@@ -0,0 +1,543 @@
+package cli
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lpcalisi/otelcompare/pkg/trace"
+)
+
+func TestLoadTracesGzip(t *testing.T) {
+	data, err := os.ReadFile("../../examples/baseline.json")
+	if err != nil {
+		t.Fatalf("error reading fixture: %v", err)
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatalf("error compressing fixture: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("error compressing fixture: %v", err)
+	}
+
+	dir := t.TempDir()
+	tests := []struct {
+		name string
+		file string
+	}{
+		{name: "gz extension", file: "baseline.json.gz"},
+		{name: "magic bytes only", file: "baseline.json"},
+	}
+
+	want, err := loadTraces("../../examples/baseline.json", inputOptions{})
+	if err != nil {
+		t.Fatalf("loadTraces() error = %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := filepath.Join(dir, tt.file)
+			if err := os.WriteFile(input, buf.Bytes(), 0o644); err != nil {
+				t.Fatalf("error writing fixture: %v", err)
+			}
+
+			got, err := loadTraces(input, inputOptions{})
+			if err != nil {
+				t.Fatalf("loadTraces() error = %v", err)
+			}
+			if len(got) != len(want) || len(got) == 0 || got[0].TraceID != want[0].TraceID {
+				t.Errorf("loadTraces() returned %d traces, want the %d traces of the fixture", len(got), len(want))
+			}
+		})
+	}
+}
+
+func TestOpenInputFileGzipClose(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(`[]`))
+	if err := gz.Close(); err != nil {
+		t.Fatalf("error compressing fixture: %v", err)
+	}
+	input := filepath.Join(t.TempDir(), "traces.json.gz")
+	if err := os.WriteFile(input, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("error writing fixture: %v", err)
+	}
+
+	r, err := openInputFile(input)
+	if err != nil {
+		t.Fatalf("openInputFile() error = %v", err)
+	}
+	if _, err := io.ReadAll(r); err != nil {
+		t.Fatalf("error reading %s: %v", input, err)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	gzr, ok := r.(gzipReadCloser)
+	if !ok {
+		t.Fatalf("openInputFile() returned %T, want a gzipReadCloser", r)
+	}
+	if err := gzr.file.Close(); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("file was not closed with the gzip reader: %v", err)
+	}
+}
+
+// archiveEntry is a file or directory of an archive built by a test
+type archiveEntry struct {
+	name string
+	data []byte
+	dir  bool
+}
+
+// newTarGz builds a gzip-compressed tar archive of entries in memory
+func newTarGz(t *testing.T, entries []archiveEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: 0o644, Size: int64(len(e.data)), Typeflag: tar.TypeReg}
+		if e.dir {
+			header = &tar.Header{Name: e.name, Mode: 0o755, Typeflag: tar.TypeDir}
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("error writing tar header: %v", err)
+		}
+		if _, err := tw.Write(e.data); err != nil {
+			t.Fatalf("error writing tar entry: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("error closing tar: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("error closing gzip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// newZip builds a zip archive of entries in memory. Directories are the
+// entries whose name ends with a slash.
+func newZip(t *testing.T, entries []archiveEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		w, err := zw.Create(e.name)
+		if err != nil {
+			t.Fatalf("error creating zip entry: %v", err)
+		}
+		if _, err := w.Write(e.data); err != nil {
+			t.Fatalf("error writing zip entry: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("error closing zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestLoadTraceSetsArchive(t *testing.T) {
+	baseline, err := os.ReadFile("../../examples/baseline.json")
+	if err != nil {
+		t.Fatalf("error reading fixture: %v", err)
+	}
+	modified, err := os.ReadFile("../../examples/modified.json")
+	if err != nil {
+		t.Fatalf("error reading fixture: %v", err)
+	}
+	entries := []archiveEntry{
+		{name: "traces/", dir: true},
+		{name: "traces/baseline.json", data: baseline},
+		{name: "traces/README.md", data: []byte("# Traces")},
+		{name: "traces/modified.JSON", data: modified},
+	}
+
+	dir := t.TempDir()
+	tests := []struct {
+		name  string
+		file  string
+		build func(*testing.T, []archiveEntry) []byte
+		read  func([]byte) ([]inputFile, int, error)
+	}{
+		{name: "tar.gz", file: "traces.tar.gz", build: newTarGz, read: readTarGz},
+		{name: "tgz", file: "traces.tgz", build: newTarGz, read: readTarGz},
+		{name: "zip", file: "traces.zip", build: newZip, read: readZip},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.build(t, entries)
+			files, skipped, err := tt.read(data)
+			if err != nil {
+				t.Fatalf("reading the archive returned error %v", err)
+			}
+			if skipped != 1 {
+				t.Errorf("reading the archive skipped %d entries, want only the README", skipped)
+			}
+			if len(files) != 2 {
+				t.Errorf("reading the archive returned %d files, want the 2 JSON entries", len(files))
+			}
+
+			input := filepath.Join(dir, tt.file)
+			if err := os.WriteFile(input, data, 0o644); err != nil {
+				t.Fatalf("error writing fixture: %v", err)
+			}
+			sets, err := loadTraceSets([]string{input}, inputOptions{})
+			if err != nil {
+				t.Fatalf("loadTraceSets() error = %v", err)
+			}
+			var names []string
+			for _, set := range sets {
+				names = append(names, set.Name)
+				if len(set.Traces) == 0 {
+					t.Errorf("trace set %s has no traces", set.Name)
+				}
+			}
+			if want := "traces/baseline.json,traces/modified.JSON"; strings.Join(names, ",") != want {
+				t.Errorf("loadTraceSets() returned sets %v, want %s", names, want)
+			}
+
+			noJSON := tt.build(t, []archiveEntry{{name: "docs/", dir: true}, {name: "docs/README.md", data: []byte("# Traces")}})
+			_, err = expandInputFile(tt.file, noJSON)
+			if err == nil || !strings.Contains(err.Error(), "contains no JSON files") {
+				t.Errorf("expandInputFile() error = %v, want an archive without JSON files rejected", err)
+			}
+		})
+	}
+}
+
+func TestLoadGitTraceSets(t *testing.T) {
+	if err := exec.Command("git", "rev-parse", "HEAD").Run(); err != nil {
+		t.Skip("not inside a git checkout")
+	}
+
+	traceSets, err := loadGitTraceSets([]string{"HEAD", "HEAD"}, "examples/baseline.json", inputOptions{})
+	if err != nil {
+		t.Fatalf("loadGitTraceSets() error = %v", err)
+	}
+	if len(traceSets) != 2 || traceSets[0].Name != "HEAD:examples/baseline.json" || len(traceSets[0].Traces) == 0 {
+		t.Errorf("loadGitTraceSets() = %+v, want two sets named HEAD:examples/baseline.json", traceSets)
+	}
+
+	_, err = loadGitTraceSets([]string{"HEAD"}, "examples/missing.json", inputOptions{})
+	if err == nil || !strings.Contains(err.Error(), "examples/missing.json at git ref HEAD") {
+		t.Errorf("loadGitTraceSets() error = %v, want an error naming the missing file and ref", err)
+	}
+}
+
+func TestExpandInputPaths(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.json", "a.json", "c.JSON", "notes.txt", "sub/d.json"} {
+		file := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte("[]"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	empty := filepath.Join(dir, "empty")
+	if err := os.Mkdir(empty, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	join := func(names ...string) []string {
+		paths := make([]string, len(names))
+		for i, name := range names {
+			paths[i] = filepath.Join(dir, name)
+		}
+		return paths
+	}
+
+	tests := []struct {
+		name    string
+		inputs  []string
+		want    []string
+		wantErr string
+	}{
+		{name: "plain files are kept", inputs: join("b.json", "missing.json"), want: join("b.json", "missing.json")},
+		{name: "glob is sorted", inputs: join("*.json"), want: join("a.json", "b.json")},
+		{name: "directory reads json files", inputs: []string{dir}, want: join("a.json", "b.json", "c.JSON")},
+		{name: "glob and file keep input order", inputs: append(join("sub/d.json"), join("[ab].json")...), want: join("sub/d.json", "a.json", "b.json")},
+		{name: "glob without matches", inputs: join("*.ndjson"), wantErr: "matches no files"},
+		{name: "directory without json files", inputs: []string{empty}, wantErr: "contains no JSON files"},
+		{name: "label is kept", inputs: []string{filepath.Join(dir, "a*.json") + "=main"}, want: []string{filepath.Join(dir, "a.json") + "=main"}},
+		{name: "label on several files", inputs: []string{dir + "=main"}, wantErr: "matches 3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandInputPaths(tt.inputs)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expandInputPaths() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expandInputPaths() error = %v", err)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("expandInputPaths() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithBaseline(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		inputs   []string
+		baseline string
+		want     []string
+		wantErr  string
+	}{
+		{name: "baseline moves to the front", inputs: []string{"a.json", "b.json", "c.json"}, baseline: "b.json", want: []string{"b.json", "a.json", "c.json"}},
+		{name: "baseline is added", inputs: []string{"a.json"}, baseline: "main.json", want: []string{"main.json", "a.json"}},
+		{name: "equivalent paths match", inputs: []string{"./traces/b.json", "a.json"}, baseline: "traces/b.json", want: []string{"traces/b.json", "a.json"}},
+		{name: "archive", inputs: []string{"a.json"}, baseline: "traces.zip", wantErr: "single trace file"},
+		{name: "glob", inputs: []string{"a.json"}, baseline: "*.json", wantErr: "single trace file"},
+		{name: "directory", inputs: []string{"a.json"}, baseline: dir, wantErr: "single trace file"},
+		{name: "labelled input matches", inputs: []string{"a.json", "b.json=main"}, baseline: "b.json", want: []string{"b.json=main", "a.json"}},
+		{name: "labelled baseline", inputs: []string{"a.json", "b.json=old"}, baseline: "b.json=main", want: []string{"b.json=main", "a.json"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := withBaseline(tt.inputs, tt.baseline)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("withBaseline() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("withBaseline() error = %v", err)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("withBaseline() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitInputLabel(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "run=1.json")
+	if err := os.WriteFile(existing, []byte("[]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input     string
+		wantPath  string
+		wantLabel string
+	}{
+		{input: "base.json", wantPath: "base.json"},
+		{input: "base.json=main", wantPath: "base.json", wantLabel: "main"},
+		{input: "base.json=feature=x", wantPath: "base.json=feature", wantLabel: "x"},
+		{input: "base.json=", wantPath: "base.json="},
+		{input: existing, wantPath: existing},
+		{input: existing + "=main", wantPath: existing, wantLabel: "main"},
+		{input: "https://ci.example.com/base.json=main", wantPath: "https://ci.example.com/base.json", wantLabel: "main"},
+		{input: "https://ci.example.com/base.json?build=42", wantPath: "https://ci.example.com/base.json?build=42"},
+		{input: "https://ci.example.com/base.json?build=42=main", wantPath: "https://ci.example.com/base.json?build=42", wantLabel: "main"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			path, label := splitInputLabel(tt.input)
+			if path != tt.wantPath || label != tt.wantLabel {
+				t.Errorf("splitInputLabel(%q) = %q, %q, want %q, %q", tt.input, path, label, tt.wantPath, tt.wantLabel)
+			}
+		})
+	}
+}
+
+func TestLoadTraceSetsLabels(t *testing.T) {
+	traceSets, err := loadTraceSets([]string{"../../examples/baseline.json=main", "../../examples/modified.json"}, inputOptions{})
+	if err != nil {
+		t.Fatalf("loadTraceSets() error = %v", err)
+	}
+	if traceSets[0].Name != "main" || traceSets[1].Name != "../../examples/modified.json" {
+		t.Errorf("loadTraceSets() names = %q, %q, want the label and the file name", traceSets[0].Name, traceSets[1].Name)
+	}
+}
+
+func TestLoadTracesStrict(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "otlp.json")
+	if err := os.WriteFile(input, []byte(`{"resourceSpans": []}`), 0o644); err != nil {
+		t.Fatalf("error writing input: %v", err)
+	}
+
+	if _, err := loadTraces(input, inputOptions{}); err != nil {
+		t.Errorf("loadTraces() error = %v, want only warnings", err)
+	}
+	_, err := loadTraces(input, inputOptions{strict: true})
+	if err == nil || !strings.Contains(err.Error(), "has no spans") {
+		t.Errorf("loadTraces() error = %v, want the shape warnings as an error", err)
+	}
+}
+
+func TestLoadTracesEmpty(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		format  string
+		wantErr string
+	}{
+		{name: "empty file", content: "", wantErr: "no traces found in"},
+		{name: "whitespace only", content: " \n\t\n", wantErr: "the file is empty"},
+		{name: "empty zipkin file", content: "\n", format: "zipkin", wantErr: "no traces found in"},
+		{name: "empty otlp-proto file", content: "", format: "otlp-proto", wantErr: "no traces found in"},
+		// A valid empty array is only warned about, unless --strict
+		{name: "empty array", content: "[]\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := filepath.Join(t.TempDir(), "traces.json")
+			if err := os.WriteFile(input, []byte(tt.content), 0o644); err != nil {
+				t.Fatalf("error writing input: %v", err)
+			}
+
+			traces, err := loadTraces(input, inputOptions{format: tt.format})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadTraces() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || len(traces) != 0 {
+				t.Fatalf("loadTraces() = %d traces, %v, want no traces and no error", len(traces), err)
+			}
+			if _, err := loadTraces(input, inputOptions{format: tt.format, strict: true}); err == nil || !strings.Contains(err.Error(), "no traces found") {
+				t.Errorf("loadTraces() with strict error = %v, want no traces found", err)
+			}
+		})
+	}
+}
+
+func TestLoadBaseline(t *testing.T) {
+	traces, err := loadTraces("../../examples/baseline.json", inputOptions{})
+	if err != nil {
+		t.Fatalf("loadTraces() error = %v", err)
+	}
+	data, err := trace.MarshalBaseline(trace.Baseline{Name: "main", Traces: traces}, false)
+	if err != nil {
+		t.Fatalf("MarshalBaseline() error = %v", err)
+	}
+	input := filepath.Join(t.TempDir(), "baseline.json")
+	if err := os.WriteFile(input, data, 0o644); err != nil {
+		t.Fatalf("error writing input: %v", err)
+	}
+
+	set, err := loadBaseline(input, inputOptions{})
+	if err != nil {
+		t.Fatalf("loadBaseline() error = %v", err)
+	}
+	if set.Name != "main" || len(set.Traces) != len(traces) {
+		t.Fatalf("loadBaseline() = %s with %d traces, want main with %d", set.Name, len(set.Traces), len(traces))
+	}
+
+	// The baseline is filtered like the other inputs
+	filtered, err := loadBaseline(input, inputOptions{minDuration: time.Hour})
+	if err != nil {
+		t.Fatalf("loadBaseline() error = %v", err)
+	}
+	if len(filtered.Traces) != 0 {
+		t.Errorf("loadBaseline() kept %d traces with spans shorter than --min-duration", len(filtered.Traces))
+	}
+
+	if _, err := loadBaseline("../../examples/baseline.json", inputOptions{}); err == nil || !strings.Contains(err.Error(), "not a baseline artifact") {
+		t.Errorf("loadBaseline() of a trace file error = %v, want not a baseline artifact", err)
+	}
+}
+
+// traceFile returns an input file with the given number of traces, each
+// with a few spans
+func traceFile(name string, traces int) inputFile {
+	var buf bytes.Buffer
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	buf.WriteString("[")
+	for i := 0; i < traces; i++ {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		fmt.Fprintf(&buf, `{"trace_id": "%s-%d", "spans": [`, name, i)
+		for j := 0; j < 5; j++ {
+			if j > 0 {
+				buf.WriteString(",")
+			}
+			fmt.Fprintf(&buf, `{"span_id": "s%d", "name": "op%d", "start_time": %q, "end_time": %q, "attributes": {"http.method": "GET"}}`,
+				j, j, start.Format(time.RFC3339Nano), start.Add(time.Duration(j)*time.Millisecond).Format(time.RFC3339Nano))
+		}
+		buf.WriteString("]}")
+	}
+	buf.WriteString("]")
+	return memoryFile(name, buf.Bytes())
+}
+
+func TestParseInputFiles(t *testing.T) {
+	var files []inputFile
+	for i := 0; i < 20; i++ {
+		files = append(files, traceFile(fmt.Sprintf("file%d", i), 3))
+	}
+
+	parsed, err := parseInputFiles(files, "json", 4)
+	if err != nil {
+		t.Fatalf("parseInputFiles() error = %v", err)
+	}
+	for i, traces := range parsed {
+		if len(traces) != 3 || traces[0].TraceID != fmt.Sprintf("file%d-0", i) {
+			t.Errorf("parseInputFiles() file %d = %+v, want the traces of file%d", i, traces, i)
+		}
+	}
+
+	// The failing file is reported, not the ones cancelled because of it
+	files[7] = memoryFile("broken.json", []byte(`[{"trace_id": 1}]`))
+	_, err = parseInputFiles(files, "json", 4)
+	if err == nil || !strings.Contains(err.Error(), "error parsing traces from broken.json") {
+		t.Errorf("parseInputFiles() error = %v, want it to name broken.json", err)
+	}
+}
+
+func BenchmarkParseInputFiles(b *testing.B) {
+	var files []inputFile
+	for i := 0; i < 8; i++ {
+		files = append(files, traceFile(fmt.Sprintf("file%d", i), 500))
+	}
+
+	for _, bm := range []struct {
+		name    string
+		workers int
+	}{
+		{name: "sequential", workers: 1},
+		{name: "concurrent", workers: runtime.GOMAXPROCS(0)},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := parseInputFiles(files, "json", bm.workers); err != nil {
+					b.Fatalf("parseInputFiles() error = %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lpcalisi/otelcompare/pkg/github"
+	"github.com/lpcalisi/otelcompare/pkg/gitlab"
+)
+
+// CommentPoster posts reports on a pull or merge request. The project is
+// owner/repo on GitHub and the full project path on GitLab.
+type CommentPoster interface {
+	// CreateComment adds a new comment
+	CreateComment(project string, number int, body string) error
+
+	// UpsertComment updates the previous comment that contains marker, or
+	// adds a new one if there is none
+	UpsertComment(project string, number int, marker, body string) error
+
+	// AddLabels adds labels, keeping the ones already applied
+	AddLabels(project string, number int, labels []string) error
+}
+
+// githubPoster posts comments on GitHub pull requests
+type githubPoster struct {
+	client *github.Client
+}
+
+// CreateComment adds a new comment to a pull request
+func (p githubPoster) CreateComment(project string, number int, body string) error {
+	owner, repo, _ := strings.Cut(project, "/")
+	return p.client.CommentPR(owner, repo, number, body)
+}
+
+// UpsertComment updates the previous comment with marker on a pull request
+func (p githubPoster) UpsertComment(project string, number int, marker, body string) error {
+	owner, repo, _ := strings.Cut(project, "/")
+	return p.client.UpsertComment(owner, repo, number, marker, body)
+}
+
+// AddLabels adds labels to a pull request
+func (p githubPoster) AddLabels(project string, number int, labels []string) error {
+	owner, repo, _ := strings.Cut(project, "/")
+	return p.client.AddLabels(owner, repo, number, labels)
+}
+
+// gitlabPoster posts comments on GitLab merge requests
+type gitlabPoster struct {
+	client *gitlab.Client
+}
+
+// CreateComment adds a new comment to a merge request
+func (p gitlabPoster) CreateComment(project string, number int, body string) error {
+	return p.client.CommentMR(project, number, body)
+}
+
+// UpsertComment updates the previous comment with marker on a merge request
+func (p gitlabPoster) UpsertComment(project string, number int, marker, body string) error {
+	return p.client.UpsertComment(project, number, marker, body)
+}
+
+// AddLabels adds labels to a merge request
+func (p gitlabPoster) AddLabels(project string, number int, labels []string) error {
+	return p.client.AddLabels(project, number, labels)
+}
+
+// commentTarget identifies the pull or merge request a report is posted on
+type commentTarget struct {
+	// provider is the code host: github or gitlab
+	provider string
+
+	// githubURL is the GitHub Enterprise URL. Empty uses github.com.
+	githubURL string
+
+	owner  string
+	repo   string
+	number int
+}
+
+// publicGitHubAPI is the API URL of github.com, which GitHub Actions sets
+// as GITHUB_API_URL
+const publicGitHubAPI = "https://api.github.com"
+
+// newCommentPoster returns the poster for a target's provider,
+// authenticated with the provider's token from the environment
+func newCommentPoster(target commentTarget) (CommentPoster, error) {
+	switch target.provider {
+	case "", "github":
+		client, err := newGitHubClient(target.githubURL)
+		if err != nil {
+			return nil, err
+		}
+		return githubPoster{client: client}, nil
+	case "gitlab":
+		token := os.Getenv("GITLAB_TOKEN")
+		if token == "" {
+			return nil, fmt.Errorf("GITLAB_TOKEN environment variable is required when not using --dry-run")
+		}
+		baseURL := os.Getenv("GITLAB_URL")
+		if baseURL == "" {
+			baseURL = gitlab.DefaultBaseURL
+		}
+		return gitlabPoster{client: gitlab.NewClient(baseURL, token)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported provider %q: must be github or gitlab", target.provider)
+	}
+}
+
+// newGitHubClient returns a GitHub client authenticated with GITHUB_TOKEN,
+// for the GitHub Enterprise instance at githubURL or GITHUB_API_URL when
+// either is set
+func newGitHubClient(githubURL string) (*github.Client, error) {
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("GITHUB_TOKEN environment variable is required when not using --dry-run")
+	}
+	baseURL := githubURL
+	if baseURL == "" {
+		baseURL = os.Getenv("GITHUB_API_URL")
+	}
+	if baseURL == "" || strings.TrimSuffix(baseURL, "/") == publicGitHubAPI {
+		return github.NewClient(token), nil
+	}
+	return github.NewEnterpriseClient(token, baseURL)
+}
+
+// postComment posts the report of a command, such as compare, on the
+// target pull or merge request. By default the previous comment of the same
+// command is updated in place; newComment always adds a new one.
+func postComment(target commentTarget, command, body string, newComment bool) error {
+	poster, err := newCommentPoster(target)
+	if err != nil {
+		return err
+	}
+
+	marker := github.CommentMarker(command)
+	body = marker + "\n" + body
+	project := target.owner + "/" + target.repo
+	if newComment {
+		return apiError(poster.CreateComment(project, target.number, body))
+	}
+	return apiError(poster.UpsertComment(project, target.number, marker, body))
+}
@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestNewCommentPoster(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		env      map[string]string
+		want     string
+		wantErr  string
+	}{
+		{name: "github", provider: "github", env: map[string]string{"GITHUB_TOKEN": "token"}, want: "cli.githubPoster"},
+		{name: "default is github", provider: "", env: map[string]string{"GITHUB_TOKEN": "token"}, want: "cli.githubPoster"},
+		{name: "gitlab", provider: "gitlab", env: map[string]string{"GITLAB_TOKEN": "token"}, want: "cli.gitlabPoster"},
+		{name: "missing github token", provider: "github", wantErr: "GITHUB_TOKEN"},
+		{name: "missing gitlab token", provider: "gitlab", env: map[string]string{"GITHUB_TOKEN": "token"}, wantErr: "GITLAB_TOKEN"},
+		{name: "unsupported provider", provider: "bitbucket", wantErr: `unsupported provider "bitbucket"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GITHUB_TOKEN", "")
+			t.Setenv("GITLAB_TOKEN", "")
+			t.Setenv("GITHUB_API_URL", "")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			got, err := newCommentPoster(commentTarget{provider: tt.provider})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("newCommentPoster() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newCommentPoster() error = %v", err)
+			}
+			if gotType := fmt.Sprintf("%T", got); gotType != tt.want {
+				t.Errorf("newCommentPoster() = %s, want %s", gotType, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/lpcalisi/otelcompare/pkg/trace"
+	"github.com/spf13/cobra"
+)
+
+var (
+	summaryInputFile string
+	summaryFormat    string
+	summaryOutput    string
+	summaryService   string
+)
+
+var summaryCmd = &cobra.Command{
+	Use:   "summary",
+	Short: "Print aggregate statistics of a trace file",
+	Long: `Print the number of traces, spans and errors, trace duration percentiles
+and the services of a trace file, without posting anything.
+For example:
+  otelcompare summary -i traces.json
+  otelcompare summary -i traces.json -o plain`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Flags are valid at this point, so errors are not usage mistakes
+		cmd.SilenceUsage = true
+		return runSummary(summaryInputFile)
+	},
+}
+
+func init() {
+	summaryCmd.Flags().StringVarP(&summaryInputFile, "input", "i", "", "Input JSON file or http(s) URL containing traces")
+	summaryCmd.Flags().StringVar(&summaryFormat, "format", "json", "Input trace format: json, ndjson, zipkin or otlp-proto")
+	summaryCmd.Flags().StringVarP(&summaryOutput, "output", "o", "markdown", "Output format: markdown or plain")
+	summaryCmd.Flags().StringVar(&summaryService, "service", "", "Only summarize spans whose service.name is this service")
+
+	summaryCmd.MarkFlagRequired("input")
+
+	rootCmd.AddCommand(summaryCmd)
+}
+
+func runSummary(inputFile string) error {
+	traces, err := loadTraces(inputFile, inputOptions{format: summaryFormat, service: summaryService, strict: strictInput})
+	if err != nil {
+		return err
+	}
+
+	summary := trace.Summarize(traces)
+	switch summaryOutput {
+	case "markdown":
+		fmt.Print(trace.GenerateSummaryMarkdown(summary))
+	case "plain":
+		fmt.Print(trace.GenerateSummaryText(summary))
+	default:
+		return fmt.Errorf("unsupported output %q: must be markdown or plain", summaryOutput)
+	}
+	return nil
+}
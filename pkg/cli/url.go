@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// urlAuthEnv names the environment variable holding the Authorization
+// header sent when fetching inputs from URLs, e.g. "Bearer <token>"
+const urlAuthEnv = "OTELCOMPARE_URL_AUTHORIZATION"
+
+// urlTimeout bounds how long fetching a single input URL may take
+var urlTimeout = 30 * time.Second
+
+// isURL reports whether an input names an http or https URL
+func isURL(input string) bool {
+	return strings.HasPrefix(input, "http://") || strings.HasPrefix(input, "https://")
+}
+
+// fetchURL downloads an input from a URL, sending the Authorization header
+// from urlAuthEnv when it is set
+func fetchURL(url string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid input URL %s: %w", url, err)
+	}
+	if auth := os.Getenv(urlAuthEnv); auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+
+	client := &http.Client{Timeout: urlTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("error fetching %s: server returned %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s: %w", url, err)
+	}
+	return data, nil
+}
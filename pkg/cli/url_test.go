@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadTracesURL(t *testing.T) {
+	data, err := os.ReadFile("../../examples/baseline.json")
+	if err != nil {
+		t.Fatalf("error reading fixture: %v", err)
+	}
+
+	var gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		switch r.URL.Path {
+		case "/baseline.json":
+			w.Write(data)
+		case "/slow.json":
+			time.Sleep(100 * time.Millisecond)
+			w.Write(data)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	t.Setenv(urlAuthEnv, "Bearer secret")
+
+	want, err := loadTraces("../../examples/baseline.json", inputOptions{})
+	if err != nil {
+		t.Fatalf("loadTraces() error = %v", err)
+	}
+	got, err := loadTraces(server.URL+"/baseline.json?build=42", inputOptions{})
+	if err != nil {
+		t.Fatalf("loadTraces() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("loadTraces() from a URL = %+v, want %+v", got, want)
+	}
+	if gotAuth != "Bearer secret" {
+		t.Errorf("Authorization header = %q, want %q", gotAuth, "Bearer secret")
+	}
+
+	_, err = loadTraces(server.URL+"/missing.json", inputOptions{})
+	if err == nil || !strings.Contains(err.Error(), "404 Not Found") {
+		t.Errorf("loadTraces() error = %v, want the 404 status", err)
+	}
+
+	defer func(timeout time.Duration) { urlTimeout = timeout }(urlTimeout)
+	urlTimeout = 10 * time.Millisecond
+	if _, err := loadTraces(server.URL+"/slow.json", inputOptions{}); err == nil {
+		t.Error("loadTraces() expected an error when the server is too slow")
+	}
+}
+
+func TestExpandInputPathsURL(t *testing.T) {
+	url := "https://artifacts.example.com/traces.json?build=42"
+	got, err := expandInputPaths([]string{url})
+	if err != nil {
+		t.Fatalf("expandInputPaths() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, []string{url}) {
+		t.Errorf("expandInputPaths() = %v, want the URL unchanged", got)
+	}
+}
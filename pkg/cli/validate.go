@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lpcalisi/otelcompare/pkg/trace"
+	"github.com/spf13/cobra"
+)
+
+var (
+	validateInputFile       string
+	validateFormat          string
+	validateService         string
+	validateFutureTolerance time.Duration
+	validateNoEmoji         bool
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check a trace file for span tree and timing problems",
+	Long: `Check the spans of a trace file for problems before comparing it: missing
+parents, parent cycles, spans ending before they start, children starting
+before or ending after their parent, zero-duration spans, overlapping
+siblings and timestamps far in the future. The findings are
+printed as a markdown table, and any finding of severity error exits with 1.
+For example:
+  otelcompare validate -i traces.json
+  otelcompare validate -i traces.json --future-tolerance 1h`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if validateFutureTolerance < 0 {
+			return fmt.Errorf("--future-tolerance must not be negative, got %s", validateFutureTolerance)
+		}
+		// Flags are valid at this point, so errors are not usage mistakes
+		cmd.SilenceUsage = true
+		return runValidate(validateInputFile)
+	},
+}
+
+func init() {
+	validateCmd.Flags().StringVarP(&validateInputFile, "input", "i", "", "Input JSON file or http(s) URL containing traces")
+	validateCmd.Flags().StringVar(&validateFormat, "format", "json", "Input trace format: json, ndjson, zipkin or otlp-proto")
+	validateCmd.Flags().StringVar(&validateService, "service", "", "Only validate spans whose service.name is this service")
+	validateCmd.Flags().DurationVar(&validateFutureTolerance, "future-tolerance", trace.DefaultFutureTolerance, "How far past the current time a span timestamp may be before it is reported")
+
+	validateCmd.Flags().BoolVar(&validateNoEmoji, "no-emoji", false, "Use plain ASCII markers such as [FAIL] and [WARN] instead of emoji")
+
+	validateCmd.MarkFlagRequired("input")
+
+	rootCmd.AddCommand(validateCmd)
+}
+
+func runValidate(inputFile string) error {
+	traces, err := loadTraces(inputFile, inputOptions{format: validateFormat, service: validateService, strict: strictInput})
+	if err != nil {
+		return err
+	}
+
+	findings := trace.RunChecks(traces, trace.DefaultChecks(time.Now(), validateFutureTolerance)...)
+	fmt.Print(trace.GenerateFindingsMarkdown(findings, len(traces), trace.Options{NoEmoji: validateNoEmoji}))
+
+	errorCount := 0
+	for _, f := range findings {
+		if f.Severity == trace.SeverityError {
+			errorCount++
+		}
+	}
+	if errorCount > 0 {
+		return &ExitError{Code: ExitRegression, Err: fmt.Errorf("%d span timing error(s) found", errorCount)}
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package cli
+
+import (
+	"runtime/debug"
+)
+
+// version is the otelcompare version, set at build time with
+// -ldflags "-X github.com/lpcalisi/otelcompare/pkg/cli.version=v1.2.3"
+var version string
+
+// toolVersion returns the version set at build time, or the module version
+// when installed with go install
+func toolVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
@@ -0,0 +1,157 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/go-github/v60/github"
+)
+
+// defaultBatchInterval is the default time between two comments of a batch.
+// GitHub asks clients to wait at least a second between requests that
+// create content, to stay clear of its secondary rate limits.
+const defaultBatchInterval = time.Second
+
+// defaultSecondaryRetryAfter is how long to wait after a secondary rate
+// limit error that does not say when to retry, as GitHub recommends
+const defaultSecondaryRetryAfter = time.Minute
+
+// defaultBatchRetries is the default number of times a rate limited comment
+// is retried
+const defaultBatchRetries = 3
+
+// BatchComment is a comment to post on a PR as part of a batch
+type BatchComment struct {
+	Owner    string
+	Repo     string
+	PRNumber int
+	Body     string
+}
+
+// String names the PR of the comment as owner/repo#number
+func (b BatchComment) String() string {
+	return fmt.Sprintf("%s/%s#%d", b.Owner, b.Repo, b.PRNumber)
+}
+
+// BatchResult is the outcome of posting one comment of a batch. Err is nil
+// when the comment was posted.
+type BatchResult struct {
+	Comment BatchComment
+	Err     error
+}
+
+// BatchOptions configure how CommentPRs paces a batch
+type BatchOptions struct {
+	// Interval is the least time between two comments. Zero uses one
+	// second.
+	Interval time.Duration
+
+	// Retries is how many times a comment that hit a rate limit is retried
+	// before it counts as failed. Zero uses 3 and a negative value disables
+	// retries.
+	Retries int
+
+	// Progress, when set, is called after every comment with its result
+	// and how many of the total comments are done
+	Progress func(result BatchResult, done, total int)
+}
+
+// CommentPRs posts a new comment on each PR of a batch, one at a time.
+// Comments are spaced by the interval, the batch waits for the primary rate
+// limit to reset when GitHub reports it exhausted, and comments that hit a
+// primary or secondary rate limit are retried after the wait GitHub asks
+// for. A comment that fails does not stop the batch. The results are in the
+// order of the comments, and the error joins the failures, naming their PRs.
+func (c *Client) CommentPRs(comments []BatchComment, opts BatchOptions) ([]BatchResult, error) {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = defaultBatchInterval
+	}
+	retries := opts.Retries
+	switch {
+	case retries == 0:
+		retries = defaultBatchRetries
+	case retries < 0:
+		retries = 0
+	}
+
+	results := make([]BatchResult, 0, len(comments))
+	var errs []error
+	var wait time.Duration
+	for i, comment := range comments {
+		var err error
+		for attempt := 0; ; attempt++ {
+			if i > 0 || attempt > 0 {
+				if err = c.sleep(c.ctx, wait); err != nil {
+					break
+				}
+			}
+
+			var resp *github.Response
+			resp, err = c.createComment(comment.Owner, comment.Repo, comment.PRNumber, comment.Body)
+			// The next comment waits out a rate limit even when this one
+			// has no retries left
+			retryAfter, rateLimited := retryDelay(err, interval, time.Now())
+			if !rateLimited {
+				wait = nextDelay(resp, interval, time.Now())
+				break
+			}
+			wait = retryAfter
+			if attempt >= retries {
+				break
+			}
+		}
+
+		result := BatchResult{Comment: comment, Err: err}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", comment, err))
+		}
+		results = append(results, result)
+		if opts.Progress != nil {
+			opts.Progress(result, i+1, len(comments))
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// nextDelay returns how long to wait before the next request: the interval,
+// or until the primary rate limit resets when the response used it up
+func nextDelay(resp *github.Response, interval time.Duration, now time.Time) time.Duration {
+	if resp == nil || resp.Rate.Limit == 0 || resp.Rate.Remaining > 0 {
+		return interval
+	}
+	return max(resp.Rate.Reset.Sub(now), interval)
+}
+
+// retryDelay reports whether err is a rate limit error worth retrying and
+// how long to wait before doing so, which is never less than the interval
+func retryDelay(err error, interval time.Duration, now time.Time) (time.Duration, bool) {
+	var secondary *github.AbuseRateLimitError
+	if errors.As(err, &secondary) {
+		if secondary.RetryAfter == nil {
+			return max(defaultSecondaryRetryAfter, interval), true
+		}
+		return max(*secondary.RetryAfter, interval), true
+	}
+
+	var primary *github.RateLimitError
+	if errors.As(err, &primary) {
+		return max(primary.Rate.Reset.Sub(now), interval), true
+	}
+	return 0, false
+}
+
+// sleepContext waits for d, or returns the error of ctx when it is done
+// first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
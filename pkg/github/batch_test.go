@@ -0,0 +1,174 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v60/github"
+)
+
+// secondaryRateLimit responds like GitHub does when a secondary rate limit
+// is hit
+func secondaryRateLimit(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", "0")
+	w.WriteHeader(http.StatusForbidden)
+	w.Write([]byte(`{"message": "You have exceeded a secondary rate limit", "documentation_url": "https://docs.github.com/rest/overview/rate-limits-for-the-rest-api#about-secondary-rate-limits"}`))
+}
+
+func TestCommentPRs(t *testing.T) {
+	requests := make(map[string]int)
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		switch {
+		case r.URL.Path == "/repos/owner/repo/issues/2/comments":
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message": "server error"}`))
+		case r.URL.Path == "/repos/owner/repo/issues/3/comments" && requests[r.URL.Path] == 1:
+			secondaryRateLimit(w)
+		default:
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": 1}`))
+		}
+	})
+	var sleeps []time.Duration
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+
+	comments := []BatchComment{
+		{Owner: "owner", Repo: "repo", PRNumber: 1, Body: "one"},
+		{Owner: "owner", Repo: "repo", PRNumber: 2, Body: "two"},
+		{Owner: "owner", Repo: "repo", PRNumber: 3, Body: "three"},
+	}
+	var progress []string
+	results, err := c.CommentPRs(comments, BatchOptions{
+		Interval: 10 * time.Millisecond,
+		Progress: func(result BatchResult, done, total int) {
+			progress = append(progress, fmt.Sprintf("%d/%d %s %v", done, total, result.Comment, result.Err == nil))
+		},
+	})
+
+	if err == nil || !strings.Contains(err.Error(), "owner/repo#2") || strings.Contains(err.Error(), "owner/repo#3") {
+		t.Errorf("CommentPRs() error = %v, want only the failure of owner/repo#2", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("CommentPRs() returned %d results, want 3", len(results))
+	}
+	for i, succeeded := range []bool{true, false, true} {
+		if (results[i].Err == nil) != succeeded || results[i].Comment != comments[i] {
+			t.Errorf("result %d = %+v, want succeeded %v", i, results[i], succeeded)
+		}
+	}
+	if requests["/repos/owner/repo/issues/3/comments"] != 2 {
+		t.Errorf("rate limited comment was sent %d times, want 2", requests["/repos/owner/repo/issues/3/comments"])
+	}
+
+	wantProgress := []string{"1/3 owner/repo#1 true", "2/3 owner/repo#2 false", "3/3 owner/repo#3 true"}
+	if strings.Join(progress, "\n") != strings.Join(wantProgress, "\n") {
+		t.Errorf("progress = %q, want %q", progress, wantProgress)
+	}
+	// One wait before each later comment and one before the retry
+	if len(sleeps) != 3 {
+		t.Errorf("slept %v, want 3 waits", sleeps)
+	}
+	for _, d := range sleeps {
+		if d < 10*time.Millisecond {
+			t.Errorf("slept %v, want at least the interval", d)
+		}
+	}
+}
+
+func TestCommentPRsRetriesExhausted(t *testing.T) {
+	var count int
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		count++
+		secondaryRateLimit(w)
+	})
+	c.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+
+	results, err := c.CommentPRs([]BatchComment{{Owner: "owner", Repo: "repo", PRNumber: 1}}, BatchOptions{Retries: 1})
+	if count != 2 {
+		t.Errorf("sent %d requests, want the comment and 1 retry", count)
+	}
+	var rateErr *github.AbuseRateLimitError
+	if !errors.As(err, &rateErr) || !errors.As(results[0].Err, &rateErr) {
+		t.Errorf("CommentPRs() error = %v, want a secondary rate limit error", err)
+	}
+}
+
+func TestCommentPRsCanceled(t *testing.T) {
+	var count int
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		count++
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 1}`))
+	})
+	c.sleep = func(ctx context.Context, d time.Duration) error { return context.Canceled }
+
+	results, err := c.CommentPRs([]BatchComment{
+		{Owner: "owner", Repo: "repo", PRNumber: 1},
+		{Owner: "owner", Repo: "repo", PRNumber: 2},
+	}, BatchOptions{})
+	if count != 1 {
+		t.Errorf("sent %d requests, want only the first before the cancellation", count)
+	}
+	if results[0].Err != nil || !errors.Is(results[1].Err, context.Canceled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("CommentPRs() = %+v, %v, want the second comment canceled", results, err)
+	}
+}
+
+func TestNextDelay(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		resp     *github.Response
+		expected time.Duration
+	}{
+		{name: "no response", expected: time.Second},
+		{name: "no rate headers", resp: &github.Response{}, expected: time.Second},
+		{name: "requests left", resp: &github.Response{Rate: github.Rate{Limit: 5000, Remaining: 1, Reset: github.Timestamp{Time: now.Add(time.Hour)}}}, expected: time.Second},
+		{name: "exhausted", resp: &github.Response{Rate: github.Rate{Limit: 5000, Remaining: 0, Reset: github.Timestamp{Time: now.Add(time.Hour)}}}, expected: time.Hour},
+		{name: "already reset", resp: &github.Response{Rate: github.Rate{Limit: 5000, Remaining: 0, Reset: github.Timestamp{Time: now.Add(-time.Minute)}}}, expected: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextDelay(tt.resp, time.Second, now); got != tt.expected {
+				t.Errorf("nextDelay() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	retryAfter := 30 * time.Second
+	tests := []struct {
+		name        string
+		err         error
+		expected    time.Duration
+		rateLimited bool
+	}{
+		{name: "success"},
+		{name: "other error", err: errors.New("server error")},
+		{name: "secondary with retry after", err: &github.AbuseRateLimitError{RetryAfter: &retryAfter}, expected: 30 * time.Second, rateLimited: true},
+		{name: "secondary without retry after", err: &github.AbuseRateLimitError{}, expected: time.Minute, rateLimited: true},
+		{name: "primary", err: &github.RateLimitError{Rate: github.Rate{Reset: github.Timestamp{Time: now.Add(10 * time.Minute)}}}, expected: 10 * time.Minute, rateLimited: true},
+		{name: "wrapped primary already reset", err: fmt.Errorf("posting: %w", &github.RateLimitError{Rate: github.Rate{Reset: github.Timestamp{Time: now}}}), expected: time.Second, rateLimited: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rateLimited := retryDelay(tt.err, time.Second, now)
+			if got != tt.expected || rateLimited != tt.rateLimited {
+				t.Errorf("retryDelay() = %v, %v, want %v, %v", got, rateLimited, tt.expected, tt.rateLimited)
+			}
+		})
+	}
+}
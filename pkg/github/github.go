@@ -2,15 +2,54 @@ package github
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/google/go-github/v60/github"
+	"github.com/lpcalisi/otelcompare/pkg/trace"
 	"golang.org/x/oauth2"
 )
 
+// CommentMarker returns the hidden marker that identifies the comments an
+// otelcompare command posts, such as <!-- otelcompare:compare -->, so later
+// runs of the same command update them instead of adding new ones. Each
+// command has its own marker, so their comments do not replace each other.
+func CommentMarker(command string) string {
+	return fmt.Sprintf("<!-- otelcompare:%s -->", command)
+}
+
+// CheckRunName is the name of the check runs posted by otelcompare
+const CheckRunName = "otelcompare"
+
+// maxCheckRunText is the largest check run output text GitHub accepts
+const maxCheckRunText = 65535
+
+// CheckRun is the outcome of a comparison, reported as a completed check
+// run on a commit
+type CheckRun struct {
+	// HeadSHA is the commit the check run is reported on
+	HeadSHA string
+
+	// Success concludes the check run as successful instead of failed
+	Success bool
+
+	// Title and Summary head the check run output, followed by Text,
+	// which is truncated to the size GitHub accepts
+	Title   string
+	Summary string
+	Text    string
+}
+
 // Client represents a GitHub client
 type Client struct {
 	client *github.Client
 	ctx    context.Context
+
+	// sleep waits between the comments of a batch. Tests replace it to
+	// avoid waiting.
+	sleep func(ctx context.Context, d time.Duration) error
 }
 
 // NewClient creates a new GitHub client
@@ -20,25 +59,147 @@ func NewClient(token string) *Client {
 		&oauth2.Token{AccessToken: token},
 	)
 	tc := oauth2.NewClient(ctx, ts)
-	client := github.NewClient(tc)
+	return newClient(ctx, tc)
+}
 
+// NewEnterpriseClient creates a GitHub client for a GitHub Enterprise
+// Server instance. baseURL is the instance URL; the /api/v3/ API path is
+// added when missing.
+func NewEnterpriseClient(token, baseURL string) (*Client, error) {
+	c := NewClient(token)
+	enterprise, err := c.client.WithEnterpriseURLs(baseURL, baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitHub Enterprise URL %q: %w", baseURL, err)
+	}
+	c.client = enterprise
+	return c, nil
+}
+
+// newClient creates a GitHub client that sends requests through httpClient
+func newClient(ctx context.Context, httpClient *http.Client) *Client {
 	return &Client{
-		client: client,
+		client: github.NewClient(httpClient),
 		ctx:    ctx,
+		sleep:  sleepContext,
 	}
 }
 
 // CommentPR adds a comment to a PR with the trace visualization
 func (c *Client) CommentPR(owner, repo string, prNumber int, htmlContent string) error {
-	_, _, err := c.client.Issues.CreateComment(c.ctx, owner, repo, prNumber, &github.IssueComment{
-		Body: &htmlContent,
+	_, err := c.createComment(owner, repo, prNumber, htmlContent)
+	return err
+}
+
+// createComment adds a comment to a PR and returns the response, whose rate
+// limit headers pace batches
+func (c *Client) createComment(owner, repo string, prNumber int, body string) (*github.Response, error) {
+	_, resp, err := c.client.Issues.CreateComment(c.ctx, owner, repo, prNumber, &github.IssueComment{
+		Body: &body,
 	})
+	return resp, err
+}
 
+// AddLabels adds labels to a PR, keeping the labels it already has.
+// Labels that do not exist in the repository are created.
+func (c *Client) AddLabels(owner, repo string, prNumber int, labels []string) error {
+	_, _, err := c.client.Issues.AddLabelsToIssue(c.ctx, owner, repo, prNumber, labels)
 	return err
 }
 
-// CompareTraces compares traces between two versions and generates a comment in the PR
-func (c *Client) CompareTraces(owner, repo string, prNumber int, baseHTML, headHTML string) error {
-	// TODO: Implement trace comparison
-	return nil
+// CreateCheckRun reports a completed check run named CheckRunName on a
+// commit
+func (c *Client) CreateCheckRun(owner, repo string, run CheckRun) error {
+	conclusion := "failure"
+	if run.Success {
+		conclusion = "success"
+	}
+	text := run.Text
+	if len(text) > maxCheckRunText {
+		text = strings.ToValidUTF8(text[:maxCheckRunText], "")
+	}
+
+	_, _, err := c.client.Checks.CreateCheckRun(c.ctx, owner, repo, github.CreateCheckRunOptions{
+		Name:       CheckRunName,
+		HeadSHA:    run.HeadSHA,
+		Status:     github.String("completed"),
+		Conclusion: github.String(conclusion),
+		Output: &github.CheckRunOutput{
+			Title:   github.String(run.Title),
+			Summary: github.String(run.Summary),
+			Text:    github.String(text),
+		},
+	})
+	return err
+}
+
+// UpsertComment updates the PR comment with marker posted by the
+// authenticated user, or creates a new comment if there is none. Comments of
+// other users are never edited, even when they quote the marker. The marker
+// is added to the body when missing.
+func (c *Client) UpsertComment(owner, repo string, prNumber int, marker, body string) error {
+	if !strings.Contains(body, marker) {
+		body = marker + "\n" + body
+	}
+
+	ownComment, err := c.commentAuthor()
+	if err != nil {
+		return err
+	}
+
+	opts := &github.IssueListCommentsOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		comments, resp, err := c.client.Issues.ListComments(c.ctx, owner, repo, prNumber, opts)
+		if err != nil {
+			return fmt.Errorf("error listing PR comments: %w", err)
+		}
+
+		for _, comment := range comments {
+			if ownComment(comment) && strings.Contains(comment.GetBody(), marker) {
+				_, _, err := c.client.Issues.EditComment(c.ctx, owner, repo, comment.GetID(), &github.IssueComment{
+					Body: &body,
+				})
+				return err
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return c.CommentPR(owner, repo, prNumber, body)
+}
+
+// commentAuthor returns a function reporting whether a comment was posted by
+// the authenticated user. GitHub App installation tokens, such as the
+// GITHUB_TOKEN of GitHub Actions, cannot look up their user, so for them it
+// reports comments posted by a bot.
+func (c *Client) commentAuthor() (func(*github.IssueComment) bool, error) {
+	user, resp, err := c.client.Users.Get(c.ctx, "")
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusForbidden {
+			return func(comment *github.IssueComment) bool {
+				return comment.GetUser().GetType() == "Bot"
+			}, nil
+		}
+		return nil, fmt.Errorf("error getting the authenticated user: %w", err)
+	}
+	login := user.GetLogin()
+	return func(comment *github.IssueComment) bool {
+		return comment.GetUser().GetLogin() == login
+	}, nil
+}
+
+// CompareTraces compares the base and head traces of a PR and comments the
+// comparison on it, with the full report in a collapsible section
+func (c *Client) CompareTraces(owner, repo string, prNumber int, base, head []trace.Trace) error {
+	body := fmt.Sprintf("### OpenTelemetry Traces Comparison\n\n"+
+		"Comparing %d base trace(s) with %d head trace(s).\n\n"+
+		"<details>\n<summary>Comparison details</summary>\n\n%s\n</details>\n",
+		len(base), len(head), trace.CompareTraces(base, head))
+
+	return c.CommentPR(owner, repo, prNumber, body)
 }
@@ -0,0 +1,279 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lpcalisi/otelcompare/pkg/trace"
+)
+
+// newTestClient returns a client that sends every request to handler
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	c := newClient(context.Background(), server.Client())
+	baseURL, err := url.Parse(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.client.BaseURL = baseURL
+	return c
+}
+
+func TestCompareTraces(t *testing.T) {
+	var gotPath, gotBody string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.Method + " " + r.URL.Path
+		var comment struct {
+			Body string `json:"body"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&comment); err != nil {
+			t.Errorf("decoding request body: %v", err)
+		}
+		gotBody = comment.Body
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 1}`))
+	})
+
+	now := time.Now()
+	base := []trace.Trace{{TraceID: "trace1", Spans: []trace.Span{{Name: "root", StartTime: now, EndTime: now.Add(time.Second)}}}}
+	head := []trace.Trace{{TraceID: "trace1", Spans: []trace.Span{{Name: "root", StartTime: now, EndTime: now.Add(2 * time.Second)}}}}
+
+	if err := c.CompareTraces("owner", "repo", 42, base, head); err != nil {
+		t.Fatalf("CompareTraces() error = %v", err)
+	}
+
+	if gotPath != "POST /repos/owner/repo/issues/42/comments" {
+		t.Errorf("CompareTraces() sent %s, want POST to the PR comments", gotPath)
+	}
+	for _, want := range []string{"### OpenTelemetry Traces Comparison", "<details>", "### Trace Comparison", "Duration Comparison"} {
+		if !strings.Contains(gotBody, want) {
+			t.Errorf("comment body does not contain %q:\n%s", want, gotBody)
+		}
+	}
+}
+
+func TestCompareTracesError(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message": "forbidden"}`))
+	})
+
+	if err := c.CompareTraces("owner", "repo", 42, nil, nil); err == nil {
+		t.Error("CompareTraces() expected an error when GitHub rejects the comment")
+	}
+}
+
+func TestUpsertComment(t *testing.T) {
+	const (
+		own    = `"user": {"login": "otelcompare-bot", "type": "User"}`
+		quoted = `"user": {"login": "alice", "type": "User"}`
+		bot    = `"user": {"login": "github-actions[bot]", "type": "Bot"}`
+	)
+	tests := []struct {
+		name       string
+		user       string
+		comments   string
+		wantMethod string
+		wantPath   string
+	}{
+		{
+			name:       "edits existing comment",
+			user:       `{"login": "otelcompare-bot"}`,
+			comments:   `[{"id": 7, "body": "unrelated", ` + own + `}, {"id": 8, "body": "<!-- otelcompare:info -->\ninfo report", ` + own + `}, {"id": 9, "body": "<!-- otelcompare:compare -->\nold report", ` + own + `}]`,
+			wantMethod: http.MethodPatch,
+			wantPath:   "/repos/owner/repo/issues/comments/9",
+		},
+		{
+			name:       "skips comments of other users quoting the marker",
+			user:       `{"login": "otelcompare-bot"}`,
+			comments:   `[{"id": 6, "body": "> <!-- otelcompare:compare -->\n> old report\nWhy?", ` + quoted + `}, {"id": 9, "body": "<!-- otelcompare:compare -->\nold report", ` + own + `}]`,
+			wantMethod: http.MethodPatch,
+			wantPath:   "/repos/owner/repo/issues/comments/9",
+		},
+		{
+			name:       "creates new comment next to another command's",
+			user:       `{"login": "otelcompare-bot"}`,
+			comments:   `[{"id": 7, "body": "unrelated", ` + own + `}, {"id": 8, "body": "<!-- otelcompare:info -->\ninfo report", ` + own + `}, {"id": 6, "body": "<!-- otelcompare:compare -->", ` + quoted + `}]`,
+			wantMethod: http.MethodPost,
+			wantPath:   "/repos/owner/repo/issues/42/comments",
+		},
+		{
+			name:       "installation token edits the bot's comment",
+			comments:   `[{"id": 6, "body": "<!-- otelcompare:compare -->", ` + quoted + `}, {"id": 9, "body": "<!-- otelcompare:compare -->\nold report", ` + bot + `}]`,
+			wantMethod: http.MethodPatch,
+			wantPath:   "/repos/owner/repo/issues/comments/9",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotMethod, gotPath, gotBody string
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Path == "/user" && tt.user == "":
+					w.WriteHeader(http.StatusForbidden)
+					w.Write([]byte(`{"message": "Resource not accessible by integration"}`))
+					return
+				case r.URL.Path == "/user":
+					w.Write([]byte(tt.user))
+					return
+				case r.Method == http.MethodGet:
+					w.Write([]byte(tt.comments))
+					return
+				}
+				gotMethod, gotPath = r.Method, r.URL.Path
+				var comment struct {
+					Body string `json:"body"`
+				}
+				json.NewDecoder(r.Body).Decode(&comment)
+				gotBody = comment.Body
+				w.Write([]byte(`{"id": 1}`))
+			})
+
+			if err := c.UpsertComment("owner", "repo", 42, CommentMarker("compare"), "new report"); err != nil {
+				t.Fatalf("UpsertComment() error = %v", err)
+			}
+			if gotMethod != tt.wantMethod || gotPath != tt.wantPath {
+				t.Errorf("UpsertComment() sent %s %s, want %s %s", gotMethod, gotPath, tt.wantMethod, tt.wantPath)
+			}
+			if !strings.HasPrefix(gotBody, "<!-- otelcompare:compare -->\n") || !strings.Contains(gotBody, "new report") {
+				t.Errorf("UpsertComment() body = %q, want the marker followed by the report", gotBody)
+			}
+		})
+	}
+}
+
+func TestNewEnterpriseClient(t *testing.T) {
+	var gotPath, gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.Method + " " + r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 1}`))
+	}))
+	t.Cleanup(server.Close)
+
+	c, err := NewEnterpriseClient("secret", server.URL)
+	if err != nil {
+		t.Fatalf("NewEnterpriseClient() error = %v", err)
+	}
+	if err := c.CommentPR("owner", "repo", 42, "report"); err != nil {
+		t.Fatalf("CommentPR() error = %v", err)
+	}
+	if gotPath != "POST /api/v3/repos/owner/repo/issues/42/comments" {
+		t.Errorf("CommentPR() sent %s, want POST to the Enterprise API", gotPath)
+	}
+	if gotAuth != "Bearer secret" {
+		t.Errorf("CommentPR() sent Authorization %q, want the token", gotAuth)
+	}
+
+	if _, err := NewEnterpriseClient("secret", "://bad"); err == nil {
+		t.Error("NewEnterpriseClient() expected an error for an invalid URL")
+	}
+}
+
+func TestAddLabels(t *testing.T) {
+	var gotPath string
+	var gotLabels []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.Method + " " + r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&gotLabels); err != nil {
+			t.Errorf("decoding request body: %v", err)
+		}
+		w.Write([]byte(`[{"name": "perf-regression"}]`))
+	})
+
+	if err := c.AddLabels("owner", "repo", 42, []string{"perf-regression"}); err != nil {
+		t.Fatalf("AddLabels() error = %v", err)
+	}
+	if gotPath != "POST /repos/owner/repo/issues/42/labels" {
+		t.Errorf("AddLabels() sent %s, want POST to the PR labels", gotPath)
+	}
+	if len(gotLabels) != 1 || gotLabels[0] != "perf-regression" {
+		t.Errorf("AddLabels() sent labels %v, want [perf-regression]", gotLabels)
+	}
+}
+
+// roundTripFunc is an http.RoundTripper that answers requests itself
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestCreateCheckRun(t *testing.T) {
+	var gotPath string
+	var got struct {
+		Name       string `json:"name"`
+		HeadSHA    string `json:"head_sha"`
+		Status     string `json:"status"`
+		Conclusion string `json:"conclusion"`
+		Output     struct {
+			Title   string `json:"title"`
+			Summary string `json:"summary"`
+			Text    string `json:"text"`
+		} `json:"output"`
+	}
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		gotPath = r.Method + " " + r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding request body: %v", err)
+		}
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"id": 1}`)),
+			Request:    r,
+		}, nil
+	})
+	c := newClient(context.Background(), &http.Client{Transport: transport})
+
+	tests := []struct {
+		name           string
+		run            CheckRun
+		wantConclusion string
+		wantTextLength int
+	}{
+		{
+			name:           "success",
+			run:            CheckRun{HeadSHA: "abc123", Success: true, Title: "No regressions", Summary: "fine", Text: "report"},
+			wantConclusion: "success",
+			wantTextLength: len("report"),
+		},
+		{
+			name:           "failure with a long report",
+			run:            CheckRun{HeadSHA: "abc123", Title: "1 regression(s) over 10.0%", Summary: "- checkout", Text: strings.Repeat("x", maxCheckRunText+10)},
+			wantConclusion: "failure",
+			wantTextLength: maxCheckRunText,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := c.CreateCheckRun("owner", "repo", tt.run); err != nil {
+				t.Fatalf("CreateCheckRun() error = %v", err)
+			}
+			if gotPath != "POST /repos/owner/repo/check-runs" {
+				t.Errorf("CreateCheckRun() sent %s, want POST to the check runs", gotPath)
+			}
+			if got.Name != CheckRunName || got.HeadSHA != "abc123" || got.Status != "completed" || got.Conclusion != tt.wantConclusion {
+				t.Errorf("CreateCheckRun() sent %+v, want a completed %s check run on abc123", got, tt.wantConclusion)
+			}
+			if got.Output.Title != tt.run.Title || got.Output.Summary != tt.run.Summary || len(got.Output.Text) != tt.wantTextLength {
+				t.Errorf("CreateCheckRun() sent output %q / %q with %d bytes of text, want %q / %q with %d",
+					got.Output.Title, got.Output.Summary, len(got.Output.Text), tt.run.Title, tt.run.Summary, tt.wantTextLength)
+			}
+		})
+	}
+}
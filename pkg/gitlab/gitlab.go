@@ -0,0 +1,151 @@
+package gitlab
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// CommentMarker returns the hidden marker that identifies the comments an
+// otelcompare command posts, such as <!-- otelcompare:compare -->, so later
+// runs of the same command update them instead of adding new ones. Each
+// command has its own marker, so their comments do not replace each other.
+func CommentMarker(command string) string {
+	return fmt.Sprintf("<!-- otelcompare:%s -->", command)
+}
+
+// DefaultBaseURL is the URL of gitlab.com
+const DefaultBaseURL = "https://gitlab.com"
+
+// Client represents a GitLab client
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// note is a comment on a merge request
+type note struct {
+	ID   int    `json:"id"`
+	Body string `json:"body"`
+}
+
+// NewClient creates a new GitLab client for the instance at baseURL,
+// authenticated with a personal, project or CI job token
+func NewClient(baseURL, token string) *Client {
+	return newClient(baseURL, token, http.DefaultClient)
+}
+
+// newClient creates a GitLab client that sends requests through httpClient
+func newClient(baseURL, token string, httpClient *http.Client) *Client {
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		token:      token,
+		httpClient: httpClient,
+	}
+}
+
+// CommentMR adds a comment to a merge request. The project is its full
+// path, such as group/subgroup/name, or its numeric ID.
+func (c *Client) CommentMR(project string, mrID int, body string) error {
+	return c.do(http.MethodPost, c.notesPath(project, mrID), body)
+}
+
+// UpsertComment updates the merge request comment that contains marker, or
+// creates a new comment if there is none. The marker is added to the body
+// when missing.
+func (c *Client) UpsertComment(project string, mrID int, marker, body string) error {
+	if !strings.Contains(body, marker) {
+		body = marker + "\n" + body
+	}
+
+	page := "1"
+	for page != "" {
+		var notes []note
+		path := c.notesPath(project, mrID) + "?per_page=100&page=" + page
+		resp, err := c.request(http.MethodGet, path, nil)
+		if err != nil {
+			return fmt.Errorf("error listing MR comments: %w", err)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&notes)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("error listing MR comments: %w", err)
+		}
+
+		for _, n := range notes {
+			if strings.Contains(n.Body, marker) {
+				return c.do(http.MethodPut, c.notesPath(project, mrID)+"/"+strconv.Itoa(n.ID), body)
+			}
+		}
+		page = resp.Header.Get("X-Next-Page")
+	}
+
+	return c.CommentMR(project, mrID, body)
+}
+
+// AddLabels adds labels to a merge request, keeping the labels it already
+// has
+func (c *Client) AddLabels(project string, mrID int, labels []string) error {
+	payload, err := json.Marshal(map[string]string{"add_labels": strings.Join(labels, ",")})
+	if err != nil {
+		return err
+	}
+	path := fmt.Sprintf("/api/v4/projects/%s/merge_requests/%d", url.PathEscape(project), mrID)
+	resp, err := c.request(http.MethodPut, path, payload)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// notesPath returns the API path of a merge request's notes
+func (c *Client) notesPath(project string, mrID int) string {
+	return fmt.Sprintf("/api/v4/projects/%s/merge_requests/%d/notes", url.PathEscape(project), mrID)
+}
+
+// do sends a note body to the API and discards the response
+func (c *Client) do(method, path, body string) error {
+	payload, err := json.Marshal(map[string]string{"body": body})
+	if err != nil {
+		return err
+	}
+	resp, err := c.request(method, path, payload)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// request sends an authenticated API request and returns the response when
+// it succeeded
+func (c *Client) request(method, path string, payload []byte) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("PRIVATE-TOKEN", c.token)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(message)))
+	}
+	return resp, nil
+}
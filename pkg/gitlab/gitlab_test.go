@@ -0,0 +1,134 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newTestClient returns a client that sends every request to handler
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return newClient(server.URL+"/", "secret", server.Client())
+}
+
+func TestCommentMR(t *testing.T) {
+	var gotPath, gotToken, gotBody string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.Method + " " + r.URL.EscapedPath()
+		gotToken = r.Header.Get("PRIVATE-TOKEN")
+		var n note
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			t.Errorf("decoding request body: %v", err)
+		}
+		gotBody = n.Body
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 1}`))
+	})
+
+	if err := c.CommentMR("group/project", 42, "report"); err != nil {
+		t.Fatalf("CommentMR() error = %v", err)
+	}
+	if gotPath != "POST /api/v4/projects/group%2Fproject/merge_requests/42/notes" {
+		t.Errorf("CommentMR() sent %s, want POST to the MR notes", gotPath)
+	}
+	if gotToken != "secret" || gotBody != "report" {
+		t.Errorf("CommentMR() sent token %q and body %q, want secret and report", gotToken, gotBody)
+	}
+}
+
+func TestCommentMRError(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message": "403 Forbidden"}`))
+	})
+
+	err := c.CommentMR("group/project", 42, "report")
+	if err == nil || !strings.Contains(err.Error(), "403 Forbidden") {
+		t.Errorf("CommentMR() error = %v, want the GitLab error message", err)
+	}
+}
+
+func TestUpsertComment(t *testing.T) {
+	tests := []struct {
+		name       string
+		pages      []string
+		wantMethod string
+		wantPath   string
+	}{
+		{
+			name: "edits existing comment on a later page",
+			pages: []string{
+				`[{"id": 7, "body": "unrelated"}, {"id": 8, "body": "<!-- otelcompare:info -->\ninfo report"}]`,
+				`[{"id": 9, "body": "<!-- otelcompare:compare -->\nold report"}]`,
+			},
+			wantMethod: http.MethodPut,
+			wantPath:   "/api/v4/projects/group%2Fproject/merge_requests/42/notes/9",
+		},
+		{
+			name:       "creates new comment next to another command's",
+			pages:      []string{`[{"id": 7, "body": "unrelated"}, {"id": 8, "body": "<!-- otelcompare:info -->\ninfo report"}]`},
+			wantMethod: http.MethodPost,
+			wantPath:   "/api/v4/projects/group%2Fproject/merge_requests/42/notes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotMethod, gotPath, gotBody string
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet {
+					page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+					if page < len(tt.pages) {
+						w.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+					}
+					w.Write([]byte(tt.pages[page-1]))
+					return
+				}
+				gotMethod, gotPath = r.Method, r.URL.EscapedPath()
+				var n note
+				json.NewDecoder(r.Body).Decode(&n)
+				gotBody = n.Body
+				w.Write([]byte(`{"id": 1}`))
+			})
+
+			if err := c.UpsertComment("group/project", 42, CommentMarker("compare"), "new report"); err != nil {
+				t.Fatalf("UpsertComment() error = %v", err)
+			}
+			if gotMethod != tt.wantMethod || gotPath != tt.wantPath {
+				t.Errorf("UpsertComment() sent %s %s, want %s %s", gotMethod, gotPath, tt.wantMethod, tt.wantPath)
+			}
+			if !strings.HasPrefix(gotBody, "<!-- otelcompare:compare -->\n") || !strings.Contains(gotBody, "new report") {
+				t.Errorf("UpsertComment() body = %q, want the marker followed by the report", gotBody)
+			}
+		})
+	}
+}
+
+func TestAddLabels(t *testing.T) {
+	var gotPath string
+	var got map[string]string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.Method + " " + r.URL.EscapedPath()
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding request body: %v", err)
+		}
+		w.Write([]byte(`{"iid": 42}`))
+	})
+
+	if err := c.AddLabels("group/project", 42, []string{"perf-regression", "needs-review"}); err != nil {
+		t.Fatalf("AddLabels() error = %v", err)
+	}
+	if gotPath != "PUT /api/v4/projects/group%2Fproject/merge_requests/42" {
+		t.Errorf("AddLabels() sent %s, want PUT to the MR", gotPath)
+	}
+	if got["add_labels"] != "perf-regression,needs-review" {
+		t.Errorf("AddLabels() sent %v, want add_labels with both labels", got)
+	}
+}
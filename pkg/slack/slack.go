@@ -0,0 +1,277 @@
+package slack
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxMessageLength is the longest message text, in bytes, that is sent in a
+// single message. Slack truncates messages longer than 40,000 characters.
+const MaxMessageLength = 40000
+
+// codeFence opens and closes a Slack code block
+const codeFence = "```\n"
+
+// defaultClient bounds how long posting a single message may take
+var defaultClient = &http.Client{Timeout: 30 * time.Second}
+
+var (
+	htmlComment   = regexp.MustCompile(`<!--.*?-->`)
+	summaryTag    = regexp.MustCompile(`<summary>(.*?)</summary>`)
+	detailsTag    = regexp.MustCompile(`</?details>`)
+	lineBreak     = regexp.MustCompile(`<br>\s*`)
+	heading       = regexp.MustCompile(`^#{1,6}\s+(.*)$`)
+	bold          = regexp.MustCompile(`\*\*(.+?)\*\*`)
+	link          = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	listItem      = regexp.MustCompile(`^(\s*)[-*]\s+`)
+	separatorCell = regexp.MustCompile(`^:?-+:?$`)
+	blankLines    = regexp.MustCompile(`\n{3,}`)
+)
+
+// PostMessage converts a markdown report to Slack mrkdwn and posts it to an
+// incoming webhook. Reports longer than MaxMessageLength are posted as
+// several messages.
+func PostMessage(webhookURL, markdown string) error {
+	return postMessage(defaultClient, webhookURL, markdown)
+}
+
+// postMessage posts a markdown report through httpClient
+func postMessage(httpClient *http.Client, webhookURL, markdown string) error {
+	messages := splitMessage(ToMrkdwn(markdown), MaxMessageLength)
+	for i, text := range messages {
+		if err := post(httpClient, webhookURL, text); err != nil {
+			if len(messages) > 1 {
+				return fmt.Errorf("message %d of %d: %w", i+1, len(messages), err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// post sends a single message to the webhook. Errors never include the
+// webhook URL, which is a secret.
+func post(httpClient *http.Client, webhookURL, text string) error {
+	payload, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient.Post(webhookURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// ToMrkdwn converts a markdown report to Slack's mrkdwn format. Headings
+// and bold text become bold, links use Slack's <url|text> syntax and tables
+// become aligned code blocks, as Slack has no tables. Collapsible details
+// are expanded, with their summary in bold.
+func ToMrkdwn(markdown string) string {
+	var sb strings.Builder
+	var table [][]string
+	flushTable := func() {
+		if len(table) > 0 {
+			writeTable(&sb, table)
+			table = nil
+		}
+	}
+
+	inCode := false
+	for _, line := range strings.Split(markdown, "\n") {
+		switch {
+		case strings.HasPrefix(line, "```"):
+			// Slack code blocks have no language
+			flushTable()
+			inCode = !inCode
+			sb.WriteString(codeFence)
+		case inCode:
+			sb.WriteString(escape(line) + "\n")
+		case strings.HasPrefix(line, "|"):
+			if cells := tableCells(line); !isSeparatorRow(cells) {
+				table = append(table, cells)
+			}
+		default:
+			flushTable()
+			converted := convertLine(line)
+			if converted == "" && strings.TrimSpace(line) != "" {
+				// The line only held HTML such as <details>
+				continue
+			}
+			sb.WriteString(converted + "\n")
+		}
+	}
+	flushTable()
+
+	return strings.TrimSpace(blankLines.ReplaceAllString(sb.String(), "\n\n")) + "\n"
+}
+
+// convertLine converts a line of markdown outside tables and code blocks
+func convertLine(line string) string {
+	line = htmlComment.ReplaceAllString(line, "")
+	line = summaryTag.ReplaceAllString(line, "**$1**")
+	line = detailsTag.ReplaceAllString(line, "")
+	line = lineBreak.ReplaceAllString(line, "; ")
+	if strings.TrimSpace(line) == "" {
+		return ""
+	}
+
+	line = escape(line)
+	if m := heading.FindStringSubmatch(line); m != nil {
+		line = "**" + strings.ReplaceAll(m[1], "**", "") + "**"
+	}
+	line = bold.ReplaceAllString(line, "*$1*")
+	line = link.ReplaceAllString(line, "<$2|$1>")
+	return listItem.ReplaceAllString(line, "$1• ")
+}
+
+// tableCells splits a markdown table row into its cells, as plain text
+func tableCells(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, cell.String())
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	cells = append(cells, cell.String())
+
+	for i, c := range cells {
+		c = lineBreak.ReplaceAllString(c, "; ")
+		c = strings.NewReplacer("**", "", "`", "").Replace(c)
+		cells[i] = escape(strings.TrimSpace(c))
+	}
+	return cells
+}
+
+// isSeparatorRow reports whether table cells are the row separating the
+// header from the body, e.g. |---|:---:|
+func isSeparatorRow(cells []string) bool {
+	for _, c := range cells {
+		if !separatorCell.MatchString(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// writeTable writes table rows as a code block with aligned columns
+func writeTable(sb *strings.Builder, rows [][]string) {
+	var widths []int
+	for _, row := range rows {
+		for i, c := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], utf8.RuneCountInString(c))
+		}
+	}
+
+	sb.WriteString(codeFence)
+	for _, row := range rows {
+		var line strings.Builder
+		for i, c := range row {
+			if i > 0 {
+				line.WriteString("  ")
+			}
+			line.WriteString(c)
+			line.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(c)))
+		}
+		sb.WriteString(strings.TrimRight(line.String(), " ") + "\n")
+	}
+	sb.WriteString(codeFence)
+}
+
+// escape escapes the characters Slack reserves for its own markup
+func escape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// splitMessage splits text into messages of at most limit bytes, breaking
+// between lines. A code block that is split is closed at the end of one
+// message and reopened in the next.
+func splitMessage(text string, limit int) []string {
+	if len(text) <= limit {
+		return []string{text}
+	}
+
+	// Leave room to close and reopen a code block around every line
+	maxLine := limit - 2*len(codeFence)
+
+	var messages []string
+	var sb strings.Builder
+	inCode := false
+	for _, line := range strings.SplitAfter(text, "\n") {
+		for _, piece := range splitLine(line, maxLine) {
+			reserve := 0
+			if inCode {
+				reserve = len(codeFence)
+			}
+			if sb.Len() > 0 && sb.Len()+len(piece)+reserve > limit {
+				if inCode {
+					sb.WriteString(codeFence)
+				}
+				messages = append(messages, sb.String())
+				sb.Reset()
+				if inCode {
+					sb.WriteString(codeFence)
+				}
+			}
+			sb.WriteString(piece)
+		}
+		if strings.HasPrefix(line, "```") {
+			inCode = !inCode
+		}
+	}
+	if sb.Len() > 0 {
+		messages = append(messages, sb.String())
+	}
+	return messages
+}
+
+// splitLine cuts a line longer than limit bytes into pieces that end in a
+// newline, never splitting a UTF-8 character
+func splitLine(line string, limit int) []string {
+	var pieces []string
+	for len(line) > limit {
+		cut := limit - 1
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		pieces = append(pieces, line[:cut]+"\n")
+		line = line[cut:]
+	}
+	return append(pieces, line)
+}
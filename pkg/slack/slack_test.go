@@ -0,0 +1,141 @@
+package slack
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestToMrkdwn(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		expected string
+	}{
+		{
+			name:     "headings and bold",
+			markdown: "### Multiple Traces Comparison\n\n**✅ No regressions**\n",
+			expected: "*Multiple Traces Comparison*\n\n*✅ No regressions*\n",
+		},
+		{
+			name:     "links and reserved characters",
+			markdown: "See [the run](https://ci.example.com/runs/1) for p90 < 1s & more\n",
+			expected: "See <https://ci.example.com/runs/1|the run> for p90 &lt; 1s &amp; more\n",
+		},
+		{
+			name:     "list items",
+			markdown: "- Trace `trace1`: span b has a missing parent\n",
+			expected: "• Trace `trace1`: span b has a missing parent\n",
+		},
+		{
+			name:     "details are expanded",
+			markdown: "<!-- otelcompare -->\n<details>\n<summary>Trace trace1</summary>\n\ncontent\n</details>\n",
+			expected: "*Trace trace1*\n\ncontent\n",
+		},
+		{
+			name: "tables become aligned code blocks",
+			markdown: "| Trace Name | base | Duration Diff |\n" +
+				"|------------|------|---------------|\n" +
+				"| **Total spans** | 4 | - |\n" +
+				"| `GET /a\\|b` | 10<br> 20 | +1 |\n",
+			expected: "```\n" +
+				"Trace Name   base    Duration Diff\n" +
+				"Total spans  4       -\n" +
+				"GET /a|b     10; 20  +1\n" +
+				"```\n",
+		},
+		{
+			name:     "code blocks lose their language",
+			markdown: "```mermaid\ngantt\n    a :0, 1ms\n```\n",
+			expected: "```\ngantt\n    a :0, 1ms\n```\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToMrkdwn(tt.markdown); got != tt.expected {
+				t.Errorf("ToMrkdwn() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSplitMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		limit    int
+		expected []string
+	}{
+		{name: "short", text: "a\nb\n", limit: 20, expected: []string{"a\nb\n"}},
+		{name: "between lines", text: "aaaaaaaa\nbbbbbbbb\ncccc\n", limit: 20, expected: []string{"aaaaaaaa\nbbbbbbbb\n", "cccc\n"}},
+		{
+			name:     "code block is reopened",
+			text:     "```\naaaa\nbbbb\ncccc\n```\n",
+			limit:    20,
+			expected: []string{"```\naaaa\nbbbb\n```\n", "```\ncccc\n```\n"},
+		},
+		{
+			name:     "long line is cut",
+			text:     strings.Repeat("a", 25) + "\n",
+			limit:    20,
+			expected: []string{strings.Repeat("a", 11) + "\n", strings.Repeat("a", 11) + "\naaa\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitMessage(tt.text, tt.limit)
+			if strings.Join(got, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("splitMessage() = %q, want %q", got, tt.expected)
+			}
+			for _, message := range got {
+				if len(message) > tt.limit {
+					t.Errorf("splitMessage() message %q is longer than %d bytes", message, tt.limit)
+				}
+			}
+		})
+	}
+}
+
+func TestPostMessage(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decoding request body: %v", err)
+		}
+		got = append(got, payload["text"])
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	if err := postMessage(server.Client(), server.URL+"/services/T000/B000/secret", "### Report\n"); err != nil {
+		t.Fatalf("postMessage() error = %v", err)
+	}
+	if len(got) != 1 || got[0] != "*Report*\n" {
+		t.Errorf("postMessage() sent %q, want the report as mrkdwn", got)
+	}
+}
+
+func TestPostMessageError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("invalid_token"))
+	}))
+	defer server.Close()
+
+	err := postMessage(server.Client(), server.URL+"/services/T000/B000/secret", "report")
+	if err == nil || !strings.Contains(err.Error(), "invalid_token") {
+		t.Fatalf("postMessage() error = %v, want the Slack error", err)
+	}
+
+	// The webhook URL is a secret and must not end up in CI logs
+	server.Close()
+	err = postMessage(server.Client(), server.URL+"/services/T000/B000/secret", "report")
+	if err == nil || strings.Contains(err.Error(), "secret") {
+		t.Errorf("postMessage() error = %v, want an error without the webhook URL", err)
+	}
+}
@@ -0,0 +1,408 @@
+package trace
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OperationDurations collects the durations of all spans in the traces,
+// keyed by operation (span) name
+func OperationDurations(traces []Trace) map[string][]time.Duration {
+	durations := make(map[string][]time.Duration)
+	for _, t := range traces {
+		for _, span := range t.Spans {
+			durations[span.Name] = append(durations[span.Name], spanDuration(span))
+		}
+	}
+	return durations
+}
+
+// Percentile returns the p-th percentile (0-100) of a set of durations
+// using the nearest-rank method, or 0 when there are no durations
+func Percentile(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+// DurationStats holds percentile statistics of a set of durations
+type DurationStats struct {
+	Count int
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+// NewDurationStats computes the percentile statistics of a set of durations
+func NewDurationStats(durations []time.Duration) DurationStats {
+	return DurationStats{
+		Count: len(durations),
+		P50:   Percentile(durations, 50),
+		P90:   Percentile(durations, 90),
+		P99:   Percentile(durations, 99),
+		Max:   Percentile(durations, 100),
+	}
+}
+
+// TraceGroup holds duration statistics of the traces sharing an identifier
+type TraceGroup struct {
+	Name  string
+	Count int
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+// GroupTraces groups traces by the given identifier attribute and computes
+// duration statistics per group. Groups are sorted by name.
+func GroupTraces(traces []Trace, attribute string) []TraceGroup {
+	durations := make(map[string][]time.Duration)
+	for _, t := range traces {
+		name := getTraceIdentifier(t, attribute)
+		durations[name] = append(durations[name], getTraceDuration(t))
+	}
+
+	groups := make([]TraceGroup, 0, len(durations))
+	for name, d := range durations {
+		stats := NewDurationStats(d)
+		groups = append(groups, TraceGroup{
+			Name:  name,
+			Count: stats.Count,
+			P50:   stats.P50,
+			P90:   stats.P90,
+			P99:   stats.P99,
+			Max:   stats.Max,
+		})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups
+}
+
+// writeAggregateOverview writes a table with duration statistics per trace
+// group instead of a row per trace
+func writeAggregateOverview(sb *strings.Builder, traces []Trace, attribute string) {
+	sb.WriteString("| Trace | Count | p50 | p90 | p99 | Max |\n")
+	sb.WriteString("|-------|-------|-----|-----|-----|-----|\n")
+	for _, g := range GroupTraces(traces, attribute) {
+		sb.WriteString(fmt.Sprintf("| %s | %d | %s | %s | %s | %s |\n",
+			escapeMarkdownCell(g.Name), g.Count,
+			formatDuration(g.P50), formatDuration(g.P90), formatDuration(g.P99), formatDuration(g.Max)))
+	}
+}
+
+// groupMatch matches trace groups, and the spans of their traces, across
+// trace sets. The markdown report and the structured comparison of
+// aggregated traces are both built from it.
+type groupMatch struct {
+	// groups index the trace groups of each set by name, and names holds
+	// the names of the groups of every set, sorted
+	groups []map[string]TraceGroup
+	names  []string
+
+	// spanStats holds the span statistics of each set per group, and
+	// matched the names of the groups found in every set, sorted
+	spanStats []map[string]map[spanRef]DurationStats
+	matched   []string
+}
+
+// matchTraceGroups groups the traces of each set by identifier attribute
+// and matches the groups across the sets
+func matchTraceGroups(traceSets []TraceSet, attribute string) groupMatch {
+	m := groupMatch{
+		groups:    make([]map[string]TraceGroup, len(traceSets)),
+		spanStats: make([]map[string]map[spanRef]DurationStats, len(traceSets)),
+	}
+	allNames := make(map[string]bool)
+	for i, set := range traceSets {
+		m.groups[i] = make(map[string]TraceGroup)
+		for _, g := range GroupTraces(set.Traces, attribute) {
+			m.groups[i][g.Name] = g
+			allNames[g.Name] = true
+		}
+		m.spanStats[i] = groupSpanStats(set.Traces, attribute)
+	}
+
+	for name := range allNames {
+		m.names = append(m.names, name)
+		inAll := true
+		for _, groups := range m.groups {
+			if _, ok := groups[name]; !ok {
+				inAll = false
+				break
+			}
+		}
+		if inAll {
+			m.matched = append(m.matched, name)
+		}
+	}
+	sort.Strings(m.names)
+	sort.Strings(m.matched)
+	return m
+}
+
+// spanRefs returns the spans of a group in any set, sorted
+func (m groupMatch) spanRefs(group string) []spanRef {
+	allRefs := make(map[spanRef]bool)
+	for _, setStats := range m.spanStats {
+		for ref := range setStats[group] {
+			allRefs[ref] = true
+		}
+	}
+	var refs []spanRef
+	for ref := range allRefs {
+		refs = append(refs, ref)
+	}
+	sortSpanRefs(refs)
+	return refs
+}
+
+// writeAggregateComparison writes a summary table comparing the p90
+// duration of every trace group across trace sets
+func writeAggregateComparison(sb *strings.Builder, traceSets []TraceSet, m groupMatch, ind indicators) {
+	sb.WriteString("**Comparison Summary (p90):**\n\n")
+	sb.WriteString("| Trace Name |")
+	for _, set := range traceSets {
+		sb.WriteString(fmt.Sprintf(" %s |", escapeMarkdownCell(getFileNameWithoutExt(set.Name))))
+	}
+	diffHeaders := metricDiffHeaders(traceSets, "p90")
+	sb.WriteString(fmt.Sprintf(" %s |\n|------------", strings.Join(diffHeaders, " | ")))
+	for range traceSets {
+		sb.WriteString("|------------")
+	}
+	sb.WriteString(strings.Repeat("|------------", len(diffHeaders)))
+	sb.WriteString("|\n")
+
+	for _, name := range m.names {
+		sb.WriteString(fmt.Sprintf("| %s |", escapeMarkdownCell(name)))
+		var durations []time.Duration
+		var present []bool
+		for i := range traceSets {
+			g, ok := m.groups[i][name]
+			present = append(present, ok)
+			if !ok {
+				sb.WriteString(fmt.Sprintf(" %s |", ind.missing))
+				durations = append(durations, 0)
+				continue
+			}
+			sb.WriteString(fmt.Sprintf(" %s (n=%d) |", formatDuration(g.P90), g.Count))
+			durations = append(durations, g.P90)
+		}
+		sb.WriteString(fmt.Sprintf(" %s |\n", strings.Join(durationDiffCells(durations, present, ind), " | ")))
+	}
+	sb.WriteString("\n")
+}
+
+// SpanStats groups traces by the given identifier attribute and computes
+// the duration statistics of every span across the traces of each group.
+// Spans are keyed like in comparisons, so repeated names are numbered by
+// occurrence within their trace, e.g. "db.query #2".
+func SpanStats(traces []Trace, attribute string) map[string]map[string]DurationStats {
+	stats := make(map[string]map[string]DurationStats)
+	for group, spans := range groupSpanStats(traces, attribute) {
+		stats[group] = make(map[string]DurationStats, len(spans))
+		for ref, s := range spans {
+			stats[group][ref.key()] = s
+		}
+	}
+	return stats
+}
+
+// groupSpanStats computes SpanStats keyed by spanRef
+func groupSpanStats(traces []Trace, attribute string) map[string]map[spanRef]DurationStats {
+	durations := make(map[string]map[spanRef][]time.Duration)
+	for i := range traces {
+		group := getTraceIdentifier(traces[i], attribute)
+		if durations[group] == nil {
+			durations[group] = make(map[spanRef][]time.Duration)
+		}
+		for ref, span := range spanIndex(&traces[i]) {
+			durations[group][ref] = append(durations[group][ref], spanDuration(*span))
+		}
+	}
+
+	stats := make(map[string]map[spanRef]DurationStats, len(durations))
+	for group, spans := range durations {
+		stats[group] = make(map[spanRef]DurationStats, len(spans))
+		for ref, d := range spans {
+			stats[group][ref] = NewDurationStats(d)
+		}
+	}
+	return stats
+}
+
+// writeSpanPercentileComparison writes the detailed comparison of
+// aggregated traces: the p50 and p90 duration of every span of each trace
+// group found in all trace sets, across all of the group's traces
+func writeSpanPercentileComparison(sb *strings.Builder, traceSets []TraceSet, m groupMatch, ind indicators) {
+	sb.WriteString("**Detailed Comparison (p50 / p90):**\n\n")
+	for _, group := range m.matched {
+		sb.WriteString(fmt.Sprintf("<details>\n<summary>%s</summary>\n\n", escapeMarkdownSummary(group)))
+		sb.WriteString("| Span Name |")
+		for _, set := range traceSets {
+			sb.WriteString(fmt.Sprintf(" %s |", escapeMarkdownCell(getFileNameWithoutExt(set.Name))))
+		}
+		diffHeaders := append(metricDiffHeaders(traceSets, "p50"), metricDiffHeaders(traceSets, "p90")...)
+		sb.WriteString(fmt.Sprintf(" %s |\n|-----------", strings.Join(diffHeaders, " | ")))
+		for range traceSets {
+			sb.WriteString("|-----------")
+		}
+		sb.WriteString(strings.Repeat("|------------", len(diffHeaders)))
+		sb.WriteString("|\n")
+
+		for _, ref := range m.spanRefs(group) {
+			sb.WriteString(fmt.Sprintf("| %s |", escapeMarkdownCell(ref.key())))
+			var p50s, p90s []time.Duration
+			var present []bool
+			for _, setStats := range m.spanStats {
+				s, ok := setStats[group][ref]
+				present = append(present, ok)
+				p50s = append(p50s, s.P50)
+				p90s = append(p90s, s.P90)
+				if !ok {
+					sb.WriteString(fmt.Sprintf(" %s |", ind.missing))
+					continue
+				}
+				sb.WriteString(fmt.Sprintf(" %s / %s (n=%d) |", formatDuration(s.P50), formatDuration(s.P90), s.Count))
+			}
+			diffs := append(durationDiffCells(p50s, present, ind), durationDiffCells(p90s, present, ind)...)
+			sb.WriteString(fmt.Sprintf(" %s |\n", strings.Join(diffs, " | ")))
+		}
+		sb.WriteString("\n</details>\n\n")
+	}
+}
+
+// Apdex computes the Apdex score of a set of durations for the target T.
+// Durations up to T are satisfied, durations up to 4T are tolerating and
+// anything slower is frustrated. The score is
+// (satisfied + tolerating/2) / total, or 0 when there are no durations.
+func Apdex(durations []time.Duration, target time.Duration) float64 {
+	if len(durations) == 0 {
+		return 0
+	}
+
+	var satisfied, tolerating int
+	for _, d := range durations {
+		switch {
+		case d <= target:
+			satisfied++
+		case d <= 4*target:
+			tolerating++
+		}
+	}
+
+	return (float64(satisfied) + float64(tolerating)/2) / float64(len(durations))
+}
+
+// apdexTarget returns the Apdex target for an operation, preferring a
+// per-operation override over the default target
+func apdexTarget(opts Options, operation string) time.Duration {
+	if target, ok := opts.ApdexTargets[operation]; ok {
+		return target
+	}
+	return opts.ApdexTarget
+}
+
+// writeApdexComparison writes a table with the Apdex score of every
+// operation in each trace set and the change relative to the first set
+func writeApdexComparison(sb *strings.Builder, traceSets []TraceSet, opts Options) {
+	ind := opts.indicators()
+	operationDurations := make([]map[string][]time.Duration, len(traceSets))
+	allOperations := make(map[string]bool)
+	for i, set := range traceSets {
+		operationDurations[i] = OperationDurations(set.Traces)
+		for name := range operationDurations[i] {
+			allOperations[name] = true
+		}
+	}
+
+	var operations []string
+	for name := range allOperations {
+		if apdexTarget(opts, name) > 0 {
+			operations = append(operations, name)
+		}
+	}
+	if len(operations) == 0 {
+		return
+	}
+	sort.Strings(operations)
+
+	sb.WriteString("**Apdex Scores:**\n\n")
+	sb.WriteString("| Operation | T |")
+	for _, set := range traceSets {
+		sb.WriteString(fmt.Sprintf(" %s |", escapeMarkdownCell(getFileNameWithoutExt(set.Name))))
+	}
+	sb.WriteString(" Change |\n|-----------|---")
+	for range traceSets {
+		sb.WriteString("|-----------")
+	}
+	sb.WriteString("|--------|\n")
+
+	for _, name := range operations {
+		target := apdexTarget(opts, name)
+		sb.WriteString(fmt.Sprintf("| %s | %s |", escapeMarkdownCell(name), formatDuration(target)))
+
+		var scores []float64
+		var present []bool
+		for i := range traceSets {
+			durations, ok := operationDurations[i][name]
+			if !ok {
+				sb.WriteString(fmt.Sprintf(" %s |", ind.missing))
+				scores = append(scores, 0)
+				present = append(present, false)
+				continue
+			}
+			score := Apdex(durations, target)
+			sb.WriteString(fmt.Sprintf(" %.2f |", score))
+			scores = append(scores, score)
+			present = append(present, true)
+		}
+
+		// Show the largest change relative to the first set
+		var maxChange float64
+		if present[0] {
+			for i := 1; i < len(scores); i++ {
+				if !present[i] {
+					continue
+				}
+				change := scores[i] - scores[0]
+				if abs(change) > abs(maxChange) {
+					maxChange = change
+				}
+			}
+		}
+
+		switch {
+		case maxChange < 0:
+			sb.WriteString(fmt.Sprintf(" %s %.2f |\n", ind.slower, maxChange))
+		case maxChange > 0:
+			sb.WriteString(fmt.Sprintf(" %s +%.2f |\n", ind.faster, maxChange))
+		default:
+			sb.WriteString(" - |\n")
+		}
+	}
+	sb.WriteString("\n")
+}
+
+func abs(f float64) float64 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
@@ -0,0 +1,287 @@
+package trace
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApdex(t *testing.T) {
+	tests := []struct {
+		name      string
+		durations []time.Duration
+		target    time.Duration
+		expected  float64
+	}{
+		{
+			name:      "all satisfied",
+			durations: []time.Duration{100 * time.Millisecond, 200 * time.Millisecond},
+			target:    200 * time.Millisecond,
+			expected:  1,
+		},
+		{
+			name:      "mixed",
+			durations: []time.Duration{100 * time.Millisecond, 500 * time.Millisecond, 900 * time.Millisecond, time.Second},
+			target:    200 * time.Millisecond,
+			expected:  0.375,
+		},
+		{
+			name:      "all frustrated",
+			durations: []time.Duration{time.Second},
+			target:    100 * time.Millisecond,
+			expected:  0,
+		},
+		{
+			name:      "no durations",
+			durations: nil,
+			target:    100 * time.Millisecond,
+			expected:  0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Apdex(tt.durations, tt.target)
+			if got != tt.expected {
+				t.Errorf("Apdex() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestOperationDurations(t *testing.T) {
+	now := time.Now()
+	traces := []Trace{
+		{Spans: []Span{
+			{Name: "checkout", StartTime: now, EndTime: now.Add(time.Second)},
+			{Name: "db.query", StartTime: now, EndTime: now.Add(time.Millisecond)},
+		}},
+		{Spans: []Span{
+			{Name: "checkout", StartTime: now, EndTime: now.Add(2 * time.Second)},
+		}},
+	}
+
+	got := OperationDurations(traces)
+	if len(got["checkout"]) != 2 || len(got["db.query"]) != 1 {
+		t.Errorf("OperationDurations() = %v, want 2 checkout and 1 db.query durations", got)
+	}
+}
+
+func TestCompareMultipleTracesApdex(t *testing.T) {
+	now := time.Now()
+	traceSets := []TraceSet{
+		{Name: "base.json", Traces: []Trace{{TraceID: "trace1", Spans: []Span{
+			{Name: "checkout", StartTime: now, EndTime: now.Add(100 * time.Millisecond)},
+		}}}},
+		{Name: "head.json", Traces: []Trace{{TraceID: "trace1", Spans: []Span{
+			{Name: "checkout", StartTime: now, EndTime: now.Add(time.Second)},
+		}}}},
+	}
+
+	got := CompareMultipleTraces(traceSets, "trace_id", Options{
+		ApdexTargets: map[string]time.Duration{"checkout": 200 * time.Millisecond},
+	})
+	if !strings.Contains(got, "| checkout | 200.00ms | 1.00 | 0.00 | 🔴 -1.00 |") {
+		t.Errorf("CompareMultipleTraces() output missing Apdex row:\n%s", got)
+	}
+	if strings.Contains(CompareMultipleTraces(traceSets, "trace_id", Options{}), "Apdex") {
+		t.Errorf("CompareMultipleTraces() should not report Apdex without a target")
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var durations []time.Duration
+	for i := 10; i >= 1; i-- {
+		durations = append(durations, time.Duration(i)*time.Second)
+	}
+
+	tests := []struct {
+		name      string
+		durations []time.Duration
+		p         float64
+		expected  time.Duration
+	}{
+		{name: "p50", durations: durations, p: 50, expected: 5 * time.Second},
+		{name: "p90", durations: durations, p: 90, expected: 9 * time.Second},
+		{name: "p99", durations: durations, p: 99, expected: 10 * time.Second},
+		{name: "max", durations: durations, p: 100, expected: 10 * time.Second},
+		{name: "min", durations: durations, p: 0, expected: time.Second},
+		{name: "single", durations: []time.Duration{time.Second}, p: 90, expected: time.Second},
+		{name: "empty", durations: nil, p: 90, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Percentile(tt.durations, tt.p); got != tt.expected {
+				t.Errorf("Percentile() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+
+	// The input must not be reordered
+	if durations[0] != 10*time.Second {
+		t.Errorf("Percentile() sorted the caller's durations")
+	}
+}
+
+func TestGroupTraces(t *testing.T) {
+	now := time.Now()
+	var traces []Trace
+	for i := 1; i <= 10; i++ {
+		traces = append(traces, Trace{TraceID: fmt.Sprintf("checkout-%d", i), Spans: []Span{
+			{SpanID: "a", Name: "checkout", StartTime: now, EndTime: now.Add(time.Duration(i) * 100 * time.Millisecond)},
+		}})
+	}
+	traces = append(traces, Trace{TraceID: "login", Spans: []Span{
+		{SpanID: "a", Name: "login", StartTime: now, EndTime: now.Add(time.Second)},
+	}})
+
+	groups := GroupTraces(traces, "name")
+	if len(groups) != 2 {
+		t.Fatalf("GroupTraces() returned %d groups, want 2", len(groups))
+	}
+
+	expected := TraceGroup{
+		Name:  "checkout",
+		Count: 10,
+		P50:   500 * time.Millisecond,
+		P90:   900 * time.Millisecond,
+		P99:   time.Second,
+		Max:   time.Second,
+	}
+	if groups[0] != expected {
+		t.Errorf("GroupTraces() first group = %+v, want %+v", groups[0], expected)
+	}
+	if groups[1].Name != "login" || groups[1].Count != 1 {
+		t.Errorf("GroupTraces() second group = %+v, want a single login trace", groups[1])
+	}
+}
+
+func TestAggregateReports(t *testing.T) {
+	now := time.Now()
+	newSet := func(name string, durations ...time.Duration) TraceSet {
+		set := TraceSet{Name: name}
+		for i, d := range durations {
+			set.Traces = append(set.Traces, Trace{TraceID: fmt.Sprintf("%s-%d", name, i), Spans: []Span{
+				{SpanID: "a", Name: "checkout", StartTime: now, EndTime: now.Add(d)},
+			}})
+		}
+		return set
+	}
+	base := newSet("base.json", time.Second, 2*time.Second)
+	head := newSet("head.json", 2*time.Second, 3*time.Second)
+
+	markdown := GenerateMarkdown(base.Traces, Options{AggregateBy: "name"})
+	if !strings.Contains(markdown, "| checkout | 2 | 1.00s | 2.00s | 2.00s | 2.00s |") {
+		t.Errorf("GenerateMarkdown() did not aggregate the overview:\n%s", markdown)
+	}
+	if strings.Contains(markdown, "| Trace ID | Duration |") {
+		t.Errorf("GenerateMarkdown() still lists traces individually:\n%s", markdown)
+	}
+
+	comparison := CompareMultipleTraces([]TraceSet{base, head}, "name", Options{AggregateBy: "name"})
+	if !strings.Contains(comparison, "| checkout | 2.00s (n=2) | 3.00s (n=2) | 🔴 +1.00s (+50.0%) |") {
+		t.Errorf("CompareMultipleTraces() did not compare p90s:\n%s", comparison)
+	}
+	if !strings.Contains(comparison, "| checkout | 1.00s / 2.00s (n=2) | 2.00s / 3.00s (n=2) | 🔴 +1.00s (+100.0%) | 🔴 +1.00s (+50.0%) |") {
+		t.Errorf("CompareMultipleTraces() did not compare span percentiles:\n%s", comparison)
+	}
+	if strings.Contains(comparison, "checkout #2") {
+		t.Errorf("CompareMultipleTraces() compared traces individually:\n%s", comparison)
+	}
+
+	// Three sets get one diff per file compared with the first, for every
+	// percentile
+	next := newSet("next.json", time.Second, time.Second)
+	comparison = CompareMultipleTraces([]TraceSet{base, head, next}, "name", Options{AggregateBy: "name"})
+	for _, want := range []string{
+		"| Trace Name | base | head | next | p90 Δ head | p90 Δ next |\n|------------|------------|------------|------------|------------|------------|\n",
+		"| checkout | 2.00s (n=2) | 3.00s (n=2) | 1.00s (n=2) | 🔴 +1.00s (+50.0%) | 🟢 -1.00s (-50.0%) |\n",
+		"| Span Name | base | head | next | p50 Δ head | p50 Δ next | p90 Δ head | p90 Δ next |\n",
+		"| checkout | 1.00s / 2.00s (n=2) | 2.00s / 3.00s (n=2) | 1.00s / 1.00s (n=2) | 🔴 +1.00s (+100.0%) | - | 🔴 +1.00s (+50.0%) | 🟢 -1.00s (-50.0%) |\n",
+	} {
+		if !strings.Contains(comparison, want) {
+			t.Errorf("CompareMultipleTraces() does not contain %q:\n%s", want, comparison)
+		}
+	}
+}
+
+func TestNewDurationStats(t *testing.T) {
+	var durations []time.Duration
+	for i := 10; i >= 1; i-- {
+		durations = append(durations, time.Duration(i)*time.Millisecond)
+	}
+
+	tests := []struct {
+		name      string
+		durations []time.Duration
+		want      DurationStats
+	}{
+		{name: "empty", durations: nil, want: DurationStats{}},
+		{name: "single", durations: []time.Duration{time.Second}, want: DurationStats{Count: 1, P50: time.Second, P90: time.Second, P99: time.Second, Max: time.Second}},
+		{name: "unsorted", durations: durations, want: DurationStats{Count: 10, P50: 5 * time.Millisecond, P90: 9 * time.Millisecond, P99: 10 * time.Millisecond, Max: 10 * time.Millisecond}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewDurationStats(tt.durations); got != tt.want {
+				t.Errorf("NewDurationStats() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpanStats(t *testing.T) {
+	now := time.Now()
+	ms := time.Millisecond
+	var traces []Trace
+	for i := 1; i <= 4; i++ {
+		d := time.Duration(i) * 10 * ms
+		traces = append(traces, Trace{TraceID: fmt.Sprintf("checkout-%d", i), Spans: []Span{
+			{SpanID: "a", Name: "checkout", StartTime: now, EndTime: now.Add(10 * d)},
+			{SpanID: "b", ParentSpanID: "a", Name: "db.query", StartTime: now, EndTime: now.Add(d)},
+			{SpanID: "c", ParentSpanID: "a", Name: "db.query", StartTime: now.Add(d), EndTime: now.Add(3 * d)},
+		}})
+	}
+	traces = append(traces, Trace{TraceID: "login", Spans: []Span{
+		{SpanID: "a", Name: "login", StartTime: now, EndTime: now.Add(time.Second)},
+	}})
+
+	stats := SpanStats(traces, "name")
+	if len(stats) != 2 {
+		t.Fatalf("SpanStats() returned %d groups, want 2", len(stats))
+	}
+
+	tests := []struct {
+		group string
+		span  string
+		want  DurationStats
+	}{
+		{group: "checkout", span: "checkout", want: DurationStats{Count: 4, P50: 200 * ms, P90: 400 * ms, P99: 400 * ms, Max: 400 * ms}},
+		{group: "checkout", span: "db.query", want: DurationStats{Count: 4, P50: 20 * ms, P90: 40 * ms, P99: 40 * ms, Max: 40 * ms}},
+		{group: "checkout", span: "db.query #2", want: DurationStats{Count: 4, P50: 40 * ms, P90: 80 * ms, P99: 80 * ms, Max: 80 * ms}},
+		{group: "login", span: "login", want: DurationStats{Count: 1, P50: time.Second, P90: time.Second, P99: time.Second, Max: time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.group+"/"+tt.span, func(t *testing.T) {
+			if got := stats[tt.group][tt.span]; got != tt.want {
+				t.Errorf("SpanStats()[%q][%q] = %+v, want %+v", tt.group, tt.span, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpanPercentileComparisonEscapesGroup(t *testing.T) {
+	now := time.Now()
+	newSet := func(name string) TraceSet {
+		return TraceSet{Name: name, Traces: []Trace{{TraceID: "t1", Spans: []Span{
+			{SpanID: "a", Name: "GET /a|b <script>", StartTime: now, EndTime: now.Add(time.Second)},
+		}}}}
+	}
+
+	got := CompareMultipleTraces([]TraceSet{newSet("base.json"), newSet("head.json")}, "name", Options{AggregateBy: "name"})
+	if want := "<summary>GET /a\\|b &lt;script&gt;</summary>"; !strings.Contains(got, want) {
+		t.Errorf("CompareMultipleTraces() does not contain %q:\n%s", want, got)
+	}
+}
@@ -0,0 +1,122 @@
+package trace
+
+import (
+	"fmt"
+	"sort"
+)
+
+// AttributeChangeType is the kind of change of an attribute between two
+// versions of a span
+type AttributeChangeType string
+
+// Attribute change types
+const (
+	AttributeAdded     AttributeChangeType = "added"
+	AttributeRemoved   AttributeChangeType = "removed"
+	AttributeChanged   AttributeChangeType = "changed"
+	AttributeUnchanged AttributeChangeType = "unchanged"
+)
+
+// AttributeChange describes an attribute key in two versions of a span
+type AttributeChange struct {
+	Key  string
+	Type AttributeChangeType
+	Old  string
+	New  string
+}
+
+// DiffAttributes compares two attribute maps and returns every key of
+// either map, sorted by key
+func DiffAttributes(base, other map[string]string) []AttributeChange {
+	keys := make(map[string]bool)
+	for k := range base {
+		keys[k] = true
+	}
+	for k := range other {
+		keys[k] = true
+	}
+
+	changes := make([]AttributeChange, 0, len(keys))
+	for k := range keys {
+		oldValue, inBase := base[k]
+		newValue, inOther := other[k]
+		change := AttributeChange{Key: k, Old: oldValue, New: newValue}
+		switch {
+		case !inBase:
+			change.Type = AttributeAdded
+		case !inOther:
+			change.Type = AttributeRemoved
+		case oldValue != newValue:
+			change.Type = AttributeChanged
+		default:
+			change.Type = AttributeUnchanged
+		}
+		changes = append(changes, change)
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// AttributeDiff holds the keys that were added, removed or changed between
+// two attribute maps
+type AttributeDiff struct {
+	// Added and Removed map keys to their value
+	Added   map[string]string
+	Removed map[string]string
+
+	// Changed maps keys to their old and new value
+	Changed map[string][2]string
+}
+
+// CompareAttributes returns the attributes added, removed or changed
+// between two attribute maps. Unchanged keys are left out.
+func CompareAttributes(base, other map[string]string) AttributeDiff {
+	diff := AttributeDiff{
+		Added:   make(map[string]string),
+		Removed: make(map[string]string),
+		Changed: make(map[string][2]string),
+	}
+	for _, c := range DiffAttributes(base, other) {
+		switch c.Type {
+		case AttributeAdded:
+			diff.Added[c.Key] = c.New
+		case AttributeRemoved:
+			diff.Removed[c.Key] = c.Old
+		case AttributeChanged:
+			diff.Changed[c.Key] = [2]string{c.Old, c.New}
+		}
+	}
+	return diff
+}
+
+// Empty reports whether no attribute was added, removed or changed
+func (d AttributeDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// hasAttributeChanges reports whether any attribute was added, removed or
+// changed
+func hasAttributeChanges(changes []AttributeChange) bool {
+	for _, c := range changes {
+		if c.Type != AttributeUnchanged {
+			return true
+		}
+	}
+	return false
+}
+
+// formatAttributeChange formats an attribute change for a table cell
+func formatAttributeChange(c AttributeChange, ind indicators) string {
+	key := escapeMarkdownCell(c.Key)
+	switch c.Type {
+	case AttributeAdded:
+		return fmt.Sprintf("%s **%s**: %s", ind.added, key, escapeMarkdownCell(c.New))
+	case AttributeRemoved:
+		return fmt.Sprintf("%s ~~%s: %s~~", ind.removed, key, escapeMarkdownCell(c.Old))
+	case AttributeChanged:
+		return fmt.Sprintf("%s **%s**: %s → %s", ind.changed, key, escapeMarkdownCell(c.Old), escapeMarkdownCell(c.New))
+	default:
+		return fmt.Sprintf("%s: %s", key, escapeMarkdownCell(c.New))
+	}
+}
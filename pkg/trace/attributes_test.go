@@ -0,0 +1,98 @@
+package trace
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffAttributes(t *testing.T) {
+	base := map[string]string{"http.status_code": "200", "http.method": "GET", "cache": "hit"}
+	other := map[string]string{"http.status_code": "500", "http.method": "GET", "retry": "1"}
+
+	expected := []AttributeChange{
+		{Key: "cache", Type: AttributeRemoved, Old: "hit"},
+		{Key: "http.method", Type: AttributeUnchanged, Old: "GET", New: "GET"},
+		{Key: "http.status_code", Type: AttributeChanged, Old: "200", New: "500"},
+		{Key: "retry", Type: AttributeAdded, New: "1"},
+	}
+
+	got := DiffAttributes(base, other)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("DiffAttributes() = %+v, want %+v", got, expected)
+	}
+	if hasAttributeChanges(DiffAttributes(base, base)) {
+		t.Error("hasAttributeChanges() = true for identical attributes")
+	}
+}
+
+func TestCompareAttributes(t *testing.T) {
+	tests := []struct {
+		name  string
+		base  map[string]string
+		other map[string]string
+		want  AttributeDiff
+	}{
+		{
+			name:  "no changes",
+			base:  map[string]string{"a": "1"},
+			other: map[string]string{"a": "1"},
+			want:  AttributeDiff{Added: map[string]string{}, Removed: map[string]string{}, Changed: map[string][2]string{}},
+		},
+		{
+			name:  "added, removed and changed",
+			base:  map[string]string{"kept": "1", "gone": "2", "status": "200"},
+			other: map[string]string{"kept": "1", "new": "3", "status": "500"},
+			want: AttributeDiff{
+				Added:   map[string]string{"new": "3"},
+				Removed: map[string]string{"gone": "2"},
+				Changed: map[string][2]string{"status": {"200", "500"}},
+			},
+		},
+		{
+			name:  "nil maps",
+			base:  nil,
+			other: map[string]string{"new": "3"},
+			want:  AttributeDiff{Added: map[string]string{"new": "3"}, Removed: map[string]string{}, Changed: map[string][2]string{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CompareAttributes(tt.base, tt.other)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CompareAttributes() = %+v, want %+v", got, tt.want)
+			}
+			if got.Empty() != (tt.name == "no changes") {
+				t.Errorf("Empty() = %v", got.Empty())
+			}
+		})
+	}
+}
+
+func TestCompareMultipleTracesAttributeChanges(t *testing.T) {
+	now := time.Now()
+	traceSets := []TraceSet{
+		{Name: "base.json", Traces: []Trace{{TraceID: "trace1", Spans: []Span{
+			{SpanID: "a", Name: "GET /users", StartTime: now, EndTime: now.Add(time.Second),
+				Attributes: map[string]string{"http.status_code": "200", "cache": "hit"}},
+		}}}},
+		{Name: "head.json", Traces: []Trace{{TraceID: "trace1", Spans: []Span{
+			{SpanID: "a", Name: "GET /users", StartTime: now, EndTime: now.Add(time.Second),
+				Attributes: map[string]string{"http.status_code": "500", "retry": "1"}},
+		}}}},
+	}
+
+	got := CompareMultipleTraces(traceSets, "trace_id", Options{})
+
+	expected := []string{
+		"| GET /users | 1.00s | 1.00s | - | 🔄 attributes changed |",
+		"| Attributes | cache: hit<br> http.status_code: 200 | ➖ ~~cache: hit~~<br> ✏️ **http.status_code**: 200 → 500<br> ➕ **retry**: 1 |",
+	}
+	for _, want := range expected {
+		if !strings.Contains(got, want) {
+			t.Errorf("CompareMultipleTraces() output does not contain %q:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// BaselineVersion is the version of the baseline artifact format written
+// by MarshalBaseline
+const BaselineVersion = 1
+
+// Baseline is a stored set of known-good traces, so later comparisons can
+// use it as their first trace set without producing the traces again
+type Baseline struct {
+	// Version is the artifact format version, which also tells baseline
+	// artifacts apart from plain trace files
+	Version int `json:"otelcompare_baseline"`
+
+	// Name names the baseline in reports
+	Name string `json:"name"`
+
+	// CreatedAt is when the artifact was written
+	CreatedAt time.Time `json:"created_at"`
+
+	// ToolVersion is the otelcompare version that wrote the artifact
+	ToolVersion string `json:"tool_version,omitempty"`
+
+	// Source is the input the traces were read from
+	Source string `json:"source,omitempty"`
+
+	Traces []Trace `json:"traces"`
+}
+
+// TraceSet returns the baseline's traces as a trace set named after it
+func (b Baseline) TraceSet() TraceSet {
+	return TraceSet{Name: b.Name, Traces: b.Traces}
+}
+
+// MarshalBaseline encodes a baseline artifact with the current
+// BaselineVersion. Pretty indents the JSON for reading.
+func MarshalBaseline(b Baseline, pretty bool) ([]byte, error) {
+	b.Version = BaselineVersion
+	if b.Traces == nil {
+		b.Traces = []Trace{}
+	}
+	if pretty {
+		return json.MarshalIndent(b, "", "  ")
+	}
+	return json.Marshal(b)
+}
+
+// ParseBaseline reads a baseline artifact written by MarshalBaseline. It
+// fails on plain trace files, artifacts from a newer format version and
+// artifacts without a name or traces.
+func ParseBaseline(data []byte) (Baseline, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return Baseline{}, ErrEmptyInput
+	}
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if isNDJSON(data) || !errors.As(err, &typeErr) || typeErr.Value != "array" {
+			return Baseline{}, fmt.Errorf("error unmarshaling baseline: %w", locateError(data, err))
+		}
+		return Baseline{}, fmt.Errorf("not a baseline artifact: the input is a list of traces; create a baseline with otelcompare export --as-baseline")
+	}
+
+	switch {
+	case b.Version == 0:
+		return Baseline{}, fmt.Errorf("not a baseline artifact: missing otelcompare_baseline version; create a baseline with otelcompare export --as-baseline")
+	case b.Version > BaselineVersion:
+		return Baseline{}, fmt.Errorf("unsupported baseline version %d: this otelcompare reads version %d, upgrade it to read this baseline", b.Version, BaselineVersion)
+	case b.Name == "":
+		return Baseline{}, fmt.Errorf("invalid baseline: missing name")
+	case len(b.Traces) == 0:
+		return Baseline{}, fmt.Errorf("invalid baseline %q: no traces", b.Name)
+	}
+	return b, nil
+}
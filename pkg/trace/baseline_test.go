@@ -0,0 +1,72 @@
+package trace
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBaselineRoundTrip(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	baseline := Baseline{
+		Name:        "main",
+		CreatedAt:   start,
+		ToolVersion: "v1.2.3",
+		Source:      "traces.json",
+		Traces: []Trace{{TraceID: "t1", Spans: []Span{
+			{SpanID: "a", Name: "GET /users", StartTime: start, EndTime: start.Add(time.Second)},
+		}}},
+	}
+
+	for _, pretty := range []bool{false, true} {
+		data, err := MarshalBaseline(baseline, pretty)
+		if err != nil {
+			t.Fatalf("MarshalBaseline() error = %v", err)
+		}
+		got, err := ParseBaseline(data)
+		if err != nil {
+			t.Fatalf("ParseBaseline() error = %v", err)
+		}
+
+		want := baseline
+		want.Version = BaselineVersion
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseBaseline(MarshalBaseline()) = %+v, want %+v", got, want)
+		}
+		if set := got.TraceSet(); set.Name != "main" || len(set.Traces) != 1 {
+			t.Errorf("TraceSet() = %+v, want the main traces", set)
+		}
+	}
+}
+
+func TestParseBaselineInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{name: "empty", input: " \n", wantErr: "input is empty"},
+		{name: "trace list", input: `[{"trace_id": "t1"}]`, wantErr: "the input is a list of traces"},
+		{name: "ndjson traces", input: "{\"trace_id\": \"t1\"}\n{\"trace_id\": \"t2\"}\n", wantErr: "error unmarshaling baseline"},
+		{name: "missing version", input: `{"name": "main", "traces": [{"trace_id": "t1"}]}`, wantErr: "missing otelcompare_baseline version"},
+		{name: "newer version", input: `{"otelcompare_baseline": 2, "name": "main", "traces": [{"trace_id": "t1"}]}`, wantErr: "unsupported baseline version 2"},
+		{name: "missing name", input: `{"otelcompare_baseline": 1, "traces": [{"trace_id": "t1"}]}`, wantErr: "missing name"},
+		{name: "no traces", input: `{"otelcompare_baseline": 1, "name": "main", "traces": []}`, wantErr: "no traces"},
+		{name: "malformed", input: `{"otelcompare_baseline": 1, "name": "main", "traces": [}`, wantErr: "line 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseBaseline([]byte(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseBaseline() error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := ParseBaseline(nil); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("ParseBaseline(nil) error = %v, want ErrEmptyInput", err)
+	}
+}
@@ -0,0 +1,93 @@
+package trace
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// cardinalityExamples is the number of example values shown for a
+// high-cardinality attribute
+const cardinalityExamples = 3
+
+// AttributeCardinality is the number of distinct values a span attribute
+// takes across a set of traces
+type AttributeCardinality struct {
+	Key string `json:"key"`
+
+	// Values is the number of distinct values of the attribute
+	Values int `json:"values"`
+
+	// Examples are a few of the values, sorted
+	Examples []string `json:"examples"`
+}
+
+// AttributeCardinalities counts the distinct values of every span attribute
+// key across all traces, sorted by count in descending order and then by key
+func AttributeCardinalities(traces []Trace) []AttributeCardinality {
+	values := make(map[string]map[string]bool)
+	for _, t := range traces {
+		for _, span := range t.Spans {
+			for k, v := range span.Attributes {
+				if values[k] == nil {
+					values[k] = make(map[string]bool)
+				}
+				values[k][v] = true
+			}
+		}
+	}
+
+	result := make([]AttributeCardinality, 0, len(values))
+	for k, set := range values {
+		examples := make([]string, 0, len(set))
+		for v := range set {
+			examples = append(examples, v)
+		}
+		sort.Strings(examples)
+		if len(examples) > cardinalityExamples {
+			examples = examples[:cardinalityExamples]
+		}
+		result = append(result, AttributeCardinality{Key: k, Values: len(set), Examples: examples})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Values != result[j].Values {
+			return result[i].Values > result[j].Values
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+// HighCardinalityAttributes returns the span attribute keys with more
+// distinct values than the threshold, which usually means an ID or full URL
+// was recorded where a template should have been
+func HighCardinalityAttributes(traces []Trace, threshold int) []AttributeCardinality {
+	var result []AttributeCardinality
+	for _, c := range AttributeCardinalities(traces) {
+		if c.Values > threshold {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// writeCardinalityWarnings writes a table of the high-cardinality span
+// attributes, if there are any
+func writeCardinalityWarnings(sb *strings.Builder, traces []Trace, threshold int, ind indicators) {
+	attributes := HighCardinalityAttributes(traces, threshold)
+	if len(attributes) == 0 {
+		return
+	}
+
+	sb.WriteString(fmt.Sprintf("**%s High-Cardinality Attributes (more than %d distinct values):**\n\n", ind.warning, threshold))
+	sb.WriteString("| Attribute | Distinct Values | Examples |\n")
+	sb.WriteString("|-----------|-----------------|----------|\n")
+	for _, c := range attributes {
+		examples := make([]string, len(c.Examples))
+		for i, example := range c.Examples {
+			examples[i] = fmt.Sprintf("`%s`", escapeMarkdownCell(example))
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %d | %s |\n", escapeMarkdownCell(c.Key), c.Values, strings.Join(examples, ", ")))
+	}
+	sb.WriteString("\n")
+}
@@ -0,0 +1,74 @@
+package trace
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHighCardinalityAttributes(t *testing.T) {
+	span := func(attrs map[string]string) Span {
+		return Span{Name: "GET", Attributes: attrs}
+	}
+	traces := []Trace{
+		{TraceID: "t1", Spans: []Span{
+			span(map[string]string{"http.url": "/users/1", "http.method": "GET", "user.id": "a"}),
+			span(map[string]string{"http.url": "/users/2", "http.method": "GET"}),
+		}},
+		{TraceID: "t2", Spans: []Span{
+			span(map[string]string{"http.url": "/users/3", "http.method": "POST", "user.id": "b"}),
+			span(map[string]string{"http.url": "/users/4", "http.method": "GET", "user.id": "b"}),
+		}},
+	}
+
+	tests := []struct {
+		name      string
+		threshold int
+		expected  []AttributeCardinality
+	}{
+		{
+			name:      "only keys above the threshold",
+			threshold: 2,
+			expected: []AttributeCardinality{
+				{Key: "http.url", Values: 4, Examples: []string{"/users/1", "/users/2", "/users/3"}},
+			},
+		},
+		{
+			name:      "ties sorted by key",
+			threshold: 1,
+			expected: []AttributeCardinality{
+				{Key: "http.url", Values: 4, Examples: []string{"/users/1", "/users/2", "/users/3"}},
+				{Key: "http.method", Values: 2, Examples: []string{"GET", "POST"}},
+				{Key: "user.id", Values: 2, Examples: []string{"a", "b"}},
+			},
+		},
+		{
+			name:      "nothing above the threshold",
+			threshold: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HighCardinalityAttributes(traces, tt.threshold)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("HighCardinalityAttributes() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGenerateMarkdownCardinalityWarnings(t *testing.T) {
+	traces := []Trace{{TraceID: "t1", Spans: []Span{
+		{SpanID: "a", Name: "GET", Attributes: map[string]string{"http.url": "/users/1"}},
+		{SpanID: "b", ParentSpanID: "a", Name: "GET", Attributes: map[string]string{"http.url": "/users/2"}},
+	}}}
+
+	want := "| http.url | 2 | `/users/1`, `/users/2` |\n"
+	if got := GenerateMarkdown(traces, Options{CardinalityThreshold: 1}); !strings.Contains(got, want) {
+		t.Errorf("GenerateMarkdown() does not contain %q:\n%s", want, got)
+	}
+	if got := GenerateMarkdown(traces, Options{}); strings.Contains(got, "High-Cardinality") {
+		t.Errorf("GenerateMarkdown() warned about cardinality without a threshold:\n%s", got)
+	}
+}
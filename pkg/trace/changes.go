@@ -0,0 +1,37 @@
+package trace
+
+import "math"
+
+// ChangesOnly configures comparisons to leave out the details of traces
+// that did not change significantly
+type ChangesOnly struct {
+	// Threshold is the percentage a trace's duration must change by, in
+	// either direction, to count as a change
+	Threshold float64
+}
+
+// traceChanged reports whether the trace with the given name changed in
+// any set compared to the first one: its duration moved by more than the
+// threshold, or an attribute of the trace or one of its spans changed.
+func traceChanged(traceMaps []map[string]*Trace, name string, threshold float64) bool {
+	base := traceMaps[0][name]
+	baseSpans := spanIndex(base)
+	for _, traceMap := range traceMaps[1:] {
+		other := traceMap[name]
+		change := DurationChange{Baseline: getTraceDuration(*base), Current: getTraceDuration(*other)}
+		if math.Abs(change.Percent()) > threshold {
+			return true
+		}
+		if hasAttributeChanges(DiffAttributes(base.Attributes, other.Attributes)) ||
+			hasAttributeChanges(DiffAttributes(base.ResourceAttrs, other.ResourceAttrs)) {
+			return true
+		}
+		otherSpans := spanIndex(other)
+		for ref, span := range baseSpans {
+			if otherSpan, ok := otherSpans[ref]; ok && hasAttributeChanges(DiffAttributes(span.Attributes, otherSpan.Attributes)) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTraceChanged(t *testing.T) {
+	now := time.Now()
+	makeTrace := func(duration time.Duration, traceAttrs, spanAttrs map[string]string) *Trace {
+		return &Trace{TraceID: "trace1", Attributes: traceAttrs, Spans: []Span{
+			{SpanID: "root", Name: "root", StartTime: now, EndTime: now.Add(duration), Attributes: spanAttrs},
+		}}
+	}
+	base := makeTrace(100*time.Millisecond, map[string]string{"env": "prod"}, map[string]string{"http.method": "GET"})
+
+	tests := []struct {
+		name  string
+		other *Trace
+		want  bool
+	}{
+		{
+			name:  "unchanged",
+			other: makeTrace(100*time.Millisecond, map[string]string{"env": "prod"}, map[string]string{"http.method": "GET"}),
+			want:  false,
+		},
+		{
+			name:  "within threshold",
+			other: makeTrace(105*time.Millisecond, map[string]string{"env": "prod"}, map[string]string{"http.method": "GET"}),
+			want:  false,
+		},
+		{
+			name:  "regression",
+			other: makeTrace(150*time.Millisecond, map[string]string{"env": "prod"}, map[string]string{"http.method": "GET"}),
+			want:  true,
+		},
+		{
+			name:  "improvement",
+			other: makeTrace(50*time.Millisecond, map[string]string{"env": "prod"}, map[string]string{"http.method": "GET"}),
+			want:  true,
+		},
+		{
+			name:  "trace attribute changed",
+			other: makeTrace(100*time.Millisecond, map[string]string{"env": "staging"}, map[string]string{"http.method": "GET"}),
+			want:  true,
+		},
+		{
+			name:  "span attribute added",
+			other: makeTrace(100*time.Millisecond, map[string]string{"env": "prod"}, map[string]string{"http.method": "GET", "http.status_code": "200"}),
+			want:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traceMaps := []map[string]*Trace{{"trace1": base}, {"trace1": tt.other}}
+			if got := traceChanged(traceMaps, "trace1", 10); got != tt.want {
+				t.Errorf("traceChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareMultipleTracesChangesOnly(t *testing.T) {
+	now := time.Now()
+	makeTrace := func(id string, duration time.Duration) Trace {
+		return Trace{TraceID: id, Spans: []Span{{SpanID: "root", Name: "root", StartTime: now, EndTime: now.Add(duration)}}}
+	}
+	traceSets := []TraceSet{
+		{Name: "base.json", Traces: []Trace{makeTrace("fast", 100*time.Millisecond), makeTrace("steady", 100*time.Millisecond)}},
+		{Name: "head.json", Traces: []Trace{makeTrace("fast", 200*time.Millisecond), makeTrace("steady", 101*time.Millisecond)}},
+	}
+
+	got := CompareMultipleTraces(traceSets, "trace_id", Options{ChangesOnly: &ChangesOnly{Threshold: 10}})
+	if !strings.Contains(got, "<summary>fast</summary>") {
+		t.Errorf("CompareMultipleTraces() is missing the details of the changed trace:\n%s", got)
+	}
+	if strings.Contains(got, "<summary>steady</summary>") {
+		t.Errorf("CompareMultipleTraces() shows the details of the unchanged trace:\n%s", got)
+	}
+	if !strings.Contains(got, "_1 unchanged trace(s) hidden._") {
+		t.Errorf("CompareMultipleTraces() does not mention the hidden trace:\n%s", got)
+	}
+
+	all := CompareMultipleTraces(traceSets, "trace_id", Options{})
+	if !strings.Contains(all, "<summary>steady</summary>") {
+		t.Errorf("CompareMultipleTraces() without ChangesOnly hides the unchanged trace:\n%s", all)
+	}
+}
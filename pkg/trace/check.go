@@ -0,0 +1,290 @@
+package trace
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Severity is how serious a finding of a Check is
+type Severity string
+
+// Severities of a finding, from most to least serious
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// rank orders severities from most to least serious
+func (s Severity) rank() int {
+	switch s {
+	case SeverityError:
+		return 0
+	case SeverityWarning:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Finding is a problem a Check found in a trace
+type Finding struct {
+	Severity Severity
+	Check    string
+	TraceID  string
+	SpanID   string
+	SpanName string
+	Message  string
+}
+
+// Check inspects a trace and returns the problems it finds
+type Check func(t Trace) []Finding
+
+// DefaultFutureTolerance is how far past the current time a timestamp may be
+// before CheckFutureTimestamps reports it
+const DefaultFutureTolerance = 5 * time.Minute
+
+// DefaultChecks returns every check, using now as the current time and
+// tolerance as how far past it a timestamp may be
+func DefaultChecks(now time.Time, tolerance time.Duration) []Check {
+	return append(validateChecks(),
+		CheckZeroDuration,
+		CheckOverlappingSiblings,
+		CheckFutureTimestamps(now, tolerance),
+	)
+}
+
+// validateChecks are the checks of Validate, which find the problems that
+// make the span tree of a trace unreliable
+func validateChecks() []Check {
+	return []Check{
+		CheckOrphanParent,
+		CheckParentCycle,
+		CheckEndsBeforeStart,
+		CheckStartsBeforeParent,
+		CheckEndsAfterParent,
+	}
+}
+
+// RunChecks runs the checks over every trace and returns their findings,
+// most serious first
+func RunChecks(traces []Trace, checks ...Check) []Finding {
+	var findings []Finding
+	for _, t := range traces {
+		for _, check := range checks {
+			findings = append(findings, check(t)...)
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Severity.rank() < findings[j].Severity.rank()
+	})
+	return findings
+}
+
+// CheckOrphanParent reports spans whose parent is not in the trace. Sampling
+// and partial exports drop spans, so these are warnings
+func CheckOrphanParent(t Trace) []Finding {
+	spans := spansByID(t)
+	var findings []Finding
+	for i := range t.Spans {
+		span := &t.Spans[i]
+		if _, ok := spans[span.ParentSpanID]; span.ParentSpanID != "" && !ok {
+			findings = append(findings, newFinding(t, span, SeverityWarning, "orphan-parent",
+				fmt.Sprintf("has parent %s, which is not in the trace", span.ParentSpanID)))
+		}
+	}
+	return findings
+}
+
+// CheckParentCycle reports spans whose parent chain leads back to them,
+// once for every cycle
+func CheckParentCycle(t Trace) []Finding {
+	spans := spansByID(t)
+	reported := make(map[string]bool)
+	var findings []Finding
+	for i := range t.Spans {
+		span := &t.Spans[i]
+		cycle := parentCycle(span, spans)
+		if cycle == nil || reported[cycleKey(cycle)] {
+			continue
+		}
+		reported[cycleKey(cycle)] = true
+		findings = append(findings, newFinding(t, span, SeverityError, "parent-cycle",
+			fmt.Sprintf("forms a parent cycle %s", strings.Join(cycle, " → "))))
+	}
+	return findings
+}
+
+// CheckEndsBeforeStart reports spans that end before they start, usually
+// because of clock skew. Their duration is counted as 0.
+func CheckEndsBeforeStart(t Trace) []Finding {
+	var findings []Finding
+	for i := range t.Spans {
+		span := &t.Spans[i]
+		if span.EndTime.Before(span.StartTime) {
+			findings = append(findings, newFinding(t, span, SeverityError, "ends-before-start",
+				fmt.Sprintf("ends %s before it starts, likely because of clock skew; its duration is counted as 0",
+					formatDuration(span.StartTime.Sub(span.EndTime)))))
+		}
+	}
+	return findings
+}
+
+// CheckStartsBeforeParent reports spans that start before their parent
+func CheckStartsBeforeParent(t Trace) []Finding {
+	var findings []Finding
+	forEachChild(t, func(span, parent *Span) {
+		if span.StartTime.Before(parent.StartTime) {
+			findings = append(findings, newFinding(t, span, SeverityError, "starts-before-parent",
+				fmt.Sprintf("starts %s before its parent %s", formatDuration(parent.StartTime.Sub(span.StartTime)), describeSpan(parent))))
+		}
+	})
+	return findings
+}
+
+// CheckEndsAfterParent reports spans that end after their parent. Spans of
+// asynchronous work legitimately outlive their parent, so these are warnings
+func CheckEndsAfterParent(t Trace) []Finding {
+	var findings []Finding
+	forEachChild(t, func(span, parent *Span) {
+		if span.EndTime.After(parent.EndTime) {
+			findings = append(findings, newFinding(t, span, SeverityWarning, "ends-after-parent",
+				fmt.Sprintf("ends %s after its parent %s", formatDuration(span.EndTime.Sub(parent.EndTime)), describeSpan(parent))))
+		}
+	})
+	return findings
+}
+
+// CheckZeroDuration reports spans that take no time
+func CheckZeroDuration(t Trace) []Finding {
+	var findings []Finding
+	for i := range t.Spans {
+		span := &t.Spans[i]
+		if span.EndTime.Equal(span.StartTime) {
+			findings = append(findings, newFinding(t, span, SeverityWarning, "zero-duration", "has a duration of 0"))
+		}
+	}
+	return findings
+}
+
+// CheckOverlappingSiblings reports spans that start before an earlier
+// sibling has ended. Concurrent work overlaps by design, so these are only
+// informational
+func CheckOverlappingSiblings(t Trace) []Finding {
+	children := make(map[string][]*Span)
+	for i := range t.Spans {
+		span := &t.Spans[i]
+		if span.ParentSpanID != "" {
+			children[span.ParentSpanID] = append(children[span.ParentSpanID], span)
+		}
+	}
+
+	parentIDs := make([]string, 0, len(children))
+	for id := range children {
+		parentIDs = append(parentIDs, id)
+	}
+	sort.Strings(parentIDs)
+
+	var findings []Finding
+	for _, id := range parentIDs {
+		siblings := children[id]
+		sort.SliceStable(siblings, func(i, j int) bool {
+			return siblings[i].StartTime.Before(siblings[j].StartTime)
+		})
+
+		// The sibling that ends last among those started so far
+		var latest *Span
+		for _, span := range siblings {
+			if latest != nil && span.StartTime.Before(latest.EndTime) {
+				overlap := latest.EndTime.Sub(span.StartTime)
+				if span.EndTime.Before(latest.EndTime) {
+					overlap = span.EndTime.Sub(span.StartTime)
+				}
+				findings = append(findings, newFinding(t, span, SeverityInfo, "overlapping-siblings",
+					fmt.Sprintf("overlaps its sibling %s by %s", describeSpan(latest), formatDuration(overlap))))
+			}
+			if latest == nil || span.EndTime.After(latest.EndTime) {
+				latest = span
+			}
+		}
+	}
+	return findings
+}
+
+// CheckFutureTimestamps returns a check reporting spans that start or end
+// more than tolerance after now, which points at a skewed clock or a unit
+// mistake in the exporter
+func CheckFutureTimestamps(now time.Time, tolerance time.Duration) Check {
+	limit := now.Add(tolerance)
+	return func(t Trace) []Finding {
+		var findings []Finding
+		for i := range t.Spans {
+			span := &t.Spans[i]
+			latest := span.StartTime
+			if span.EndTime.After(latest) {
+				latest = span.EndTime
+			}
+			if latest.After(limit) {
+				findings = append(findings, newFinding(t, span, SeverityError, "future-timestamp",
+					fmt.Sprintf("has a timestamp %s in the future (%s)", formatDuration(latest.Sub(now)), latest.UTC().Format(time.RFC3339))))
+			}
+		}
+		return findings
+	}
+}
+
+// forEachChild calls fn for every span whose parent is in the trace, skipping
+// spans in a parent cycle
+func forEachChild(t Trace, fn func(span, parent *Span)) {
+	spans := spansByID(t)
+	for i := range t.Spans {
+		span := &t.Spans[i]
+		parent, ok := spans[span.ParentSpanID]
+		if !ok || span.ParentSpanID == "" || parentCycle(span, spans) != nil {
+			continue
+		}
+		fn(span, parent)
+	}
+}
+
+func newFinding(t Trace, span *Span, severity Severity, check, message string) Finding {
+	return Finding{
+		Severity: severity,
+		Check:    check,
+		TraceID:  t.TraceID,
+		SpanID:   span.SpanID,
+		SpanName: span.Name,
+		Message:  message,
+	}
+}
+
+// GenerateFindingsMarkdown renders the findings of checking traceCount traces
+// as a markdown table
+func GenerateFindingsMarkdown(findings []Finding, traceCount int, opts Options) string {
+	ind := opts.indicators()
+	var sb strings.Builder
+	sb.WriteString("### Trace Validation\n\n")
+
+	if len(findings) == 0 {
+		sb.WriteString(fmt.Sprintf("%s No problems found in %d trace(s)\n", ind.passing, traceCount))
+		return sb.String()
+	}
+
+	counts := make(map[Severity]int)
+	for _, f := range findings {
+		counts[f.Severity]++
+	}
+	sb.WriteString(fmt.Sprintf("Checked %d trace(s): **%d error(s)**, %d warning(s), %d info\n\n",
+		traceCount, counts[SeverityError], counts[SeverityWarning], counts[SeverityInfo]))
+
+	sb.WriteString("| Severity | Check | Trace | Span | Problem |\n")
+	sb.WriteString("|----------|-------|-------|------|---------|\n")
+	for _, f := range findings {
+		sb.WriteString(fmt.Sprintf("| %s %s | %s | %s | %s (%s) | %s |\n",
+			ind.severity(f.Severity), f.Severity, f.Check, escapeMarkdownCell(f.TraceID),
+			escapeMarkdownCell(f.SpanName), escapeMarkdownCell(f.SpanID), escapeMarkdownCell(f.Message)))
+	}
+	return sb.String()
+}
@@ -0,0 +1,139 @@
+package trace
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChecks(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		check    Check
+		spans    []Span
+		expected []string
+	}{
+		{
+			name:  "starts before parent",
+			check: CheckStartsBeforeParent,
+			spans: []Span{
+				{SpanID: "a", Name: "root", StartTime: now, EndTime: now.Add(time.Second)},
+				{SpanID: "b", ParentSpanID: "a", Name: "early", StartTime: now.Add(-100 * time.Millisecond), EndTime: now.Add(time.Second)},
+				{SpanID: "c", ParentSpanID: "a", Name: "fine", StartTime: now, EndTime: now.Add(time.Second)},
+			},
+			expected: []string{`error starts-before-parent b: starts 100.00ms before its parent "root" (a)`},
+		},
+		{
+			name:  "ends after parent",
+			check: CheckEndsAfterParent,
+			spans: []Span{
+				{SpanID: "a", Name: "root", StartTime: now, EndTime: now.Add(time.Second)},
+				{SpanID: "b", ParentSpanID: "a", Name: "late", StartTime: now, EndTime: now.Add(2 * time.Second)},
+			},
+			expected: []string{`warning ends-after-parent b: ends 1.00s after its parent "root" (a)`},
+		},
+		{
+			name:  "parent cycle is skipped",
+			check: CheckStartsBeforeParent,
+			spans: []Span{
+				{SpanID: "a", ParentSpanID: "b", Name: "first", StartTime: now},
+				{SpanID: "b", ParentSpanID: "a", Name: "second", StartTime: now.Add(time.Second)},
+			},
+		},
+		{
+			name:  "zero duration",
+			check: CheckZeroDuration,
+			spans: []Span{
+				{SpanID: "a", Name: "instant", StartTime: now, EndTime: now},
+				{SpanID: "b", Name: "skewed", StartTime: now, EndTime: now.Add(-time.Second)},
+				{SpanID: "c", Name: "fine", StartTime: now, EndTime: now.Add(time.Second)},
+			},
+			expected: []string{"warning zero-duration a: has a duration of 0"},
+		},
+		{
+			name:  "overlapping siblings",
+			check: CheckOverlappingSiblings,
+			spans: []Span{
+				{SpanID: "a", Name: "root", StartTime: now, EndTime: now.Add(3 * time.Second)},
+				{SpanID: "b", ParentSpanID: "a", Name: "first", StartTime: now, EndTime: now.Add(2 * time.Second)},
+				{SpanID: "c", ParentSpanID: "a", Name: "second", StartTime: now.Add(time.Second), EndTime: now.Add(3 * time.Second)},
+				{SpanID: "d", ParentSpanID: "a", Name: "inner", StartTime: now.Add(2500 * time.Millisecond), EndTime: now.Add(2600 * time.Millisecond)},
+				{SpanID: "e", ParentSpanID: "b", Name: "sequential", StartTime: now, EndTime: now.Add(time.Second)},
+				{SpanID: "f", ParentSpanID: "b", Name: "sequential", StartTime: now.Add(time.Second), EndTime: now.Add(2 * time.Second)},
+			},
+			expected: []string{
+				`info overlapping-siblings c: overlaps its sibling "first" (b) by 1.00s`,
+				`info overlapping-siblings d: overlaps its sibling "second" (c) by 100.00ms`,
+			},
+		},
+		{
+			name:  "future timestamps",
+			check: CheckFutureTimestamps(now, time.Minute),
+			spans: []Span{
+				{SpanID: "a", Name: "slightly ahead", StartTime: now, EndTime: now.Add(30 * time.Second)},
+				{SpanID: "b", Name: "far ahead", StartTime: now.Add(time.Hour), EndTime: now.Add(time.Hour + time.Second)},
+			},
+			expected: []string{"error future-timestamp b: has a timestamp 1h0m in the future (2024-01-01T13:00:01Z)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, f := range tt.check(Trace{TraceID: "t1", Spans: tt.spans}) {
+				got = append(got, string(f.Severity)+" "+f.Check+" "+f.SpanID+": "+f.Message)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRunChecks(t *testing.T) {
+	now := time.Now()
+	traces := []Trace{
+		{TraceID: "t1", Spans: []Span{
+			{SpanID: "a", Name: "root", StartTime: now, EndTime: now},
+		}},
+		{TraceID: "t2", Spans: []Span{
+			{SpanID: "a", Name: "root", StartTime: now, EndTime: now.Add(time.Second)},
+			{SpanID: "b", ParentSpanID: "a", Name: "early", StartTime: now.Add(-time.Second), EndTime: now},
+		}},
+	}
+
+	findings := RunChecks(traces, DefaultChecks(now, DefaultFutureTolerance)...)
+	if len(findings) != 2 {
+		t.Fatalf("expected 2 findings, got %+v", findings)
+	}
+	if findings[0].Severity != SeverityError || findings[0].TraceID != "t2" {
+		t.Errorf("expected the error of t2 first, got %+v", findings[0])
+	}
+	if findings[1].Severity != SeverityWarning || findings[1].TraceID != "t1" {
+		t.Errorf("expected the warning of t1 second, got %+v", findings[1])
+	}
+}
+
+func TestGenerateFindingsMarkdown(t *testing.T) {
+	md := GenerateFindingsMarkdown(nil, 3, Options{})
+	if !strings.Contains(md, "No problems found in 3 trace(s)") {
+		t.Errorf("expected a clean report, got:\n%s", md)
+	}
+
+	md = GenerateFindingsMarkdown([]Finding{
+		{Severity: SeverityError, Check: "zero-duration", TraceID: "t1", SpanID: "a", SpanName: "a|b", Message: "ends 1.00s before it starts"},
+		{Severity: SeverityInfo, Check: "overlapping-siblings", TraceID: "t1", SpanID: "b", SpanName: "c", Message: "overlaps"},
+	}, 1, Options{})
+	for _, want := range []string{
+		"**1 error(s)**, 0 warning(s), 1 info",
+		"| Severity | Check | Trace | Span | Problem |",
+		`| ❌ error | zero-duration | t1 | a\|b (a) | ends 1.00s before it starts |`,
+		"| ℹ️ info | overlapping-siblings | t1 | c (b) | overlaps |",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("expected %q in:\n%s", want, md)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package trace
+
+import (
+	"sort"
+	"strconv"
+)
+
+// CollapsedCountAttribute is the span attribute holding how many spans a
+// collapsed span stands for
+const CollapsedCountAttribute = "otelcompare.span_count"
+
+// CollapseSpans returns the traces with the spans of each name merged into
+// a single span, which keeps comparisons of very wide traces small. The
+// merged span starts with the earliest of its spans and lasts the sum of
+// their durations, so it may outlast its parent when the spans overlap. It
+// keeps the attributes all of its spans agree on, along with their count
+// in CollapsedCountAttribute, the events and links of all of them, and an
+// error status if any of them failed. The input traces are not modified.
+func CollapseSpans(traces []Trace) []Trace {
+	collapsed := make([]Trace, len(traces))
+	for i, t := range traces {
+		collapsed[i] = t
+		collapsed[i].Spans = collapseTraceSpans(t.Spans)
+	}
+	return collapsed
+}
+
+// collapseTraceSpans merges the spans of a single trace by name, in order
+// of their earliest start
+func collapseTraceSpans(spans []Span) []Span {
+	sorted := append([]Span(nil), spans...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].StartTime.Before(sorted[j].StartTime)
+	})
+
+	byID := make(map[string]*Span, len(sorted))
+	for i := range sorted {
+		byID[sorted[i].SpanID] = &sorted[i]
+	}
+
+	var result []Span
+	index := make(map[string]int)
+	counts := make(map[string]int)
+	for _, span := range sorted {
+		counts[span.Name]++
+		i, ok := index[span.Name]
+		if !ok {
+			// The earliest span of a name stands for all of them
+			index[span.Name] = len(result)
+			span.Attributes = copyAttributes(span.Attributes)
+			span.Events = append([]Event(nil), span.Events...)
+			span.Links = append([]SpanLink(nil), span.Links...)
+			result = append(result, span)
+			continue
+		}
+
+		merged := &result[i]
+		merged.EndTime = merged.EndTime.Add(spanDuration(span))
+		for k, v := range merged.Attributes {
+			if span.Attributes[k] != v {
+				delete(merged.Attributes, k)
+			}
+		}
+		merged.Events = append(merged.Events, span.Events...)
+		merged.Links = append(merged.Links, span.Links...)
+		if isError(span) && !isError(*merged) {
+			merged.Status = span.Status
+		}
+	}
+
+	// Point every merged span at the merged span of its nearest ancestor
+	// with another name
+	for i := range result {
+		span := &result[i]
+		if span.Attributes == nil {
+			span.Attributes = make(map[string]string)
+		}
+		span.Attributes[CollapsedCountAttribute] = strconv.Itoa(counts[span.Name])
+
+		parentID := ""
+		seen := map[string]bool{span.SpanID: true}
+		for parent, ok := byID[span.ParentSpanID]; ok && !seen[parent.SpanID]; parent, ok = byID[parent.ParentSpanID] {
+			seen[parent.SpanID] = true
+			if parent.Name != span.Name {
+				parentID = result[index[parent.Name]].SpanID
+				break
+			}
+		}
+		if parentID == "" && byID[span.ParentSpanID] == nil {
+			// Keep references to spans missing from the trace
+			parentID = span.ParentSpanID
+		}
+		span.ParentSpanID = parentID
+	}
+	return result
+}
+
+// copyAttributes returns a copy of an attribute map
+func copyAttributes(attributes map[string]string) map[string]string {
+	copied := make(map[string]string, len(attributes))
+	for k, v := range attributes {
+		copied[k] = v
+	}
+	return copied
+}
@@ -0,0 +1,92 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollapseSpans(t *testing.T) {
+	now := time.Now()
+	ms := time.Millisecond
+	original := Trace{TraceID: "t1", Spans: []Span{
+		{SpanID: "root", Name: "GET /users", StartTime: now, EndTime: now.Add(100 * ms)},
+		{SpanID: "q2", ParentSpanID: "root", Name: "SELECT", StartTime: now.Add(30 * ms), EndTime: now.Add(50 * ms),
+			Attributes: map[string]string{"db.system": "postgres", "db.table": "orders"}, Status: Status{Code: StatusError}},
+		{SpanID: "q1", ParentSpanID: "root", Name: "SELECT", StartTime: now.Add(10 * ms), EndTime: now.Add(20 * ms),
+			Attributes: map[string]string{"db.system": "postgres", "db.table": "users"}},
+		{SpanID: "fetch", ParentSpanID: "q2", Name: "fetch", StartTime: now.Add(35 * ms), EndTime: now.Add(40 * ms)},
+		{SpanID: "nested", ParentSpanID: "q1", Name: "SELECT", StartTime: now.Add(12 * ms), EndTime: now.Add(15 * ms)},
+	}}
+
+	collapsed := CollapseSpans([]Trace{original})
+	spans := collapsed[0].Spans
+	if len(spans) != 3 {
+		t.Fatalf("CollapseSpans() returned %d spans, want 3", len(spans))
+	}
+
+	tests := []struct {
+		name      string
+		spanID    string
+		parentID  string
+		duration  time.Duration
+		count     string
+		wantError bool
+	}{
+		{name: "GET /users", spanID: "root", duration: 100 * ms, count: "1"},
+		{name: "SELECT", spanID: "q1", parentID: "root", duration: 33 * ms, count: "3", wantError: true},
+		{name: "fetch", spanID: "fetch", parentID: "q1", duration: 5 * ms, count: "1"},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span := spans[i]
+			if span.Name != tt.name || span.SpanID != tt.spanID || span.ParentSpanID != tt.parentID {
+				t.Errorf("span = %s %s (parent %q), want %s %s (parent %q)", span.Name, span.SpanID, span.ParentSpanID, tt.name, tt.spanID, tt.parentID)
+			}
+			if got := spanDuration(span); got != tt.duration {
+				t.Errorf("duration = %v, want %v", got, tt.duration)
+			}
+			if got := span.Attributes[CollapsedCountAttribute]; got != tt.count {
+				t.Errorf("count = %q, want %q", got, tt.count)
+			}
+			if isError(span) != tt.wantError {
+				t.Errorf("isError() = %v, want %v", isError(span), tt.wantError)
+			}
+		})
+	}
+
+	// Only attributes shared by every merged span are kept
+	if got := spans[1].Attributes; got["db.system"] != "" || got["db.table"] != "" {
+		t.Errorf("merged attributes = %v, want only the span count", got)
+	}
+
+	// The input is left untouched
+	if len(original.Spans) != 5 || original.Spans[2].Attributes[CollapsedCountAttribute] != "" {
+		t.Errorf("CollapseSpans() modified its input")
+	}
+}
+
+func TestCompareMultipleTracesCollapsedSpans(t *testing.T) {
+	now := time.Now()
+	ms := time.Millisecond
+	wide := func(queries int) []Trace {
+		spans := []Span{{SpanID: "root", Name: "GET /users", StartTime: now, EndTime: now.Add(100 * ms)}}
+		for i := 0; i < queries; i++ {
+			start := now.Add(time.Duration(i) * 10 * ms)
+			spans = append(spans, Span{SpanID: "q" + string(rune('a'+i)), ParentSpanID: "root", Name: "SELECT", StartTime: start, EndTime: start.Add(5 * ms)})
+		}
+		return CollapseSpans([]Trace{{TraceID: "t1", Spans: spans}})
+	}
+
+	markdown := CompareMultipleTraces([]TraceSet{
+		{Name: "base", Traces: wide(2)},
+		{Name: "head", Traces: wide(4)},
+	}, "name", Options{})
+
+	if strings.Contains(markdown, "SELECT #2") {
+		t.Errorf("collapsed comparison should not number repeated spans:\n%s", markdown)
+	}
+	if !strings.Contains(markdown, "10.00ms") || !strings.Contains(markdown, "20.00ms") {
+		t.Errorf("collapsed comparison should show summed durations:\n%s", markdown)
+	}
+}
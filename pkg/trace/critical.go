@@ -0,0 +1,81 @@
+package trace
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CriticalPath returns the chain of spans that determines when a trace
+// finished: starting from the root that ends last, it follows the child that
+// finishes last at each level down to a leaf. Spans whose parent is not in
+// the trace count as roots. Among spans ending at the same time, the longer
+// one wins. A trace without roots has no critical path.
+func CriticalPath(t Trace) []Span {
+	return criticalPath(t, spansByID(t))
+}
+
+// finishesLater reports whether span a should be preferred over span b on
+// the critical path
+func finishesLater(a, b Span) bool {
+	if endA, endB := spanEnd(a), spanEnd(b); !endA.Equal(endB) {
+		return endA.After(endB)
+	}
+	return spanDuration(a) > spanDuration(b)
+}
+
+// criticalPath computes the critical path using a map of the trace's spans
+// by ID
+func criticalPath(t Trace, spanMap map[string]*Span) []Span {
+	children := make(map[string][]int)
+	var roots []int
+	for i, span := range t.Spans {
+		if isRoot(span, spanMap) {
+			roots = append(roots, i)
+		} else if span.ParentSpanID != span.SpanID {
+			children[span.ParentSpanID] = append(children[span.ParentSpanID], i)
+		}
+	}
+
+	// last returns the candidate that finishes last, or -1 when none is left
+	visited := make(map[int]bool)
+	last := func(candidates []int) int {
+		next := -1
+		for _, i := range candidates {
+			if visited[i] {
+				continue
+			}
+			if next == -1 || finishesLater(t.Spans[i], t.Spans[next]) {
+				next = i
+			}
+		}
+		return next
+	}
+
+	// Visited guards against cycles through duplicate span IDs
+	spans := []Span{}
+	for i := last(roots); i != -1; i = last(children[t.Spans[i].SpanID]) {
+		visited[i] = true
+		spans = append(spans, t.Spans[i])
+	}
+	return spans
+}
+
+// writeCriticalPath writes the critical path of a trace as an ordered list
+// with each span's end offset from the start of the trace
+func writeCriticalPath(sb *strings.Builder, t Trace, spanMap map[string]*Span) {
+	path := criticalPath(t, spanMap)
+	if len(path) == 0 {
+		return
+	}
+
+	start := traceStart(t)
+	sb.WriteString("**Critical Path:**\n\n")
+	for i, span := range path {
+		sb.WriteString(fmt.Sprintf("%d. **%s** (%s, cumulative %s)\n",
+			i+1,
+			escapeMarkdownCell(span.Name),
+			formatDuration(spanDuration(span)),
+			formatDuration(spanEnd(span).Sub(start))))
+	}
+	sb.WriteString("\n")
+}
@@ -0,0 +1,132 @@
+package trace
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCriticalPath(t *testing.T) {
+	now := time.Now()
+	span := func(id, parent string, startMs, endMs int) Span {
+		return Span{SpanID: id, ParentSpanID: parent, Name: id,
+			StartTime: now.Add(time.Duration(startMs) * time.Millisecond),
+			EndTime:   now.Add(time.Duration(endMs) * time.Millisecond)}
+	}
+
+	tests := []struct {
+		name  string
+		spans []Span
+		want  []string
+	}{
+		{
+			name: "last finishing branch",
+			spans: []Span{
+				span("root", "", 0, 100),
+				span("cache", "root", 0, 10),
+				span("db", "root", 10, 90),
+				span("query", "db", 20, 85),
+			},
+			want: []string{"root", "db", "query"},
+		},
+		{
+			name: "later end beats longer child",
+			spans: []Span{
+				span("root", "", 0, 100),
+				span("a", "root", 0, 50),
+				span("b", "root", 60, 90),
+				span("b1", "b", 65, 88),
+			},
+			want: []string{"root", "b", "b1"},
+		},
+		{
+			name: "tie picks the longer span",
+			spans: []Span{
+				span("root", "", 0, 100),
+				span("a", "root", 60, 80),
+				span("b", "root", 40, 80),
+			},
+			want: []string{"root", "b"},
+		},
+		{
+			name: "orphans count as roots",
+			spans: []Span{
+				span("root", "", 0, 10),
+				span("orphan", "missing", 0, 50),
+			},
+			want: []string{"orphan"},
+		},
+		{
+			name: "parent cycle has no root",
+			spans: []Span{
+				span("a", "b", 0, 10),
+				span("b", "a", 0, 10),
+			},
+			want: []string{},
+		},
+		{
+			name:  "empty trace",
+			spans: nil,
+			want:  []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := CriticalPath(Trace{TraceID: "trace1", Spans: tt.spans})
+			got := make([]string, 0, len(path))
+			for _, span := range path {
+				got = append(got, span.Name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("CriticalPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateMarkdownCriticalPath(t *testing.T) {
+	now := time.Now()
+	traces := []Trace{{TraceID: "trace1", Spans: []Span{
+		{SpanID: "root", Name: "handler", StartTime: now, EndTime: now.Add(100 * time.Millisecond)},
+		{SpanID: "a", ParentSpanID: "root", Name: "db", StartTime: now.Add(10 * time.Millisecond), EndTime: now.Add(85 * time.Millisecond)},
+	}}}
+
+	got := GenerateMarkdown(traces, Options{})
+	for _, want := range []string{
+		"**Critical Path:**",
+		"1. **handler** (100.00ms, cumulative 100.00ms)",
+		"2. **db** (75.00ms, cumulative 85.00ms)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("GenerateMarkdown() output does not contain %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestCriticalPathCumulativeWithinTrace(t *testing.T) {
+	data, err := os.ReadFile("../../examples/nested-trace.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	traces, err := ParseTraces(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tr := range traces {
+		var sb strings.Builder
+		writeCriticalPath(&sb, tr, spansByID(tr))
+		duration := getTraceDuration(tr)
+		start := traceStart(tr)
+		for _, span := range CriticalPath(tr) {
+			if offset := spanEnd(span).Sub(start); offset > duration {
+				t.Errorf("span %s ends at %s, after the trace's %s", span.Name, formatDuration(offset), formatDuration(duration))
+			}
+		}
+		if want := "cumulative " + formatDuration(duration); !strings.Contains(sb.String(), want) {
+			t.Errorf("writeCriticalPath() does not contain %q:\n%s", want, sb.String())
+		}
+	}
+}
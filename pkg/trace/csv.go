@@ -0,0 +1,98 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// GenerateCSV renders the duration of every span in every file as CSV, one
+// row per trace and span. Traces are identified by the given attribute and
+// spans by name and occurrence. Durations and deltas are in milliseconds,
+// with one delta column per file compared to the first. Spans missing from
+// a file leave their cells empty so every column stays numeric.
+func GenerateCSV(traceSets []TraceSet, attribute string) (string, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	header := []string{"trace", "span"}
+	for _, set := range traceSets {
+		header = append(header, getFileNameWithoutExt(set.Name)+" (ms)")
+	}
+	for _, set := range traceSets[min(1, len(traceSets)):] {
+		header = append(header, "delta "+getFileNameWithoutExt(set.Name)+" (ms)")
+	}
+	if err := w.Write(header); err != nil {
+		return "", err
+	}
+
+	traceMaps := make([]map[string]*Trace, len(traceSets))
+	allNames := make(map[string]bool)
+	for i, set := range traceSets {
+		traceMaps[i] = traceIndex(set, attribute)
+		for name := range traceMaps[i] {
+			allNames[name] = true
+		}
+	}
+	var names []string
+	for name := range allNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		spanIndexes := make([]map[spanRef]*Span, len(traceMaps))
+		allRefs := make(map[spanRef]bool)
+		for i, traceMap := range traceMaps {
+			if t, ok := traceMap[name]; ok {
+				spanIndexes[i] = spanIndex(t)
+				for ref := range spanIndexes[i] {
+					allRefs[ref] = true
+				}
+			}
+		}
+		var refs []spanRef
+		for ref := range allRefs {
+			refs = append(refs, ref)
+		}
+		sortSpanRefs(refs)
+
+		for _, ref := range refs {
+			row := []string{name, ref.key()}
+			durations := make([]*time.Duration, len(spanIndexes))
+			for i, index := range spanIndexes {
+				cell := ""
+				if span, ok := index[ref]; ok {
+					d := spanDuration(*span)
+					durations[i] = &d
+					cell = formatMilliseconds(d)
+				}
+				row = append(row, cell)
+			}
+			for i := 1; i < len(durations); i++ {
+				cell := ""
+				if durations[0] != nil && durations[i] != nil {
+					cell = formatMilliseconds(*durations[i] - *durations[0])
+				}
+				row = append(row, cell)
+			}
+			if err := w.Write(row); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", fmt.Errorf("error writing CSV: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// formatMilliseconds formats a duration as a number of milliseconds
+func formatMilliseconds(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64)
+}
@@ -0,0 +1,49 @@
+package trace
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGenerateCSV(t *testing.T) {
+	now := time.Now()
+	span := func(name string, ms float64) Span {
+		return Span{SpanID: name, Name: name, StartTime: now, EndTime: now.Add(time.Duration(ms * float64(time.Millisecond)))}
+	}
+	traceSets := []TraceSet{
+		{Name: "base.json", Traces: []Trace{{TraceID: "trace1", Spans: []Span{span("root", 100), span("db", 40), span("db", 20)}}}},
+		{Name: "head.json", Traces: []Trace{{TraceID: "trace1", Spans: []Span{span("root", 120.5), span("cache", 1)}}}},
+	}
+
+	got, err := GenerateCSV(traceSets, "trace_id")
+	if err != nil {
+		t.Fatalf("GenerateCSV() error = %v", err)
+	}
+
+	expected := "trace,span,base (ms),head (ms),delta head (ms)\n" +
+		"trace1,cache,,1,\n" +
+		"trace1,db,40,,\n" +
+		"trace1,db #2,20,,\n" +
+		"trace1,root,100,120.5,20.5\n"
+	if got != expected {
+		t.Errorf("GenerateCSV() =\n%s\nwant\n%s", got, expected)
+	}
+}
+
+func TestFormatMilliseconds(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		expected string
+	}{
+		{duration: 1500 * time.Microsecond, expected: "1.5"},
+		{duration: 2 * time.Second, expected: "2000"},
+		{duration: -250 * time.Millisecond, expected: "-250"},
+		{duration: 0, expected: "0"},
+	}
+
+	for _, tt := range tests {
+		if got := formatMilliseconds(tt.duration); got != tt.expected {
+			t.Errorf("formatMilliseconds(%v) = %q, want %q", tt.duration, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package trace
+
+// RootCount returns the number of root spans in a trace: spans without a
+// parent or whose parent is not in the trace
+func RootCount(t Trace) int {
+	return rootCount(t, spansByID(t))
+}
+
+// MaxDepth returns the number of levels in the deepest branch of a trace,
+// counting a root span as depth 1. Spans only reachable through a parent
+// cycle are not counted.
+func MaxDepth(t Trace) int {
+	return maxDepth(t, spansByID(t))
+}
+
+// spansByID maps the spans of a trace by ID
+func spansByID(t Trace) map[string]*Span {
+	spanMap := make(map[string]*Span, len(t.Spans))
+	for i := range t.Spans {
+		spanMap[t.Spans[i].SpanID] = &t.Spans[i]
+	}
+	return spanMap
+}
+
+// isRoot reports whether a span has no parent in the trace
+func isRoot(span Span, spanMap map[string]*Span) bool {
+	_, ok := spanMap[span.ParentSpanID]
+	return span.ParentSpanID == "" || !ok
+}
+
+// rootCount counts the root spans using a map of the trace's spans by ID
+func rootCount(t Trace, spanMap map[string]*Span) int {
+	var count int
+	for _, span := range t.Spans {
+		if isRoot(span, spanMap) {
+			count++
+		}
+	}
+	return count
+}
+
+// maxDepth walks the span tree level by level from the roots, using a map
+// of the trace's spans by ID. Each span is visited once, so cycles end the
+// walk instead of looping.
+func maxDepth(t Trace, spanMap map[string]*Span) int {
+	children := make(map[string][]int)
+	var level []int
+	for i, span := range t.Spans {
+		if isRoot(span, spanMap) {
+			level = append(level, i)
+		} else {
+			children[span.ParentSpanID] = append(children[span.ParentSpanID], i)
+		}
+	}
+
+	visited := make(map[int]bool)
+	var depth int
+	for len(level) > 0 {
+		depth++
+		var next []int
+		for _, i := range level {
+			visited[i] = true
+		}
+		for _, i := range level {
+			for _, child := range children[t.Spans[i].SpanID] {
+				if !visited[child] {
+					visited[child] = true
+					next = append(next, child)
+				}
+			}
+		}
+		level = next
+	}
+	return depth
+}
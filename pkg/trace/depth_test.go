@@ -0,0 +1,104 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRootCountAndMaxDepth(t *testing.T) {
+	span := func(id, parent string) Span {
+		return Span{SpanID: id, ParentSpanID: parent, Name: id}
+	}
+
+	tests := []struct {
+		name      string
+		spans     []Span
+		wantRoots int
+		wantDepth int
+	}{
+		{
+			name:      "empty trace",
+			wantRoots: 0,
+			wantDepth: 0,
+		},
+		{
+			name:      "single root",
+			spans:     []Span{span("root", "")},
+			wantRoots: 1,
+			wantDepth: 1,
+		},
+		{
+			name: "deepest branch wins",
+			spans: []Span{
+				span("root", ""),
+				span("a", "root"),
+				span("b", "root"),
+				span("b1", "b"),
+				span("b2", "b1"),
+			},
+			wantRoots: 1,
+			wantDepth: 4,
+		},
+		{
+			name: "orphans are roots",
+			spans: []Span{
+				span("root", ""),
+				span("child", "root"),
+				span("orphan", "missing"),
+			},
+			wantRoots: 2,
+			wantDepth: 2,
+		},
+		{
+			name: "cycle does not hang",
+			spans: []Span{
+				span("root", ""),
+				span("a", "b"),
+				span("b", "a"),
+				span("self", "self"),
+			},
+			wantRoots: 1,
+			wantDepth: 1,
+		},
+		{
+			name: "cycle below a root",
+			spans: []Span{
+				span("root", ""),
+				span("a", "root"),
+				span("a", "a"),
+			},
+			wantRoots: 1,
+			wantDepth: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trace := Trace{TraceID: "trace1", Spans: tt.spans}
+			if got := RootCount(trace); got != tt.wantRoots {
+				t.Errorf("RootCount() = %d, want %d", got, tt.wantRoots)
+			}
+			if got := MaxDepth(trace); got != tt.wantDepth {
+				t.Errorf("MaxDepth() = %d, want %d", got, tt.wantDepth)
+			}
+		})
+	}
+}
+
+func TestGenerateMarkdownRootsAndDepth(t *testing.T) {
+	traces := []Trace{{TraceID: "trace1", Spans: []Span{
+		{SpanID: "root", Name: "root"},
+		{SpanID: "child", ParentSpanID: "root", Name: "child"},
+		{SpanID: "orphan", ParentSpanID: "missing", Name: "orphan"},
+	}}}
+
+	got := GenerateMarkdown(traces, Options{})
+	for _, want := range []string{
+		"| Trace ID | Duration | Spans | Root Spans | Max Depth | Services | Errors |",
+		"| `trace1` | 0ns | 3 | 2 | 2 | 0 | 0 |",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("GenerateMarkdown() output does not contain %q:\n%s", want, got)
+		}
+	}
+}
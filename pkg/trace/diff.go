@@ -0,0 +1,96 @@
+package trace
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// GenerateDiff returns a terse, line-per-difference text diff of two trace
+// sets and whether they differ at all. Traces are matched by the given
+// identifier attribute. Traces or spans missing on one side, trace or span
+// durations that changed by more than threshold percent, and changed trace,
+// span or event attributes all count as differences.
+func GenerateDiff(first, second TraceSet, attribute string, threshold float64) (string, bool) {
+	var sb strings.Builder
+	firstName := getFileNameWithoutExt(first.Name)
+	secondName := getFileNameWithoutExt(second.Name)
+
+	result := Compare(first.Traces, second.Traces, attribute, Options{})
+	for _, name := range result.OnlyInFirst {
+		sb.WriteString(fmt.Sprintf("- %s: only in %s\n", name, firstName))
+	}
+	for _, name := range result.OnlyInSecond {
+		sb.WriteString(fmt.Sprintf("+ %s: only in %s\n", name, secondName))
+	}
+
+	firstMap := traceIndex(first, attribute)
+	secondMap := traceIndex(second, attribute)
+	for _, c := range result.Matching {
+		t1, t2 := firstMap[c.Name], secondMap[c.Name]
+		prefix := "~ " + c.Name
+
+		if line, ok := durationLine(c.FirstDuration, c.SecondDuration, threshold); ok {
+			sb.WriteString(fmt.Sprintf("%s: duration %s\n", prefix, line))
+		}
+		writeAttributeDiff(&sb, prefix, "attribute", DiffAttributes(t1.Attributes, t2.Attributes))
+		writeAttributeDiff(&sb, prefix, "resource attribute", DiffAttributes(t1.ResourceAttrs, t2.ResourceAttrs))
+
+		for _, span := range c.SpansOnlyInFirst {
+			sb.WriteString(fmt.Sprintf("%s: span %s only in %s\n", prefix, span, firstName))
+		}
+		for _, span := range c.SpansOnlyInSecond {
+			sb.WriteString(fmt.Sprintf("%s: span %s only in %s\n", prefix, span, secondName))
+		}
+
+		spans1 := spansByKey(t1)
+		spans2 := spansByKey(t2)
+		for _, s := range c.Spans {
+			spanPrefix := fmt.Sprintf("%s: span %s", prefix, s.Name)
+			if line, ok := durationLine(s.FirstDuration, s.SecondDuration, threshold); ok {
+				sb.WriteString(fmt.Sprintf("%s: duration %s\n", spanPrefix, line))
+			}
+			writeAttributeDiff(&sb, spanPrefix, "attribute", DiffAttributes(spans1[s.Name].Attributes, spans2[s.Name].Attributes))
+			for _, e := range s.EventChanges {
+				sb.WriteString(fmt.Sprintf("%s: event %s %s\n", spanPrefix, e.Name, e.Type))
+			}
+		}
+	}
+
+	return sb.String(), sb.Len() > 0
+}
+
+// durationLine formats a duration change and reports whether it exceeds
+// threshold percent in either direction
+func durationLine(base, current time.Duration, threshold float64) (string, bool) {
+	percent := DurationChange{Baseline: base, Current: current}.Percent()
+	if math.Abs(percent) <= threshold {
+		return "", false
+	}
+	return fmt.Sprintf("%s → %s (%s)", formatDuration(base), formatDuration(current), formatPercentChange(percent)), true
+}
+
+// writeAttributeDiff writes a line for every added, removed or changed
+// attribute
+func writeAttributeDiff(sb *strings.Builder, prefix, label string, changes []AttributeChange) {
+	for _, c := range changes {
+		switch c.Type {
+		case AttributeAdded:
+			sb.WriteString(fmt.Sprintf("%s: %s %s added: %s\n", prefix, label, c.Key, c.New))
+		case AttributeRemoved:
+			sb.WriteString(fmt.Sprintf("%s: %s %s removed: %s\n", prefix, label, c.Key, c.Old))
+		case AttributeChanged:
+			sb.WriteString(fmt.Sprintf("%s: %s %s changed: %s → %s\n", prefix, label, c.Key, c.Old, c.New))
+		}
+	}
+}
+
+// spansByKey indexes the spans of a trace by their spanRef key
+func spansByKey(t *Trace) map[string]*Span {
+	spans := make(map[string]*Span)
+	for ref, span := range spanIndex(t) {
+		spans[ref.key()] = span
+	}
+	return spans
+}
@@ -0,0 +1,84 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateDiff(t *testing.T) {
+	now := time.Now()
+	makeSet := func(name string, rootMs int, attrs map[string]string, extra ...Span) TraceSet {
+		spans := append([]Span{
+			{SpanID: "root", Name: "handler", StartTime: now, EndTime: now.Add(time.Duration(rootMs) * time.Millisecond), Attributes: attrs},
+		}, extra...)
+		return TraceSet{Name: name, Traces: []Trace{{TraceID: "trace1", Spans: spans}}}
+	}
+	cache := Span{SpanID: "cache", ParentSpanID: "root", Name: "cache", StartTime: now, EndTime: now.Add(time.Millisecond)}
+
+	tests := []struct {
+		name       string
+		first      TraceSet
+		second     TraceSet
+		wantDiffer bool
+		wantLines  []string
+	}{
+		{
+			name:       "identical",
+			first:      makeSet("a.json", 100, map[string]string{"k": "v"}),
+			second:     makeSet("b.json", 100, map[string]string{"k": "v"}),
+			wantDiffer: false,
+		},
+		{
+			name:       "duration within threshold",
+			first:      makeSet("a.json", 100, nil),
+			second:     makeSet("b.json", 105, nil),
+			wantDiffer: false,
+		},
+		{
+			name:       "duration beyond threshold",
+			first:      makeSet("a.json", 100, nil),
+			second:     makeSet("b.json", 150, nil),
+			wantDiffer: true,
+			wantLines: []string{
+				"~ trace1: duration 100.00ms → 150.00ms (+50.0%)",
+				"~ trace1: span handler: duration 100.00ms → 150.00ms (+50.0%)",
+			},
+		},
+		{
+			name:       "structure and attributes",
+			first:      makeSet("a.json", 100, map[string]string{"k": "v"}, cache),
+			second:     makeSet("b.json", 100, map[string]string{"k": "w", "new": "x"}),
+			wantDiffer: true,
+			wantLines: []string{
+				"~ trace1: span cache only in a",
+				"~ trace1: span handler: attribute k changed: v → w",
+				"~ trace1: span handler: attribute new added: x",
+			},
+		},
+		{
+			name:       "missing trace",
+			first:      makeSet("a.json", 100, nil),
+			second:     TraceSet{Name: "b.json"},
+			wantDiffer: true,
+			wantLines:  []string{"- trace1: only in a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, differ := GenerateDiff(tt.first, tt.second, "trace_id", 10)
+			if differ != tt.wantDiffer {
+				t.Fatalf("GenerateDiff() differ = %v, want %v:\n%s", differ, tt.wantDiffer, got)
+			}
+			if !differ && got != "" {
+				t.Errorf("GenerateDiff() = %q, want no output for identical traces", got)
+			}
+			for _, want := range tt.wantLines {
+				if !strings.Contains(got, want+"\n") {
+					t.Errorf("GenerateDiff() output does not contain %q:\n%s", want, got)
+				}
+			}
+		})
+	}
+}
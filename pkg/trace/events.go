@@ -0,0 +1,92 @@
+package trace
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// EventChangeType is the kind of change of an event between two versions
+// of a span
+type EventChangeType string
+
+// Event change types
+const (
+	EventAdded   EventChangeType = "added"
+	EventRemoved EventChangeType = "removed"
+	EventChanged EventChangeType = "changed"
+)
+
+// EventChange describes an event that was added, removed or whose
+// attributes changed between two versions of a span. Repeated event names
+// are compared by occurrence and numbered like spans, e.g. "retry #2".
+type EventChange struct {
+	Name string          `json:"name"`
+	Type EventChangeType `json:"type"`
+}
+
+// DiffEvents compares the events of two versions of a span by name and
+// returns the changes sorted by name and occurrence
+func DiffEvents(base, other []Event) []EventChange {
+	baseIndex := eventIndex(base)
+	otherIndex := eventIndex(other)
+
+	var refs []spanRef
+	for ref := range baseIndex {
+		refs = append(refs, ref)
+	}
+	for ref := range otherIndex {
+		if _, ok := baseIndex[ref]; !ok {
+			refs = append(refs, ref)
+		}
+	}
+	sortSpanRefs(refs)
+
+	var changes []EventChange
+	for _, ref := range refs {
+		b, inBase := baseIndex[ref]
+		o, inOther := otherIndex[ref]
+		switch {
+		case !inBase:
+			changes = append(changes, EventChange{Name: ref.key(), Type: EventAdded})
+		case !inOther:
+			changes = append(changes, EventChange{Name: ref.key(), Type: EventRemoved})
+		case hasAttributeChanges(DiffAttributes(b.Attributes, o.Attributes)):
+			changes = append(changes, EventChange{Name: ref.key(), Type: EventChanged})
+		}
+	}
+	return changes
+}
+
+// eventIndex maps events by name and occurrence, counted in time order
+func eventIndex(events []Event) map[spanRef]*Event {
+	events = append([]Event(nil), events...)
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+
+	index := make(map[spanRef]*Event, len(events))
+	occurrences := make(map[string]int)
+	for i := range events {
+		occurrences[events[i].Name]++
+		index[spanRef{name: events[i].Name, occurrence: occurrences[events[i].Name]}] = &events[i]
+	}
+	return index
+}
+
+// formatEventChanges formats event changes for a markdown table cell
+func formatEventChanges(changes []EventChange, ind indicators) string {
+	parts := make([]string, 0, len(changes))
+	for _, c := range changes {
+		name := escapeMarkdownCell(c.Name)
+		switch c.Type {
+		case EventAdded:
+			parts = append(parts, "+"+name)
+		case EventRemoved:
+			parts = append(parts, "−"+name)
+		default:
+			parts = append(parts, fmt.Sprintf("%s (attributes changed)", name))
+		}
+	}
+	return ind.events + " events: " + strings.Join(parts, ", ")
+}
@@ -0,0 +1,56 @@
+package trace
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffEvents(t *testing.T) {
+	now := time.Now()
+	base := []Event{
+		{Time: now, Name: "cache.hit"},
+		{Time: now.Add(time.Millisecond), Name: "retry", Attributes: map[string]string{"attempt": "1"}},
+	}
+	other := []Event{
+		{Time: now.Add(time.Millisecond), Name: "retry", Attributes: map[string]string{"attempt": "2"}},
+		{Time: now.Add(2 * time.Millisecond), Name: "retry", Attributes: map[string]string{"attempt": "3"}},
+	}
+
+	expected := []EventChange{
+		{Name: "cache.hit", Type: EventRemoved},
+		{Name: "retry", Type: EventChanged},
+		{Name: "retry #2", Type: EventAdded},
+	}
+	if got := DiffEvents(base, other); !reflect.DeepEqual(got, expected) {
+		t.Errorf("DiffEvents() = %+v, want %+v", got, expected)
+	}
+	if got := DiffEvents(base, base); len(got) != 0 {
+		t.Errorf("DiffEvents() = %+v for identical events, want none", got)
+	}
+}
+
+func TestComparisonsShowEventChanges(t *testing.T) {
+	now := time.Now()
+	newTrace := func(events ...Event) Trace {
+		return Trace{TraceID: "trace1", Spans: []Span{
+			{SpanID: "a", Name: "GET /users", StartTime: now, EndTime: now.Add(time.Second), Events: events},
+		}}
+	}
+	base := newTrace(Event{Time: now, Name: "cache.hit"})
+	head := newTrace(Event{Time: now, Name: "retry"})
+
+	got := CompareMultipleTraces([]TraceSet{
+		{Name: "base.json", Traces: []Trace{base}},
+		{Name: "head.json", Traces: []Trace{head}},
+	}, "trace_id", Options{})
+	if want := "| GET /users | 1.00s | 1.00s | - | 📌 events: −cache.hit, +retry |"; !strings.Contains(got, want) {
+		t.Errorf("CompareMultipleTraces() output does not contain %q:\n%s", want, got)
+	}
+
+	got = CompareTraces([]Trace{base}, []Trace{head})
+	if want := "**Event Changes:**\n\n- GET /users: 📌 events: −cache.hit, +retry\n"; !strings.Contains(got, want) {
+		t.Errorf("CompareTraces() output does not contain %q:\n%s", want, got)
+	}
+}
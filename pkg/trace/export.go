@@ -0,0 +1,15 @@
+package trace
+
+import "encoding/json"
+
+// MarshalTraces encodes traces in the tool's own JSON trace format, which
+// ParseTraces reads back unchanged. Pretty indents the JSON for reading.
+func MarshalTraces(traces []Trace, pretty bool) ([]byte, error) {
+	if traces == nil {
+		traces = []Trace{}
+	}
+	if pretty {
+		return json.MarshalIndent(traces, "", "  ")
+	}
+	return json.Marshal(traces)
+}
@@ -0,0 +1,67 @@
+package trace
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMarshalTracesRoundTrip(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 123_456_789, time.UTC)
+	traces := []Trace{{
+		TraceID:       "4bf92f3577b34da6a3ce929d0e0e4736",
+		Attributes:    map[string]string{"http.route": "/users"},
+		ResourceAttrs: map[string]string{"service.name": "api"},
+		Spans: []Span{
+			{
+				SpanID:     "a",
+				TraceState: "rojo=00f067aa0ba902b7",
+				Name:       "GET /users",
+				Kind:       SpanKindServer,
+				StartTime:  start,
+				EndTime:    start.Add(time.Second),
+				Attributes: map[string]string{"http.method": "GET"},
+				Events:     []Event{{Time: start.Add(time.Millisecond), Name: "retry", Attributes: map[string]string{"attempt": "2"}}},
+				Status:     Status{Code: StatusError, Message: "timeout"},
+				Scope:      InstrumentationScope{Name: "net/http", Version: "0.49.0"},
+			},
+			{
+				SpanID:       "b",
+				ParentSpanID: "a",
+				Name:         "select",
+				StartTime:    start.Add(time.Millisecond),
+				EndTime:      start.Add(2 * time.Millisecond),
+				Links:        []SpanLink{{TraceID: "abcd", SpanID: "1234"}},
+			},
+		},
+	}}
+
+	for _, pretty := range []bool{false, true} {
+		data, err := MarshalTraces(traces, pretty)
+		if err != nil {
+			t.Fatalf("MarshalTraces() error = %v", err)
+		}
+		if got := strings.Contains(string(data), "\n  "); got != pretty {
+			t.Errorf("MarshalTraces(pretty = %v) indented = %v", pretty, got)
+		}
+
+		got, err := ParseTraces(data)
+		if err != nil {
+			t.Fatalf("ParseTraces() error = %v", err)
+		}
+		if !reflect.DeepEqual(got, traces) {
+			t.Errorf("ParseTraces(MarshalTraces()) = %+v, want %+v", got, traces)
+		}
+	}
+}
+
+func TestMarshalTracesEmpty(t *testing.T) {
+	data, err := MarshalTraces(nil, false)
+	if err != nil {
+		t.Fatalf("MarshalTraces() error = %v", err)
+	}
+	if string(data) != "[]" {
+		t.Errorf("MarshalTraces(nil) = %s, want []", data)
+	}
+}
@@ -0,0 +1,103 @@
+package trace
+
+import (
+	"regexp"
+	"time"
+)
+
+// FilterByService returns the traces keeping only the spans whose
+// service.name, from the span, trace or resource attributes, equals
+// service. The input traces are not modified.
+func FilterByService(traces []Trace, service string) []Trace {
+	return filterSpans(traces, func(t *Trace, span *Span) bool {
+		name, _ := getSpanAttribute(t, span, "service.name")
+		return name == service
+	})
+}
+
+// FilterSpans returns the traces keeping only the spans whose name matches
+// at least one include pattern, or any name if there are none, and no
+// exclude pattern. The input traces are not modified.
+func FilterSpans(traces []Trace, include, exclude []*regexp.Regexp) []Trace {
+	if len(include) == 0 && len(exclude) == 0 {
+		return traces
+	}
+	return filterSpans(traces, func(t *Trace, span *Span) bool {
+		return (len(include) == 0 || matchesAny(include, span.Name)) && !matchesAny(exclude, span.Name)
+	})
+}
+
+// FilterByMinDuration returns the traces keeping only the spans that took
+// at least min. Children of dropped spans are re-parented to their nearest
+// kept ancestor. The input traces are not modified.
+func FilterByMinDuration(traces []Trace, min time.Duration) []Trace {
+	if min <= 0 {
+		return traces
+	}
+	return filterSpans(traces, func(t *Trace, span *Span) bool {
+		return spanDuration(*span) >= min
+	})
+}
+
+// matchesAny reports whether s matches any of the patterns
+func matchesAny(patterns []*regexp.Regexp, s string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterSpans returns the traces keeping only the spans accepted by keep.
+// Spans whose parent was dropped are re-parented to their nearest kept
+// ancestor, so the span hierarchy stays intact. Traces without any kept
+// span are dropped.
+func filterSpans(traces []Trace, keep func(t *Trace, span *Span) bool) []Trace {
+	var filtered []Trace
+	for i := range traces {
+		t := &traces[i]
+
+		kept := make(map[string]bool, len(t.Spans))
+		parents := make(map[string]string, len(t.Spans))
+		for j := range t.Spans {
+			span := &t.Spans[j]
+			parents[span.SpanID] = span.ParentSpanID
+			if keep(t, span) {
+				kept[span.SpanID] = true
+			}
+		}
+		if len(kept) == 0 {
+			continue
+		}
+
+		result := *t
+		result.Spans = nil
+		for _, span := range t.Spans {
+			if !kept[span.SpanID] {
+				continue
+			}
+			span.ParentSpanID = keptAncestor(span.ParentSpanID, parents, kept)
+			result.Spans = append(result.Spans, span)
+		}
+		filtered = append(filtered, result)
+	}
+	return filtered
+}
+
+// keptAncestor walks up the parent chain from parentID and returns the first
+// kept span, or "" if there is none. The walk is bounded by the number of
+// spans so parent cycles cannot loop forever.
+func keptAncestor(parentID string, parents map[string]string, kept map[string]bool) string {
+	for steps := 0; parentID != "" && steps <= len(parents); steps++ {
+		if kept[parentID] {
+			return parentID
+		}
+		next, ok := parents[parentID]
+		if !ok {
+			break
+		}
+		parentID = next
+	}
+	return ""
+}
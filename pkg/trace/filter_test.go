@@ -0,0 +1,225 @@
+package trace
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestFilterByService(t *testing.T) {
+	traces := []Trace{
+		{
+			TraceID:       "trace1",
+			ResourceAttrs: map[string]string{"service.name": "frontend"},
+			Spans: []Span{
+				{SpanID: "a", Name: "GET /checkout"},
+				{SpanID: "b", ParentSpanID: "a", Name: "charge", Attributes: map[string]string{"service.name": "payments"}},
+				{SpanID: "c", ParentSpanID: "b", Name: "render"},
+			},
+		},
+		{
+			TraceID:       "trace2",
+			ResourceAttrs: map[string]string{"service.name": "worker"},
+			Spans: []Span{
+				{SpanID: "d", Name: "job"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		service  string
+		expected map[string]string // span ID -> parent span ID
+	}{
+		{
+			name:     "resource service with re-parenting",
+			service:  "frontend",
+			expected: map[string]string{"a": "", "c": "a"},
+		},
+		{
+			name:     "span service",
+			service:  "payments",
+			expected: map[string]string{"b": ""},
+		},
+		{
+			name:     "no match",
+			service:  "missing",
+			expected: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FilterByService(traces, tt.service)
+
+			spans := make(map[string]string)
+			for _, trace := range got {
+				for _, span := range trace.Spans {
+					spans[span.SpanID] = span.ParentSpanID
+				}
+			}
+			if len(spans) != len(tt.expected) {
+				t.Fatalf("FilterByService() kept spans %v, want %v", spans, tt.expected)
+			}
+			for id, parent := range tt.expected {
+				if got, ok := spans[id]; !ok || got != parent {
+					t.Errorf("FilterByService() span %s parent = %q, want %q", id, got, parent)
+				}
+			}
+		})
+	}
+
+	// The input must not be modified
+	if len(traces[0].Spans) != 3 || traces[0].Spans[2].ParentSpanID != "b" {
+		t.Errorf("FilterByService() modified the input traces")
+	}
+}
+
+func TestFilterByServiceCycle(t *testing.T) {
+	traces := []Trace{{TraceID: "trace1", Spans: []Span{
+		{SpanID: "a", ParentSpanID: "b", Attributes: map[string]string{"service.name": "other"}},
+		{SpanID: "b", ParentSpanID: "a", Attributes: map[string]string{"service.name": "other"}},
+		{SpanID: "c", ParentSpanID: "a", Attributes: map[string]string{"service.name": "api"}},
+	}}}
+
+	got := FilterByService(traces, "api")
+	if len(got) != 1 || len(got[0].Spans) != 1 || got[0].Spans[0].ParentSpanID != "" {
+		t.Errorf("FilterByService() = %+v, want the api span as a root", got)
+	}
+}
+
+func TestFilterSpans(t *testing.T) {
+	traces := []Trace{{TraceID: "trace1", Spans: []Span{
+		{SpanID: "a", Name: "http.server GET /users"},
+		{SpanID: "b", ParentSpanID: "a", Name: "internal.middleware"},
+		{SpanID: "c", ParentSpanID: "b", Name: "http.client GET /profile"},
+		{SpanID: "d", ParentSpanID: "a", Name: "http.client GET /health"},
+	}}}
+
+	tests := []struct {
+		name     string
+		include  []string
+		exclude  []string
+		expected map[string]string // span ID -> parent span ID
+	}{
+		{
+			name:     "no patterns",
+			expected: map[string]string{"a": "", "b": "a", "c": "b", "d": "a"},
+		},
+		{
+			name:     "include",
+			include:  []string{`^http\.`},
+			expected: map[string]string{"a": "", "c": "a", "d": "a"},
+		},
+		{
+			name:     "exclude",
+			exclude:  []string{`^internal\.`, `/health$`},
+			expected: map[string]string{"a": "", "c": "a"},
+		},
+		{
+			name:     "include and exclude",
+			include:  []string{`^http\.client`},
+			exclude:  []string{`/health$`},
+			expected: map[string]string{"c": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FilterSpans(traces, compilePatterns(tt.include), compilePatterns(tt.exclude))
+
+			spans := make(map[string]string)
+			for _, trace := range got {
+				for _, span := range trace.Spans {
+					spans[span.SpanID] = span.ParentSpanID
+				}
+			}
+			if len(spans) != len(tt.expected) {
+				t.Fatalf("FilterSpans() kept spans %v, want %v", spans, tt.expected)
+			}
+			for id, parent := range tt.expected {
+				if got, ok := spans[id]; !ok || got != parent {
+					t.Errorf("FilterSpans() span %s parent = %q, want %q", id, got, parent)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterByMinDuration(t *testing.T) {
+	now := time.Now()
+	span := func(id, parent string, d time.Duration) Span {
+		return Span{SpanID: id, ParentSpanID: parent, Name: id, StartTime: now, EndTime: now.Add(d)}
+	}
+	traces := []Trace{
+		{TraceID: "trace1", Spans: []Span{
+			span("a", "", 10*time.Millisecond),
+			span("b", "a", 500*time.Microsecond),
+			span("c", "b", 300*time.Microsecond),
+			span("d", "c", 2*time.Millisecond),
+			span("e", "a", time.Millisecond),
+		}},
+		{TraceID: "trace2", Spans: []Span{
+			span("f", "", 100*time.Microsecond),
+		}},
+	}
+
+	tests := []struct {
+		name     string
+		min      time.Duration
+		expected map[string]string // span ID -> parent span ID
+	}{
+		{
+			name:     "zero keeps every span",
+			expected: map[string]string{"a": "", "b": "a", "c": "b", "d": "c", "e": "a", "f": ""},
+		},
+		{
+			name:     "children skip dropped ancestors",
+			min:      time.Millisecond,
+			expected: map[string]string{"a": "", "d": "a", "e": "a"},
+		},
+		{
+			name:     "dropped root",
+			min:      2 * time.Millisecond,
+			expected: map[string]string{"a": "", "d": "a"},
+		},
+		{
+			name:     "children of a dropped root become roots",
+			min:      5 * time.Millisecond,
+			expected: map[string]string{"a": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FilterByMinDuration(traces, tt.min)
+
+			spans := make(map[string]string)
+			for _, trace := range got {
+				for _, span := range trace.Spans {
+					spans[span.SpanID] = span.ParentSpanID
+				}
+			}
+			if !reflect.DeepEqual(spans, tt.expected) {
+				t.Errorf("FilterByMinDuration() kept spans %v, want %v", spans, tt.expected)
+			}
+		})
+	}
+
+	// Traces without any span left are dropped, and the input is untouched
+	if got := FilterByMinDuration(traces, time.Millisecond); len(got) != 1 {
+		t.Errorf("FilterByMinDuration() kept %d traces, want 1", len(got))
+	}
+	if traces[0].Spans[3].ParentSpanID != "c" {
+		t.Errorf("FilterByMinDuration() modified its input")
+	}
+}
+
+func compilePatterns(patterns []string) []*regexp.Regexp {
+	var compiled []*regexp.Regexp
+	for _, p := range patterns {
+		compiled = append(compiled, regexp.MustCompile(p))
+	}
+	return compiled
+}
@@ -0,0 +1,61 @@
+package trace
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// StructureAttribute is the identifier attribute that matches traces by
+// their Fingerprint instead of by name or ID
+const StructureAttribute = "structure"
+
+// Fingerprint returns an identifier for the shape of a trace: the root
+// span's name followed by a hash of its tree of span names, such as
+// "GET /users [3f2a9c1b04d7e6a5]". Siblings are ordered by their own
+// subtrees, so span order, IDs and timings do not change the fingerprint,
+// while a missing, extra or renamed span does. Spans only reachable through
+// a parent cycle are left out.
+func Fingerprint(t Trace) string {
+	spanMap := spansByID(t)
+	children := make(map[string][]int)
+	var roots []int
+	for i, span := range t.Spans {
+		if isRoot(span, spanMap) {
+			roots = append(roots, i)
+		} else {
+			children[span.ParentSpanID] = append(children[span.ParentSpanID], i)
+		}
+	}
+
+	visited := make(map[int]bool)
+	var subtree func(i int) string
+	subtree = func(i int) string {
+		visited[i] = true
+		var parts []string
+		for _, child := range children[t.Spans[i].SpanID] {
+			if !visited[child] {
+				parts = append(parts, subtree(child))
+			}
+		}
+		return canonicalTree(t.Spans[i].Name, parts)
+	}
+
+	var parts []string
+	for _, root := range roots {
+		parts = append(parts, subtree(root))
+	}
+	sum := sha256.Sum256([]byte(canonicalTree("", parts)))
+
+	name, _ := lookupTraceIdentifier(t, "name")
+	return fmt.Sprintf("%s [%s]", name, hex.EncodeToString(sum[:8]))
+}
+
+// canonicalTree encodes a span name and the canonical forms of its
+// subtrees. Names are length-prefixed so no name can mimic the structure.
+func canonicalTree(name string, subtrees []string) string {
+	sort.Strings(subtrees)
+	return fmt.Sprintf("%d:%s(%s)", len(name), name, strings.Join(subtrees, ","))
+}
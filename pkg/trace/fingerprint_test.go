@@ -0,0 +1,111 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFingerprint(t *testing.T) {
+	now := time.Now()
+	span := func(id, parent, name string, offset time.Duration) Span {
+		return Span{SpanID: id, ParentSpanID: parent, Name: name, StartTime: now.Add(offset), EndTime: now.Add(offset + time.Millisecond)}
+	}
+	base := Trace{TraceID: "t1", Spans: []Span{
+		span("a", "", "GET /users/42", 0),
+		span("b", "a", "auth", 1),
+		span("c", "a", "db.query", 2),
+		span("d", "c", "pool.acquire", 3),
+		span("e", "a", "db.query", 4),
+	}}
+
+	tests := []struct {
+		name  string
+		trace Trace
+		same  bool
+	}{
+		{name: "identical", trace: base, same: true},
+		{
+			name: "reordered spans with other IDs and timings",
+			trace: Trace{TraceID: "t2", Spans: []Span{
+				span("5", "1", "db.query", 10),
+				span("4", "3", "pool.acquire", 20),
+				span("3", "1", "db.query", 30),
+				span("2", "1", "auth", 40),
+				span("1", "", "GET /users/42", 0),
+			}},
+			same: true,
+		},
+		{
+			name: "child moved to another parent",
+			trace: Trace{TraceID: "t3", Spans: []Span{
+				span("a", "", "GET /users/42", 0),
+				span("b", "a", "auth", 1),
+				span("c", "a", "db.query", 2),
+				span("d", "b", "pool.acquire", 3),
+				span("e", "a", "db.query", 4),
+			}},
+		},
+		{
+			name: "extra span",
+			trace: Trace{TraceID: "t4", Spans: append(append([]Span(nil), base.Spans...),
+				span("f", "a", "cache.get", 5))},
+		},
+		{
+			name: "renamed span",
+			trace: Trace{TraceID: "t5", Spans: []Span{
+				span("a", "", "GET /users/42", 0),
+				span("b", "a", "authz", 1),
+				span("c", "a", "db.query", 2),
+				span("d", "c", "pool.acquire", 3),
+				span("e", "a", "db.query", 4),
+			}},
+		},
+	}
+
+	want := Fingerprint(base)
+	if !strings.HasPrefix(want, "GET /users/42 [") {
+		t.Fatalf("Fingerprint() = %q, want it to start with the root span name", want)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Fingerprint(tt.trace)
+			if (got == want) != tt.same {
+				t.Errorf("Fingerprint() = %q, base = %q, want same = %v", got, want, tt.same)
+			}
+		})
+	}
+}
+
+func TestFingerprintParentCycle(t *testing.T) {
+	trace := Trace{TraceID: "t1", Spans: []Span{
+		{SpanID: "a", Name: "root"},
+		{SpanID: "b", ParentSpanID: "c", Name: "loop"},
+		{SpanID: "c", ParentSpanID: "b", Name: "loop"},
+	}}
+	if got, want := Fingerprint(trace), Fingerprint(Trace{Spans: trace.Spans[:1]}); got != want {
+		t.Errorf("Fingerprint() = %q, want %q ignoring the cycle", got, want)
+	}
+}
+
+func TestCompareMultipleTracesByStructure(t *testing.T) {
+	now := time.Now()
+	newTrace := func(id, route string, d time.Duration) Trace {
+		return Trace{TraceID: id, Spans: []Span{
+			{SpanID: id + "-root", Name: route, StartTime: now, EndTime: now.Add(d)},
+			{SpanID: id + "-db", ParentSpanID: id + "-root", Name: "db.query", StartTime: now, EndTime: now.Add(d / 2)},
+		}}
+	}
+
+	markdown := CompareMultipleTraces([]TraceSet{
+		{Name: "base.json", Traces: []Trace{newTrace("abc", "checkout", time.Second)}},
+		{Name: "head.json", Traces: []Trace{newTrace("def", "checkout", 2*time.Second)}},
+	}, StructureAttribute, Options{})
+
+	if strings.Contains(markdown, "None of the") {
+		t.Errorf("traces with the same structure should match:\n%s", markdown)
+	}
+	if !strings.Contains(markdown, "<summary>checkout [") {
+		t.Errorf("matched trace should be named by its fingerprint:\n%s", markdown)
+	}
+}
@@ -0,0 +1,114 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Attributes are the attributes of a trace, span, event or link. Values are
+// strings; JSON input may also hold numbers, booleans, objects and arrays,
+// which are flattened into strings while parsing:
+//
+//   - numbers keep the text they are written with, so 1.50 stays 1.50
+//   - booleans become true or false, and null becomes an empty string
+//   - objects add their keys to the parent key with a dot, so
+//     {"db": {"name": "users"}} becomes db.name=users
+//   - arrays add the index of each element, so {"tags": ["a", "b"]}
+//     becomes tags.0=a and tags.1=b
+//   - empty objects and arrays keep the parent key with the value {} or []
+//   - a key given directly in the input wins over the same key produced by
+//     flattening, so {"db.name": "a", "db": {"name": "b"}} keeps db.name=a
+type Attributes map[string]string
+
+// UnmarshalJSON reads an attribute object, flattening nested values
+func (a *Attributes) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		*a = nil
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var raw map[string]any
+	if err := decoder.Decode(&raw); err != nil {
+		return err
+	}
+
+	attrs := make(Attributes, len(raw))
+	// Direct scalar values first, so they win over flattened keys
+	keys := make([]string, 0, len(raw))
+	for key, value := range raw {
+		keys = append(keys, key)
+		if s, ok := scalarAttribute(value); ok {
+			attrs[key] = s
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if _, ok := scalarAttribute(raw[key]); !ok {
+			flattenAttribute(attrs, key, raw[key])
+		}
+	}
+	*a = attrs
+	return nil
+}
+
+// scalarAttribute formats a JSON string, number, boolean or null as an
+// attribute value. It reports false for objects and arrays.
+func scalarAttribute(value any) (string, bool) {
+	switch v := value.(type) {
+	case nil:
+		return "", true
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
+
+// flattenAttribute adds a value to attrs under key, adding the keys of
+// objects and the indexes of arrays to it with a dot. Keys already in attrs
+// are kept.
+func flattenAttribute(attrs Attributes, key string, value any) {
+	if s, ok := scalarAttribute(value); ok {
+		if _, exists := attrs[key]; !exists {
+			attrs[key] = s
+		}
+		return
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		if len(v) == 0 {
+			flattenAttribute(attrs, key, "{}")
+			return
+		}
+		for _, k := range sortedAnyKeys(v) {
+			flattenAttribute(attrs, key+"."+k, v[k])
+		}
+	case []any:
+		if len(v) == 0 {
+			flattenAttribute(attrs, key, "[]")
+			return
+		}
+		for i, element := range v {
+			flattenAttribute(attrs, fmt.Sprintf("%s.%d", key, i), element)
+		}
+	}
+}
+
+// sortedAnyKeys returns the keys of a JSON object in sorted order
+func sortedAnyKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,121 @@
+package trace
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestAttributesUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Attributes
+	}{
+		{
+			name:     "strings",
+			input:    `{"http.method": "GET", "http.url": "/users"}`,
+			expected: Attributes{"http.method": "GET", "http.url": "/users"},
+		},
+		{
+			name:     "scalars",
+			input:    `{"count": 3, "ratio": 1.50, "big": 1e21, "cached": true, "missing": null}`,
+			expected: Attributes{"count": "3", "ratio": "1.50", "big": "1e21", "cached": "true", "missing": ""},
+		},
+		{
+			name:     "nested object",
+			input:    `{"db": {"system": "postgresql", "connection": {"port": 5432}}}`,
+			expected: Attributes{"db.system": "postgresql", "db.connection.port": "5432"},
+		},
+		{
+			name:     "array",
+			input:    `{"db.statement": {"params": ["alice", 42, {"limit": 10}, [true]]}}`,
+			expected: Attributes{"db.statement.params.0": "alice", "db.statement.params.1": "42", "db.statement.params.2.limit": "10", "db.statement.params.3.0": "true"},
+		},
+		{
+			name:     "empty object and array",
+			input:    `{"headers": {}, "tags": []}`,
+			expected: Attributes{"headers": "{}", "tags": "[]"},
+		},
+		{
+			name:     "direct key wins",
+			input:    `{"db": {"name": "flattened"}, "db.name": "direct"}`,
+			expected: Attributes{"db.name": "direct"},
+		},
+		{
+			name:     "first flattened key wins",
+			input:    `{"a": {"b.c": "first"}, "a.b": {"c": "second"}}`,
+			expected: Attributes{"a.b.c": "first"},
+		},
+		{
+			name:  "null",
+			input: `null`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Attributes
+			if err := json.Unmarshal([]byte(tt.input), &got); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestAttributesUnmarshalJSONError(t *testing.T) {
+	var got Attributes
+	if err := json.Unmarshal([]byte(`["not", "an", "object"]`), &got); err == nil {
+		t.Errorf("expected an error for an attribute array, got %v", got)
+	}
+}
+
+func TestParseTracesNestedAttributes(t *testing.T) {
+	data := []byte(`[{
+		"trace_id": "t1",
+		"attributes": {"deploy": {"canary": true}},
+		"resource_attributes": {"service.name": "api", "host": {"cpu": {"count": 8}}},
+		"spans": [{
+			"span_id": "a",
+			"name": "query",
+			"attributes": {"db.statement": {"params": ["alice", 42]}},
+			"events": [{"name": "retry", "attributes": {"attempt": 2}}],
+			"links": [{"trace_id": "t2", "span_id": "b", "attributes": {"reason": ["fan-in"]}}]
+		}]
+	}]`)
+
+	traces, err := ParseTraces(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tr := traces[0]
+	span := tr.Spans[0]
+	checks := []struct {
+		name     string
+		got      Attributes
+		expected Attributes
+	}{
+		{name: "trace", got: tr.Attributes, expected: Attributes{"deploy.canary": "true"}},
+		{name: "resource", got: tr.ResourceAttrs, expected: Attributes{"service.name": "api", "host.cpu.count": "8"}},
+		{name: "span", got: span.Attributes, expected: Attributes{"db.statement.params.0": "alice", "db.statement.params.1": "42"}},
+		{name: "event", got: span.Events[0].Attributes, expected: Attributes{"attempt": "2"}},
+		{name: "link", got: span.Links[0].Attributes, expected: Attributes{"reason.0": "fan-in"}},
+	}
+	for _, c := range checks {
+		if !reflect.DeepEqual(c.got, c.expected) {
+			t.Errorf("%s attributes: expected %v, got %v", c.name, c.expected, c.got)
+		}
+	}
+
+	// Flattened attributes are written back as plain strings
+	out, err := json.Marshal(span.Attributes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `{"db.statement.params.0":"alice","db.statement.params.1":"42"}`; string(out) != want {
+		t.Errorf("expected %s, got %s", want, out)
+	}
+}
@@ -0,0 +1,42 @@
+package trace
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Footer ends a report with a legend of its symbols and a line saying when
+// and by which version of otelcompare it was generated
+type Footer struct {
+	// Version is the otelcompare version named in the footer. Empty
+	// shows "dev".
+	Version string
+
+	// Now returns the generation time. Nil uses time.Now; tests set it to
+	// keep the output reproducible.
+	Now func() time.Time
+}
+
+// writeFooter writes the legend and generation line as a single compact
+// block. A nil footer writes nothing.
+func writeFooter(sb *strings.Builder, footer *Footer, legend string) {
+	if footer == nil {
+		return
+	}
+
+	now := time.Now
+	if footer.Now != nil {
+		now = footer.Now
+	}
+	version := footer.Version
+	if version == "" {
+		version = "dev"
+	}
+
+	// The blank line keeps the rule from turning the previous line into a
+	// heading
+	sb.WriteString("\n---\n\n")
+	sb.WriteString(fmt.Sprintf("<sub>%s<br>Generated by otelcompare %s on %s</sub>\n",
+		legend, version, now().UTC().Format("2006-01-02 15:04:05 UTC")))
+}
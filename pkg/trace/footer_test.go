@@ -0,0 +1,54 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFooter(t *testing.T) {
+	now := func() time.Time {
+		return time.Date(2024, 3, 5, 14, 30, 0, 0, time.FixedZone("CET", 3600))
+	}
+	traces := []Trace{{TraceID: "trace1", Spans: []Span{
+		{SpanID: "a", Name: "root", StartTime: now(), EndTime: now().Add(time.Second)},
+	}}}
+	traceSets := []TraceSet{{Name: "base.json", Traces: traces}, {Name: "head.json", Traces: traces}}
+
+	tests := []struct {
+		name     string
+		generate func(Options) string
+		footer   *Footer
+		want     string
+	}{
+		{
+			name:     "info",
+			generate: func(opts Options) string { return GenerateMarkdown(traces, opts) },
+			footer:   &Footer{Version: "v1.2.3", Now: now},
+			want:     "</details>\n\n\n---\n\n<sub>" + emojiIndicators.infoLegend() + "<br>Generated by otelcompare v1.2.3 on 2024-03-05 13:30:00 UTC</sub>\n",
+		},
+		{
+			name:     "compare",
+			generate: func(opts Options) string { return CompareMultipleTraces(traceSets, "trace_id", opts) },
+			footer:   &Footer{Version: "v1.2.3", Now: now},
+			want:     "\n---\n\n<sub>" + emojiIndicators.compareLegend() + "<br>Generated by otelcompare v1.2.3 on 2024-03-05 13:30:00 UTC</sub>\n",
+		},
+		{
+			name:     "default version",
+			generate: func(opts Options) string { return GenerateMarkdown(traces, opts) },
+			footer:   &Footer{Now: now},
+			want:     "Generated by otelcompare dev on 2024-03-05 13:30:00 UTC</sub>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.generate(Options{Footer: tt.footer}); !strings.HasSuffix(got, tt.want) {
+				t.Errorf("output does not end with %q:\n%s", tt.want, got)
+			}
+			if got := tt.generate(Options{}); strings.Contains(got, "Generated by otelcompare") {
+				t.Errorf("output without a footer contains one:\n%s", got)
+			}
+		})
+	}
+}
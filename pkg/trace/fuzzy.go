@@ -0,0 +1,134 @@
+package trace
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Similarity returns how similar two operation names are, from 0 (nothing
+// in common) to 1 (equal). Names are compared case-insensitively and
+// ignoring punctuation, so GetUserByID and get_user_by_id are identical.
+// The score is one minus the Levenshtein distance divided by the length of
+// the longer normalized name.
+func Similarity(a, b string) float64 {
+	a, b = normalizeOperationName(a), normalizeOperationName(b)
+	longest := len([]rune(a))
+	if n := len([]rune(b)); n > longest {
+		longest = n
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(a, b))/float64(longest)
+}
+
+// normalizeOperationName lowercases a name and drops everything that is not
+// a letter or a digit
+func normalizeOperationName(name string) string {
+	var sb strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return sb.String()
+}
+
+// levenshtein returns the edit distance between two strings
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// fuzzyMatch pairs names that only exist on one side with names that only
+// exist on the other, best matches first, as long as their similarity
+// reaches the threshold. It returns a map from base name to other name.
+func fuzzyMatch(baseNames, otherNames []string, threshold float64) map[string]string {
+	type candidate struct {
+		base, other string
+		score       float64
+	}
+
+	var candidates []candidate
+	for _, base := range baseNames {
+		for _, other := range otherNames {
+			if score := Similarity(base, other); score >= threshold {
+				candidates = append(candidates, candidate{base, other, score})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	matches := make(map[string]string)
+	used := make(map[string]bool)
+	for _, c := range candidates {
+		if _, ok := matches[c.base]; ok || used[c.other] {
+			continue
+		}
+		matches[c.base] = c.other
+		used[c.other] = true
+	}
+	return matches
+}
+
+// fuzzySpanAliases matches span names of a trace that are missing from the
+// first trace set against the unmatched span names of the first set. The
+// result holds, per trace set, a map from the first set's span name to the
+// approximately matching span name in that set. Span names are compared as
+// returned by spanName.
+func fuzzySpanAliases(traceMaps []map[string]*Trace, traceName string, threshold float64, spanName func(string) string) []map[string]string {
+	aliases := make([]map[string]string, len(traceMaps))
+	if threshold <= 0 || len(traceMaps) == 0 {
+		return aliases
+	}
+
+	baseNames := spanNameSet(traceMaps[0][traceName], spanName)
+	for i := 1; i < len(traceMaps); i++ {
+		otherNames := spanNameSet(traceMaps[i][traceName], spanName)
+
+		var onlyBase, onlyOther []string
+		for name := range baseNames {
+			if !otherNames[name] {
+				onlyBase = append(onlyBase, name)
+			}
+		}
+		for name := range otherNames {
+			if !baseNames[name] {
+				onlyOther = append(onlyOther, name)
+			}
+		}
+		sort.Strings(onlyBase)
+		sort.Strings(onlyOther)
+
+		aliases[i] = fuzzyMatch(onlyBase, onlyOther, threshold)
+	}
+	return aliases
+}
+
+func spanNameSet(t *Trace, spanName func(string) string) map[string]bool {
+	names := make(map[string]bool)
+	for _, span := range t.Spans {
+		names[spanName(span.Name)] = true
+	}
+	return names
+}
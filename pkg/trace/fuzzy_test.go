@@ -0,0 +1,79 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		expected float64
+	}{
+		{name: "case and punctuation", a: "GetUserByID", b: "get_user_by_id", expected: 1},
+		{name: "one edit", a: "fetchUser", b: "fetchUsers", expected: 0.9},
+		{name: "unrelated", a: "abc", b: "xyz", expected: 0},
+		{name: "both empty", a: "", b: "", expected: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Similarity(tt.a, tt.b)
+			if got != tt.expected {
+				t.Errorf("Similarity() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+		{"same", "same", 0},
+	}
+
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.expected {
+			t.Errorf("levenshtein(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.expected)
+		}
+	}
+}
+
+func TestFuzzyMatch(t *testing.T) {
+	got := fuzzyMatch(
+		[]string{"GetUserByID", "ListOrders"},
+		[]string{"getUserById", "deleteCart"},
+		0.8,
+	)
+	if len(got) != 1 || got["GetUserByID"] != "getUserById" {
+		t.Errorf("fuzzyMatch() = %v, want only GetUserByID -> getUserById", got)
+	}
+}
+
+func TestCompareMultipleTracesFuzzyMatch(t *testing.T) {
+	now := time.Now()
+	traceSets := []TraceSet{
+		{Name: "base.json", Traces: []Trace{{TraceID: "trace1", Spans: []Span{
+			{Name: "GetUserByID", StartTime: now, EndTime: now.Add(time.Second)},
+		}}}},
+		{Name: "head.json", Traces: []Trace{{TraceID: "trace1", Spans: []Span{
+			{Name: "getUserById", StartTime: now, EndTime: now.Add(2 * time.Second)},
+		}}}},
+	}
+
+	exact := CompareMultipleTraces(traceSets, "trace_id", Options{})
+	if !strings.Contains(exact, "| getUserById | ✗ |") {
+		t.Errorf("CompareMultipleTraces() without fuzzy matching should keep names apart:\n%s", exact)
+	}
+
+	fuzzy := CompareMultipleTraces(traceSets, "trace_id", Options{FuzzyMatch: 0.9})
+	if !strings.Contains(fuzzy, "| GetUserByID ≈ getUserById _(approximate)_ | 1.00s | 2.00s |") {
+		t.Errorf("CompareMultipleTraces() did not label the fuzzy match:\n%s", fuzzy)
+	}
+}
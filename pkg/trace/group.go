@@ -0,0 +1,136 @@
+package trace
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SpanGroup holds the summed duration of the spans sharing a group value
+type SpanGroup struct {
+	Name  string        `json:"name"`
+	Count int           `json:"count"`
+	Total time.Duration `json:"total_ns"`
+}
+
+// Average returns the average duration of the spans in the group
+func (g SpanGroup) Average() time.Duration {
+	if g.Count == 0 {
+		return 0
+	}
+	return g.Total / time.Duration(g.Count)
+}
+
+// GroupSpans rolls up the spans of all traces by the given attribute, see
+// getSpanIdentifier. Groups are sorted by name.
+func GroupSpans(traces []Trace, attribute string) []SpanGroup {
+	groups := make(map[string]*SpanGroup)
+	for i := range traces {
+		t := &traces[i]
+		for j := range t.Spans {
+			name := getSpanIdentifier(t, &t.Spans[j], attribute)
+			if groups[name] == nil {
+				groups[name] = &SpanGroup{Name: name}
+			}
+			groups[name].Count++
+			groups[name].Total += spanDuration(t.Spans[j])
+		}
+	}
+
+	result := make([]SpanGroup, 0, len(groups))
+	for _, g := range groups {
+		result = append(result, *g)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// getSpanIdentifier returns the group of a span. Like getTraceIdentifier,
+// the attribute may be a comma-separated list of attributes that are tried
+// in order; "name" is the span name and other attributes are looked up on
+// the span and then on its trace. Spans without any of them are
+// "unassigned".
+func getSpanIdentifier(t *Trace, span *Span, attribute string) string {
+	for _, name := range strings.Split(attribute, ",") {
+		name = strings.TrimSpace(name)
+		if name == "name" {
+			return span.Name
+		}
+		if value, ok := getSpanAttribute(t, span, name); ok && value != "" {
+			return value
+		}
+	}
+	return unassignedSection
+}
+
+// spanGroupMatch matches span groups across trace sets. The markdown
+// report and the structured comparison of span groups are both built from
+// it.
+type spanGroupMatch struct {
+	// groups index the span groups of each set by name, and names holds
+	// the names of the groups of every set, sorted
+	groups []map[string]SpanGroup
+	names  []string
+}
+
+// matchSpanGroups groups the spans of each set by attribute and matches the
+// groups across the sets
+func matchSpanGroups(traceSets []TraceSet, attribute string) spanGroupMatch {
+	m := spanGroupMatch{groups: make([]map[string]SpanGroup, len(traceSets))}
+	allNames := make(map[string]bool)
+	for i, set := range traceSets {
+		m.groups[i] = make(map[string]SpanGroup)
+		for _, g := range GroupSpans(set.Traces, attribute) {
+			m.groups[i][g.Name] = g
+			allNames[g.Name] = true
+		}
+	}
+
+	for name := range allNames {
+		m.names = append(m.names, name)
+	}
+	sort.Strings(m.names)
+	return m
+}
+
+// writeGroupComparison writes a summary table comparing the total and
+// average span duration of every span group across trace sets
+func writeGroupComparison(sb *strings.Builder, traceSets []TraceSet, attribute string, ind indicators) {
+	m := matchSpanGroups(traceSets, attribute)
+
+	sb.WriteString(fmt.Sprintf("**Comparison Summary by %s:**\n\n", escapeMarkdownCell(attribute)))
+	sb.WriteString(fmt.Sprintf("| %s |", escapeMarkdownCell(attribute)))
+	for _, set := range traceSets {
+		sb.WriteString(fmt.Sprintf(" %s |", escapeMarkdownCell(getFileNameWithoutExt(set.Name))))
+	}
+	diffHeaders := append(metricDiffHeaders(traceSets, "Total"), metricDiffHeaders(traceSets, "Average")...)
+	sb.WriteString(fmt.Sprintf(" %s |\n|------------", strings.Join(diffHeaders, " | ")))
+	for range traceSets {
+		sb.WriteString("|------------")
+	}
+	sb.WriteString(strings.Repeat("|------------", len(diffHeaders)))
+	sb.WriteString("|\n")
+
+	for _, name := range m.names {
+		sb.WriteString(fmt.Sprintf("| %s |", escapeMarkdownCell(name)))
+		var totals, averages []time.Duration
+		var present []bool
+		for i := range traceSets {
+			g, ok := m.groups[i][name]
+			present = append(present, ok)
+			if !ok {
+				sb.WriteString(fmt.Sprintf(" %s |", ind.missing))
+				totals = append(totals, 0)
+				averages = append(averages, 0)
+				continue
+			}
+			sb.WriteString(fmt.Sprintf(" %s (avg %s, n=%d) |", formatDuration(g.Total), formatDuration(g.Average()), g.Count))
+			totals = append(totals, g.Total)
+			averages = append(averages, g.Average())
+		}
+		diffs := append(durationDiffCells(totals, present, ind), durationDiffCells(averages, present, ind)...)
+		sb.WriteString(fmt.Sprintf(" %s |\n", strings.Join(diffs, " | ")))
+	}
+	sb.WriteString("\n")
+}
@@ -0,0 +1,114 @@
+package trace
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGroupSpans(t *testing.T) {
+	now := time.Now()
+	span := func(name string, d time.Duration, attrs map[string]string) Span {
+		return Span{Name: name, StartTime: now, EndTime: now.Add(d), Attributes: attrs}
+	}
+	traces := []Trace{
+		{TraceID: "t1", ResourceAttrs: map[string]string{"service.name": "api"}, Spans: []Span{
+			span("GET /users", 300*time.Millisecond, nil),
+			span("select", 100*time.Millisecond, map[string]string{"service.name": "db"}),
+		}},
+		{TraceID: "t2", ResourceAttrs: map[string]string{"service.name": "api"}, Spans: []Span{
+			span("GET /users", 100*time.Millisecond, map[string]string{"peer.service": "users"}),
+		}},
+		{TraceID: "t3", Spans: []Span{
+			span("cron", time.Second, nil),
+		}},
+	}
+
+	tests := []struct {
+		name      string
+		attribute string
+		expected  []SpanGroup
+	}{
+		{
+			name:      "by service",
+			attribute: "service.name",
+			expected: []SpanGroup{
+				{Name: "api", Count: 2, Total: 400 * time.Millisecond},
+				{Name: "db", Count: 1, Total: 100 * time.Millisecond},
+				{Name: "unassigned", Count: 1, Total: time.Second},
+			},
+		},
+		{
+			name:      "fallback chain",
+			attribute: "peer.service, service.name",
+			expected: []SpanGroup{
+				{Name: "api", Count: 1, Total: 300 * time.Millisecond},
+				{Name: "db", Count: 1, Total: 100 * time.Millisecond},
+				{Name: "unassigned", Count: 1, Total: time.Second},
+				{Name: "users", Count: 1, Total: 100 * time.Millisecond},
+			},
+		},
+		{
+			name:      "by span name",
+			attribute: "name",
+			expected: []SpanGroup{
+				{Name: "GET /users", Count: 2, Total: 400 * time.Millisecond},
+				{Name: "cron", Count: 1, Total: time.Second},
+				{Name: "select", Count: 1, Total: 100 * time.Millisecond},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GroupSpans(traces, tt.attribute)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("GroupSpans() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+
+	if avg := (SpanGroup{Count: 2, Total: 400 * time.Millisecond}).Average(); avg != 200*time.Millisecond {
+		t.Errorf("Average() = %v, want 200ms", avg)
+	}
+	if avg := (SpanGroup{}).Average(); avg != 0 {
+		t.Errorf("Average() of an empty group = %v, want 0", avg)
+	}
+}
+
+func TestCompareMultipleTracesGroupBy(t *testing.T) {
+	now := time.Now()
+	newSet := func(name string, d time.Duration) TraceSet {
+		return TraceSet{Name: name, Traces: []Trace{{
+			TraceID:       "t1",
+			ResourceAttrs: map[string]string{"service.name": "api"},
+			Spans:         []Span{{SpanID: "a", Name: "GET /users", StartTime: now, EndTime: now.Add(d)}},
+		}}}
+	}
+
+	got := CompareMultipleTraces([]TraceSet{newSet("base.json", time.Second), newSet("head.json", 1500*time.Millisecond)}, "name", Options{GroupBy: "service.name"})
+	for _, want := range []string{
+		"**Comparison Summary by service.name:**\n",
+		"| api | 1.00s (avg 1.00s, n=1) | 1.50s (avg 1.50s, n=1) | 🔴 +500.00ms (+50.0%) | 🔴 +500.00ms (+50.0%) |\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("CompareMultipleTraces() does not contain %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "| Trace Name |") {
+		t.Errorf("CompareMultipleTraces() kept the per-trace summary when grouping:\n%s", got)
+	}
+
+	// Three sets get one total and one average diff per file compared with
+	// the first
+	got = CompareMultipleTraces([]TraceSet{newSet("base.json", time.Second), newSet("head.json", 1500*time.Millisecond), newSet("next.json", 500*time.Millisecond)}, "name", Options{GroupBy: "service.name"})
+	for _, want := range []string{
+		"| service.name | base | head | next | Total Δ head | Total Δ next | Average Δ head | Average Δ next |\n",
+		"| api | 1.00s (avg 1.00s, n=1) | 1.50s (avg 1.50s, n=1) | 500.00ms (avg 500.00ms, n=1) | 🔴 +500.00ms (+50.0%) | 🟢 -500.00ms (-50.0%) | 🔴 +500.00ms (+50.0%) | 🟢 -500.00ms (-50.0%) |\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("CompareMultipleTraces() does not contain %q:\n%s", want, got)
+		}
+	}
+}
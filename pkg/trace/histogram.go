@@ -0,0 +1,93 @@
+package trace
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// histogramWidth is the length of the bar of the fullest bucket
+const histogramWidth = 30
+
+// Bucket counts the durations between Low and High. Buckets include their
+// lower bound and exclude their upper bound, except for the last one.
+type Bucket struct {
+	Low   time.Duration `json:"low_ns"`
+	High  time.Duration `json:"high_ns"`
+	Count int           `json:"count"`
+}
+
+// Histogram buckets durations into the given number of equal-width buckets
+// spanning the shortest to the longest duration. When all durations are the
+// same there is a single bucket, and there are none without durations.
+func Histogram(durations []time.Duration, buckets int) []Bucket {
+	if len(durations) == 0 {
+		return nil
+	}
+	buckets = max(buckets, 1)
+
+	low, high := durations[0], durations[0]
+	for _, d := range durations {
+		low, high = min(low, d), max(high, d)
+	}
+	if low == high {
+		return []Bucket{{Low: low, High: high, Count: len(durations)}}
+	}
+
+	// Never make buckets narrower than a nanosecond
+	width := max((high-low+time.Duration(buckets)-1)/time.Duration(buckets), 1)
+	buckets = int((high - low + width - 1) / width)
+	result := make([]Bucket, buckets)
+	for i := range result {
+		result[i].Low = low + time.Duration(i)*width
+		result[i].High = min(result[i].Low+width, high)
+	}
+	for _, d := range durations {
+		i := min(int((d-low)/width), buckets-1)
+		result[i].Count++
+	}
+	return result
+}
+
+// GenerateHistograms renders a histogram of the span durations of every
+// operation name as ASCII bars, sorted by operation
+func GenerateHistograms(traces []Trace, buckets int) string {
+	var sb strings.Builder
+	writeHistograms(&sb, traces, buckets)
+	return sb.String()
+}
+
+// writeHistograms writes a histogram of the span durations of every
+// operation name
+func writeHistograms(sb *strings.Builder, traces []Trace, buckets int) {
+	durations := OperationDurations(traces)
+	operations := make([]string, 0, len(durations))
+	for name := range durations {
+		operations = append(operations, name)
+	}
+	sort.Strings(operations)
+
+	sb.WriteString("**Duration Histograms:**\n")
+	for _, name := range operations {
+		sb.WriteString(fmt.Sprintf("\n`%s` (n=%d)\n\n```\n", name, len(durations[name])))
+		histogram := Histogram(durations[name], buckets)
+
+		// Pad the bounds so the bars line up
+		var lows, highs []string
+		var lowWidth, highWidth, maxCount int
+		for _, b := range histogram {
+			lows = append(lows, formatDuration(b.Low))
+			highs = append(highs, formatDuration(b.High))
+			lowWidth = max(lowWidth, len([]rune(lows[len(lows)-1])))
+			highWidth = max(highWidth, len([]rune(highs[len(highs)-1])))
+			maxCount = max(maxCount, b.Count)
+		}
+		for i, b := range histogram {
+			bar := strings.Repeat("#", (b.Count*histogramWidth+maxCount-1)/maxCount)
+			sb.WriteString(fmt.Sprintf("%*s - %*s | %s %d\n",
+				lowWidth, lows[i], highWidth, highs[i], bar, b.Count))
+		}
+		sb.WriteString("```\n")
+	}
+}
@@ -0,0 +1,104 @@
+package trace
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistogram(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name      string
+		durations []time.Duration
+		buckets   int
+		expected  []Bucket
+	}{
+		{
+			name:    "no durations",
+			buckets: 5,
+		},
+		{
+			name:      "single data point",
+			durations: []time.Duration{40 * ms},
+			buckets:   5,
+			expected:  []Bucket{{Low: 40 * ms, High: 40 * ms, Count: 1}},
+		},
+		{
+			name:      "identical durations",
+			durations: []time.Duration{40 * ms, 40 * ms, 40 * ms},
+			buckets:   5,
+			expected:  []Bucket{{Low: 40 * ms, High: 40 * ms, Count: 3}},
+		},
+		{
+			name:      "longest duration goes in the last bucket",
+			durations: []time.Duration{0, 10 * ms, 19 * ms, 20 * ms, 40 * ms},
+			buckets:   4,
+			expected: []Bucket{
+				{Low: 0, High: 10 * ms, Count: 1},
+				{Low: 10 * ms, High: 20 * ms, Count: 2},
+				{Low: 20 * ms, High: 30 * ms, Count: 1},
+				{Low: 30 * ms, High: 40 * ms, Count: 1},
+			},
+		},
+		{
+			name:      "bimodal",
+			durations: []time.Duration{10 * ms, 11 * ms, 12 * ms, 100 * ms, 110 * ms},
+			buckets:   2,
+			expected: []Bucket{
+				{Low: 10 * ms, High: 60 * ms, Count: 3},
+				{Low: 60 * ms, High: 110 * ms, Count: 2},
+			},
+		},
+		{
+			name:      "non-positive bucket count uses one bucket",
+			durations: []time.Duration{10 * ms, 20 * ms},
+			buckets:   0,
+			expected:  []Bucket{{Low: 10 * ms, High: 20 * ms, Count: 2}},
+		},
+		{
+			name:      "more buckets than nanoseconds",
+			durations: []time.Duration{1, 3},
+			buckets:   10,
+			expected: []Bucket{
+				{Low: 1, High: 2, Count: 1},
+				{Low: 2, High: 3, Count: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Histogram(tt.durations, tt.buckets)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Histogram() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGenerateHistograms(t *testing.T) {
+	now := time.Now()
+	span := func(name string, d time.Duration) Span {
+		return Span{Name: name, StartTime: now, EndTime: now.Add(d)}
+	}
+	traces := []Trace{{TraceID: "t1", Spans: []Span{
+		span("select", 10*time.Millisecond),
+		span("select", 10*time.Millisecond),
+		span("select", 30*time.Millisecond),
+		span("GET /users", time.Second),
+	}}}
+
+	got := GenerateHistograms(traces, 2)
+	want := "**Duration Histograms:**\n\n" +
+		"`GET /users` (n=1)\n\n```\n1.00s - 1.00s | ############################## 1\n```\n\n" +
+		"`select` (n=3)\n\n```\n10.00ms - 20.00ms | ############################## 2\n20.00ms - 30.00ms | ############### 1\n```\n"
+	if got != want {
+		t.Errorf("GenerateHistograms() = %q, want %q", got, want)
+	}
+
+	if got := GenerateMarkdown(traces, Options{}); strings.Contains(got, "Duration Histograms") {
+		t.Errorf("GenerateMarkdown() added histograms without Options.Histogram:\n%s", got)
+	}
+}
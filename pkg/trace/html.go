@@ -0,0 +1,121 @@
+package trace
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+)
+
+// htmlStyle is the stylesheet embedded in the generated HTML page
+const htmlStyle = `body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #ddd; padding: 4px 8px; text-align: left; vertical-align: top; }
+summary { cursor: pointer; font-weight: bold; margin: 1em 0; }
+.timeline { position: relative; width: 100%; min-width: 300px; }
+.bar { height: 14px; background: #4c8bf5; min-width: 1px; }
+.bar.error { background: #e5534b; }
+.attrs { font-size: 0.85em; color: #555; }
+`
+
+// GenerateHTML generates a self-contained HTML page showing every trace as a
+// collapsible section with a gantt-style bar per span
+func GenerateHTML(traces []Trace) string {
+	var sb strings.Builder
+
+	sb.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	sb.WriteString("<title>OpenTelemetry Traces</title>\n")
+	sb.WriteString(fmt.Sprintf("<style>\n%s</style>\n", htmlStyle))
+	sb.WriteString("</head>\n<body>\n<h1>OpenTelemetry Traces</h1>\n")
+
+	for _, t := range traces {
+		duration := getTraceDuration(t)
+		sb.WriteString(fmt.Sprintf("<details open>\n<summary>Trace %s &mdash; %s, %d spans</summary>\n",
+			html.EscapeString(t.TraceID), html.EscapeString(formatDuration(duration)), len(t.Spans)))
+
+		if len(t.Attributes) > 0 {
+			sb.WriteString(fmt.Sprintf("<p class=\"attrs\">%s</p>\n", htmlAttributes(t.Attributes)))
+		}
+
+		sb.WriteString("<table>\n<tr><th>Span</th><th>Duration</th><th>Timeline</th></tr>\n")
+		start := traceStart(t)
+		depths := spanDepths(t)
+
+		// Show spans in start order, indented by their depth in the tree
+		spans := append([]Span(nil), t.Spans...)
+		sort.SliceStable(spans, func(i, j int) bool {
+			return spans[i].StartTime.Before(spans[j].StartTime)
+		})
+		for _, span := range spans {
+			d := spanDuration(span)
+			offset, width := 0.0, 100.0
+			if duration > 0 {
+				offset = float64(span.StartTime.Sub(start)) / float64(duration) * 100
+				width = float64(d) / float64(duration) * 100
+			}
+
+			class := "bar"
+			if isError(span) {
+				class += " error"
+			}
+
+			sb.WriteString(fmt.Sprintf("<tr><td style=\"padding-left: %.1fem\">%s", 0.5+1.5*float64(depths[span.SpanID]), html.EscapeString(span.Name)))
+			if len(span.Attributes) > 0 {
+				sb.WriteString(fmt.Sprintf("<div class=\"attrs\">%s</div>", htmlAttributes(span.Attributes)))
+			}
+			sb.WriteString(fmt.Sprintf("</td><td>%s</td>", html.EscapeString(formatDuration(d))))
+			sb.WriteString(fmt.Sprintf("<td><div class=\"timeline\"><div class=\"%s\" style=\"margin-left: %.2f%%; width: %.2f%%\"></div></div></td></tr>\n",
+				class, offset, width))
+		}
+		sb.WriteString("</table>\n</details>\n")
+	}
+
+	sb.WriteString("</body>\n</html>\n")
+	return sb.String()
+}
+
+// htmlAttributes formats attributes as escaped, sorted key: value pairs
+func htmlAttributes(attrs map[string]string) string {
+	var pairs []string
+	for k, v := range attrs {
+		pairs = append(pairs, fmt.Sprintf("%s: %s", html.EscapeString(k), html.EscapeString(v)))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "<br>")
+}
+
+// traceStart returns the start time of the earliest span in a trace
+func traceStart(t Trace) time.Time {
+	var start time.Time
+	for i, span := range t.Spans {
+		if i == 0 || span.StartTime.Before(start) {
+			start = span.StartTime
+		}
+	}
+	return start
+}
+
+// spanDepths returns the depth of every span in the span tree, keyed by span
+// ID. Spans whose parent is missing are treated as roots, and parent cycles
+// are cut off so they cannot loop forever.
+func spanDepths(t Trace) map[string]int {
+	parents := make(map[string]string, len(t.Spans))
+	for _, span := range t.Spans {
+		parents[span.SpanID] = span.ParentSpanID
+	}
+
+	depths := make(map[string]int, len(t.Spans))
+	for _, span := range t.Spans {
+		depth := 0
+		for parent := span.ParentSpanID; parent != "" && depth < len(t.Spans); depth++ {
+			next, ok := parents[parent]
+			if !ok {
+				break
+			}
+			parent = next
+		}
+		depths[span.SpanID] = depth
+	}
+	return depths
+}
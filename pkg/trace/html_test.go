@@ -0,0 +1,63 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateHTML(t *testing.T) {
+	now := time.Now()
+	traces := []Trace{
+		{
+			TraceID:    "trace1",
+			Attributes: map[string]string{"env": "<prod>"},
+			Spans: []Span{
+				{SpanID: "a", Name: "GET /users?id=1&name=<x>", StartTime: now, EndTime: now.Add(time.Second)},
+				{SpanID: "b", ParentSpanID: "a", Name: "select", StartTime: now.Add(500 * time.Millisecond), EndTime: now.Add(750 * time.Millisecond),
+					Attributes: map[string]string{"db.statement": `SELECT "name"`}},
+			},
+		},
+	}
+
+	got := GenerateHTML(traces)
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "document", want: "<!DOCTYPE html>"},
+		{name: "collapsible trace", want: "<summary>Trace trace1"},
+		{name: "escaped span name", want: "GET /users?id=1&amp;name=&lt;x&gt;"},
+		{name: "escaped trace attribute", want: "env: &lt;prod&gt;"},
+		{name: "escaped span attribute", want: "db.statement: SELECT &#34;name&#34;"},
+		{name: "root bar", want: "margin-left: 0.00%; width: 100.00%"},
+		{name: "child bar", want: "margin-left: 50.00%; width: 25.00%"},
+		{name: "child indentation", want: "padding-left: 2.0em\">select"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("GenerateHTML() output does not contain %q:\n%s", tt.want, got)
+			}
+		})
+	}
+
+	if strings.Contains(got, "<x>") || strings.Contains(got, "<prod>") {
+		t.Errorf("GenerateHTML() did not escape HTML-special characters:\n%s", got)
+	}
+}
+
+func TestSpanDepthsCycle(t *testing.T) {
+	trace := Trace{Spans: []Span{
+		{SpanID: "a", ParentSpanID: "b"},
+		{SpanID: "b", ParentSpanID: "a"},
+	}}
+
+	// Only needs to terminate; the depth of a cycle is capped
+	depths := spanDepths(trace)
+	if depths["a"] > len(trace.Spans) {
+		t.Errorf("spanDepths() = %v, want depths capped at %d", depths, len(trace.Spans))
+	}
+}
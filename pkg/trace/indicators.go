@@ -0,0 +1,95 @@
+package trace
+
+import "fmt"
+
+// indicators are the markers reports put next to values to show how they
+// changed. Every report picks them through Options.indicators, so emoji and
+// plain output differ in this one place.
+type indicators struct {
+	slower            string
+	faster            string
+	present           string
+	missing           string
+	added             string
+	removed           string
+	changed           string
+	attributesChanged string
+	links             string
+	events            string
+	scope             string
+	failing           string
+	passing           string
+	warning           string
+	info              string
+}
+
+// emojiIndicators are the default markers
+var emojiIndicators = indicators{
+	slower:            "🔴",
+	faster:            "🟢",
+	present:           "✓",
+	missing:           "✗",
+	added:             "➕",
+	removed:           "➖",
+	changed:           "✏️",
+	attributesChanged: "🔄",
+	links:             "🔗",
+	events:            "📌",
+	scope:             "📦",
+	failing:           "❌",
+	passing:           "✅",
+	warning:           "⚠️",
+	info:              "ℹ️",
+}
+
+// plainIndicators are ASCII markers for terminals and screen readers that do
+// not handle emoji
+var plainIndicators = indicators{
+	slower:            "[SLOW]",
+	faster:            "[FAST]",
+	present:           "[Y]",
+	missing:           "[N]",
+	added:             "[+]",
+	removed:           "[-]",
+	changed:           "[~]",
+	attributesChanged: "[ATTR]",
+	links:             "[LINK]",
+	events:            "[EVENT]",
+	scope:             "[SCOPE]",
+	failing:           "[FAIL]",
+	passing:           "[OK]",
+	warning:           "[WARN]",
+	info:              "[INFO]",
+}
+
+// indicators returns the plain markers with NoEmoji and the emoji otherwise
+func (o Options) indicators() indicators {
+	if o.NoEmoji {
+		return plainIndicators
+	}
+	return emojiIndicators
+}
+
+// infoLegend explains the symbols of an info report
+func (ind indicators) infoLegend() string {
+	return fmt.Sprintf("%s warning · Self Time excludes time spent in child spans", ind.warning)
+}
+
+// compareLegend explains the symbols of a comparison report
+func (ind indicators) compareLegend() string {
+	return fmt.Sprintf("%s slower · %s faster · %s present · %s missing · %s added · %s removed · %s changed · %s attributes changed · %s links changed · %s events changed · %s scope changed · %s started failing · %s stopped failing · %s warning",
+		ind.slower, ind.faster, ind.present, ind.missing, ind.added, ind.removed, ind.changed,
+		ind.attributesChanged, ind.links, ind.events, ind.scope, ind.failing, ind.passing, ind.warning)
+}
+
+// severity returns the marker of a finding severity
+func (ind indicators) severity(s Severity) string {
+	switch s {
+	case SeverityError:
+		return ind.failing
+	case SeverityWarning:
+		return ind.warning
+	default:
+		return ind.info
+	}
+}
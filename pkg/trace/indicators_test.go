@@ -0,0 +1,92 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNoEmoji(t *testing.T) {
+	now := time.Now()
+	base := []Trace{
+		{TraceID: "t1", Spans: []Span{
+			{SpanID: "a", Name: "root", StartTime: now, EndTime: now.Add(time.Second), Attributes: map[string]string{"k": "v"}},
+			{SpanID: "b", ParentSpanID: "a", Name: "db", StartTime: now, EndTime: now.Add(500 * time.Millisecond)},
+		}},
+		{TraceID: "t2", Spans: []Span{
+			{SpanID: "c", Name: "root", StartTime: now, EndTime: now.Add(time.Second)},
+		}},
+	}
+	head := []Trace{
+		{TraceID: "t1", Spans: []Span{
+			{SpanID: "a", Name: "root", StartTime: now, EndTime: now.Add(2 * time.Second), Attributes: map[string]string{"k": "w"}},
+		}},
+		{TraceID: "t2", Spans: []Span{
+			{SpanID: "c", Name: "root", StartTime: now, EndTime: now.Add(500 * time.Millisecond)},
+		}},
+	}
+	orphan := []Trace{{TraceID: "t3", Spans: []Span{
+		{SpanID: "d", ParentSpanID: "x", Name: "orphan", StartTime: now, EndTime: now.Add(time.Second)},
+	}}}
+	zero := []Trace{{TraceID: "t4", Spans: []Span{
+		{SpanID: "e", Name: "noop", StartTime: now, EndTime: now},
+		{SpanID: "f", Name: "skewed", StartTime: now, EndTime: now.Add(-time.Second)},
+	}}}
+	footer := &Footer{Now: func() time.Time { return now }}
+
+	tests := []struct {
+		name     string
+		generate func(opts Options) string
+		plain    []string
+		emoji    []string
+	}{
+		{
+			name: "compare",
+			generate: func(opts Options) string {
+				return CompareMultipleTraces([]TraceSet{{Name: "base.json", Traces: base}, {Name: "head.json", Traces: head}}, "trace_id", opts)
+			},
+			plain: []string{"[SLOW] +1.00s", "[FAST] -500.00ms", "| [Y] |", "| db | 500.00ms | [N] |", "[ATTR] attributes changed", "[~] **k**: v → w", "[OK] No regressions", "[EVENT] events changed", "[SCOPE] scope changed"},
+			emoji: []string{"🔴 +1.00s", "🟢 -500.00ms", "| ✓ |", "| db | 500.00ms | ✗ |", "🔄 attributes changed", "✏️ **k**: v → w", "✅ No regressions", "📌 events changed", "📦 scope changed"},
+		},
+		{
+			name:     "info",
+			generate: func(opts Options) string { return GenerateMarkdown(orphan, opts) },
+			plain:    []string{"**[WARN] Warnings:**", "<sub>[WARN] warning"},
+			emoji:    []string{"**⚠️ Warnings:**", "<sub>⚠️ warning"},
+		},
+		{
+			name: "validate",
+			generate: func(opts Options) string {
+				return GenerateFindingsMarkdown(RunChecks(zero, CheckZeroDuration, CheckEndsBeforeStart), 1, opts)
+			},
+			plain: []string{"| [FAIL] error |", "| [WARN] warning |"},
+			emoji: []string{"| ❌ error |", "| ⚠️ warning |"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{Verdict: &Verdict{Threshold: 200}, Footer: footer}
+			got := tt.generate(opts)
+			for _, want := range tt.emoji {
+				if !strings.Contains(got, want) {
+					t.Errorf("expected %q in:\n%s", want, got)
+				}
+			}
+
+			opts.NoEmoji = true
+			got = tt.generate(opts)
+			for _, want := range tt.plain {
+				if !strings.Contains(got, want) {
+					t.Errorf("expected %q with NoEmoji in:\n%s", want, got)
+				}
+			}
+			for _, r := range got {
+				if r >= 0x2600 {
+					t.Errorf("expected no emoji with NoEmoji, found %q in:\n%s", r, got)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package trace
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+)
+
+// junitTestSuites is the root element of a JUnit XML report
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite groups the test cases of one compared file
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase is a single matching trace
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+// junitFailure describes a trace that regressed beyond the threshold
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// GenerateJUnit renders the matching traces of a comparison as a JUnit XML
+// report. Every file compared against the first one becomes a test suite
+// and every matching trace a test case, which fails when the trace got more
+// than threshold percent slower.
+func GenerateJUnit(traceSets []TraceSet, attribute string, threshold float64) (string, error) {
+	report := junitTestSuites{Name: "otelcompare"}
+	suites := make(map[string]int)
+	for _, change := range DurationChanges(traceSets, attribute) {
+		index, ok := suites[change.File]
+		if !ok {
+			index = len(report.Suites)
+			suites[change.File] = index
+			report.Suites = append(report.Suites, junitTestSuite{Name: change.File})
+		}
+		suite := &report.Suites[index]
+
+		testCase := junitTestCase{
+			Name:      change.Name,
+			ClassName: "otelcompare." + change.File,
+			Time:      fmt.Sprintf("%.6f", change.Current.Seconds()),
+		}
+		if change.Percent() > threshold {
+			message := fmt.Sprintf("duration %s → %s (%s), more than %.1f%% slower",
+				formatDuration(change.Baseline),
+				formatDuration(change.Current),
+				formatPercentChange(change.Percent()),
+				threshold)
+			testCase.Failure = &junitFailure{
+				Message: message,
+				Type:    "regression",
+				Text:    fmt.Sprintf("%s in %s: %s", change.Name, change.File, message),
+			}
+			suite.Failures++
+			report.Failures++
+		}
+		suite.Tests++
+		report.Tests++
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	out, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("error marshaling JUnit report: %w", err)
+	}
+	return xml.Header + string(out) + "\n", nil
+}
+
+// formatPercentChange returns a signed percentage, or +∞% for a trace that
+// took no time in the baseline
+func formatPercentChange(percent float64) string {
+	if math.IsInf(percent, 1) {
+		return "+∞%"
+	}
+	return fmt.Sprintf("%+.1f%%", percent)
+}
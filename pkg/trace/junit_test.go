@@ -0,0 +1,61 @@
+package trace
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateJUnit(t *testing.T) {
+	now := time.Now()
+	makeTrace := func(id string, duration time.Duration) Trace {
+		return Trace{TraceID: id, Spans: []Span{{SpanID: "root", Name: "root", StartTime: now, EndTime: now.Add(duration)}}}
+	}
+	traceSets := []TraceSet{
+		{Name: "baseline.json", Traces: []Trace{makeTrace("checkout", 100*time.Millisecond), makeTrace("login", 100*time.Millisecond)}},
+		{Name: "head.json", Traces: []Trace{makeTrace("checkout", 150*time.Millisecond), makeTrace("login", 105*time.Millisecond)}},
+	}
+
+	got, err := GenerateJUnit(traceSets, "trace_id", 10)
+	if err != nil {
+		t.Fatalf("GenerateJUnit() error = %v", err)
+	}
+
+	var report junitTestSuites
+	if err := xml.Unmarshal([]byte(got), &report); err != nil {
+		t.Fatalf("GenerateJUnit() output is not valid XML: %v\n%s", err, got)
+	}
+	if report.Tests != 2 || report.Failures != 1 || len(report.Suites) != 1 {
+		t.Fatalf("GenerateJUnit() = %d tests, %d failures, %d suites, want 2, 1, 1:\n%s",
+			report.Tests, report.Failures, len(report.Suites), got)
+	}
+
+	cases := report.Suites[0].TestCases
+	if cases[0].Name != "checkout" || cases[0].Failure == nil {
+		t.Fatalf("GenerateJUnit() first test case = %+v, want a failing checkout", cases[0])
+	}
+	if want := "100.00ms → 150.00ms (+50.0%)"; !strings.Contains(cases[0].Failure.Message, want) {
+		t.Errorf("GenerateJUnit() failure message = %q, want it to contain %q", cases[0].Failure.Message, want)
+	}
+	if cases[1].Name != "login" || cases[1].Failure != nil {
+		t.Errorf("GenerateJUnit() second test case = %+v, want a passing login", cases[1])
+	}
+}
+
+func TestFormatPercentChange(t *testing.T) {
+	tests := []struct {
+		percent  float64
+		expected string
+	}{
+		{percent: 12.345, expected: "+12.3%"},
+		{percent: -5, expected: "-5.0%"},
+		{percent: 0, expected: "+0.0%"},
+	}
+
+	for _, tt := range tests {
+		if got := formatPercentChange(tt.percent); got != tt.expected {
+			t.Errorf("formatPercentChange(%v) = %q, want %q", tt.percent, got, tt.expected)
+		}
+	}
+}
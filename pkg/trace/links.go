@@ -0,0 +1,42 @@
+package trace
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SpanLink points from a span to a span of the same or another trace, as
+// used to connect producers and consumers of asynchronous messages
+type SpanLink struct {
+	TraceID    string     `json:"trace_id"`
+	SpanID     string     `json:"span_id"`
+	Attributes Attributes `json:"attributes"`
+}
+
+// writeLinks lists the links of a span below it in the span hierarchy
+func writeLinks(sb *strings.Builder, links []SpanLink, ind indicators) {
+	if len(links) == 0 {
+		return
+	}
+	sb.WriteString("  **Links:**\n")
+	for _, link := range links {
+		sb.WriteString(fmt.Sprintf("  - %s trace `%s` span `%s`\n", ind.links, escapeMarkdownCell(link.TraceID), escapeMarkdownCell(link.SpanID)))
+		for _, k := range sortedKeys(link.Attributes) {
+			sb.WriteString(fmt.Sprintf("    - %s: %s\n", escapeMarkdownCell(k), escapeMarkdownCell(link.Attributes[k])))
+		}
+	}
+}
+
+// linkChange describes links added to or removed from a span, or returns
+// "" when the number of links is the same. Links are counted rather than
+// matched, since the linked trace and span IDs differ between runs.
+func linkChange(base, span Span, ind indicators) string {
+	delta := len(span.Links) - len(base.Links)
+	switch {
+	case delta > 0:
+		return fmt.Sprintf("%s links: %d → %d (+%d)", ind.links, len(base.Links), len(span.Links), delta)
+	case delta < 0:
+		return fmt.Sprintf("%s links: %d → %d (−%d)", ind.links, len(base.Links), len(span.Links), -delta)
+	}
+	return ""
+}
@@ -0,0 +1,67 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTracesLinks(t *testing.T) {
+	input := []byte(`[{"trace_id": "consumer", "spans": [
+		{"span_id": "c1", "name": "process", "links": [
+			{"trace_id": "producer", "span_id": "p1", "attributes": {"messaging.operation": "publish"}}
+		]}
+	]}]`)
+
+	traces, err := ParseTraces(input)
+	if err != nil {
+		t.Fatalf("ParseTraces() error = %v", err)
+	}
+	links := traces[0].Spans[0].Links
+	if len(links) != 1 || links[0].TraceID != "producer" || links[0].SpanID != "p1" || links[0].Attributes["messaging.operation"] != "publish" {
+		t.Errorf("links = %+v, want the producer link", links)
+	}
+
+	got := GenerateMarkdown(traces, Options{})
+	want := "  **Links:**\n  - 🔗 trace `producer` span `p1`\n    - messaging.operation: publish\n"
+	if !strings.Contains(got, want) {
+		t.Errorf("GenerateMarkdown() output does not contain %q:\n%s", want, got)
+	}
+}
+
+func TestLinkChange(t *testing.T) {
+	link := SpanLink{TraceID: "t", SpanID: "s"}
+	tests := []struct {
+		name     string
+		base     []SpanLink
+		other    []SpanLink
+		expected string
+	}{
+		{name: "unchanged", base: []SpanLink{link}, other: []SpanLink{{TraceID: "u", SpanID: "v"}}, expected: ""},
+		{name: "added", base: nil, other: []SpanLink{link, link}, expected: "🔗 links: 0 → 2 (+2)"},
+		{name: "removed", base: []SpanLink{link}, other: nil, expected: "🔗 links: 1 → 0 (−1)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := linkChange(Span{Links: tt.base}, Span{Links: tt.other}, emojiIndicators); got != tt.expected {
+				t.Errorf("linkChange() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCompareMultipleTracesLinks(t *testing.T) {
+	now := time.Now()
+	newSet := func(name string, links ...SpanLink) TraceSet {
+		return TraceSet{Name: name, Traces: []Trace{{TraceID: "trace1", Spans: []Span{
+			{SpanID: "a", Name: "consume", StartTime: now, EndTime: now.Add(time.Second), Links: links},
+		}}}}
+	}
+	traceSets := []TraceSet{newSet("base.json"), newSet("head.json", SpanLink{TraceID: "producer", SpanID: "p1"})}
+
+	got := CompareMultipleTraces(traceSets, "trace_id", Options{})
+	if !strings.Contains(got, "| consume | 1.00s | 1.00s | - | 🔗 links: 0 → 1 (+1) |") {
+		t.Errorf("CompareMultipleTraces() did not note the added link:\n%s", got)
+	}
+}
@@ -0,0 +1,169 @@
+package trace
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// traceMatch matches traces and their spans across trace sets. The markdown
+// report and the structured comparison are both built from it, so they list
+// the same traces and spans for the same options.
+type traceMatch struct {
+	// traceMaps index the traces of each set by identifier
+	traceMaps []map[string]*Trace
+
+	// allNames holds the names of the traces of every set, and names those
+	// kept by Options.Top, both sorted
+	allNames []string
+	names    []string
+
+	// traces are the kept traces found in every set, without the ones left
+	// out by Options.ChangesOnly, which are counted in unchanged
+	traces    []matchedTrace
+	unchanged int
+}
+
+// matchedTrace is a trace found in every set, with its spans matched
+type matchedTrace struct {
+	name string
+
+	// spanIndexes index the spans of the trace in each set, named as the
+	// options compare them. aliases map span names of the first set to the
+	// approximately matching name in each set, see fuzzySpanAliases.
+	spanIndexes []map[spanRef]*Span
+	aliases     []map[string]string
+	aliased     []map[string]bool
+
+	// spans are the spans of every set, sorted, without the ones matched to
+	// a span of the first set. sections holds their Options.SectionBy value.
+	spans    []spanRef
+	sections map[spanRef]string
+}
+
+// matchTraces matches the traces of the trace sets by identifier attribute,
+// applying Options.Top and Options.ChangesOnly, and matches the spans of
+// the traces found in every set
+func matchTraces(traceSets []TraceSet, attribute string, opts Options) traceMatch {
+	m := traceMatch{traceMaps: make([]map[string]*Trace, len(traceSets))}
+	allNames := make(map[string]bool)
+	for i, set := range traceSets {
+		m.traceMaps[i] = traceIndex(set, attribute)
+		for name := range m.traceMaps[i] {
+			allNames[name] = true
+		}
+	}
+	for name := range allNames {
+		m.allNames = append(m.allNames, name)
+	}
+	sort.Strings(m.allNames)
+
+	// Keep only the slowest traces
+	m.names = slowestTraceNames(m.traceMaps, m.allNames, opts.Top)
+
+	for _, name := range m.names {
+		if !inAllSets(m.traceMaps, name) {
+			continue
+		}
+		if opts.ChangesOnly != nil && !traceChanged(m.traceMaps, name, opts.ChangesOnly.Threshold) {
+			m.unchanged++
+			continue
+		}
+		m.traces = append(m.traces, matchSpans(m.traceMaps, name, opts))
+	}
+	return m
+}
+
+// matchSpans matches the spans of a trace found in every set. Repeated span
+// names are compared by occurrence, so the n-th db.query is compared with
+// the n-th.
+func matchSpans(traceMaps []map[string]*Trace, name string, opts Options) matchedTrace {
+	t := matchedTrace{
+		name:        name,
+		spanIndexes: make([]map[spanRef]*Span, len(traceMaps)),
+		aliases:     fuzzySpanAliases(traceMaps, name, opts.FuzzyMatch, opts.spanName),
+		aliased:     make([]map[string]bool, len(traceMaps)),
+		sections:    make(map[spanRef]string),
+	}
+	for i, setAliases := range t.aliases {
+		t.aliased[i] = make(map[string]bool)
+		for _, alias := range setAliases {
+			t.aliased[i][alias] = true
+		}
+	}
+
+	// Skip names that were matched to a span of the first set
+	allRefs := make(map[spanRef]bool)
+	for i, traceMap := range traceMaps {
+		t.spanIndexes[i] = spanIndexBy(traceMap[name], opts.spanName)
+		for ref := range t.spanIndexes[i] {
+			if !t.aliased[i][ref.name] {
+				allRefs[ref] = true
+			}
+		}
+	}
+	for ref := range allRefs {
+		t.spans = append(t.spans, ref)
+	}
+	sortSpanRefs(t.spans)
+
+	if opts.SectionBy != "" {
+		for _, ref := range t.spans {
+			t.sections[ref] = getComparisonSection(traceMaps, t.spanIndexes, name, ref, opts.SectionBy)
+		}
+	}
+	return t
+}
+
+// inAllSets reports whether a trace name exists in every trace map
+func inAllSets(traceMaps []map[string]*Trace, name string) bool {
+	for _, traceMap := range traceMaps {
+		if _, ok := traceMap[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// matchingTraceCount returns how many of the trace names exist in every
+// trace map
+func matchingTraceCount(traceMaps []map[string]*Trace, names []string) int {
+	count := 0
+	for _, name := range names {
+		if inAllSets(traceMaps, name) {
+			count++
+		}
+	}
+	return count
+}
+
+// matchWarning returns a warning when no trace, or less than minFraction of
+// them, matches across all files, which usually means the files are
+// unrelated or traces are identified by the wrong attribute. It returns ""
+// when enough traces match.
+func matchWarning(traceMaps []map[string]*Trace, names []string, attribute string, minFraction float64, ind indicators) string {
+	identifier := strings.Join(strings.Split(attribute, ","), "`, `")
+	problem := matchProblem(traceMaps, names, escapeMarkdownCell(identifier), minFraction)
+	if problem == "" {
+		return ""
+	}
+	return fmt.Sprintf("**%s %s.** Check that the files are related, or identify traces by a different `--attribute`.\n\n", ind.warning, problem)
+}
+
+// matchProblem describes how few traces match across all files by the
+// identifier, or returns "" when enough traces match
+func matchProblem(traceMaps []map[string]*Trace, names []string, identifier string, minFraction float64) string {
+	if len(names) == 0 || len(traceMaps) < 2 {
+		return ""
+	}
+	matching := matchingTraceCount(traceMaps, names)
+	fraction := float64(matching) / float64(len(names))
+	if matching > 0 && fraction >= minFraction {
+		return ""
+	}
+
+	if matching == 0 {
+		return fmt.Sprintf("None of the %d traces match across the files by `%s`", len(names), identifier)
+	}
+	return fmt.Sprintf("Only %d of %d traces (%.1f%%) match across the files by `%s`", matching, len(names), fraction*100, identifier)
+}
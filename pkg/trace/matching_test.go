@@ -0,0 +1,69 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompareMultipleTracesMatchWarning(t *testing.T) {
+	now := time.Now()
+	newSet := func(name string, traceIDs ...string) TraceSet {
+		set := TraceSet{Name: name}
+		for _, id := range traceIDs {
+			set.Traces = append(set.Traces, Trace{TraceID: id, Spans: []Span{
+				{SpanID: "a", Name: "GET /users", StartTime: now, EndTime: now.Add(time.Second)},
+			}})
+		}
+		return set
+	}
+
+	tests := []struct {
+		name        string
+		traceSets   []TraceSet
+		attribute   string
+		minFraction float64
+		want        string
+	}{
+		{
+			name:      "disjoint files",
+			traceSets: []TraceSet{newSet("a.json", "t1", "t2"), newSet("b.json", "t3")},
+			attribute: "trace_id",
+			want:      "**⚠️ None of the 3 traces match across the files by `trace_id`.**",
+		},
+		{
+			name:        "below the minimum fraction",
+			traceSets:   []TraceSet{newSet("a.json", "t1", "t2", "t3"), newSet("b.json", "t1", "t4")},
+			attribute:   "trace_id",
+			minFraction: 0.5,
+			want:        "**⚠️ Only 1 of 4 traces (25.0%) match across the files by `trace_id`.**",
+		},
+		{
+			name:      "some matches without a minimum",
+			traceSets: []TraceSet{newSet("a.json", "t1", "t2", "t3"), newSet("b.json", "t1", "t4")},
+			attribute: "trace_id",
+		},
+		{
+			name:      "other attribute matches",
+			traceSets: []TraceSet{newSet("a.json", "t1"), newSet("b.json", "t2")},
+			attribute: "name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CompareMultipleTraces(tt.traceSets, tt.attribute, Options{MinMatchFraction: tt.minFraction, Verdict: &Verdict{Threshold: 10}})
+			if tt.want == "" {
+				if strings.Contains(got, "⚠️") {
+					t.Errorf("CompareMultipleTraces() warned about matching traces:\n%s", got)
+				}
+				return
+			}
+			// The warning comes before the verdict it makes meaningless
+			warning, verdict := strings.Index(got, tt.want), strings.Index(got, "No regressions")
+			if warning < 0 || warning > verdict {
+				t.Errorf("CompareMultipleTraces() output does not start with %q:\n%s", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package trace
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// mermaidReplacer strips characters that Mermaid gantt syntax or the
+// surrounding code fence would interpret
+var mermaidReplacer = strings.NewReplacer(
+	":", " ",
+	";", ",",
+	"#", "",
+	"`", "'",
+	"\r\n", " ",
+	"\n", " ",
+	"\r", " ",
+)
+
+// GenerateMermaid generates a Mermaid gantt diagram of a trace, with one
+// section per span positioned by its offset from the start of the trace.
+// Sections are flat and ordered by start time, so deeply nested or cyclic
+// parent relationships cannot break the diagram.
+func GenerateMermaid(t Trace) string {
+	var sb strings.Builder
+
+	sb.WriteString("```mermaid\ngantt\n")
+	sb.WriteString(fmt.Sprintf("    title Trace %s\n", mermaidText(t.TraceID)))
+	sb.WriteString("    dateFormat x\n")
+	sb.WriteString("    axisFormat %S.%Ls\n")
+
+	start := traceStart(t)
+	spans := append([]Span(nil), t.Spans...)
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].StartTime.Before(spans[j].StartTime)
+	})
+	for _, span := range spans {
+		offset := span.StartTime.Sub(start).Milliseconds()
+		end := span.EndTime.Sub(start).Milliseconds()
+		// Spans shorter than a millisecond would not be drawn at all
+		if end <= offset {
+			end = offset + 1
+		}
+
+		name := mermaidText(span.Name)
+		status := ""
+		if isError(span) {
+			status = "crit, "
+		}
+		sb.WriteString(fmt.Sprintf("    section %s\n", name))
+		sb.WriteString(fmt.Sprintf("    %s (%s) :%s%d, %d\n", name, formatDuration(spanDuration(span)), status, offset, end))
+	}
+
+	sb.WriteString("```\n")
+	return sb.String()
+}
+
+// mermaidText makes a string safe to use as a Mermaid gantt label
+func mermaidText(s string) string {
+	s = strings.TrimSpace(mermaidReplacer.Replace(s))
+	if s == "" {
+		return "unnamed"
+	}
+	return s
+}
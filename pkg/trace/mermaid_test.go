@@ -0,0 +1,53 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateMermaid(t *testing.T) {
+	now := time.Now()
+	trace := Trace{
+		TraceID: "trace1",
+		Spans: []Span{
+			{SpanID: "b", ParentSpanID: "a", Name: "db: select #1", StartTime: now.Add(100 * time.Millisecond), EndTime: now.Add(300 * time.Millisecond),
+				Status: Status{Code: StatusError}},
+			{SpanID: "a", Name: "GET /users", StartTime: now, EndTime: now.Add(time.Second)},
+			{SpanID: "c", ParentSpanID: "b", Name: "cache", StartTime: now.Add(400 * time.Millisecond), EndTime: now.Add(400*time.Millisecond + time.Microsecond)},
+		},
+	}
+
+	got := GenerateMermaid(trace)
+
+	expected := []string{
+		"```mermaid\ngantt\n",
+		"    section GET /users\n    GET /users (1.00s) :0, 1000\n",
+		"    section db  select 1\n    db  select 1 (200.00ms) :crit, 100, 300\n",
+		"    cache (1.00µs) :400, 401\n",
+	}
+	for _, want := range expected {
+		if !strings.Contains(got, want) {
+			t.Errorf("GenerateMermaid() output does not contain %q:\n%s", want, got)
+		}
+	}
+
+	// Spans are ordered by start time, not input order
+	if strings.Index(got, "GET /users") > strings.Index(got, "db  select") {
+		t.Errorf("GenerateMermaid() did not order spans by start time:\n%s", got)
+	}
+}
+
+func TestGenerateMarkdownMermaid(t *testing.T) {
+	now := time.Now()
+	traces := []Trace{{TraceID: "trace1", Spans: []Span{
+		{SpanID: "a", Name: "root", StartTime: now, EndTime: now.Add(time.Second)},
+	}}}
+
+	if got := GenerateMarkdown(traces, Options{}); strings.Contains(got, "```mermaid") {
+		t.Errorf("GenerateMarkdown() added a diagram without Options.Mermaid:\n%s", got)
+	}
+	if got := GenerateMarkdown(traces, Options{Mermaid: true}); !strings.Contains(got, "**Timeline:**\n\n```mermaid") {
+		t.Errorf("GenerateMarkdown() did not add a diagram with Options.Mermaid:\n%s", got)
+	}
+}
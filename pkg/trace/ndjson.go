@@ -0,0 +1,28 @@
+package trace
+
+import (
+	"bufio"
+	"bytes"
+)
+
+// ParseNDJSON reads newline-delimited JSON with one trace object per line.
+// Blank lines are skipped.
+func ParseNDJSON(data []byte) ([]Trace, error) {
+	var traces []Trace
+	pr := newPositionReader(bytes.NewReader(data))
+	err := streamNDJSON(pr, bufio.NewReader(pr), func(t Trace) error {
+		traces = append(traces, t)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return traces, nil
+}
+
+// isNDJSON reports whether data looks like a stream of trace objects
+// rather than a JSON array
+func isNDJSON(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
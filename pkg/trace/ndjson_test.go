@@ -0,0 +1,66 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseNDJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantIDs []string
+		wantErr string
+	}{
+		{
+			name: "one trace per line",
+			input: `{"trace_id": "t1", "spans": [{"span_id": "a", "name": "root"}]}
+{"trace_id": "t2", "spans": []}
+`,
+			wantIDs: []string{"t1", "t2"},
+		},
+		{
+			name:    "blank lines skipped",
+			input:   "\n{\"trace_id\": \"t1\"}\n\n  \r\n{\"trace_id\": \"t2\"}",
+			wantIDs: []string{"t1", "t2"},
+		},
+		{
+			name:    "malformed line",
+			input:   "{\"trace_id\": \"t1\"}\n\n{\"trace_id\": \n",
+			wantErr: "line 3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traces, err := ParseNDJSON([]byte(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseNDJSON() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseNDJSON() error = %v", err)
+			}
+			if len(traces) != len(tt.wantIDs) {
+				t.Fatalf("ParseNDJSON() returned %d traces, want %d", len(traces), len(tt.wantIDs))
+			}
+			for i, id := range tt.wantIDs {
+				if traces[i].TraceID != id {
+					t.Errorf("ParseNDJSON() trace %d = %q, want %q", i, traces[i].TraceID, id)
+				}
+			}
+		})
+	}
+}
+
+func TestParseTracesDetectsNDJSON(t *testing.T) {
+	traces, err := ParseTraces([]byte("{\"trace_id\": \"t1\"}\n{\"trace_id\": \"t2\"}\n"))
+	if err != nil {
+		t.Fatalf("ParseTraces() error = %v", err)
+	}
+	if len(traces) != 2 {
+		t.Errorf("ParseTraces() returned %d traces, want 2", len(traces))
+	}
+}
@@ -0,0 +1,57 @@
+package trace
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultNormalizePattern matches a trailing number, version or UUID
+// after a separator, such as the "-42" in "process-job-42", the "/v2" in
+// "GET /api/v2" or the UUID in "order 4f7c1a2e-9b3d-4c8e-a1f0-2d6b5e8c9a7f"
+const DefaultNormalizePattern = `[-_./:# ]+(v?[0-9]+(\.[0-9]+)*|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})$`
+
+// NormalizeSpanName removes every match of pattern from a span name, so
+// names that only differ by a volatile suffix compare as the same span.
+// A nil pattern, or one that would leave nothing of the name, keeps the
+// name unchanged.
+func NormalizeSpanName(name string, pattern *regexp.Regexp) string {
+	if pattern == nil {
+		return name
+	}
+	if normalized := pattern.ReplaceAllString(name, ""); normalized != "" {
+		return normalized
+	}
+	return name
+}
+
+// spanName returns the name spans are compared by
+func (o Options) spanName(name string) string {
+	return NormalizeSpanName(name, o.NormalizeNames)
+}
+
+// originalNames lists the names of the spans resolved by refs in each trace
+// set when any of them differs from the name it is compared by, such as
+// " _(process-job-12 ≈ process-job-98)_", or returns an empty string
+func originalNames(spanIndexes []map[spanRef]*Span, refs []spanRef) string {
+	var names []string
+	seen := make(map[string]bool)
+	var renamed bool
+	for i, index := range spanIndexes {
+		span, ok := index[refs[i]]
+		if !ok {
+			continue
+		}
+		if span.Name != refs[i].name {
+			renamed = true
+		}
+		if !seen[span.Name] {
+			seen[span.Name] = true
+			names = append(names, escapeMarkdownCell(span.Name))
+		}
+	}
+	if !renamed {
+		return ""
+	}
+	return fmt.Sprintf(" _(%s)_", strings.Join(names, " ≈ "))
+}
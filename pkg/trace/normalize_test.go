@@ -0,0 +1,63 @@
+package trace
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalizeSpanName(t *testing.T) {
+	defaultPattern := regexp.MustCompile(DefaultNormalizePattern)
+	tests := []struct {
+		name    string
+		span    string
+		pattern *regexp.Regexp
+		want    string
+	}{
+		{name: "no pattern", span: "process-job-42", pattern: nil, want: "process-job-42"},
+		{name: "numeric suffix", span: "process-job-42", pattern: defaultPattern, want: "process-job"},
+		{name: "path segment", span: "GET /users/1234", pattern: defaultPattern, want: "GET /users"},
+		{name: "version suffix", span: "render.v2.1", pattern: defaultPattern, want: "render"},
+		{name: "uuid suffix", span: "order 4f7c1a2e-9b3d-4c8e-a1f0-2d6b5e8c9a7f", pattern: defaultPattern, want: "order"},
+		{name: "digits inside a word", span: "md5", pattern: defaultPattern, want: "md5"},
+		{name: "number in the middle", span: "batch-7-flush", pattern: defaultPattern, want: "batch-7-flush"},
+		{name: "nothing left", span: "42", pattern: regexp.MustCompile(`[0-9]+`), want: "42"},
+		{name: "custom pattern", span: "tenant=acme SELECT", pattern: regexp.MustCompile(`^tenant=\w+ `), want: "SELECT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeSpanName(tt.span, tt.pattern); got != tt.want {
+				t.Errorf("NormalizeSpanName(%q) = %q, want %q", tt.span, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareMultipleTracesNormalizeNames(t *testing.T) {
+	now := time.Now()
+	newSet := func(name, job string, d time.Duration) TraceSet {
+		return TraceSet{Name: name, Traces: []Trace{{TraceID: "t1", Spans: []Span{
+			{SpanID: "root", Name: "worker", StartTime: now, EndTime: now.Add(2 * d)},
+			{SpanID: "job", ParentSpanID: "root", Name: job, StartTime: now, EndTime: now.Add(d)},
+		}}}}
+	}
+	traceSets := []TraceSet{
+		newSet("base.json", "process-job-12", time.Second),
+		newSet("head.json", "process-job-98", 2*time.Second),
+	}
+
+	plain := CompareMultipleTraces(traceSets, "trace_id", Options{})
+	if !strings.Contains(plain, "| process-job-12 | 1.00s | ✗ |") {
+		t.Errorf("span names should not match without normalization:\n%s", plain)
+	}
+
+	normalized := CompareMultipleTraces(traceSets, "trace_id", Options{NormalizeNames: regexp.MustCompile(DefaultNormalizePattern)})
+	if !strings.Contains(normalized, "| process-job _(process-job-12 ≈ process-job-98)_ | 1.00s | 2.00s |") {
+		t.Errorf("normalized span names should match and show the original names:\n%s", normalized)
+	}
+	if !strings.Contains(normalized, "| worker | 2.00s | 4.00s |") {
+		t.Errorf("unchanged span names should be shown as they are:\n%s", normalized)
+	}
+}
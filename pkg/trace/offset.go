@@ -0,0 +1,18 @@
+package trace
+
+import "time"
+
+// spanOffset returns how long after the start of its trace a span started,
+// where the trace starts with its earliest span
+func spanOffset(t Trace, span Span) time.Duration {
+	return span.StartTime.Sub(traceStart(t))
+}
+
+// formatDurationDelta formats a signed duration change, e.g. "+120.00ms"
+// or "-1.50s"
+func formatDurationDelta(d time.Duration) string {
+	if d < 0 {
+		return "-" + formatDuration(-d)
+	}
+	return "+" + formatDuration(d)
+}
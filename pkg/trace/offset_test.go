@@ -0,0 +1,63 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompareSpanOffsets(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+	traces1 := []Trace{{TraceID: "trace1", Spans: []Span{
+		{SpanID: "a", Name: "GET /users", StartTime: now, EndTime: now.Add(time.Second)},
+		{SpanID: "b", ParentSpanID: "a", Name: "select", StartTime: now.Add(100 * time.Millisecond), EndTime: now.Add(300 * time.Millisecond)},
+	}}}
+	// The second run happened an hour later; the select waited 400ms longer
+	// before starting but ran for the same time
+	traces2 := []Trace{{TraceID: "trace1", Spans: []Span{
+		{SpanID: "a", Name: "GET /users", StartTime: later, EndTime: later.Add(time.Second)},
+		{SpanID: "b", ParentSpanID: "a", Name: "select", StartTime: later.Add(500 * time.Millisecond), EndTime: later.Add(700 * time.Millisecond)},
+	}}}
+
+	got := Compare(traces1, traces2, "trace_id", Options{})
+	if len(got.Matching) != 1 {
+		t.Fatalf("Compare() returned %d matching traces, want 1", len(got.Matching))
+	}
+	tests := []struct {
+		first, second, delta time.Duration
+	}{
+		{first: 0, second: 0, delta: 0},
+		{first: 100 * time.Millisecond, second: 500 * time.Millisecond, delta: 400 * time.Millisecond},
+	}
+	for i, tt := range tests {
+		span := got.Matching[0].Spans[i]
+		if span.FirstOffset != tt.first || span.SecondOffset != tt.second || span.OffsetDelta != tt.delta {
+			t.Errorf("Compare() span %s offsets = %v, %v, %v, want %v, %v, %v",
+				span.Name, span.FirstOffset, span.SecondOffset, span.OffsetDelta, tt.first, tt.second, tt.delta)
+		}
+	}
+
+	markdown := CompareTraces(traces1, traces2)
+	want := "| select | +100.00ms | +500.00ms | +400.00ms | 200.00ms | 200.00ms | +0ns (0.0%) |\n"
+	if !strings.Contains(markdown, want) {
+		t.Errorf("CompareTraces() output does not contain %q:\n%s", want, markdown)
+	}
+}
+
+func TestFormatDurationDelta(t *testing.T) {
+	tests := []struct {
+		d        time.Duration
+		expected string
+	}{
+		{d: 0, expected: "+0ns"},
+		{d: 120 * time.Millisecond, expected: "+120.00ms"},
+		{d: -1500 * time.Millisecond, expected: "-1.50s"},
+	}
+
+	for _, tt := range tests {
+		if got := formatDurationDelta(tt.d); got != tt.expected {
+			t.Errorf("formatDurationDelta(%v) = %q, want %q", tt.d, got, tt.expected)
+		}
+	}
+}
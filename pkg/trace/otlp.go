@@ -0,0 +1,192 @@
+package trace
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// ParseOTLPProto reads a binary OTLP TracesData message, as written by the
+// collector's file exporter, and groups its spans into traces by trace ID
+// in order of first appearance. The resource attributes of the trace's
+// root span (or first span when there is no root) become the trace's
+// resource attributes, and spans from other services carry their own
+// service.name attribute. Each span keeps the instrumentation scope it was
+// grouped under.
+func ParseOTLPProto(data []byte) ([]Trace, error) {
+	if len(data) == 0 {
+		return nil, ErrEmptyInput
+	}
+	var traces tracepb.TracesData
+	if err := proto.Unmarshal(data, &traces); err != nil {
+		return nil, fmt.Errorf("error unmarshaling otlp traces: %w", err)
+	}
+
+	var result []Trace
+	traceIndex := make(map[string]int)
+	resources := make(map[string]map[string]string)
+	for _, rs := range traces.GetResourceSpans() {
+		resourceAttrs := convertOTLPAttributes(rs.GetResource().GetAttributes())
+		for _, ss := range rs.GetScopeSpans() {
+			for _, ps := range ss.GetSpans() {
+				traceID := hex.EncodeToString(ps.GetTraceId())
+				i, ok := traceIndex[traceID]
+				if !ok {
+					i = len(result)
+					traceIndex[traceID] = i
+					result = append(result, Trace{TraceID: traceID})
+				}
+
+				span := convertOTLPSpan(ps, ss.GetScope())
+				resources[traceID+"/"+span.SpanID] = resourceAttrs
+				result[i].Spans = append(result[i].Spans, span)
+			}
+		}
+	}
+
+	// Use the root's resource and keep other services per span
+	for i := range result {
+		spans := result[i].Spans
+		root := spans[0]
+		for _, span := range spans {
+			if span.ParentSpanID == "" {
+				root = span
+				break
+			}
+		}
+
+		rootResource := resources[result[i].TraceID+"/"+root.SpanID]
+		if len(rootResource) > 0 {
+			result[i].ResourceAttrs = rootResource
+		}
+		for j := range spans {
+			service := resources[result[i].TraceID+"/"+spans[j].SpanID]["service.name"]
+			if service == "" || service == rootResource["service.name"] {
+				continue
+			}
+			if spans[j].Attributes == nil {
+				spans[j].Attributes = make(map[string]string)
+			}
+			spans[j].Attributes["service.name"] = service
+		}
+	}
+
+	return result, nil
+}
+
+// convertOTLPSpan converts an OTLP span and its instrumentation scope into
+// a Span
+func convertOTLPSpan(ps *tracepb.Span, scope *commonpb.InstrumentationScope) Span {
+	span := Span{
+		SpanID:       hex.EncodeToString(ps.GetSpanId()),
+		ParentSpanID: hex.EncodeToString(ps.GetParentSpanId()),
+		TraceState:   ps.GetTraceState(),
+		Name:         ps.GetName(),
+		Kind:         convertOTLPKind(ps.GetKind()),
+		StartTime:    fromNanos(ps.GetStartTimeUnixNano()),
+		EndTime:      fromNanos(ps.GetEndTimeUnixNano()),
+		Attributes:   convertOTLPAttributes(ps.GetAttributes()),
+		Status: Status{
+			Code:    convertOTLPStatusCode(ps.GetStatus().GetCode()),
+			Message: ps.GetStatus().GetMessage(),
+		},
+		Scope: InstrumentationScope{
+			Name:    scope.GetName(),
+			Version: scope.GetVersion(),
+		},
+	}
+
+	for _, event := range ps.GetEvents() {
+		span.Events = append(span.Events, Event{
+			Time:       fromNanos(event.GetTimeUnixNano()),
+			Name:       event.GetName(),
+			Attributes: convertOTLPAttributes(event.GetAttributes()),
+		})
+	}
+
+	for _, link := range ps.GetLinks() {
+		span.Links = append(span.Links, SpanLink{
+			TraceID:    hex.EncodeToString(link.GetTraceId()),
+			SpanID:     hex.EncodeToString(link.GetSpanId()),
+			Attributes: convertOTLPAttributes(link.GetAttributes()),
+		})
+	}
+
+	return span
+}
+
+// convertOTLPKind converts an OTLP span kind, leaving unspecified kinds empty
+func convertOTLPKind(kind tracepb.Span_SpanKind) SpanKind {
+	if kind == tracepb.Span_SPAN_KIND_UNSPECIFIED {
+		return ""
+	}
+	return SpanKind(strings.TrimPrefix(kind.String(), "SPAN_KIND_"))
+}
+
+// convertOTLPStatusCode converts an OTLP status code
+func convertOTLPStatusCode(code tracepb.Status_StatusCode) StatusCode {
+	switch code {
+	case tracepb.Status_STATUS_CODE_OK:
+		return StatusOK
+	case tracepb.Status_STATUS_CODE_ERROR:
+		return StatusError
+	default:
+		return StatusUnset
+	}
+}
+
+// convertOTLPAttributes converts OTLP key-values into an attribute map, or
+// nil when there are none
+func convertOTLPAttributes(kvs []*commonpb.KeyValue) map[string]string {
+	if len(kvs) == 0 {
+		return nil
+	}
+	attrs := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		attrs[kv.GetKey()] = formatOTLPValue(kv.GetValue())
+	}
+	return attrs
+}
+
+// formatOTLPValue formats an OTLP attribute value as a string. Arrays and
+// key-value lists are written in a JSON-like form and bytes as base64.
+func formatOTLPValue(v *commonpb.AnyValue) string {
+	switch value := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return value.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return strconv.FormatBool(value.BoolValue)
+	case *commonpb.AnyValue_IntValue:
+		return strconv.FormatInt(value.IntValue, 10)
+	case *commonpb.AnyValue_DoubleValue:
+		return strconv.FormatFloat(value.DoubleValue, 'g', -1, 64)
+	case *commonpb.AnyValue_BytesValue:
+		return base64.StdEncoding.EncodeToString(value.BytesValue)
+	case *commonpb.AnyValue_ArrayValue:
+		values := make([]string, 0, len(value.ArrayValue.GetValues()))
+		for _, item := range value.ArrayValue.GetValues() {
+			values = append(values, formatOTLPValue(item))
+		}
+		return "[" + strings.Join(values, ",") + "]"
+	case *commonpb.AnyValue_KvlistValue:
+		values := make([]string, 0, len(value.KvlistValue.GetValues()))
+		for _, kv := range value.KvlistValue.GetValues() {
+			values = append(values, kv.GetKey()+":"+formatOTLPValue(kv.GetValue()))
+		}
+		return "{" + strings.Join(values, ",") + "}"
+	default:
+		return ""
+	}
+}
+
+// fromNanos converts nanoseconds since the Unix epoch to a UTC time
+func fromNanos(ns uint64) time.Time {
+	return time.Unix(0, int64(ns)).UTC()
+}
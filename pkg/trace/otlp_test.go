@@ -0,0 +1,158 @@
+package trace
+
+import (
+	"testing"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func stringAttr(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
+
+func TestParseOTLPProto(t *testing.T) {
+	start := uint64(time.Date(2024, 3, 6, 10, 0, 0, 0, time.UTC).UnixNano())
+	traceID := []byte{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}
+	data, err := proto.Marshal(&tracepb.TracesData{ResourceSpans: []*tracepb.ResourceSpans{
+		{
+			Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{stringAttr("service.name", "frontend"), stringAttr("service.version", "1.2.3")}},
+			ScopeSpans: []*tracepb.ScopeSpans{{
+				Scope: &commonpb.InstrumentationScope{Name: "net/http", Version: "0.49.0"},
+				Spans: []*tracepb.Span{{
+					TraceId:           traceID,
+					SpanId:            []byte{0, 0, 0, 0, 0, 0, 0, 1},
+					Name:              "GET /users",
+					Kind:              tracepb.Span_SPAN_KIND_SERVER,
+					StartTimeUnixNano: start,
+					EndTimeUnixNano:   start + 150_000_640,
+					Attributes: []*commonpb.KeyValue{
+						stringAttr("http.method", "GET"),
+						{Key: "http.status_code", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: 500}}},
+						{Key: "retry", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: true}}},
+					},
+					Events: []*tracepb.Span_Event{{TimeUnixNano: start + 1000, Name: "exception", Attributes: []*commonpb.KeyValue{stringAttr("exception.type", "Timeout")}}},
+					Status: &tracepb.Status{Code: tracepb.Status_STATUS_CODE_ERROR, Message: "timeout"},
+				}},
+			}},
+		},
+		{
+			Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{stringAttr("service.name", "users-db")}},
+			ScopeSpans: []*tracepb.ScopeSpans{{
+				Spans: []*tracepb.Span{{
+					TraceId:      traceID,
+					SpanId:       []byte{0, 0, 0, 0, 0, 0, 0, 2},
+					ParentSpanId: []byte{0, 0, 0, 0, 0, 0, 0, 1},
+					TraceState:   "congo=t61rcWkgMzE, rojo=00f067aa0ba902b7",
+					Name:         "select",
+					Kind:         tracepb.Span_SPAN_KIND_CLIENT,
+					Links: []*tracepb.Span_Link{{
+						TraceId:    []byte{0xab, 0xcd},
+						SpanId:     []byte{0x12, 0x34},
+						Attributes: []*commonpb.KeyValue{stringAttr("messaging.operation", "publish")},
+					}},
+					StartTimeUnixNano: start + 10_000_000,
+					EndTimeUnixNano:   start + 60_000_000,
+				}},
+			}},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("proto.Marshal() error = %v", err)
+	}
+
+	traces, err := ParseOTLPProto(data)
+	if err != nil {
+		t.Fatalf("ParseOTLPProto() error = %v", err)
+	}
+	if len(traces) != 1 || len(traces[0].Spans) != 2 {
+		t.Fatalf("ParseOTLPProto() returned %d traces, want 1 with 2 spans", len(traces))
+	}
+
+	tr := traces[0]
+	if tr.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace ID = %q, want hex encoded", tr.TraceID)
+	}
+	if tr.ResourceAttrs["service.name"] != "frontend" || tr.ResourceAttrs["service.version"] != "1.2.3" {
+		t.Errorf("resource attributes = %v, want the root span's resource", tr.ResourceAttrs)
+	}
+
+	root, child := tr.Spans[0], tr.Spans[1]
+	if root.SpanID != "0000000000000001" || child.ParentSpanID != root.SpanID {
+		t.Errorf("span IDs = %q -> %q, want hex encoded parent reference", child.ParentSpanID, root.SpanID)
+	}
+	if root.ParentSpanID != "" {
+		t.Errorf("root parent span ID = %q, want empty", root.ParentSpanID)
+	}
+	if got := root.EndTime.Sub(root.StartTime); got != 150_000_640*time.Nanosecond {
+		t.Errorf("root span duration = %v, want nanosecond precision", got)
+	}
+	if root.Kind != SpanKindServer || child.Kind != SpanKindClient {
+		t.Errorf("span kinds = %q, %q, want SERVER, CLIENT", root.Kind, child.Kind)
+	}
+	if root.Status != (Status{Code: StatusError, Message: "timeout"}) || child.Status.Code != StatusUnset {
+		t.Errorf("statuses = %v, %v, want ERROR with message and UNSET", root.Status, child.Status)
+	}
+	wantAttrs := map[string]string{
+		"http.method":      "GET",
+		"http.status_code": "500",
+		"retry":            "true",
+	}
+	for k, want := range wantAttrs {
+		if got := root.Attributes[k]; got != want {
+			t.Errorf("root attribute %s = %q, want %q", k, got, want)
+		}
+	}
+	if root.Scope != (InstrumentationScope{Name: "net/http", Version: "0.49.0"}) {
+		t.Errorf("root span scope = %+v, want net/http 0.49.0", root.Scope)
+	}
+	if child.TraceState != "congo=t61rcWkgMzE, rojo=00f067aa0ba902b7" || root.TraceState != "" {
+		t.Errorf("trace states = %q, %q, want the child's kept as is", root.TraceState, child.TraceState)
+	}
+	if child.Attributes["service.name"] != "users-db" {
+		t.Errorf("child span service.name = %q, want users-db", child.Attributes["service.name"])
+	}
+	if len(child.Links) != 1 || child.Links[0].TraceID != "abcd" || child.Links[0].SpanID != "1234" ||
+		child.Links[0].Attributes["messaging.operation"] != "publish" {
+		t.Errorf("child span links = %v, want a single hex encoded link with attributes", child.Links)
+	}
+	if len(root.Events) != 1 || root.Events[0].Name != "exception" || root.Events[0].Attributes["exception.type"] != "Timeout" {
+		t.Errorf("root span events = %v, want a single exception event", root.Events)
+	}
+}
+
+func TestFormatOTLPValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    *commonpb.AnyValue
+		expected string
+	}{
+		{name: "double", value: &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: 0.25}}, expected: "0.25"},
+		{name: "bytes", value: &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: []byte("hi")}}, expected: "aGk="},
+		{name: "array", value: &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: []*commonpb.AnyValue{
+			{Value: &commonpb.AnyValue_StringValue{StringValue: "a"}},
+			{Value: &commonpb.AnyValue_IntValue{IntValue: 2}},
+		}}}}, expected: "[a,2]"},
+		{name: "kvlist", value: &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{Values: []*commonpb.KeyValue{
+			stringAttr("k", "v"),
+		}}}}, expected: "{k:v}"},
+		{name: "empty", value: nil, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatOTLPValue(tt.value); got != tt.expected {
+				t.Errorf("formatOTLPValue() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseOTLPProtoInvalid(t *testing.T) {
+	if _, err := ParseOTLPProto([]byte{0xff, 0xff, 0xff}); err == nil {
+		t.Error("ParseOTLPProto() expected an error for invalid protobuf")
+	}
+}
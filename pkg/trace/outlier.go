@@ -0,0 +1,147 @@
+package trace
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// outlierSigmas is how many standard deviations above the mean of the
+// other traces of its operation a trace must take to be an outlier
+const outlierSigmas = 3
+
+// minOutlierSamples is the fewest traces an operation needs for outliers
+// to be told apart from normal variation
+const minOutlierSamples = 4
+
+// minOutlierSpread is the smallest spread, as a fraction of the mean, that
+// durations are assumed to have. It keeps tiny differences from making
+// outliers among near-identical durations.
+const minOutlierSpread = 0.01
+
+// Outlier is a trace that took far longer than the other traces of its
+// operation
+type Outlier struct {
+	// Name is the identifier of the trace, which names its operation
+	Name string
+
+	// Trace is the slow trace
+	Trace Trace
+
+	// Mean and StdDev describe the durations of the other traces of the
+	// operation
+	Mean   time.Duration
+	StdDev time.Duration
+}
+
+// Duration returns how long the outlier took
+func (o Outlier) Duration() time.Duration {
+	return getTraceDuration(o.Trace)
+}
+
+// Sigmas returns how many standard deviations above the mean the outlier
+// took
+func (o Outlier) Sigmas() float64 {
+	return float64(o.Duration()-o.Mean) / float64(outlierSpread(o.Mean, o.StdDev))
+}
+
+// meanStdDev returns the mean and population standard deviation of
+// durations, or zeros when there are none
+func meanStdDev(durations []time.Duration) (time.Duration, time.Duration) {
+	if len(durations) == 0 {
+		return 0, 0
+	}
+	var sum float64
+	for _, d := range durations {
+		sum += float64(d)
+	}
+	mean := sum / float64(len(durations))
+
+	var squares float64
+	for _, d := range durations {
+		squares += (float64(d) - mean) * (float64(d) - mean)
+	}
+	return time.Duration(mean), time.Duration(math.Sqrt(squares / float64(len(durations))))
+}
+
+// outlierSpread returns the standard deviation used to judge outliers,
+// never less than minOutlierSpread of the mean
+func outlierSpread(mean, stdDev time.Duration) time.Duration {
+	return max(stdDev, time.Duration(float64(mean)*minOutlierSpread), 1)
+}
+
+// DetectOutliers returns the traces that took more than three standard
+// deviations longer than the mean of the other traces sharing their
+// identifier attribute. Each trace is judged against the others, so one
+// slow trace does not hide itself by inflating the statistics. Operations
+// with fewer than four traces have no outliers.
+func DetectOutliers(traces []Trace, attribute string) []Trace {
+	outliers := findOutliers(traces, attribute)
+	result := make([]Trace, len(outliers))
+	for i, o := range outliers {
+		result[i] = o.Trace
+	}
+	return result
+}
+
+// findOutliers returns the outliers among the traces, see DetectOutliers,
+// sorted by how far above the norm they are
+func findOutliers(traces []Trace, attribute string) []Outlier {
+	groups := make(map[string][]Trace)
+	for _, t := range traces {
+		name := getTraceIdentifier(t, attribute)
+		groups[name] = append(groups[name], t)
+	}
+
+	var outliers []Outlier
+	for name, group := range groups {
+		if len(group) < minOutlierSamples {
+			continue
+		}
+		durations := make([]time.Duration, len(group))
+		for i, t := range group {
+			durations[i] = getTraceDuration(t)
+		}
+
+		for i, t := range group {
+			others := append(append([]time.Duration(nil), durations[:i]...), durations[i+1:]...)
+			mean, stdDev := meanStdDev(others)
+			if durations[i]-mean > outlierSigmas*outlierSpread(mean, stdDev) {
+				outliers = append(outliers, Outlier{Name: name, Trace: t, Mean: mean, StdDev: stdDev})
+			}
+		}
+	}
+
+	sort.Slice(outliers, func(i, j int) bool {
+		if a, b := outliers[i].Sigmas(), outliers[j].Sigmas(); a != b {
+			return a > b
+		}
+		return outliers[i].Trace.TraceID < outliers[j].Trace.TraceID
+	})
+	return outliers
+}
+
+// writeOutliers lists the traces that took far longer than the other
+// traces of their operation, if any
+func writeOutliers(sb *strings.Builder, traces []Trace, attribute string) {
+	outliers := findOutliers(traces, attribute)
+	if len(outliers) == 0 {
+		return
+	}
+
+	sb.WriteString("\n**Outliers:**\n\n")
+	sb.WriteString("| Trace | Trace ID | Duration | Typical | Above Norm |\n")
+	sb.WriteString("|-------|----------|----------|---------|------------|\n")
+	for _, o := range outliers {
+		sb.WriteString(fmt.Sprintf("| %s | `%s` | %s | %s ± %s | %s (%.1fσ) |\n",
+			escapeMarkdownCell(o.Name),
+			escapeMarkdownCell(o.Trace.TraceID),
+			formatDuration(o.Duration()),
+			formatDuration(o.Mean),
+			formatDuration(o.StdDev),
+			formatDurationDelta(o.Duration()-o.Mean),
+			o.Sigmas()))
+	}
+}
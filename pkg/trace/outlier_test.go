@@ -0,0 +1,101 @@
+package trace
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMeanStdDev(t *testing.T) {
+	tests := []struct {
+		name       string
+		durations  []time.Duration
+		wantMean   time.Duration
+		wantStdDev time.Duration
+	}{
+		{name: "empty", durations: nil},
+		{name: "single sample", durations: []time.Duration{time.Second}, wantMean: time.Second},
+		{name: "identical", durations: []time.Duration{time.Second, time.Second, time.Second}, wantMean: time.Second},
+		{name: "spread", durations: []time.Duration{2, 4, 4, 4, 5, 5, 7, 9}, wantMean: 5, wantStdDev: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mean, stdDev := meanStdDev(tt.durations)
+			if mean != tt.wantMean || stdDev != tt.wantStdDev {
+				t.Errorf("meanStdDev() = %v, %v, want %v, %v", mean, stdDev, tt.wantMean, tt.wantStdDev)
+			}
+		})
+	}
+}
+
+// operationTraces returns traces of one operation with the given durations
+func operationTraces(name string, durations ...time.Duration) []Trace {
+	now := time.Now()
+	traces := make([]Trace, len(durations))
+	for i, d := range durations {
+		traces[i] = Trace{TraceID: fmt.Sprintf("%s-%d", name, i+1), Spans: []Span{
+			{SpanID: "a", Name: name, StartTime: now, EndTime: now.Add(d)},
+		}}
+	}
+	return traces
+}
+
+func TestDetectOutliers(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name   string
+		traces []Trace
+		want   []string
+	}{
+		{
+			name:   "slow trace",
+			traces: operationTraces("GET /users", 100*ms, 110*ms, 90*ms, 105*ms, 95*ms, 900*ms),
+			want:   []string{"GET /users-6"},
+		},
+		{
+			name:   "normal variation",
+			traces: operationTraces("GET /users", 100*ms, 140*ms, 60*ms, 120*ms, 80*ms),
+		},
+		{
+			name:   "too few samples",
+			traces: operationTraces("GET /users", 100*ms, 100*ms, 900*ms),
+		},
+		{
+			name:   "identical durations with jitter",
+			traces: operationTraces("GET /users", 100*ms, 100*ms, 100*ms, 101*ms),
+		},
+		{
+			name:   "operations are judged separately",
+			traces: append(operationTraces("GET /users", 100*ms, 100*ms, 100*ms, 500*ms), operationTraces("GET /orders", 500*ms, 500*ms, 500*ms, 500*ms)...),
+			want:   []string{"GET /users-4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, trace := range DetectOutliers(tt.traces, "name") {
+				got = append(got, trace.TraceID)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("DetectOutliers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateMarkdownOutliers(t *testing.T) {
+	ms := time.Millisecond
+	traces := operationTraces("GET /users", 100*ms, 100*ms, 100*ms, 500*ms)
+
+	got := GenerateMarkdown(traces, Options{OutliersBy: "name"})
+	want := "| GET /users | `GET /users-4` | 500.00ms | 100.00ms ± 0ns | +400.00ms (400.0σ) |\n"
+	if !strings.Contains(got, want) {
+		t.Errorf("GenerateMarkdown() output does not contain %q:\n%s", want, got)
+	}
+	if got := GenerateMarkdown(traces, Options{}); strings.Contains(got, "Outliers") {
+		t.Errorf("GenerateMarkdown() lists outliers without OutliersBy:\n%s", got)
+	}
+}
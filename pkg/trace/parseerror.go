@@ -0,0 +1,141 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrEmptyInput is returned when trace input is empty or only whitespace,
+// which is never valid in any input format. An empty JSON array is not an
+// error, see CheckShape.
+var ErrEmptyInput = errors.New("input is empty")
+
+// ParseError is a JSON syntax or type error in trace input, located by
+// line and column so it can be found in large files
+type ParseError struct {
+	// Line and Column are 1-based and point at the offending byte
+	Line   int
+	Column int
+
+	// Offset is the number of input bytes read when the error was found
+	Offset int64
+
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d, column %d (byte %d): %v", e.Line, e.Column, e.Offset, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// jsonErrorOffset returns the input offset reported by JSON syntax and
+// type errors
+func jsonErrorOffset(err error) (int64, bool) {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return syntaxErr.Offset, true
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return typeErr.Offset, true
+	}
+	return 0, false
+}
+
+// locateError turns a JSON error from decoding data into a ParseError.
+// Errors without an offset are returned unchanged.
+func locateError(data []byte, err error) error {
+	offset, ok := jsonErrorOffset(err)
+	if !ok {
+		return err
+	}
+	line, column := lineColumn(data, offset)
+	return &ParseError{Line: line, Column: column, Offset: offset, Err: err}
+}
+
+// lineColumn returns the line and column of the last byte read when a
+// JSON error is reported at offset
+func lineColumn(data []byte, offset int64) (int, int) {
+	i := int(min(max(offset-1, 0), int64(len(data))))
+	before := data[:i]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := i - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+// positionReader remembers where the lines of a stream start so errors
+// found while decoding it can be located without keeping the input.
+// Positions before the last discard point are forgotten.
+type positionReader struct {
+	r io.Reader
+
+	// offset is the number of bytes read so far
+	offset int64
+
+	// newlines holds the offsets of the newlines not yet discarded, and
+	// discardedLines counts the ones before them
+	newlines       []int64
+	discardedLines int
+	lastDiscarded  int64
+}
+
+func newPositionReader(r io.Reader) *positionReader {
+	return &positionReader{r: r, lastDiscarded: -1}
+}
+
+func (p *positionReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	for i, c := range b[:n] {
+		if c == '\n' {
+			p.newlines = append(p.newlines, p.offset+int64(i))
+		}
+	}
+	p.offset += int64(n)
+	return n, err
+}
+
+// discard forgets the newlines before offset, since no later error can
+// be reported there
+func (p *positionReader) discard(offset int64) {
+	kept := 0
+	for kept < len(p.newlines) && p.newlines[kept] < offset {
+		kept++
+	}
+	if kept > 0 {
+		p.lastDiscarded = p.newlines[kept-1]
+		p.discardedLines += kept
+		p.newlines = append(p.newlines[:0], p.newlines[kept:]...)
+	}
+}
+
+// locate turns a JSON error from decoding input that starts base bytes
+// into the stream into a ParseError. Input that ends early is located at
+// its end, and other errors without an offset are returned unchanged.
+func (p *positionReader) locate(err error, base int64) error {
+	var offset int64
+	if errOffset, ok := jsonErrorOffset(err); ok {
+		offset = base + errOffset
+	} else if errors.Is(err, io.ErrUnexpectedEOF) {
+		offset = p.offset
+	} else {
+		return err
+	}
+
+	i := max(offset-1, 0)
+	line := p.discardedLines + 1
+	lineStart := p.lastDiscarded
+	for _, newline := range p.newlines {
+		if newline >= i {
+			break
+		}
+		line++
+		lineStart = newline
+	}
+	return &ParseError{Line: line, Column: int(i - lineStart), Offset: offset, Err: err}
+}
@@ -0,0 +1,95 @@
+package trace
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseErrorLocation(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		wantLine   int
+		wantColumn int
+	}{
+		{
+			name:       "syntax error",
+			input:      "[\n  {\"trace_id\": \"a\", \"spans\": []},\n  {\"trace_id\" \"b\"}\n]",
+			wantLine:   3,
+			wantColumn: 15,
+		},
+		{
+			name:       "type error",
+			input:      "[\n  {\"trace_id\": \"a\"},\n  {\"trace_id\": \"b\",\n   \"spans\": 42}\n]",
+			wantLine:   4,
+			wantColumn: 14,
+		},
+		{
+			name:       "unexpected end",
+			input:      "[\n  {\"trace_id\": \"a\"",
+			wantLine:   2,
+			wantColumn: 18,
+		},
+		{
+			name:       "ndjson",
+			input:      "{\"trace_id\": \"a\"}\n\n{\"trace_id\": 7}\n",
+			wantLine:   3,
+			wantColumn: 14,
+		},
+		{
+			name:       "leading blank lines",
+			input:      "\n\n[{\"trace_id\": true}]",
+			wantLine:   3,
+			wantColumn: 18,
+		},
+	}
+
+	parsers := map[string]func(string) error{
+		"ParseTraces": func(input string) error {
+			_, err := ParseTraces([]byte(input))
+			return err
+		},
+		"ParseTracesStream": func(input string) error {
+			_, err := ParseTracesStream(strings.NewReader(input))
+			return err
+		},
+	}
+
+	for _, tt := range tests {
+		for parser, parse := range parsers {
+			t.Run(tt.name+"/"+parser, func(t *testing.T) {
+				err := parse(tt.input)
+				var parseErr *ParseError
+				if !errors.As(err, &parseErr) {
+					t.Fatalf("%s() error = %v, want a *ParseError", parser, err)
+				}
+				if parseErr.Line != tt.wantLine || parseErr.Column != tt.wantColumn {
+					t.Errorf("%s() error at line %d, column %d, want line %d, column %d (%v)",
+						parser, parseErr.Line, parseErr.Column, tt.wantLine, tt.wantColumn, err)
+				}
+			})
+		}
+	}
+}
+
+func TestPositionReaderLocatesAcrossTraces(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("[\n")
+	for i := 0; i < 1000; i++ {
+		sb.WriteString("  {\"trace_id\": \"t\", \"spans\": []},\n")
+	}
+	sb.WriteString("  {\"trace_id\": \"t\", \"spans\": [}\n]")
+
+	_, err := ParseTracesStream(strings.NewReader(sb.String()))
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("ParseTracesStream() error = %v, want a *ParseError", err)
+	}
+	if parseErr.Line != 1002 || parseErr.Column != 31 {
+		t.Errorf("error at line %d, column %d, want line 1002, column 31", parseErr.Line, parseErr.Column)
+	}
+	if !strings.Contains(err.Error(), "trace 1001: line 1002, column 31") {
+		t.Errorf("error = %q, want it to name the trace and its location", err)
+	}
+}
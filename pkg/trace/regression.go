@@ -0,0 +1,166 @@
+package trace
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// DurationChange is the duration of a trace in a file compared to its
+// duration in the first (baseline) file
+type DurationChange struct {
+	Name     string
+	File     string
+	Baseline time.Duration
+	Current  time.Duration
+}
+
+// Percent returns the change relative to the baseline as a percentage. A
+// trace that took no time in the baseline is infinitely slower unless it
+// still takes no time.
+func (c DurationChange) Percent() float64 {
+	if c.Baseline == 0 {
+		if c.Current == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	return float64(c.Current-c.Baseline) / float64(c.Baseline) * 100
+}
+
+// DurationChanges compares the duration of every trace in the first set
+// with the matching trace, by identifier attribute, in each other set.
+// Traces that only exist on one side are not included.
+func DurationChanges(traceSets []TraceSet, attribute string) []DurationChange {
+	if len(traceSets) < 2 {
+		return nil
+	}
+
+	baseline := traceIndex(traceSets[0], attribute)
+	var names []string
+	for name := range baseline {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var changes []DurationChange
+	for _, set := range traceSets[1:] {
+		current := traceIndex(set, attribute)
+		for _, name := range names {
+			t, ok := current[name]
+			if !ok {
+				continue
+			}
+			changes = append(changes, DurationChange{
+				Name:     name,
+				File:     getFileNameWithoutExt(set.Name),
+				Baseline: getTraceDuration(*baseline[name]),
+				Current:  getTraceDuration(*t),
+			})
+		}
+	}
+	return changes
+}
+
+// GroupDurationChanges compares the p90 duration of every trace group in
+// the first set, grouping traces by identifier attribute as GroupTraces
+// does, with the matching group in each other set. Groups that only exist
+// on one side are not included.
+func GroupDurationChanges(traceSets []TraceSet, attribute string) []DurationChange {
+	if len(traceSets) < 2 {
+		return nil
+	}
+
+	baseline := GroupTraces(traceSets[0].Traces, attribute)
+	var changes []DurationChange
+	for _, set := range traceSets[1:] {
+		current := make(map[string]TraceGroup)
+		for _, g := range GroupTraces(set.Traces, attribute) {
+			current[g.Name] = g
+		}
+		for _, g := range baseline {
+			c, ok := current[g.Name]
+			if !ok {
+				continue
+			}
+			changes = append(changes, DurationChange{
+				Name:     g.Name,
+				File:     getFileNameWithoutExt(set.Name),
+				Baseline: g.P90,
+				Current:  c.P90,
+			})
+		}
+	}
+	return changes
+}
+
+// DetectRegressions returns the matching traces whose duration grew by more
+// than threshold percent compared to the first set
+func DetectRegressions(traceSets []TraceSet, attribute string, threshold float64) []DurationChange {
+	return regressions(DurationChanges(traceSets, attribute), threshold)
+}
+
+// DetectGroupRegressions returns the trace groups whose p90 duration grew by
+// more than threshold percent compared to the first set
+func DetectGroupRegressions(traceSets []TraceSet, attribute string, threshold float64) []DurationChange {
+	return regressions(GroupDurationChanges(traceSets, attribute), threshold)
+}
+
+// regressions returns the changes that grew by more than threshold percent
+func regressions(changes []DurationChange, threshold float64) []DurationChange {
+	var slower []DurationChange
+	for _, change := range changes {
+		if change.Percent() > threshold {
+			slower = append(slower, change)
+		}
+	}
+	return slower
+}
+
+// Verdict configures the one-line pass/fail verdict at the top of a
+// comparison
+type Verdict struct {
+	// Threshold is the percentage a trace may get slower than in the first
+	// file before it counts as a regression
+	Threshold float64
+
+	// PassEmoji and FailEmoji prefix the verdict. They default to the
+	// passing and failing indicators, ✅ and ❌ unless NoEmoji is set.
+	PassEmoji string
+	FailEmoji string
+}
+
+// verdictLine returns the verdict for the traces that regressed by more
+// than the threshold, or for the trace groups when aggregateBy is set.
+// Traces regressing in several files count once.
+func verdictLine(traceSets []TraceSet, attribute, aggregateBy string, v Verdict, ind indicators) string {
+	noun := "trace"
+	changes := DetectRegressions(traceSets, attribute, v.Threshold)
+	if aggregateBy != "" {
+		noun = "trace group"
+		changes = DetectGroupRegressions(traceSets, aggregateBy, v.Threshold)
+	}
+
+	regressed := make(map[string]bool)
+	for _, r := range changes {
+		regressed[r.Name] = true
+	}
+
+	if len(regressed) == 0 {
+		emoji := v.PassEmoji
+		if emoji == "" {
+			emoji = ind.passing
+		}
+		return fmt.Sprintf("%s No regressions", emoji)
+	}
+
+	emoji := v.FailEmoji
+	if emoji == "" {
+		emoji = ind.failing
+	}
+	if len(regressed) != 1 {
+		noun += "s"
+	}
+	return fmt.Sprintf("%s %d %s slower than threshold (%.1f%%)", emoji, len(regressed), noun, v.Threshold)
+}
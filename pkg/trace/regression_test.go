@@ -0,0 +1,193 @@
+package trace
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDetectRegressions(t *testing.T) {
+	now := time.Now()
+	newTrace := func(id string, d time.Duration) Trace {
+		return Trace{TraceID: id, Spans: []Span{{Name: "root", StartTime: now, EndTime: now.Add(d)}}}
+	}
+	traceSets := []TraceSet{
+		{Name: "base.json", Traces: []Trace{
+			newTrace("slower", 100*time.Millisecond),
+			newTrace("within", 100*time.Millisecond),
+			newTrace("faster", 100*time.Millisecond),
+			newTrace("removed", 100*time.Millisecond),
+		}},
+		{Name: "head.json", Traces: []Trace{
+			newTrace("slower", 150*time.Millisecond),
+			newTrace("within", 105*time.Millisecond),
+			newTrace("faster", 50*time.Millisecond),
+			newTrace("added", 10*time.Second),
+		}},
+	}
+
+	regressions := DetectRegressions(traceSets, "trace_id", 10)
+	if len(regressions) != 1 {
+		t.Fatalf("DetectRegressions() returned %d regressions, want 1: %v", len(regressions), regressions)
+	}
+	got := regressions[0]
+	if got.Name != "slower" || got.File != "head" || got.Percent() != 50 {
+		t.Errorf("DetectRegressions() = %+v (%.1f%%), want slower in head at 50%%", got, got.Percent())
+	}
+
+	if got := DetectRegressions(traceSets, "trace_id", 1); len(got) != 2 {
+		t.Errorf("DetectRegressions() with 1%% threshold returned %d regressions, want 2", len(got))
+	}
+	if got := DetectRegressions(traceSets[:1], "trace_id", 10); len(got) != 0 {
+		t.Errorf("DetectRegressions() with a single set returned %d regressions, want 0", len(got))
+	}
+}
+
+func TestDurationChangePercent(t *testing.T) {
+	tests := []struct {
+		name     string
+		change   DurationChange
+		expected float64
+	}{
+		{name: "slower", change: DurationChange{Baseline: time.Second, Current: 2 * time.Second}, expected: 100},
+		{name: "faster", change: DurationChange{Baseline: time.Second, Current: 500 * time.Millisecond}, expected: -50},
+		{name: "zero to zero", change: DurationChange{}, expected: 0},
+		{name: "zero baseline", change: DurationChange{Current: time.Second}, expected: math.Inf(1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.change.Percent(); got != tt.expected {
+				t.Errorf("Percent() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCompareMultipleTracesVerdict(t *testing.T) {
+	now := time.Now()
+	newSet := func(name string, durations ...time.Duration) TraceSet {
+		set := TraceSet{Name: name}
+		for i, d := range durations {
+			set.Traces = append(set.Traces, Trace{TraceID: fmt.Sprintf("trace%d", i), Spans: []Span{
+				{SpanID: "a", Name: "root", StartTime: now, EndTime: now.Add(d)},
+			}})
+		}
+		return set
+	}
+	base := newSet("base.json", time.Second, time.Second, time.Second)
+	head := newSet("head.json", 1050*time.Millisecond, 1200*time.Millisecond, 1500*time.Millisecond)
+	other := newSet("other.json", time.Second, 1200*time.Millisecond, time.Second)
+
+	tests := []struct {
+		name     string
+		sets     []TraceSet
+		verdict  *Verdict
+		expected string
+	}{
+		{
+			name:     "no verdict",
+			sets:     []TraceSet{base, head},
+			expected: "### Multiple Traces Comparison\n\n**Comparison Summary:**",
+		},
+		{
+			name:     "pass",
+			sets:     []TraceSet{base, base},
+			verdict:  &Verdict{Threshold: 10},
+			expected: "### Multiple Traces Comparison\n\n**✅ No regressions**\n\n",
+		},
+		{
+			name:     "fail counts each trace once",
+			sets:     []TraceSet{base, head, other},
+			verdict:  &Verdict{Threshold: 10},
+			expected: "**❌ 2 traces slower than threshold (10.0%)**",
+		},
+		{
+			name:     "single trace",
+			sets:     []TraceSet{base, head},
+			verdict:  &Verdict{Threshold: 30},
+			expected: "**❌ 1 trace slower than threshold (30.0%)**",
+		},
+		{
+			name:     "custom emoji",
+			sets:     []TraceSet{base, head},
+			verdict:  &Verdict{Threshold: 100, PassEmoji: "🟢", FailEmoji: "🔴"},
+			expected: "**🟢 No regressions**",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CompareMultipleTraces(tt.sets, "trace_id", Options{Verdict: tt.verdict})
+			if !strings.Contains(got, tt.expected) {
+				t.Errorf("CompareMultipleTraces() output does not contain %q:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDetectGroupRegressions(t *testing.T) {
+	now := time.Now()
+	newSet := func(name string, durations ...time.Duration) TraceSet {
+		set := TraceSet{Name: name}
+		for i, d := range durations {
+			set.Traces = append(set.Traces, Trace{TraceID: fmt.Sprintf("trace%d", i), Spans: []Span{
+				{SpanID: "a", Name: "GET /orders", StartTime: now, EndTime: now.Add(d)},
+			}})
+		}
+		return set
+	}
+	repeat := func(d time.Duration, n int) []time.Duration {
+		durations := make([]time.Duration, n)
+		for i := range durations {
+			durations[i] = d
+		}
+		return durations
+	}
+
+	// One slow request out of twenty regresses that trace but not the p90
+	base := newSet("base.json", repeat(time.Second, 20)...)
+	spike := newSet("head.json", append([]time.Duration{3 * time.Second}, repeat(time.Second, 19)...)...)
+	slower := newSet("head.json", repeat(1200*time.Millisecond, 20)...)
+
+	tests := []struct {
+		name          string
+		sets          []TraceSet
+		wantTraces    int
+		wantGroups    int
+		wantAggregate string
+	}{
+		{
+			name:          "single slow trace",
+			sets:          []TraceSet{base, spike},
+			wantTraces:    1,
+			wantGroups:    0,
+			wantAggregate: "**✅ No regressions**",
+		},
+		{
+			name:          "whole group slower",
+			sets:          []TraceSet{base, slower},
+			wantTraces:    20,
+			wantGroups:    1,
+			wantAggregate: "**❌ 1 trace group slower than threshold (10.0%)**",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectRegressions(tt.sets, "trace_id", 10); len(got) != tt.wantTraces {
+				t.Errorf("DetectRegressions() returned %d regressions, want %d", len(got), tt.wantTraces)
+			}
+			if got := DetectGroupRegressions(tt.sets, "name", 10); len(got) != tt.wantGroups {
+				t.Errorf("DetectGroupRegressions() returned %d regressions, want %d: %v", len(got), tt.wantGroups, got)
+			}
+
+			got := CompareMultipleTraces(tt.sets, "name", Options{AggregateBy: "name", Verdict: &Verdict{Threshold: 10}})
+			if !strings.Contains(got, tt.wantAggregate) {
+				t.Errorf("CompareMultipleTraces() output does not contain %q:\n%s", tt.wantAggregate, got)
+			}
+		})
+	}
+}
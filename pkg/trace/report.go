@@ -0,0 +1,126 @@
+package trace
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Report is a per-operation summary of a comparison between trace sets
+type Report struct {
+	Files      []string
+	Operations []OperationReport
+}
+
+// OperationReport holds the mean duration of an operation in each file.
+// Present reports whether the operation exists in the file at the same index.
+type OperationReport struct {
+	Name      string
+	Durations []time.Duration
+	Present   []bool
+}
+
+// BuildReport summarizes the mean duration of every operation (span name)
+// in each trace set. Operations are sorted by name.
+func BuildReport(traceSets []TraceSet) Report {
+	report := Report{}
+	operationDurations := make([]map[string][]time.Duration, len(traceSets))
+	allOperations := make(map[string]bool)
+	for i, set := range traceSets {
+		report.Files = append(report.Files, getFileNameWithoutExt(set.Name))
+		operationDurations[i] = OperationDurations(set.Traces)
+		for name := range operationDurations[i] {
+			allOperations[name] = true
+		}
+	}
+
+	var names []string
+	for name := range allOperations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		op := OperationReport{Name: name}
+		for i := range traceSets {
+			durations, ok := operationDurations[i][name]
+			op.Durations = append(op.Durations, meanDuration(durations))
+			op.Present = append(op.Present, ok)
+		}
+		report.Operations = append(report.Operations, op)
+	}
+
+	return report
+}
+
+// RegressionRatio returns the largest ratio between the operation's mean
+// duration in any other file and in the first file. It reports false when
+// the ratio is undefined because the operation is missing from the first
+// file, has a zero duration there or exists in no other file.
+func (o OperationReport) RegressionRatio() (float64, bool) {
+	if len(o.Durations) < 2 || !o.Present[0] || o.Durations[0] == 0 {
+		return 0, false
+	}
+
+	ratio, found := 0.0, false
+	for i := 1; i < len(o.Durations); i++ {
+		if !o.Present[i] {
+			continue
+		}
+		r := o.Durations[i].Seconds() / o.Durations[0].Seconds()
+		if !found || r > ratio {
+			ratio, found = r, true
+		}
+	}
+	return ratio, found
+}
+
+// GeneratePrometheus renders the report in the Prometheus text exposition
+// format. Only per-operation series are emitted to keep label cardinality
+// bounded by the number of operations and files.
+func GeneratePrometheus(r Report) string {
+	var sb strings.Builder
+
+	sb.WriteString("# HELP otelcompare_operation_duration_seconds Mean duration of the operation in each file.\n")
+	sb.WriteString("# TYPE otelcompare_operation_duration_seconds gauge\n")
+	for _, op := range r.Operations {
+		for i, file := range r.Files {
+			if !op.Present[i] {
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("otelcompare_operation_duration_seconds{operation=\"%s\",file=\"%s\"} %g\n",
+				escapeLabelValue(op.Name),
+				escapeLabelValue(file),
+				op.Durations[i].Seconds()))
+		}
+	}
+
+	sb.WriteString("# HELP otelcompare_regression_ratio Largest ratio of the operation's mean duration in another file to the first file.\n")
+	sb.WriteString("# TYPE otelcompare_regression_ratio gauge\n")
+	for _, op := range r.Operations {
+		if ratio, ok := op.RegressionRatio(); ok {
+			sb.WriteString(fmt.Sprintf("otelcompare_regression_ratio{operation=\"%s\"} %g\n",
+				escapeLabelValue(op.Name),
+				ratio))
+		}
+	}
+
+	return sb.String()
+}
+
+// escapeLabelValue escapes a Prometheus label value
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func meanDuration(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	return total / time.Duration(len(durations))
+}
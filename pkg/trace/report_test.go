@@ -0,0 +1,63 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildReport(t *testing.T) {
+	now := time.Now()
+	traceSets := []TraceSet{
+		{Name: "base.json", Traces: []Trace{
+			{Spans: []Span{{Name: "checkout", StartTime: now, EndTime: now.Add(100 * time.Millisecond)}}},
+			{Spans: []Span{{Name: "checkout", StartTime: now, EndTime: now.Add(300 * time.Millisecond)}}},
+		}},
+		{Name: "head.json", Traces: []Trace{
+			{Spans: []Span{
+				{Name: "checkout", StartTime: now, EndTime: now.Add(500 * time.Millisecond)},
+				{Name: "fraud.check", StartTime: now, EndTime: now.Add(time.Millisecond)},
+			}},
+		}},
+	}
+
+	report := BuildReport(traceSets)
+	if len(report.Operations) != 2 {
+		t.Fatalf("BuildReport() returned %d operations, want 2", len(report.Operations))
+	}
+
+	checkout := report.Operations[0]
+	if checkout.Durations[0] != 200*time.Millisecond || checkout.Durations[1] != 500*time.Millisecond {
+		t.Errorf("BuildReport() checkout durations = %v, want [200ms 500ms]", checkout.Durations)
+	}
+	if ratio, ok := checkout.RegressionRatio(); !ok || ratio != 2.5 {
+		t.Errorf("RegressionRatio() = %v, %v, want 2.5, true", ratio, ok)
+	}
+	if _, ok := report.Operations[1].RegressionRatio(); ok {
+		t.Errorf("RegressionRatio() should be undefined for an operation missing from the first file")
+	}
+}
+
+func TestGeneratePrometheus(t *testing.T) {
+	report := Report{
+		Files: []string{"base", "head"},
+		Operations: []OperationReport{
+			{
+				Name:      `GET "/users"`,
+				Durations: []time.Duration{400 * time.Millisecond, 520 * time.Millisecond},
+				Present:   []bool{true, true},
+			},
+		},
+	}
+
+	got := GeneratePrometheus(report)
+	for _, want := range []string{
+		`otelcompare_operation_duration_seconds{operation="GET \"/users\"",file="base"} 0.4`,
+		`otelcompare_operation_duration_seconds{operation="GET \"/users\"",file="head"} 0.52`,
+		`otelcompare_regression_ratio{operation="GET \"/users\""} 1.3`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("GeneratePrometheus() output does not contain %s\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package trace
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// mixedValue is shown for a resource attribute whose value differs between
+// the traces of a single set
+const mixedValue = "_(mixed)_"
+
+// SetResourceAttributes returns the resource attributes of a trace set.
+// Keys whose value differs between traces map to mixedValue, so a set
+// mixing several builds is easy to spot.
+func SetResourceAttributes(set TraceSet) map[string]string {
+	attrs := make(map[string]string)
+	for _, t := range set.Traces {
+		for k, v := range t.ResourceAttrs {
+			if existing, ok := attrs[k]; ok && existing != v {
+				attrs[k] = mixedValue
+				continue
+			}
+			attrs[k] = v
+		}
+	}
+	return attrs
+}
+
+// writeResourceComparison writes a table of the resource attributes of
+// every set, marking values that differ from the first set with ✏️, keys
+// missing from it with ➕ and keys missing from another set with ➖
+func writeResourceComparison(sb *strings.Builder, traceSets []TraceSet, ind indicators) {
+	setAttrs := make([]map[string]string, len(traceSets))
+	allKeys := make(map[string]bool)
+	for i, set := range traceSets {
+		setAttrs[i] = SetResourceAttributes(set)
+		for k := range setAttrs[i] {
+			allKeys[k] = true
+		}
+	}
+	if len(allKeys) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(allKeys))
+	for k := range allKeys {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	sb.WriteString("**Resource Attributes:**\n\n")
+	sb.WriteString("| Attribute |")
+	for _, set := range traceSets {
+		sb.WriteString(fmt.Sprintf(" %s |", escapeMarkdownCell(getFileNameWithoutExt(set.Name))))
+	}
+	sb.WriteString("\n|-----------")
+	for range traceSets {
+		sb.WriteString("|-----------")
+	}
+	sb.WriteString("|\n")
+
+	for _, key := range keys {
+		sb.WriteString(fmt.Sprintf("| %s |", escapeMarkdownCell(key)))
+		base, inBase := setAttrs[0][key]
+		for i, attrs := range setAttrs {
+			value, ok := attrs[key]
+			cell := escapeMarkdownCell(value)
+			switch {
+			case i == 0 || (ok && inBase && value == base):
+			case !ok:
+				cell = ind.removed
+			case !inBase:
+				cell = ind.added + " " + cell
+			default:
+				cell = ind.changed + " " + cell
+			}
+			sb.WriteString(fmt.Sprintf(" %s |", cell))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n")
+}
@@ -0,0 +1,52 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetResourceAttributes(t *testing.T) {
+	set := TraceSet{Traces: []Trace{
+		{TraceID: "t1", ResourceAttrs: map[string]string{"service.version": "1.0.0", "host.name": "a"}},
+		{TraceID: "t2", ResourceAttrs: map[string]string{"service.version": "1.0.0", "host.name": "b", "vcs.sha": "abc"}},
+	}}
+
+	got := SetResourceAttributes(set)
+	expected := map[string]string{"service.version": "1.0.0", "host.name": mixedValue, "vcs.sha": "abc"}
+	if len(got) != len(expected) {
+		t.Fatalf("SetResourceAttributes() = %v, want %v", got, expected)
+	}
+	for k, v := range expected {
+		if got[k] != v {
+			t.Errorf("SetResourceAttributes()[%q] = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestCompareMultipleTracesResourceAttributes(t *testing.T) {
+	traceSets := []TraceSet{
+		{Name: "main.json", Traces: []Trace{{TraceID: "t1", ResourceAttrs: map[string]string{
+			"deployment.environment": "prod", "service.version": "1.0.0", "vcs.sha": "abc",
+		}}}},
+		{Name: "pr.json", Traces: []Trace{{TraceID: "t1", ResourceAttrs: map[string]string{
+			"deployment.environment": "prod", "service.version": "1.1.0", "host.name": "ci-1",
+		}}}},
+	}
+
+	got := CompareMultipleTraces(traceSets, "trace_id", Options{})
+	expected := "**Resource Attributes:**\n\n" +
+		"| Attribute | main | pr |\n" +
+		"|-----------|-----------|-----------|\n" +
+		"| deployment.environment | prod | prod |\n" +
+		"| host.name |  | ➕ ci-1 |\n" +
+		"| service.version | 1.0.0 | ✏️ 1.1.0 |\n" +
+		"| vcs.sha | abc | ➖ |\n"
+	if !strings.Contains(got, expected) {
+		t.Errorf("CompareMultipleTraces() output does not contain the resource table:\n%s", got)
+	}
+
+	without := CompareMultipleTraces([]TraceSet{{Name: "a.json"}, {Name: "b.json"}}, "trace_id", Options{})
+	if strings.Contains(without, "Resource Attributes") {
+		t.Errorf("CompareMultipleTraces() shows an empty resource table:\n%s", without)
+	}
+}
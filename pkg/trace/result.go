@@ -0,0 +1,302 @@
+package trace
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ComparisonResult is the structured comparison of two sets of traces.
+// Warning is set when few traces match across the sets, see
+// Options.MinMatchFraction, and Hidden counts the traces left out by
+// Options.Top and Options.ChangesOnly.
+type ComparisonResult struct {
+	Matching     []TraceComparison `json:"matching"`
+	OnlyInFirst  []string          `json:"only_in_first"`
+	OnlyInSecond []string          `json:"only_in_second"`
+	Warning      string            `json:"warning,omitempty"`
+	Hidden       int               `json:"hidden,omitempty"`
+}
+
+// TraceComparison compares a trace present in both sets. Spans are
+// identified by spanKey, so repeated span names are told apart.
+type TraceComparison struct {
+	Name              string           `json:"name"`
+	FirstDuration     time.Duration    `json:"first_duration_ns"`
+	SecondDuration    time.Duration    `json:"second_duration_ns"`
+	Delta             time.Duration    `json:"delta_ns"`
+	Spans             []SpanComparison `json:"spans"`
+	SpansOnlyInFirst  []string         `json:"spans_only_in_first"`
+	SpansOnlyInSecond []string         `json:"spans_only_in_second"`
+}
+
+// SpanComparison compares a span present in both versions of a trace.
+// Offsets are how long after the start of its trace the span started, so a
+// span that waited longer before running can be told apart from one that
+// ran longer. SecondName is set when the span was matched approximately to
+// a span of another name, see Options.FuzzyMatch, and Section holds the
+// span's Options.SectionBy value.
+type SpanComparison struct {
+	Name           string        `json:"name"`
+	SecondName     string        `json:"second_name,omitempty"`
+	Section        string        `json:"section,omitempty"`
+	FirstOffset    time.Duration `json:"first_offset_ns"`
+	SecondOffset   time.Duration `json:"second_offset_ns"`
+	OffsetDelta    time.Duration `json:"offset_delta_ns"`
+	FirstDuration  time.Duration `json:"first_duration_ns"`
+	SecondDuration time.Duration `json:"second_duration_ns"`
+	Delta          time.Duration `json:"delta_ns"`
+	EventChanges   []EventChange `json:"event_changes,omitempty"`
+}
+
+// Percent returns the trace duration change relative to the first set, see
+// DurationChange.Percent
+func (c TraceComparison) Percent() float64 {
+	return DurationChange{Baseline: c.FirstDuration, Current: c.SecondDuration}.Percent()
+}
+
+// Percent returns the span duration change relative to the first set, see
+// DurationChange.Percent
+func (c SpanComparison) Percent() float64 {
+	return DurationChange{Baseline: c.FirstDuration, Current: c.SecondDuration}.Percent()
+}
+
+// Compare matches two sets of traces by the given identifier attribute and
+// compares the durations of the matching traces and of their spans. Spans
+// are matched by name and occurrence, see spanKey. See CompareSets for how
+// the options apply.
+func Compare(traces1, traces2 []Trace, attribute string, opts Options) ComparisonResult {
+	return CompareSets([]TraceSet{{Traces: traces1}, {Traces: traces2}}, attribute, opts)[0]
+}
+
+// CompareSets compares every trace set after the first with the first one.
+// Traces and spans are matched once across all sets, the way
+// CompareMultipleTraces matches them, so the results list the same traces
+// and spans as its report: matching traces are the ones found in every set,
+// Top and ChangesOnly leave out traces, SectionBy sets the section of spans,
+// NormalizeNames and FuzzyMatch match renamed spans, AggregateBy compares
+// the p90 durations of trace groups and of their spans instead of single
+// traces, and GroupBy compares the total duration of span groups.
+func CompareSets(traceSets []TraceSet, attribute string, opts Options) []ComparisonResult {
+	if len(traceSets) < 2 {
+		return nil
+	}
+	switch {
+	case opts.GroupBy != "":
+		return compareSpanGroups(traceSets, matchSpanGroups(traceSets, opts.GroupBy))
+	case opts.AggregateBy != "":
+		return compareTraceGroups(traceSets, matchTraceGroups(traceSets, opts.AggregateBy))
+	}
+
+	match := matchTraces(traceSets, attribute, opts)
+	identifier := strings.Join(strings.Split(attribute, ","), "`, `")
+	warning := matchProblem(match.traceMaps, match.allNames, identifier, opts.MinMatchFraction)
+
+	results := make([]ComparisonResult, 0, len(traceSets)-1)
+	for i := 1; i < len(traceSets); i++ {
+		result := newComparisonResult()
+		result.Warning = warning
+		result.Hidden = len(match.allNames) - len(match.names) + match.unchanged
+		for _, name := range match.names {
+			result.addUnmatched(name, match.traceMaps[0][name] != nil, match.traceMaps[i][name] != nil)
+		}
+		for _, t := range match.traces {
+			result.Matching = append(result.Matching, t.compare(match.traceMaps, i))
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// compareTraceGroups compares the p90 durations of the trace groups, and of
+// their spans, of every set after the first with the first one
+func compareTraceGroups(traceSets []TraceSet, m groupMatch) []ComparisonResult {
+	results := make([]ComparisonResult, 0, len(traceSets)-1)
+	for i := 1; i < len(traceSets); i++ {
+		result := newComparisonResult()
+		for _, name := range m.names {
+			_, inFirst := m.groups[0][name]
+			_, inSecond := m.groups[i][name]
+			result.addUnmatched(name, inFirst, inSecond)
+		}
+
+		for _, name := range m.matched {
+			c := newTraceComparison(name, m.groups[0][name].P90, m.groups[i][name].P90)
+			for _, ref := range m.spanRefs(name) {
+				s1, inFirst := m.spanStats[0][name][ref]
+				s2, inSecond := m.spanStats[i][name][ref]
+				switch {
+				case inFirst && inSecond:
+					c.Spans = append(c.Spans, SpanComparison{
+						Name:           ref.key(),
+						FirstDuration:  s1.P90,
+						SecondDuration: s2.P90,
+						Delta:          s2.P90 - s1.P90,
+					})
+				case inFirst:
+					c.SpansOnlyInFirst = append(c.SpansOnlyInFirst, ref.key())
+				case inSecond:
+					c.SpansOnlyInSecond = append(c.SpansOnlyInSecond, ref.key())
+				}
+			}
+			result.Matching = append(result.Matching, c)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// compareSpanGroups compares the total duration of the span groups of
+// every set after the first with the first one
+func compareSpanGroups(traceSets []TraceSet, m spanGroupMatch) []ComparisonResult {
+	results := make([]ComparisonResult, 0, len(traceSets)-1)
+	for i := 1; i < len(traceSets); i++ {
+		result := newComparisonResult()
+		for _, name := range m.names {
+			g1, inFirst := m.groups[0][name]
+			g2, inSecond := m.groups[i][name]
+			if inFirst && inSecond {
+				result.Matching = append(result.Matching, newTraceComparison(name, g1.Total, g2.Total))
+				continue
+			}
+			result.addUnmatched(name, inFirst, inSecond)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// newComparisonResult returns a result with empty lists, so they are
+// written as [] rather than null
+func newComparisonResult() ComparisonResult {
+	return ComparisonResult{
+		Matching:     []TraceComparison{},
+		OnlyInFirst:  []string{},
+		OnlyInSecond: []string{},
+	}
+}
+
+// addUnmatched lists a name found in only one of the sets. Names are added
+// in sorted order.
+func (r *ComparisonResult) addUnmatched(name string, inFirst, inSecond bool) {
+	switch {
+	case inFirst && !inSecond:
+		r.OnlyInFirst = append(r.OnlyInFirst, name)
+	case inSecond && !inFirst:
+		r.OnlyInSecond = append(r.OnlyInSecond, name)
+	}
+}
+
+// newTraceComparison returns the comparison of a trace's durations, with
+// empty span lists
+func newTraceComparison(name string, first, second time.Duration) TraceComparison {
+	return TraceComparison{
+		Name:              name,
+		FirstDuration:     first,
+		SecondDuration:    second,
+		Delta:             second - first,
+		Spans:             []SpanComparison{},
+		SpansOnlyInFirst:  []string{},
+		SpansOnlyInSecond: []string{},
+	}
+}
+
+// compare compares the matched trace in set i with the first set. Spans
+// are listed in the order of the match.
+func (t matchedTrace) compare(traceMaps []map[string]*Trace, i int) TraceComparison {
+	t1, t2 := traceMaps[0][t.name], traceMaps[i][t.name]
+	c := newTraceComparison(t.name, getTraceDuration(*t1), getTraceDuration(*t2))
+
+	for _, ref := range t.spans {
+		ref2 := ref
+		if alias, ok := t.aliases[i][ref.name]; ok {
+			ref2.name = alias
+		}
+		span1, inFirst := t.spanIndexes[0][ref]
+		span2, inSecond := t.spanIndexes[i][ref2]
+		switch {
+		case inFirst && inSecond:
+			o1 := spanOffset(*t1, *span1)
+			o2 := spanOffset(*t2, *span2)
+			d1 := spanDuration(*span1)
+			d2 := spanDuration(*span2)
+			span := SpanComparison{
+				Name:           ref.key(),
+				Section:        t.sections[ref],
+				FirstOffset:    o1,
+				SecondOffset:   o2,
+				OffsetDelta:    o2 - o1,
+				FirstDuration:  d1,
+				SecondDuration: d2,
+				Delta:          d2 - d1,
+				EventChanges:   DiffEvents(span1.Events, span2.Events),
+			}
+			if ref2 != ref {
+				span.SecondName = ref2.key()
+			}
+			c.Spans = append(c.Spans, span)
+		case inFirst:
+			c.SpansOnlyInFirst = append(c.SpansOnlyInFirst, ref.key())
+		case inSecond && !t.aliased[i][ref.name]:
+			// Spans matched to one of the first set are listed with it
+			c.SpansOnlyInSecond = append(c.SpansOnlyInSecond, ref.key())
+		}
+	}
+	return c
+}
+
+// spanRef identifies the n-th span (1-based, by start time) with a given
+// name in a trace, so repeated span names are compared by occurrence
+type spanRef struct {
+	name       string
+	occurrence int
+}
+
+// key returns the display name of the span. The first occurrence keeps the
+// plain name and later ones are numbered, e.g. "db.query #2".
+func (r spanRef) key() string {
+	if r.occurrence <= 1 {
+		return r.name
+	}
+	return fmt.Sprintf("%s #%d", r.name, r.occurrence)
+}
+
+// spanIndex maps the spans of a trace by spanRef
+func spanIndex(t *Trace) map[spanRef]*Span {
+	return spanIndexBy(t, nil)
+}
+
+// spanIndexBy maps the spans of a trace by spanRef, naming spans with
+// spanName when it is not nil
+func spanIndexBy(t *Trace, spanName func(string) string) map[spanRef]*Span {
+	spans := make([]*Span, len(t.Spans))
+	for i := range t.Spans {
+		spans[i] = &t.Spans[i]
+	}
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].StartTime.Before(spans[j].StartTime)
+	})
+
+	index := make(map[spanRef]*Span, len(spans))
+	occurrences := make(map[string]int)
+	for _, span := range spans {
+		name := span.Name
+		if spanName != nil {
+			name = spanName(name)
+		}
+		occurrences[name]++
+		index[spanRef{name: name, occurrence: occurrences[name]}] = span
+	}
+	return index
+}
+
+// sortSpanRefs sorts spans by name and then by occurrence
+func sortSpanRefs(refs []spanRef) {
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].name != refs[j].name {
+			return refs[i].name < refs[j].name
+		}
+		return refs[i].occurrence < refs[j].occurrence
+	})
+}
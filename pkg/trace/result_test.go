@@ -0,0 +1,234 @@
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompare(t *testing.T) {
+	now := time.Now()
+	traces1 := []Trace{
+		{TraceID: "trace1", Spans: []Span{
+			{SpanID: "a", Name: "GET /users", StartTime: now, EndTime: now.Add(time.Second)},
+			{SpanID: "b", ParentSpanID: "a", Name: "select", StartTime: now, EndTime: now.Add(200 * time.Millisecond)},
+		}},
+		{TraceID: "trace2", Spans: []Span{
+			{SpanID: "c", Name: "GET /orders", StartTime: now, EndTime: now.Add(time.Second)},
+		}},
+	}
+	traces2 := []Trace{
+		{TraceID: "trace1", Spans: []Span{
+			{SpanID: "a", Name: "GET /users", StartTime: now, EndTime: now.Add(1500 * time.Millisecond)},
+			{SpanID: "b", ParentSpanID: "a", Name: "select", StartTime: now, EndTime: now.Add(100 * time.Millisecond)},
+		}},
+		{TraceID: "trace3", Spans: []Span{
+			{SpanID: "d", Name: "GET /items", StartTime: now, EndTime: now.Add(time.Second)},
+		}},
+	}
+
+	got := Compare(traces1, traces2, "trace_id", Options{})
+
+	if !reflect.DeepEqual(got.OnlyInFirst, []string{"trace2"}) {
+		t.Errorf("Compare() OnlyInFirst = %v, want [trace2]", got.OnlyInFirst)
+	}
+	if !reflect.DeepEqual(got.OnlyInSecond, []string{"trace3"}) {
+		t.Errorf("Compare() OnlyInSecond = %v, want [trace3]", got.OnlyInSecond)
+	}
+	if len(got.Matching) != 1 {
+		t.Fatalf("Compare() returned %d matching traces, want 1", len(got.Matching))
+	}
+
+	matching := got.Matching[0]
+	if matching.Name != "trace1" || matching.Delta != 500*time.Millisecond || matching.Percent() != 50 {
+		t.Errorf("Compare() matching trace = %+v, want trace1 500ms (50%%) slower", matching)
+	}
+	expected := []SpanComparison{
+		{Name: "GET /users", FirstDuration: time.Second, SecondDuration: 1500 * time.Millisecond, Delta: 500 * time.Millisecond},
+		{Name: "select", FirstDuration: 200 * time.Millisecond, SecondDuration: 100 * time.Millisecond, Delta: -100 * time.Millisecond},
+	}
+	if !reflect.DeepEqual(matching.Spans, expected) {
+		t.Errorf("Compare() spans = %+v, want %+v", matching.Spans, expected)
+	}
+}
+
+func TestComparisonResultJSON(t *testing.T) {
+	data, err := json.Marshal(Compare(nil, nil, "name", Options{}))
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	// Empty lists are encoded as arrays rather than null
+	want := `{"matching":[],"only_in_first":[],"only_in_second":[]}`
+	if string(data) != want {
+		t.Errorf("json.Marshal() = %s, want %s", data, want)
+	}
+}
+
+func TestCompareAddedAndRemovedSpans(t *testing.T) {
+	now := time.Now()
+	traces1 := []Trace{{TraceID: "trace1", Spans: []Span{
+		{SpanID: "a", Name: "GET /users", StartTime: now, EndTime: now.Add(time.Second)},
+		{SpanID: "b", ParentSpanID: "a", Name: "cache.get", StartTime: now, EndTime: now.Add(time.Millisecond)},
+		{SpanID: "c", ParentSpanID: "a", Name: "db.query", StartTime: now.Add(time.Millisecond), EndTime: now.Add(10 * time.Millisecond)},
+	}}}
+	traces2 := []Trace{{TraceID: "trace1", Spans: []Span{
+		{SpanID: "a", Name: "GET /users", StartTime: now, EndTime: now.Add(time.Second)},
+		{SpanID: "c", ParentSpanID: "a", Name: "db.query", StartTime: now.Add(time.Millisecond), EndTime: now.Add(10 * time.Millisecond)},
+		{SpanID: "d", ParentSpanID: "a", Name: "db.query", StartTime: now.Add(20 * time.Millisecond), EndTime: now.Add(30 * time.Millisecond)},
+	}}}
+
+	got := Compare(traces1, traces2, "trace_id", Options{})
+	if len(got.Matching) != 1 {
+		t.Fatalf("Compare() returned %d matching traces, want 1", len(got.Matching))
+	}
+
+	matching := got.Matching[0]
+	if !reflect.DeepEqual(matching.SpansOnlyInFirst, []string{"cache.get"}) {
+		t.Errorf("Compare() SpansOnlyInFirst = %v, want [cache.get]", matching.SpansOnlyInFirst)
+	}
+	// The second db.query must not clobber the first one
+	if !reflect.DeepEqual(matching.SpansOnlyInSecond, []string{"db.query #2"}) {
+		t.Errorf("Compare() SpansOnlyInSecond = %v, want [db.query #2]", matching.SpansOnlyInSecond)
+	}
+	if len(matching.Spans) != 2 {
+		t.Errorf("Compare() compared %d spans, want 2: %+v", len(matching.Spans), matching.Spans)
+	}
+
+	markdown := CompareTraces(traces1, traces2)
+	for _, want := range []string{"**Spans Only in First File:**\n\n- cache.get\n", "**Spans Only in Second File:**\n\n- db.query #2\n"} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("CompareTraces() output does not contain %q:\n%s", want, markdown)
+		}
+	}
+}
+
+func TestCompareOptions(t *testing.T) {
+	now := time.Now()
+	newTrace := func(id, service string, d time.Duration, spanNames ...string) Trace {
+		tr := Trace{TraceID: id, ResourceAttrs: Attributes{"service.name": service}, Spans: []Span{
+			{SpanID: id + "-root", Name: "checkout", StartTime: now, EndTime: now.Add(d)},
+		}}
+		for i, name := range spanNames {
+			tr.Spans = append(tr.Spans, Span{SpanID: fmt.Sprintf("%s-%d", id, i), ParentSpanID: id + "-root", Name: name, StartTime: now, EndTime: now.Add(d / 2)})
+		}
+		return tr
+	}
+	traces1 := []Trace{
+		newTrace("t1", "api", time.Second, "process-job-12", "SELECT users"),
+		newTrace("t2", "api", 3*time.Second, "process-job-13", "SELECT users"),
+	}
+	traces2 := []Trace{
+		newTrace("t1", "api", 2*time.Second, "process-job-98", "SELECT user"),
+		newTrace("t2", "api", 4*time.Second, "process-job-99", "SELECT user"),
+	}
+
+	tests := []struct {
+		name     string
+		opts     Options
+		expected []string
+	}{
+		{
+			name:     "exact names",
+			expected: []string{"t1 1.00s → 2.00s: checkout", "t2 3.00s → 4.00s: checkout"},
+		},
+		{
+			name:     "normalized and fuzzy names",
+			opts:     Options{NormalizeNames: regexp.MustCompile(DefaultNormalizePattern), FuzzyMatch: 0.8},
+			expected: []string{"t1 1.00s → 2.00s: SELECT users ≈ SELECT user, checkout, process-job", "t2 3.00s → 4.00s: SELECT users ≈ SELECT user, checkout, process-job"},
+		},
+		{
+			name:     "aggregate",
+			opts:     Options{AggregateBy: "name"},
+			expected: []string{"checkout 3.00s → 4.00s: checkout"},
+		},
+		{
+			name:     "group by",
+			opts:     Options{GroupBy: "service.name"},
+			expected: []string{"api 8.00s → 12.00s: "},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attribute := "trace_id"
+			if tt.opts.AggregateBy != "" {
+				attribute = tt.opts.AggregateBy
+			}
+			var got []string
+			for _, c := range Compare(traces1, traces2, attribute, tt.opts).Matching {
+				var spans []string
+				for _, span := range c.Spans {
+					if span.SecondName != "" {
+						spans = append(spans, span.Name+" ≈ "+span.SecondName)
+						continue
+					}
+					spans = append(spans, span.Name)
+				}
+				got = append(got, fmt.Sprintf("%s %s → %s: %s", c.Name, formatDuration(c.FirstDuration), formatDuration(c.SecondDuration), strings.Join(spans, ", ")))
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Compare() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestComparisonPercent(t *testing.T) {
+	tests := []struct {
+		name          string
+		first, second time.Duration
+		expected      float64
+	}{
+		{name: "slower", first: time.Second, second: 1500 * time.Millisecond, expected: 50},
+		{name: "both zero", expected: 0},
+		{name: "from zero", second: time.Second, expected: math.Inf(1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trace := TraceComparison{FirstDuration: tt.first, SecondDuration: tt.second, Delta: tt.second - tt.first}
+			span := SpanComparison{FirstDuration: tt.first, SecondDuration: tt.second, Delta: tt.second - tt.first}
+			if got := trace.Percent(); got != tt.expected {
+				t.Errorf("TraceComparison.Percent() = %v, want %v", got, tt.expected)
+			}
+			if got := span.Percent(); got != tt.expected {
+				t.Errorf("SpanComparison.Percent() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCompareSetsWarning(t *testing.T) {
+	now := time.Now()
+	newTrace := func(id string) Trace {
+		return Trace{TraceID: id, Spans: []Span{{SpanID: id, Name: "root", StartTime: now, EndTime: now.Add(time.Second)}}}
+	}
+	traceSets := []TraceSet{
+		{Name: "base.json", Traces: []Trace{newTrace("t1"), newTrace("t2")}},
+		{Name: "head.json", Traces: []Trace{newTrace("t1"), newTrace("t3")}},
+	}
+
+	tests := []struct {
+		name        string
+		minFraction float64
+		expected    string
+	}{
+		{name: "enough matches", minFraction: 0.3, expected: ""},
+		{name: "too few matches", minFraction: 0.5, expected: "Only 1 of 3 traces (33.3%) match across the files by `trace_id`"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := CompareSets(traceSets, "trace_id", Options{MinMatchFraction: tt.minFraction})
+			if len(results) != 1 || results[0].Warning != tt.expected {
+				t.Errorf("CompareSets() = %+v, want warning %q", results, tt.expected)
+			}
+		})
+	}
+}
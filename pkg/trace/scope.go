@@ -0,0 +1,73 @@
+package trace
+
+import "fmt"
+
+// InstrumentationScope identifies the instrumentation library that created
+// a span, such as an OpenTelemetry SDK or contrib package
+type InstrumentationScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// String formats the scope as its name followed by its version, if any
+func (s InstrumentationScope) String() string {
+	if s.Version == "" {
+		return s.Name
+	}
+	return s.Name + " " + s.Version
+}
+
+// spanScope returns the instrumentation scope of a span. Spans without one
+// fall back to the otel.scope.name and otel.scope.version attributes, or
+// their deprecated otel.library equivalents, which exporters such as Zipkin
+// record instead.
+func spanScope(span Span) InstrumentationScope {
+	if span.Scope.Name != "" {
+		return span.Scope
+	}
+	if name, ok := span.Attributes["otel.scope.name"]; ok {
+		return InstrumentationScope{Name: name, Version: span.Attributes["otel.scope.version"]}
+	}
+	return InstrumentationScope{Name: span.Attributes["otel.library.name"], Version: span.Attributes["otel.library.version"]}
+}
+
+// hasScopes reports whether any span of the traces has an instrumentation
+// scope
+func hasScopes(traces []Trace) bool {
+	for _, t := range traces {
+		for _, span := range t.Spans {
+			if spanScope(span).Name != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FilterByScope returns the traces keeping only the spans created by the
+// instrumentation scope with this name. Children of dropped spans are
+// re-parented to their nearest kept ancestor. The input traces are not
+// modified.
+func FilterByScope(traces []Trace, name string) []Trace {
+	return filterSpans(traces, func(t *Trace, span *Span) bool {
+		return spanScope(*span).Name == name
+	})
+}
+
+// scopeChange describes how the instrumentation scope of a span changed,
+// such as an SDK upgrade, or returns "" when it did not
+func scopeChange(base, span Span, ind indicators) string {
+	from, to := spanScope(base), spanScope(span)
+	if from == to {
+		return ""
+	}
+	return fmt.Sprintf("%s scope %s → %s", ind.scope, formatScope(from), formatScope(to))
+}
+
+// formatScope returns the scope for a table cell
+func formatScope(scope InstrumentationScope) string {
+	if scope.Name == "" {
+		return "-"
+	}
+	return escapeMarkdownCell(scope.String())
+}
@@ -0,0 +1,105 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSpanScope(t *testing.T) {
+	tests := []struct {
+		name     string
+		span     Span
+		expected InstrumentationScope
+	}{
+		{name: "field", span: Span{Scope: InstrumentationScope{Name: "net/http", Version: "0.49.0"}}, expected: InstrumentationScope{Name: "net/http", Version: "0.49.0"}},
+		{name: "scope attributes", span: Span{Attributes: map[string]string{"otel.scope.name": "pg", "otel.scope.version": "1.2.0"}}, expected: InstrumentationScope{Name: "pg", Version: "1.2.0"}},
+		{name: "library attributes", span: Span{Attributes: map[string]string{"otel.library.name": "pg"}}, expected: InstrumentationScope{Name: "pg"}},
+		{name: "none", span: Span{}, expected: InstrumentationScope{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := spanScope(tt.span); got != tt.expected {
+				t.Errorf("spanScope() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseTracesScope(t *testing.T) {
+	input := []byte(`[{"trace_id": "trace1", "spans": [
+		{"span_id": "a", "name": "GET /users", "scope": {"name": "net/http", "version": "0.49.0"}}
+	]}]`)
+
+	traces, err := ParseTraces(input)
+	if err != nil {
+		t.Fatalf("ParseTraces() error = %v", err)
+	}
+	if got, want := traces[0].Spans[0].Scope, (InstrumentationScope{Name: "net/http", Version: "0.49.0"}); got != want {
+		t.Errorf("span scope = %+v, want %+v", got, want)
+	}
+}
+
+func TestFilterByScope(t *testing.T) {
+	httpScope := InstrumentationScope{Name: "net/http"}
+	traces := []Trace{
+		{TraceID: "trace1", Spans: []Span{
+			{SpanID: "a", Name: "GET /users", Scope: httpScope},
+			{SpanID: "b", ParentSpanID: "a", Name: "handler"},
+			{SpanID: "c", ParentSpanID: "b", Name: "GET /profile", Scope: httpScope},
+		}},
+		{TraceID: "trace2", Spans: []Span{{SpanID: "d", Name: "cron"}}},
+	}
+
+	got := FilterByScope(traces, "net/http")
+	if len(got) != 1 || len(got[0].Spans) != 2 {
+		t.Fatalf("FilterByScope() = %+v, want the two net/http spans of trace1", got)
+	}
+	if got[0].Spans[1].ParentSpanID != "a" {
+		t.Errorf("GET /profile parent = %q, want it re-parented to a", got[0].Spans[1].ParentSpanID)
+	}
+	if len(traces[0].Spans) != 3 {
+		t.Errorf("FilterByScope() modified its input")
+	}
+}
+
+func TestGenerateMarkdownScope(t *testing.T) {
+	now := time.Now()
+	traces := []Trace{{TraceID: "trace1", Spans: []Span{
+		{SpanID: "a", Name: "GET /users", StartTime: now, EndTime: now.Add(time.Second), Scope: InstrumentationScope{Name: "net/http", Version: "0.49.0"}},
+		{SpanID: "b", ParentSpanID: "a", Name: "handler", StartTime: now, EndTime: now.Add(time.Millisecond)},
+	}}}
+
+	got := GenerateMarkdown(traces, Options{})
+	for _, want := range []string{
+		"| Trace ID | Span ID | Span Name | Kind | Scope | Duration | Parent |\n",
+		"| `trace1` | `a` | GET /users | - | net/http 0.49.0 | 1.00s | root |\n",
+		"| `trace1` | `b` | handler | - | - | 1.00ms | GET /users |\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("GenerateMarkdown() output does not contain %q:\n%s", want, got)
+		}
+	}
+
+	traces[0].Spans[0].Scope = InstrumentationScope{}
+	if got := GenerateMarkdown(traces, Options{}); strings.Contains(got, "| Scope |") {
+		t.Errorf("GenerateMarkdown() shows a Scope column without scopes:\n%s", got)
+	}
+}
+
+func TestCompareMultipleTracesScopeChange(t *testing.T) {
+	now := time.Now()
+	newSet := func(name, version string) TraceSet {
+		return TraceSet{Name: name, Traces: []Trace{{TraceID: "trace1", Spans: []Span{
+			{Name: "GET /users", StartTime: now, EndTime: now.Add(time.Second), Scope: InstrumentationScope{Name: "net/http", Version: version}},
+		}}}}
+	}
+	traceSets := []TraceSet{newSet("base.json", "0.49.0"), newSet("head.json", "0.50.0")}
+
+	got := CompareMultipleTraces(traceSets, "trace_id", Options{})
+	want := "| GET /users | 1.00s | 1.00s | - | 📦 scope net/http 0.49.0 → net/http 0.50.0 |"
+	if !strings.Contains(got, want) {
+		t.Errorf("CompareMultipleTraces() output does not contain %q:\n%s", want, got)
+	}
+}
@@ -0,0 +1,135 @@
+package trace
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SpanStat is the time spent in spans of one name within a trace
+type SpanStat struct {
+	Name string `json:"name"`
+
+	// Count is the number of spans with this name
+	Count int `json:"count"`
+
+	// SelfTime is the time spent in these spans outside of their children
+	SelfTime time.Duration `json:"self_time_ns"`
+
+	// TotalTime is the summed duration of these spans, children included
+	TotalTime time.Duration `json:"total_time_ns"`
+}
+
+// SelfTimeBreakdown returns the self time of every span name in a trace,
+// sorted by self time in descending order. A span's self time is its
+// duration minus the time covered by its children. Overlapping children are
+// merged before subtracting, so concurrent work is only subtracted once, and
+// children are clipped to the parent's window so self time is never negative.
+func SelfTimeBreakdown(t Trace) []SpanStat {
+	children := make(map[string][]Span)
+	for _, span := range t.Spans {
+		if span.ParentSpanID != "" && span.ParentSpanID != span.SpanID {
+			children[span.ParentSpanID] = append(children[span.ParentSpanID], span)
+		}
+	}
+
+	stats := make(map[string]*SpanStat)
+	var order []string
+	for _, span := range t.Spans {
+		stat, ok := stats[span.Name]
+		if !ok {
+			stat = &SpanStat{Name: span.Name}
+			stats[span.Name] = stat
+			order = append(order, span.Name)
+		}
+		duration := spanDuration(span)
+		stat.Count++
+		stat.TotalTime += duration
+		stat.SelfTime += duration - coveredTime(span, children[span.SpanID])
+	}
+
+	result := make([]SpanStat, 0, len(order))
+	for _, name := range order {
+		result = append(result, *stats[name])
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].SelfTime != result[j].SelfTime {
+			return result[i].SelfTime > result[j].SelfTime
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// coveredTime returns how much of the parent's window is covered by at
+// least one child
+func coveredTime(parent Span, children []Span) time.Duration {
+	type interval struct{ start, end time.Time }
+	var intervals []interval
+	for _, child := range children {
+		start, end := child.StartTime, child.EndTime
+		if start.Before(parent.StartTime) {
+			start = parent.StartTime
+		}
+		if end.After(parent.EndTime) {
+			end = parent.EndTime
+		}
+		if end.After(start) {
+			intervals = append(intervals, interval{start, end})
+		}
+	}
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].start.Before(intervals[j].start)
+	})
+
+	var covered time.Duration
+	var current *interval
+	for i := range intervals {
+		next := intervals[i]
+		if current != nil && !next.start.After(current.end) {
+			if next.end.After(current.end) {
+				current.end = next.end
+			}
+			continue
+		}
+		if current != nil {
+			covered += current.end.Sub(current.start)
+		}
+		current = &next
+	}
+	if current != nil {
+		covered += current.end.Sub(current.start)
+	}
+	return covered
+}
+
+// writeSelfTime writes the self time breakdown of a trace as a table
+func writeSelfTime(sb *strings.Builder, t Trace) {
+	stats := SelfTimeBreakdown(t)
+	if len(stats) == 0 {
+		return
+	}
+
+	var total time.Duration
+	for _, stat := range stats {
+		total += stat.SelfTime
+	}
+
+	sb.WriteString("**Self Time:**\n\n")
+	sb.WriteString("| Span Name | Count | Self Time | Self % | Total Time |\n")
+	sb.WriteString("|-----------|-------|-----------|--------|------------|\n")
+	for _, stat := range stats {
+		share := 0.0
+		if total > 0 {
+			share = float64(stat.SelfTime) / float64(total) * 100
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %d | %s | %.1f%% | %s |\n",
+			escapeMarkdownCell(stat.Name),
+			stat.Count,
+			formatDuration(stat.SelfTime),
+			share,
+			formatDuration(stat.TotalTime)))
+	}
+	sb.WriteString("\n")
+}
@@ -0,0 +1,92 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSelfTimeBreakdown(t *testing.T) {
+	now := time.Now()
+	at := func(ms int) time.Time { return now.Add(time.Duration(ms) * time.Millisecond) }
+
+	tests := []struct {
+		name  string
+		spans []Span
+		want  []SpanStat
+	}{
+		{
+			name: "sequential children",
+			spans: []Span{
+				{SpanID: "root", Name: "handler", StartTime: at(0), EndTime: at(100)},
+				{SpanID: "a", ParentSpanID: "root", Name: "db", StartTime: at(10), EndTime: at(40)},
+				{SpanID: "b", ParentSpanID: "root", Name: "db", StartTime: at(50), EndTime: at(70)},
+			},
+			want: []SpanStat{
+				{Name: "db", Count: 2, SelfTime: 50 * time.Millisecond, TotalTime: 50 * time.Millisecond},
+				{Name: "handler", Count: 1, SelfTime: 50 * time.Millisecond, TotalTime: 100 * time.Millisecond},
+			},
+		},
+		{
+			name: "overlapping children subtracted once",
+			spans: []Span{
+				{SpanID: "root", Name: "handler", StartTime: at(0), EndTime: at(100)},
+				{SpanID: "a", ParentSpanID: "root", Name: "fetch", StartTime: at(0), EndTime: at(60)},
+				{SpanID: "b", ParentSpanID: "root", Name: "fetch", StartTime: at(20), EndTime: at(80)},
+			},
+			want: []SpanStat{
+				{Name: "fetch", Count: 2, SelfTime: 120 * time.Millisecond, TotalTime: 120 * time.Millisecond},
+				{Name: "handler", Count: 1, SelfTime: 20 * time.Millisecond, TotalTime: 100 * time.Millisecond},
+			},
+		},
+		{
+			name: "children outside the parent are clipped",
+			spans: []Span{
+				{SpanID: "root", Name: "handler", StartTime: at(0), EndTime: at(100)},
+				{SpanID: "a", ParentSpanID: "root", Name: "async", StartTime: at(90), EndTime: at(200)},
+			},
+			want: []SpanStat{
+				{Name: "async", Count: 1, SelfTime: 110 * time.Millisecond, TotalTime: 110 * time.Millisecond},
+				{Name: "handler", Count: 1, SelfTime: 90 * time.Millisecond, TotalTime: 100 * time.Millisecond},
+			},
+		},
+		{
+			name:  "empty trace",
+			spans: nil,
+			want:  []SpanStat{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SelfTimeBreakdown(Trace{TraceID: "trace1", Spans: tt.spans})
+			if len(got) != len(tt.want) {
+				t.Fatalf("SelfTimeBreakdown() = %+v, want %+v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("SelfTimeBreakdown()[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateMarkdownSelfTime(t *testing.T) {
+	now := time.Now()
+	traces := []Trace{{TraceID: "trace1", Spans: []Span{
+		{SpanID: "root", Name: "handler", StartTime: now, EndTime: now.Add(100 * time.Millisecond)},
+		{SpanID: "a", ParentSpanID: "root", Name: "db", StartTime: now, EndTime: now.Add(75 * time.Millisecond)},
+	}}}
+
+	got := GenerateMarkdown(traces, Options{})
+	for _, want := range []string{
+		"**Self Time:**",
+		"| db | 1 | 75.00ms | 75.0% | 75.00ms |",
+		"| handler | 1 | 25.00ms | 25.0% | 100.00ms |",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("GenerateMarkdown() output does not contain %q:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package trace
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CheckShape checks that parsed traces look like traces, catching input in
+// another format that decodes without errors but into empty traces. It
+// returns a warning for missing traces, trace IDs and spans, and for spans
+// without an ID, a name or valid timestamps.
+func CheckShape(traces []Trace) []string {
+	if len(traces) == 0 {
+		return []string{"no traces found; check that --format matches the input"}
+	}
+
+	var warnings []string
+	var empty int
+	for i, t := range traces {
+		name := fmt.Sprintf("trace %d", i+1)
+		if t.TraceID == "" {
+			warnings = append(warnings, fmt.Sprintf("%s has no trace_id", name))
+		} else {
+			name = fmt.Sprintf("trace %s", t.TraceID)
+		}
+		if len(t.Spans) == 0 {
+			warnings = append(warnings, fmt.Sprintf("%s has no spans", name))
+			empty++
+			continue
+		}
+
+		var problems []string
+		for _, check := range []struct {
+			problem string
+			failed  func(Span) bool
+		}{
+			{"no span_id", func(s Span) bool { return s.SpanID == "" }},
+			{"no name", func(s Span) bool { return s.Name == "" }},
+			{"no start_time", func(s Span) bool { return s.StartTime.IsZero() }},
+			{"no end_time", func(s Span) bool { return s.EndTime.IsZero() }},
+		} {
+			var count int
+			for _, span := range t.Spans {
+				if check.failed(span) {
+					count++
+				}
+			}
+			if count > 0 {
+				problems = append(problems, fmt.Sprintf("%d of %d spans have %s", count, len(t.Spans), check.problem))
+			}
+		}
+		if len(problems) > 0 {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", name, strings.Join(problems, ", ")))
+		}
+	}
+
+	if empty == len(traces) {
+		warnings = append(warnings, "none of the traces has spans; check that --format matches the input")
+	}
+	return warnings
+}
@@ -0,0 +1,62 @@
+package trace
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCheckShape(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		traces   []Trace
+		expected []string
+	}{
+		{
+			name: "valid",
+			traces: []Trace{{TraceID: "t1", Spans: []Span{
+				{SpanID: "a", Name: "root", StartTime: now, EndTime: now.Add(time.Second)},
+			}}},
+		},
+		{
+			name:     "no traces",
+			expected: []string{"no traces found; check that --format matches the input"},
+		},
+		{
+			name:   "another format decoded into empty traces",
+			traces: []Trace{{}},
+			expected: []string{
+				"trace 1 has no trace_id",
+				"trace 1 has no spans",
+				"none of the traces has spans; check that --format matches the input",
+			},
+		},
+		{
+			name: "some empty traces",
+			traces: []Trace{
+				{TraceID: "t1", Spans: []Span{{SpanID: "a", Name: "root", StartTime: now, EndTime: now}}},
+				{TraceID: "t2"},
+			},
+			expected: []string{"trace t2 has no spans"},
+		},
+		{
+			name: "incomplete spans",
+			traces: []Trace{{TraceID: "t1", Spans: []Span{
+				{SpanID: "a", Name: "root", StartTime: now, EndTime: now},
+				{Name: "child"},
+				{SpanID: "c", EndTime: now},
+			}}},
+			expected: []string{"trace t1: 1 of 3 spans have no span_id, 1 of 3 spans have no name, 2 of 3 spans have no start_time, 1 of 3 spans have no end_time"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CheckShape(tt.traces)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("CheckShape() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package trace
+
+import "sort"
+
+// Trace orders for Options.SortBy
+const (
+	SortByDuration  = "duration"
+	SortByName      = "name"
+	SortBySpanCount = "spancount"
+)
+
+// SortTraces returns a copy of traces in the given order: slowest first for
+// SortByDuration (and the empty order), by name for SortByName and with the
+// most spans first for SortBySpanCount. Ties are broken by trace ID so the
+// order is deterministic. The traces passed in are left untouched.
+func SortTraces(traces []Trace, order string) []Trace {
+	sorted := append([]Trace(nil), traces...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		switch order {
+		case SortByName:
+			if nameA, nameB := getTraceIdentifier(a, "name"), getTraceIdentifier(b, "name"); nameA != nameB {
+				return nameA < nameB
+			}
+		case SortBySpanCount:
+			if len(a.Spans) != len(b.Spans) {
+				return len(a.Spans) > len(b.Spans)
+			}
+		default:
+			if durationA, durationB := getTraceDuration(a), getTraceDuration(b); durationA != durationB {
+				return durationA > durationB
+			}
+		}
+		return a.TraceID < b.TraceID
+	})
+	return sorted
+}
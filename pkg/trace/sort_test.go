@@ -0,0 +1,70 @@
+package trace
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSortTraces(t *testing.T) {
+	now := time.Now()
+	newTrace := func(id, name string, d time.Duration, spans int) Trace {
+		tr := Trace{TraceID: id, Spans: []Span{{SpanID: id, Name: name, StartTime: now, EndTime: now.Add(d)}}}
+		for i := 1; i < spans; i++ {
+			tr.Spans = append(tr.Spans, Span{SpanID: id + "-child", ParentSpanID: id, Name: "child", StartTime: now, EndTime: now})
+		}
+		return tr
+	}
+	traces := []Trace{
+		newTrace("t1", "checkout", 200*time.Millisecond, 1),
+		newTrace("t2", "browse", time.Second, 2),
+		newTrace("t3", "login", 500*time.Millisecond, 3),
+		newTrace("t4", "browse", 200*time.Millisecond, 2),
+	}
+
+	tests := []struct {
+		name     string
+		order    string
+		expected []string
+	}{
+		{name: "default is duration", order: "", expected: []string{"t2", "t3", "t1", "t4"}},
+		{name: "duration", order: SortByDuration, expected: []string{"t2", "t3", "t1", "t4"}},
+		{name: "name", order: SortByName, expected: []string{"t2", "t4", "t1", "t3"}},
+		{name: "span count", order: SortBySpanCount, expected: []string{"t3", "t2", "t4", "t1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, tr := range SortTraces(traces, tt.order) {
+				got = append(got, tr.TraceID)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("SortTraces() = %v, want %v", got, tt.expected)
+			}
+			if traces[0].TraceID != "t1" || traces[3].TraceID != "t4" {
+				t.Errorf("SortTraces() reordered its input")
+			}
+		})
+	}
+}
+
+func TestGenerateMarkdownSortBy(t *testing.T) {
+	now := time.Now()
+	traces := []Trace{
+		{TraceID: "slow", Spans: []Span{{SpanID: "a", Name: "b-op", StartTime: now, EndTime: now.Add(time.Second)}}},
+		{TraceID: "fast", Spans: []Span{{SpanID: "b", Name: "a-op", StartTime: now, EndTime: now.Add(time.Millisecond)}}},
+	}
+
+	got := GenerateMarkdown(traces, Options{SortBy: SortByName})
+	if strings.Index(got, "| `fast` |") > strings.Index(got, "| `slow` |") {
+		t.Errorf("GenerateMarkdown() did not sort traces by name:\n%s", got)
+	}
+
+	// Top still keeps the slowest traces, whatever the order
+	got = GenerateMarkdown(traces, Options{SortBy: SortByName, Top: 1})
+	if !strings.Contains(got, "| `slow` |") || strings.Contains(got, "| `fast` |") {
+		t.Errorf("GenerateMarkdown() did not keep the slowest trace:\n%s", got)
+	}
+}
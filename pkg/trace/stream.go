@@ -0,0 +1,124 @@
+package trace
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// StreamTraces decodes traces from r one at a time and calls fn for each,
+// so the raw input never has to be held in memory. Input that starts with
+// an object rather than an array is read as NDJSON. Decoding stops at the
+// first error returned by fn.
+func StreamTraces(r io.Reader, fn func(Trace) error) error {
+	pr := newPositionReader(r)
+	br := bufio.NewReader(pr)
+	first, err := peekNonSpace(br)
+	if err == io.EOF {
+		return ErrEmptyInput
+	}
+	if err != nil {
+		return fmt.Errorf("error reading traces: %w", err)
+	}
+	if first == '{' {
+		return streamNDJSON(pr, br, fn)
+	}
+
+	// Decoder offsets are relative to the bytes it reads from br
+	base := pr.offset - int64(br.Buffered())
+	dec := json.NewDecoder(br)
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("error unmarshaling traces: %w", pr.locate(err, base))
+	}
+	if tok == nil {
+		return nil
+	}
+	if tok != json.Delim('[') {
+		return fmt.Errorf("error unmarshaling traces: expected an array of traces, got %v", tok)
+	}
+
+	for i := 1; dec.More(); i++ {
+		// Decoding the raw trace first reports type errors relative to
+		// the trace, which is then located in the stream
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return fmt.Errorf("error unmarshaling trace %d: %w", i, pr.locate(err, base))
+		}
+		end := base + dec.InputOffset()
+		var t Trace
+		if err := json.Unmarshal(raw, &t); err != nil {
+			return fmt.Errorf("error unmarshaling trace %d: %w", i, pr.locate(err, end-int64(len(raw))))
+		}
+		pr.discard(end)
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return fmt.Errorf("error unmarshaling traces: %w", pr.locate(err, base))
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("error unmarshaling traces: unexpected data after the trace array")
+	}
+	return nil
+}
+
+// ParseTracesStream reads every trace from r without buffering the raw
+// input
+func ParseTracesStream(r io.Reader) ([]Trace, error) {
+	var traces []Trace
+	err := StreamTraces(r, func(t Trace) error {
+		traces = append(traces, t)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return traces, nil
+}
+
+// streamNDJSON decodes one trace per line of br, skipping blank lines. pr
+// is the reader under br, used to locate errors.
+func streamNDJSON(pr *positionReader, br *bufio.Reader, fn func(Trace) error) error {
+	offset := pr.offset - int64(br.Buffered())
+	for i := 1; ; {
+		line, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("error reading trace %d: %w", i, err)
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			var t Trace
+			if err := json.Unmarshal(line, &t); err != nil {
+				return fmt.Errorf("error unmarshaling trace %d: %w", i, pr.locate(err, offset))
+			}
+			if err := fn(t); err != nil {
+				return err
+			}
+			i++
+		}
+		offset += int64(len(line))
+		pr.discard(offset)
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// peekNonSpace skips leading whitespace and returns the next byte without
+// consuming it
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b, br.UnreadByte()
+	}
+}
@@ -0,0 +1,143 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTracesStream(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantIDs []string
+		wantErr string
+	}{
+		{
+			name:    "array",
+			input:   `[{"trace_id": "t1", "spans": [{"span_id": "a", "name": "root"}]}, {"trace_id": "t2"}]`,
+			wantIDs: []string{"t1", "t2"},
+		},
+		{
+			name:    "empty array",
+			input:   " []\n",
+			wantIDs: nil,
+		},
+		{
+			name:    "ndjson",
+			input:   "{\"trace_id\": \"t1\"}\n\n{\"trace_id\": \"t2\"}",
+			wantIDs: []string{"t1", "t2"},
+		},
+		{
+			name:    "empty input",
+			input:   "  \n",
+			wantErr: "input is empty",
+		},
+		{
+			name:    "not an array",
+			input:   `"traces"`,
+			wantErr: "expected an array of traces",
+		},
+		{
+			name:    "malformed element",
+			input:   `[{"trace_id": "t1"}, {"trace_id": 2}]`,
+			wantErr: "trace 2",
+		},
+		{
+			name:    "trailing data",
+			input:   `[{"trace_id": "t1"}] []`,
+			wantErr: "unexpected data",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traces, err := ParseTracesStream(strings.NewReader(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseTracesStream() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTracesStream() error = %v", err)
+			}
+			if len(traces) != len(tt.wantIDs) {
+				t.Fatalf("ParseTracesStream() returned %d traces, want %d", len(traces), len(tt.wantIDs))
+			}
+			for i, id := range tt.wantIDs {
+				if traces[i].TraceID != id {
+					t.Errorf("ParseTracesStream() trace %d = %q, want %q", i, traces[i].TraceID, id)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamTracesStopsOnCallbackError(t *testing.T) {
+	stop := errors.New("stop")
+	var seen int
+	err := StreamTraces(strings.NewReader(`[{"trace_id": "t1"}, {"trace_id": "t2"}]`), func(Trace) error {
+		seen++
+		return stop
+	})
+	if !errors.Is(err, stop) || seen != 1 {
+		t.Errorf("StreamTraces() error = %v after %d traces, want the callback error after 1", err, seen)
+	}
+}
+
+// benchmarkTraces builds a JSON array of traces large enough for the
+// difference between buffered and streamed parsing to show
+func benchmarkTraces(b *testing.B) []byte {
+	b.Helper()
+	now := time.Now()
+	traces := make([]Trace, 500)
+	for i := range traces {
+		traces[i].TraceID = fmt.Sprintf("trace%d", i)
+		for j := 0; j < 20; j++ {
+			traces[i].Spans = append(traces[i].Spans, Span{
+				SpanID:     fmt.Sprintf("span%d", j),
+				Name:       fmt.Sprintf("operation %d", j),
+				StartTime:  now,
+				EndTime:    now.Add(time.Millisecond),
+				Attributes: map[string]string{"http.method": "GET", "http.route": "/api/users"},
+			})
+		}
+	}
+	data, err := json.Marshal(traces)
+	if err != nil {
+		b.Fatalf("error marshaling traces: %v", err)
+	}
+	return data
+}
+
+func BenchmarkParseTracesReadAll(b *testing.B) {
+	data := benchmarkTraces(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		raw, err := io.ReadAll(bytes.NewReader(data))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := ParseTraces(raw); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseTracesStream(b *testing.B) {
+	data := benchmarkTraces(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseTracesStream(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
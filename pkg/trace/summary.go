@@ -0,0 +1,88 @@
+package trace
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Summary holds aggregate statistics of the traces in a file
+type Summary struct {
+	Traces int `json:"traces"`
+	Spans  int `json:"spans"`
+
+	// Errors is the number of spans with an error status
+	Errors int `json:"errors"`
+
+	// P50, P90 and P99 are percentiles of the trace durations
+	P50 time.Duration `json:"p50_ns"`
+	P90 time.Duration `json:"p90_ns"`
+	P99 time.Duration `json:"p99_ns"`
+
+	// Services are the distinct service.name values, sorted
+	Services []string `json:"services"`
+}
+
+// Summarize computes aggregate statistics of a set of traces
+func Summarize(traces []Trace) Summary {
+	s := Summary{Traces: len(traces), Services: []string{}}
+
+	durations := make([]time.Duration, 0, len(traces))
+	services := make(map[string]bool)
+	for _, t := range traces {
+		s.Spans += len(t.Spans)
+		s.Errors += errorCount(t)
+		durations = append(durations, getTraceDuration(t))
+		addServices(services, t)
+	}
+	s.P50 = Percentile(durations, 50)
+	s.P90 = Percentile(durations, 90)
+	s.P99 = Percentile(durations, 99)
+
+	for service := range services {
+		s.Services = append(s.Services, service)
+	}
+	sort.Strings(s.Services)
+	return s
+}
+
+// summaryRows returns the statistics of a summary as label/value pairs
+func summaryRows(s Summary) [][2]string {
+	services := "-"
+	if len(s.Services) > 0 {
+		services = strings.Join(s.Services, ", ")
+	}
+	return [][2]string{
+		{"Traces", fmt.Sprintf("%d", s.Traces)},
+		{"Spans", fmt.Sprintf("%d", s.Spans)},
+		{"Errors", fmt.Sprintf("%d", s.Errors)},
+		{"p50", formatDuration(s.P50)},
+		{"p90", formatDuration(s.P90)},
+		{"p99", formatDuration(s.P99)},
+		{fmt.Sprintf("Services (%d)", len(s.Services)), services},
+	}
+}
+
+// GenerateSummaryMarkdown writes a summary as a two-column markdown table
+func GenerateSummaryMarkdown(s Summary) string {
+	var sb strings.Builder
+	sb.WriteString("| Metric | Value |\n")
+	sb.WriteString("|--------|-------|\n")
+	for _, row := range summaryRows(s) {
+		sb.WriteString(fmt.Sprintf("| %s | %s |\n", row[0], escapeMarkdownCell(row[1])))
+	}
+	return sb.String()
+}
+
+// GenerateSummaryText writes a summary as aligned plain text lines
+func GenerateSummaryText(s Summary) string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	for _, row := range summaryRows(s) {
+		fmt.Fprintf(w, "%s:\t%s\n", row[0], row[1])
+	}
+	w.Flush()
+	return sb.String()
+}
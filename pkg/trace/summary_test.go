@@ -0,0 +1,81 @@
+package trace
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummarize(t *testing.T) {
+	now := time.Now()
+	newTrace := func(id string, d time.Duration, service string, spans ...Span) Trace {
+		root := Span{SpanID: id + "-root", Name: "root", StartTime: now, EndTime: now.Add(d)}
+		return Trace{
+			TraceID:       id,
+			Spans:         append([]Span{root}, spans...),
+			ResourceAttrs: map[string]string{"service.name": service},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		traces   []Trace
+		expected Summary
+	}{
+		{
+			name:     "no traces",
+			expected: Summary{Services: []string{}},
+		},
+		{
+			name: "several traces",
+			traces: []Trace{
+				newTrace("t1", 100*time.Millisecond, "api"),
+				newTrace("t2", 300*time.Millisecond, "api",
+					Span{SpanID: "db", Name: "db", StartTime: now, EndTime: now, Status: Status{Code: StatusError}},
+					Span{SpanID: "cache", Name: "cache", StartTime: now, EndTime: now, Attributes: map[string]string{"service.name": "cache"}},
+				),
+				newTrace("t3", 200*time.Millisecond, "worker"),
+			},
+			expected: Summary{
+				Traces:   3,
+				Spans:    5,
+				Errors:   1,
+				P50:      200 * time.Millisecond,
+				P90:      300 * time.Millisecond,
+				P99:      300 * time.Millisecond,
+				Services: []string{"api", "cache", "worker"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Summarize(tt.traces); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Summarize() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGenerateSummary(t *testing.T) {
+	s := Summary{Traces: 2, Spans: 7, Errors: 1, P50: time.Second, P90: 2 * time.Second, P99: 2 * time.Second, Services: []string{"api", "db"}}
+
+	markdown := GenerateSummaryMarkdown(s)
+	for _, want := range []string{"| Metric | Value |", "| Traces | 2 |", "| p90 | 2.00s |", "| Services (2) | api, db |"} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("GenerateSummaryMarkdown() does not contain %q:\n%s", want, markdown)
+		}
+	}
+
+	text := GenerateSummaryText(s)
+	for _, want := range []string{"Traces:        2\n", "p50:           1.00s\n", "Services (2):  api, db\n"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("GenerateSummaryText() does not contain %q:\n%s", want, text)
+		}
+	}
+
+	if got := GenerateSummaryText(Summary{}); !strings.Contains(got, "Services (0):  -\n") {
+		t.Errorf("GenerateSummaryText() of an empty summary = %q, want - for services", got)
+	}
+}
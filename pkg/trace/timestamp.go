@@ -0,0 +1,13 @@
+package trace
+
+import "time"
+
+// timestampLayout is RFC 3339 with milliseconds, e.g.
+// "2024-03-01T12:00:00.250Z"
+const timestampLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// formatTimestamp formats a span timestamp in UTC, so timestamps from hosts
+// in different time zones line up
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format(timestampLayout)
+}
@@ -0,0 +1,45 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatTimestamp(t *testing.T) {
+	tests := []struct {
+		name     string
+		t        time.Time
+		expected string
+	}{
+		{name: "utc", t: time.Date(2024, 3, 1, 12, 0, 0, 250_000_000, time.UTC), expected: "2024-03-01T12:00:00.250Z"},
+		{name: "whole seconds keep millis", t: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), expected: "2024-03-01T12:00:00.000Z"},
+		{name: "other zones are converted", t: time.Date(2024, 3, 1, 9, 0, 0, 1_999_999, time.FixedZone("UTC-3", -3*60*60)), expected: "2024-03-01T12:00:00.001Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatTimestamp(tt.t); got != tt.expected {
+				t.Errorf("formatTimestamp() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGenerateMarkdownShowTimestamps(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	traces := []Trace{{TraceID: "trace1", Spans: []Span{
+		{SpanID: "a", Name: "GET /users", StartTime: start, EndTime: start.Add(1500 * time.Millisecond)},
+	}}}
+
+	without := GenerateMarkdown(traces, Options{})
+	if strings.Contains(without, "Start Time") {
+		t.Errorf("GenerateMarkdown() shows timestamps by default:\n%s", without)
+	}
+
+	with := GenerateMarkdown(traces, Options{ShowTimestamps: true})
+	want := "| `trace1` | `a` | GET /users | - | 2024-03-01T12:00:00.000Z | 2024-03-01T12:00:01.500Z | 1.50s | root |\n"
+	if !strings.Contains(with, "| Start Time | End Time |") || !strings.Contains(with, want) {
+		t.Errorf("GenerateMarkdown() output does not contain %q:\n%s", want, with)
+	}
+}
@@ -0,0 +1,46 @@
+package trace
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// slowestTraces returns the n slowest traces of traces, which must already
+// be sorted by duration in descending order. A non-positive n keeps all.
+func slowestTraces(traces []Trace, n int) []Trace {
+	if n <= 0 || len(traces) <= n {
+		return traces
+	}
+	return traces[:n]
+}
+
+// slowestTraceNames returns the names of the n slowest traces, by their
+// largest duration in any set, in sorted order. A non-positive n keeps all.
+func slowestTraceNames(traceMaps []map[string]*Trace, names []string, n int) []string {
+	if n <= 0 || len(names) <= n {
+		return names
+	}
+
+	durations := make(map[string]time.Duration, len(names))
+	for _, name := range names {
+		for _, traceMap := range traceMaps {
+			if t, ok := traceMap[name]; ok {
+				durations[name] = max(durations[name], getTraceDuration(*t))
+			}
+		}
+	}
+
+	slowest := append([]string(nil), names...)
+	sort.SliceStable(slowest, func(i, j int) bool {
+		return durations[slowest[i]] > durations[slowest[j]]
+	})
+	slowest = slowest[:n]
+	sort.Strings(slowest)
+	return slowest
+}
+
+// topNote returns the note shown when only the slowest traces are reported
+func topNote(shown, total int) string {
+	return fmt.Sprintf("_Showing top %d of %d traces._\n\n", shown, total)
+}
@@ -0,0 +1,64 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlowestTraceNames(t *testing.T) {
+	now := time.Now()
+	makeTrace := func(ms int) *Trace {
+		return &Trace{Spans: []Span{{SpanID: "root", StartTime: now, EndTime: now.Add(time.Duration(ms) * time.Millisecond)}}}
+	}
+	traceMaps := []map[string]*Trace{
+		{"a": makeTrace(10), "b": makeTrace(50), "c": makeTrace(30)},
+		{"a": makeTrace(100), "d": makeTrace(20)},
+	}
+	names := []string{"a", "b", "c", "d"}
+
+	tests := []struct {
+		name     string
+		n        int
+		expected []string
+	}{
+		{name: "unlimited", n: 0, expected: []string{"a", "b", "c", "d"}},
+		{name: "top two by slowest set", n: 2, expected: []string{"a", "b"}},
+		{name: "more than available", n: 10, expected: []string{"a", "b", "c", "d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := slowestTraceNames(traceMaps, names, tt.n)
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("slowestTraceNames() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTopTraces(t *testing.T) {
+	now := time.Now()
+	var traces []Trace
+	for i, id := range []string{"fast", "slow", "medium"} {
+		d := []time.Duration{10, 300, 200}[i] * time.Millisecond
+		traces = append(traces, Trace{TraceID: id, Spans: []Span{{SpanID: id, Name: id, StartTime: now, EndTime: now.Add(d)}}})
+	}
+
+	got := GenerateMarkdown(traces, Options{Top: 2})
+	if !strings.Contains(got, "_Showing top 2 of 3 traces._") {
+		t.Errorf("GenerateMarkdown() does not note the trimmed traces:\n%s", got)
+	}
+	if !strings.Contains(got, "Trace slow") || !strings.Contains(got, "Trace medium") || strings.Contains(got, "Trace fast") {
+		t.Errorf("GenerateMarkdown() did not keep only the two slowest traces:\n%s", got)
+	}
+	if len(traces) != 3 || traces[0].TraceID != "fast" {
+		t.Errorf("GenerateMarkdown() modified its input: %+v", traces)
+	}
+
+	sets := []TraceSet{{Name: "base.json", Traces: traces}, {Name: "head.json", Traces: traces}}
+	comparison := CompareMultipleTraces(sets, "trace_id", Options{Top: 1})
+	if !strings.Contains(comparison, "_Showing top 1 of 3 traces._") || strings.Contains(comparison, "| medium |") {
+		t.Errorf("CompareMultipleTraces() did not keep only the slowest trace:\n%s", comparison)
+	}
+}
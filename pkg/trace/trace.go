@@ -1,8 +1,12 @@
 package trace
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"html"
+	"math"
+	"regexp"
 	"sort"
 	"strings"
 	"time"
@@ -10,28 +14,110 @@ import (
 
 // Trace represents a complete OpenTelemetry trace
 type Trace struct {
-	TraceID       string            `json:"trace_id"`
-	Spans         []Span            `json:"spans"`
-	Attributes    map[string]string `json:"attributes"`
-	ResourceAttrs map[string]string `json:"resource_attributes"`
+	TraceID       string     `json:"trace_id"`
+	Spans         []Span     `json:"spans"`
+	Attributes    Attributes `json:"attributes"`
+	ResourceAttrs Attributes `json:"resource_attributes"`
 }
 
 // Span represents a single span in a trace
 type Span struct {
-	SpanID       string            `json:"span_id"`
-	ParentSpanID string            `json:"parent_span_id"`
-	Name         string            `json:"name"`
-	StartTime    time.Time         `json:"start_time"`
-	EndTime      time.Time         `json:"end_time"`
-	Attributes   map[string]string `json:"attributes"`
-	Events       []Event           `json:"events"`
+	SpanID       string               `json:"span_id"`
+	ParentSpanID string               `json:"parent_span_id"`
+	TraceState   string               `json:"trace_state"`
+	Name         string               `json:"name"`
+	Kind         SpanKind             `json:"kind"`
+	StartTime    time.Time            `json:"start_time"`
+	EndTime      time.Time            `json:"end_time"`
+	Attributes   Attributes           `json:"attributes"`
+	Events       []Event              `json:"events"`
+	Links        []SpanLink           `json:"links"`
+	Status       Status               `json:"status"`
+	Scope        InstrumentationScope `json:"scope"`
+}
+
+// Status represents the outcome of a span
+type Status struct {
+	Code    StatusCode `json:"code"`
+	Message string     `json:"message"`
+}
+
+// StatusCode is the status code of a span: UNSET, OK or ERROR
+type StatusCode string
+
+// Status codes of a span
+const (
+	StatusUnset StatusCode = "UNSET"
+	StatusOK    StatusCode = "OK"
+	StatusError StatusCode = "ERROR"
+)
+
+// UnmarshalJSON accepts the status code as a name, with or without the
+// STATUS_CODE_ prefix, or as its OTLP numeric value
+func (c *StatusCode) UnmarshalJSON(data []byte) error {
+	var number int
+	if err := json.Unmarshal(data, &number); err == nil {
+		switch number {
+		case 1:
+			*c = StatusOK
+		case 2:
+			*c = StatusError
+		default:
+			*c = StatusUnset
+		}
+		return nil
+	}
+
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return fmt.Errorf("invalid status code %s", data)
+	}
+	*c = StatusCode(strings.TrimPrefix(strings.ToUpper(name), "STATUS_CODE_"))
+	return nil
+}
+
+// SpanKind is the kind of a span: INTERNAL, SERVER, CLIENT, PRODUCER or
+// CONSUMER. It is empty when unspecified.
+type SpanKind string
+
+// Span kinds
+const (
+	SpanKindInternal SpanKind = "INTERNAL"
+	SpanKindServer   SpanKind = "SERVER"
+	SpanKindClient   SpanKind = "CLIENT"
+	SpanKindProducer SpanKind = "PRODUCER"
+	SpanKindConsumer SpanKind = "CONSUMER"
+)
+
+// UnmarshalJSON accepts the span kind as a name, with or without the
+// SPAN_KIND_ prefix, or as its OTLP numeric value
+func (k *SpanKind) UnmarshalJSON(data []byte) error {
+	var number int
+	if err := json.Unmarshal(data, &number); err == nil {
+		kinds := []SpanKind{"", SpanKindInternal, SpanKindServer, SpanKindClient, SpanKindProducer, SpanKindConsumer}
+		*k = ""
+		if number > 0 && number < len(kinds) {
+			*k = kinds[number]
+		}
+		return nil
+	}
+
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return fmt.Errorf("invalid span kind %s", data)
+	}
+	*k = SpanKind(strings.TrimPrefix(strings.ToUpper(name), "SPAN_KIND_"))
+	if *k == "UNSPECIFIED" {
+		*k = ""
+	}
+	return nil
 }
 
 // Event represents an event within a span
 type Event struct {
-	Time       time.Time         `json:"time"`
-	Name       string            `json:"name"`
-	Attributes map[string]string `json:"attributes"`
+	Time       time.Time  `json:"time"`
+	Name       string     `json:"name"`
+	Attributes Attributes `json:"attributes"`
 }
 
 // TraceSet represents a set of traces from a single file
@@ -40,23 +126,175 @@ type TraceSet struct {
 	Traces []Trace
 }
 
-// ParseTraces reads a JSON file and returns a slice of traces
+// Options controls optional behavior of the report generators. The zero
+// value produces the default report.
+type Options struct {
+	// SectionBy groups span rows under a subheader per value of this
+	// attribute. Each span is sectioned on its own, so a trace whose spans
+	// belong to different owners is split across sections. Spans without
+	// the attribute fall back to the trace and resource attributes and
+	// then to "unassigned".
+	SectionBy string
+
+	// ApdexTarget is the default Apdex target T for every operation.
+	// Zero disables Apdex scoring unless ApdexTargets sets an operation.
+	ApdexTarget time.Duration
+
+	// ApdexTargets overrides the Apdex target per operation name
+	ApdexTargets map[string]time.Duration
+
+	// FoldRepeats collapses consecutive sibling spans with the same name
+	// into a single line in the span hierarchy
+	FoldRepeats bool
+
+	// MaxSpanDepth limits the span hierarchy to this many levels, counting
+	// root spans as level 1. Deeper spans are summarized by their count.
+	// Zero shows every level.
+	MaxSpanDepth int
+
+	// NormalizeNames compares spans by their name with every match of this
+	// pattern removed, see NormalizeSpanName. Reports still show the
+	// original names. Nil compares the names as they are.
+	NormalizeNames *regexp.Regexp
+
+	// FuzzyMatch is the minimum Similarity, between 0 and 1, for span names
+	// that only exist in some files to be compared as the same operation.
+	// Zero disables fuzzy matching.
+	FuzzyMatch float64
+
+	// Mermaid adds a Mermaid gantt diagram of each trace to its details
+	Mermaid bool
+
+	// AggregateBy groups traces by this identifier attribute and reports
+	// count, p50, p90, p99 and max duration per group instead of a row per
+	// trace. Comparisons then compare p90s across files.
+	AggregateBy string
+
+	// GroupBy rolls up the spans of every trace by this span attribute,
+	// such as service.name, and compares the total and average span
+	// duration per group across files instead of per trace. It may be a
+	// comma-separated list of attributes tried in order.
+	GroupBy string
+
+	// Verdict adds a one-line pass/fail verdict at the top of comparisons.
+	// Nil leaves it out.
+	Verdict *Verdict
+
+	// ChangesOnly leaves out the details of matching traces whose duration
+	// and attributes did not change significantly. Nil shows every trace.
+	ChangesOnly *ChangesOnly
+
+	// Top keeps only the given number of slowest traces in reports. Zero
+	// shows every trace.
+	Top int
+
+	// OutliersBy lists the traces that took far longer than the other
+	// traces sharing this identifier attribute in info reports. Empty
+	// leaves them out.
+	OutliersBy string
+
+	// Histogram adds a histogram of the span durations of every operation
+	// with this many buckets to info reports. Zero leaves it out.
+	Histogram int
+
+	// SortBy orders the traces of info reports: SortByDuration (the
+	// default when empty), SortByName or SortBySpanCount
+	SortBy string
+
+	// IDLength is the number of characters span IDs are truncated to.
+	// Zero uses defaultIDLength and a negative value disables truncation.
+	IDLength int
+
+	// Footer ends the report with a legend and the generation time and
+	// version. Nil leaves it out.
+	Footer *Footer
+
+	// CardinalityThreshold warns about span attributes with more distinct
+	// values than this across all traces. Zero disables the warning.
+	CardinalityThreshold int
+
+	// MinMatchFraction warns at the top of comparisons when less than this
+	// fraction of the traces, between 0 and 1, match across all files.
+	// Comparisons where no trace matches are always warned about.
+	MinMatchFraction float64
+
+	// ShowTimestamps adds the UTC start and end time of every span to the
+	// span details of info reports
+	ShowTimestamps bool
+
+	// AttrDiff is how comparisons show the trace attributes of matching
+	// traces: AttrDiffTable (the default when empty) or AttrDiffUnified
+	AttrDiff string
+
+	// NoEmoji replaces the emoji indicators of reports with plain ASCII
+	// markers such as [SLOW] and [FAST]
+	NoEmoji bool
+}
+
+// defaultIDLength is the default number of characters span IDs are
+// truncated to
+const defaultIDLength = 8
+
+// idLength returns the ID truncation length, or 0 for full IDs
+func (o Options) idLength() int {
+	switch {
+	case o.IDLength == 0:
+		return defaultIDLength
+	case o.IDLength < 0:
+		return 0
+	default:
+		return o.IDLength
+	}
+}
+
+// unassignedSection is the section used for spans without a section value
+const unassignedSection = "unassigned"
+
+// ParseTraces reads a JSON file and returns a slice of traces. Input that
+// starts with an object rather than an array is read as NDJSON.
 func ParseTraces(data []byte) ([]Trace, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, ErrEmptyInput
+	}
+	if isNDJSON(data) {
+		return ParseNDJSON(data)
+	}
 	var traces []Trace
 	if err := json.Unmarshal(data, &traces); err != nil {
-		return nil, fmt.Errorf("error unmarshaling traces: %w", err)
+		return nil, fmt.Errorf("error unmarshaling traces: %w", locateError(data, err))
 	}
 	return traces, nil
 }
 
 // GenerateMarkdown generates a Markdown representation of the traces
-func GenerateMarkdown(traces []Trace) string {
+func GenerateMarkdown(traces []Trace, opts Options) string {
 	var sb strings.Builder
+	ind := opts.indicators()
+
+	// Warn about broken parent references before anything else
+	var warnings []string
+	for _, t := range traces {
+		for _, f := range Validate(t) {
+			warnings = append(warnings, fmt.Sprintf("- Trace `%s`: span %q (%s) %s\n",
+				escapeMarkdownCell(t.TraceID), f.SpanName, f.SpanID, f.Message))
+		}
+	}
+	if len(warnings) > 0 {
+		sb.WriteString(fmt.Sprintf("**%s Warnings:**\n\n", ind.warning))
+		for _, warning := range warnings {
+			sb.WriteString(warning)
+		}
+		sb.WriteString("\n")
+	}
+	if opts.CardinalityThreshold > 0 {
+		writeCardinalityWarnings(&sb, traces, opts.CardinalityThreshold, ind)
+	}
 
 	// First table: Overview of traces
 	sb.WriteString("**Traces Overview:**\n\n")
-	sb.WriteString("| Trace ID | Duration | Spans |\n")
-	sb.WriteString("|----------|----------|-------|\n")
+
+	// Work on a copy so the caller's traces and spans are never reordered
+	traces = copyTraces(traces)
 
 	// Create a map to quickly access spans by trace ID
 	traceSpanMaps := make(map[string]map[string]*Span)
@@ -69,30 +307,64 @@ func GenerateMarkdown(traces []Trace) string {
 	}
 
 	// Sort traces by duration (descending)
-	sort.Slice(traces, func(i, j int) bool {
-		iDuration := getTraceDuration(traces[i])
-		jDuration := getTraceDuration(traces[j])
-		return iDuration > jDuration
-	})
+	traces = SortTraces(traces, SortByDuration)
+
+	// Keep only the slowest traces, aggregating over all of them, and then
+	// report them in the requested order
+	all := traces
+	traces = slowestTraces(traces, opts.Top)
+	if len(traces) < len(all) {
+		sb.WriteString(topNote(len(traces), len(all)))
+	}
+	traces = SortTraces(traces, opts.SortBy)
+
+	if opts.AggregateBy != "" {
+		writeAggregateOverview(&sb, all, opts.AggregateBy)
+	} else {
+		sb.WriteString("| Trace ID | Duration | Spans | Root Spans | Max Depth | Services | Errors |\n")
+		sb.WriteString("|----------|----------|-------|------------|-----------|----------|--------|\n")
+		for _, t := range traces {
+			duration := getTraceDuration(t)
+			spanMap := traceSpanMaps[t.TraceID]
+			sb.WriteString(fmt.Sprintf("| `%s` | %s | %d | %d | %d | %d | %d |\n",
+				escapeMarkdownCell(t.TraceID),
+				formatDuration(duration),
+				len(t.Spans),
+				rootCount(t, spanMap),
+				maxDepth(t, spanMap),
+				ServiceCount(t),
+				errorCount(t)))
+		}
+	}
 
-	for _, t := range traces {
-		duration := getTraceDuration(t)
-		sb.WriteString(fmt.Sprintf("| `%s` | %s | %d |\n",
-			t.TraceID,
-			formatDuration(duration),
-			len(t.Spans)))
+	// Traces far slower than the rest of their operation
+	if opts.OutliersBy != "" {
+		writeOutliers(&sb, all, opts.OutliersBy)
 	}
 
 	// Second table: Detailed span information
 	sb.WriteString("\n**Span Details:**\n\n")
-	sb.WriteString("| Trace ID | Span ID | Span Name | Duration | Parent |\n")
-	sb.WriteString("|----------|---------|-----------|----------|--------|\n")
+	// Optional columns are left out unless asked for or known
+	showScopes := hasScopes(traces)
+	spanHeader := "| Trace ID | Span ID | Span Name | Kind |"
+	spanSeparator := "|----------|---------|-----------|------|"
+	if showScopes {
+		spanHeader += " Scope |"
+		spanSeparator += "-------|"
+	}
+	if opts.ShowTimestamps {
+		spanHeader += " Start Time | End Time |"
+		spanSeparator += "------------|----------|"
+	}
+	spanHeader += " Duration | Parent |\n" + spanSeparator + "----------|--------|\n"
+	sections := make(map[string]*strings.Builder)
 
 	// Sort spans by duration (descending)
-	for _, t := range traces {
-		spans := t.Spans
+	for i := range traces {
+		t := &traces[i]
+		spans := append([]Span(nil), t.Spans...)
 		sort.Slice(spans, func(i, j int) bool {
-			return spans[i].EndTime.Sub(spans[i].StartTime) > spans[j].EndTime.Sub(spans[j].StartTime)
+			return spanDuration(spans[i]) > spanDuration(spans[j])
 		})
 
 		for _, span := range spans {
@@ -102,14 +374,36 @@ func GenerateMarkdown(traces []Trace) string {
 					parentName = parentSpan.Name
 				}
 			}
-			sb.WriteString(fmt.Sprintf("| `%s` | `%s` | %s | %s | %s |\n",
-				t.TraceID,
-				truncateID(span.SpanID),
-				span.Name,
-				formatDuration(span.EndTime.Sub(span.StartTime)),
-				parentName))
+			section := ""
+			if opts.SectionBy != "" {
+				section = getSpanSection(t, &span, opts.SectionBy)
+			}
+			if sections[section] == nil {
+				sections[section] = &strings.Builder{}
+			}
+			sections[section].WriteString(fmt.Sprintf("| `%s` | `%s` | %s | %s |",
+				escapeMarkdownCell(t.TraceID),
+				escapeMarkdownCell(truncateID(span.SpanID, opts.idLength())),
+				escapeMarkdownCell(span.Name),
+				formatKind(span.Kind)))
+			if showScopes {
+				sections[section].WriteString(fmt.Sprintf(" %s |", formatScope(spanScope(span))))
+			}
+			if opts.ShowTimestamps {
+				sections[section].WriteString(fmt.Sprintf(" %s | %s |", formatTimestamp(span.StartTime), formatTimestamp(span.EndTime)))
+			}
+			sections[section].WriteString(fmt.Sprintf(" %s | %s |\n",
+				formatDuration(spanDuration(span)),
+				escapeMarkdownCell(parentName)))
 		}
 	}
+	writeSections(&sb, spanHeader, sections)
+
+	// Distribution of span durations across all traces
+	if opts.Histogram > 0 {
+		sb.WriteString("\n")
+		writeHistograms(&sb, all, opts.Histogram)
+	}
 
 	// Expandable details for each trace
 	sb.WriteString("\n**Trace Details:**\n\n")
@@ -121,79 +415,390 @@ func GenerateMarkdown(traces []Trace) string {
 			sb.WriteString("**Trace Attributes:**\n\n")
 			sb.WriteString("| Key | Value |\n")
 			sb.WriteString("|-----|--------|\n")
-			for k, v := range t.Attributes {
-				sb.WriteString(fmt.Sprintf("| %s | %s |\n", k, v))
+			for _, k := range sortedKeys(t.Attributes) {
+				sb.WriteString(fmt.Sprintf("| %s | %s |\n", escapeMarkdownCell(k), escapeMarkdownCell(t.Attributes[k])))
 			}
 			sb.WriteString("\n")
 		}
 
+		// Show where the time of the trace goes
+		writeSelfTime(&sb, t)
+		writeCriticalPath(&sb, t, traceSpanMaps[t.TraceID])
+
+		// Show the timeline of the trace
+		if opts.Mermaid {
+			sb.WriteString("**Timeline:**\n\n")
+			sb.WriteString(GenerateMermaid(t))
+			sb.WriteString("\n")
+		}
+
 		// Show spans in hierarchical order
 		sb.WriteString("**Spans:**\n\n")
-		showSpan(&sb, &t, "", traceSpanMaps[t.TraceID])
+		showSpan(&sb, &t, "", traceSpanMaps[t.TraceID], opts)
 
 		sb.WriteString("</details>\n\n")
 	}
 
+	writeFooter(&sb, opts.Footer, ind.infoLegend())
 	return sb.String()
 }
 
-// showSpan recursively shows a span and its children
-func showSpan(sb *strings.Builder, t *Trace, parentID string, spanMap map[string]*Span) {
-	// Find all spans with this parent
+// formatKind returns the span kind for a table cell
+func formatKind(kind SpanKind) string {
+	if kind == "" {
+		return "-"
+	}
+	return escapeMarkdownCell(string(kind))
+}
+
+// sortedKeys returns the keys of an attribute map in sorted order, so the
+// generated output is reproducible
+func sortedKeys(attrs map[string]string) []string {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// isError reports whether a span finished with an error status
+func isError(span Span) bool {
+	return span.Status.Code == StatusError
+}
+
+// errorCount returns the number of spans with an error status in a trace
+func errorCount(t Trace) int {
+	var count int
 	for _, span := range t.Spans {
-		if span.ParentSpanID == parentID {
-			// Show this span
-			sb.WriteString(fmt.Sprintf("- **%s** (%s)\n", span.Name, formatDuration(span.EndTime.Sub(span.StartTime))))
-
-			// Show attributes if any
-			if len(span.Attributes) > 0 {
-				sb.WriteString("  **Attributes:**\n")
-				for k, v := range span.Attributes {
-					sb.WriteString(fmt.Sprintf("  - %s: %s\n", k, v))
-				}
+		if isError(span) {
+			count++
+		}
+	}
+	return count
+}
+
+// copyTraces returns a copy of the traces whose span slices can be
+// reordered without affecting the originals
+func copyTraces(traces []Trace) []Trace {
+	copied := make([]Trace, len(traces))
+	for i, t := range traces {
+		t.Spans = append([]Span(nil), t.Spans...)
+		copied[i] = t
+	}
+	return copied
+}
+
+// getSpanAttribute looks up an attribute for a span, falling back to the
+// attributes and resource attributes of the trace it belongs to
+func getSpanAttribute(t *Trace, span *Span, attribute string) (string, bool) {
+	if value, ok := span.Attributes[attribute]; ok {
+		return value, true
+	}
+	if value, ok := t.Attributes[attribute]; ok {
+		return value, true
+	}
+	if value, ok := t.ResourceAttrs[attribute]; ok {
+		return value, true
+	}
+	return "", false
+}
+
+// ServiceCount returns the number of distinct service.name values across
+// the spans of a trace, using the resource attributes for spans that do not
+// set the service themselves
+func ServiceCount(t Trace) int {
+	services := make(map[string]bool)
+	addServices(services, t)
+	return len(services)
+}
+
+// addServices adds the service.name of every span of a trace to services
+func addServices(services map[string]bool, t Trace) {
+	for i := range t.Spans {
+		if service, ok := getSpanAttribute(&t, &t.Spans[i], "service.name"); ok && service != "" {
+			services[service] = true
+		}
+	}
+}
+
+// getSpanSection returns the section a span is reported under
+func getSpanSection(t *Trace, span *Span, attribute string) string {
+	if value, ok := getSpanAttribute(t, span, attribute); ok && value != "" {
+		return value
+	}
+	return unassignedSection
+}
+
+// writeSections writes each section as a subheader followed by its own
+// table. Sections are sorted by name with "unassigned" last. A single
+// unnamed section is written as a plain table.
+func writeSections(sb *strings.Builder, header string, sections map[string]*strings.Builder) {
+	if len(sections) == 0 {
+		sb.WriteString(header)
+		return
+	}
+
+	var names []string
+	for name := range sections {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if (names[i] == unassignedSection) != (names[j] == unassignedSection) {
+			return names[j] == unassignedSection
+		}
+		return names[i] < names[j]
+	})
+
+	for i, name := range names {
+		if name != "" {
+			if i > 0 {
+				sb.WriteString("\n")
 			}
+			sb.WriteString(fmt.Sprintf("#### %s\n\n", name))
+		}
+		sb.WriteString(header)
+		sb.WriteString(sections[name].String())
+	}
+}
+
+// showSpan recursively shows a span and its children. Spans in a parent
+// cycle are not reachable from a root, so when showing the whole trace each
+// cycle is shown from its first span, after a note.
+func showSpan(sb *strings.Builder, t *Trace, parentID string, spanMap map[string]*Span, opts Options) {
+	visited := make(map[int]bool)
+	showSpanTree(sb, t, parentID, 1, opts, visited)
+	if parentID != "" {
+		return
+	}
+
+	spans := make(map[string]*Span, len(t.Spans))
+	for i := range t.Spans {
+		spans[t.Spans[i].SpanID] = &t.Spans[i]
+	}
+	for i := range t.Spans {
+		if visited[i] {
+			continue
+		}
+		if cycle := parentCycle(&t.Spans[i], spans); cycle != nil {
+			sb.WriteString(fmt.Sprintf("- _↻ parent cycle detected: %s_\n", escapeMarkdownCell(strings.Join(cycle, " → "))))
+			visited[i] = true
+			writeSpan(sb, t.Spans[i], opts.indicators())
+			showSpanTree(sb, t, t.Spans[i].SpanID, 2, opts, visited)
+		}
+	}
+}
+
+// showSpanTree shows the spans with the given parent, at the given depth,
+// and their children. Visited holds the indexes of the spans already shown,
+// so duplicate span IDs or cyclic parents cannot make it recurse forever; a
+// span reached a second time is replaced by a note.
+func showSpanTree(sb *strings.Builder, t *Trace, parentID string, depth int, opts Options, visited map[int]bool) {
+	if opts.MaxSpanDepth > 0 && depth > opts.MaxSpanDepth {
+		if hidden := hideSpanTree(t, parentID, visited); hidden > 0 {
+			sb.WriteString(fmt.Sprintf("  - _+%d deeper span(s)_\n", hidden))
+		}
+		return
+	}
+
+	// Find all spans with this parent
+	var children []Span
+	var repeated []string
+	for i, span := range t.Spans {
+		if span.ParentSpanID != parentID {
+			continue
+		}
+		if visited[i] {
+			repeated = append(repeated, span.Name)
+			continue
+		}
+		visited[i] = true
+		children = append(children, span)
+	}
+
+	for i := 0; i < len(children); i++ {
+		span := children[i]
 
-			// Show events if any
-			if len(span.Events) > 0 {
-				sb.WriteString("  **Events:**\n")
-				for _, event := range span.Events {
-					sb.WriteString(fmt.Sprintf("  - %s\n", event.Name))
-					if len(event.Attributes) > 0 {
-						for k, v := range event.Attributes {
-							sb.WriteString(fmt.Sprintf("    - %s: %s\n", k, v))
-						}
-					}
+		// Fold consecutive siblings with the same name into a single line
+		if opts.FoldRepeats {
+			end := i + 1
+			for end < len(children) && children[end].Name == span.Name {
+				end++
+			}
+			if end-i > 1 {
+				showFoldedSpans(sb, t, children[i:end])
+				i = end - 1
+				continue
+			}
+		}
+
+		// Show this span and its children
+		writeSpan(sb, span, opts.indicators())
+		showSpanTree(sb, t, span.SpanID, depth+1, opts, visited)
+	}
+
+	for _, name := range repeated {
+		sb.WriteString(fmt.Sprintf("- _↻ %s already shown above (cyclic or duplicate parent reference)_\n", escapeMarkdownCell(name)))
+	}
+}
+
+// hideSpanTree marks the descendants of a span as visited without showing
+// them and returns how many there are
+func hideSpanTree(t *Trace, parentID string, visited map[int]bool) int {
+	var hidden int
+	for i, span := range t.Spans {
+		if span.ParentSpanID != parentID || visited[i] {
+			continue
+		}
+		visited[i] = true
+		hidden += 1 + hideSpanTree(t, span.SpanID, visited)
+	}
+	return hidden
+}
+
+// writeSpan shows a single span with its attributes, events and links
+func writeSpan(sb *strings.Builder, span Span, ind indicators) {
+	sb.WriteString(fmt.Sprintf("- **%s** (%s)\n", span.Name, formatDuration(spanDuration(span))))
+
+	// Show attributes if any
+	if len(span.Attributes) > 0 {
+		sb.WriteString("  **Attributes:**\n")
+		for _, k := range sortedKeys(span.Attributes) {
+			sb.WriteString(fmt.Sprintf("  - %s: %s\n", escapeMarkdownCell(k), escapeMarkdownCell(span.Attributes[k])))
+		}
+	}
+
+	// Show events if any
+	if len(span.Events) > 0 {
+		sb.WriteString("  **Events:**\n")
+		for _, event := range span.Events {
+			sb.WriteString(fmt.Sprintf("  - %s\n", escapeMarkdownCell(event.Name)))
+			if len(event.Attributes) > 0 {
+				for _, k := range sortedKeys(event.Attributes) {
+					sb.WriteString(fmt.Sprintf("    - %s: %s\n", escapeMarkdownCell(k), escapeMarkdownCell(event.Attributes[k])))
 				}
 			}
+		}
+	}
+
+	// Show links to other spans if any
+	writeLinks(sb, span.Links, ind)
+
+	// Show the W3C trace state, which carries vendor data such as sampling
+	if span.TraceState != "" {
+		sb.WriteString(fmt.Sprintf("  **Trace State:** `%s`\n", span.TraceState))
+	}
+}
 
-			// Recursively show children
-			showSpan(sb, t, span.SpanID, spanMap)
+// showFoldedSpans shows repeated sibling spans as a single line with their
+// count and aggregate duration. Attributes, events and children of the
+// folded spans are not expanded.
+func showFoldedSpans(sb *strings.Builder, t *Trace, spans []Span) {
+	var total time.Duration
+	folded := make(map[string]bool)
+	for _, span := range spans {
+		total += spanDuration(span)
+		folded[span.SpanID] = true
+	}
+	avg := total / time.Duration(len(spans))
+
+	sb.WriteString(fmt.Sprintf("- **%s** ×%d, total %s, avg %s\n",
+		spans[0].Name,
+		len(spans),
+		formatDuration(total),
+		formatDuration(avg)))
+
+	var nested int
+	for _, span := range t.Spans {
+		if folded[span.ParentSpanID] {
+			nested++
 		}
 	}
+	if nested > 0 {
+		sb.WriteString(fmt.Sprintf("  _%d child spans folded_\n", nested))
+	}
 }
 
 // Helper functions
-func truncateID(id string) string {
-	if len(id) > 8 {
-		return id[:8]
+
+// escapeMarkdownCell escapes text so it can be placed in a markdown table
+// cell without breaking the table layout
+func escapeMarkdownCell(s string) string {
+	return markdownCellReplacer.Replace(s)
+}
+
+// escapeMarkdownSummary escapes text so it can be placed in the <summary>
+// of a collapsible section, which is HTML, as well as a table cell
+func escapeMarkdownSummary(s string) string {
+	return escapeMarkdownCell(html.EscapeString(s))
+}
+
+var markdownCellReplacer = strings.NewReplacer(
+	"|", "\\|",
+	"`", "\\`",
+	"\r\n", "<br>",
+	"\n", "<br>",
+	"\r", "<br>",
+)
+
+// truncateID shortens an ID to length characters. A length of 0 keeps the
+// full ID.
+func truncateID(id string, length int) string {
+	if length > 0 && len(id) > length {
+		return id[:length]
 	}
 	return id
 }
 
+// formatDuration formats a duration in the largest unit that keeps it
+// readable. Negative durations, such as those of spans whose clocks are
+// skewed, keep their sign in front of the same format.
 func formatDuration(d time.Duration) string {
+	if d == math.MinInt64 {
+		// -d would overflow back to itself
+		d++
+	}
+	if d < 0 {
+		return "-" + formatDuration(-d)
+	}
+	if d < time.Microsecond {
+		return fmt.Sprintf("%dns", d.Nanoseconds())
+	}
 	if d < time.Millisecond {
 		return fmt.Sprintf("%.2fµs", float64(d.Nanoseconds())/1000.0)
 	}
 	if d < time.Second {
-		return fmt.Sprintf("%.2fms", float64(d.Milliseconds()))
+		return fmt.Sprintf("%.2fms", float64(d.Nanoseconds())/1e6)
 	}
-	return fmt.Sprintf("%.2fs", d.Seconds())
+	if d < time.Minute {
+		return fmt.Sprintf("%.2fs", d.Seconds())
+	}
+	if d < time.Hour {
+		minutes := d / time.Minute
+		return fmt.Sprintf("%dm%.2fs", minutes, (d - minutes*time.Minute).Seconds())
+	}
+	hours := d / time.Hour
+	return fmt.Sprintf("%dh%dm", hours, (d-hours*time.Hour)/time.Minute)
 }
 
 func getFileNameWithoutExt(fileName string) string {
 	return strings.TrimSuffix(fileName, ".json")
 }
 
+// spanDuration returns how long a span took. Spans that end before they
+// start, usually because of clock skew between hosts, take no time.
+func spanDuration(span Span) time.Duration {
+	return max(span.EndTime.Sub(span.StartTime), 0)
+}
+
+// spanEnd returns when a span ended, never before it started
+func spanEnd(span Span) time.Time {
+	if span.EndTime.Before(span.StartTime) {
+		return span.StartTime
+	}
+	return span.EndTime
+}
+
 func getTraceDuration(t Trace) time.Duration {
 	if len(t.Spans) == 0 {
 		return 0
@@ -205,14 +810,14 @@ func getTraceDuration(t Trace) time.Duration {
 	for _, span := range t.Spans {
 		if first {
 			earliest = span.StartTime
-			latest = span.EndTime
+			latest = spanEnd(span)
 			first = false
 		} else {
 			if span.StartTime.Before(earliest) {
 				earliest = span.StartTime
 			}
-			if span.EndTime.After(latest) {
-				latest = span.EndTime
+			if spanEnd(span).After(latest) {
+				latest = spanEnd(span)
 			}
 		}
 	}
@@ -223,134 +828,100 @@ func getTraceDuration(t Trace) time.Duration {
 // CompareTraces compares two sets of traces and generates a markdown report
 func CompareTraces(traces1, traces2 []Trace) string {
 	var sb strings.Builder
-
-	// Create maps of traces by name for quick lookup
-	traces1Map := make(map[string]*Trace)
-	traces2Map := make(map[string]*Trace)
-
-	for i := range traces1 {
-		name := getTraceIdentifier(traces1[i], "name")
-		traces1Map[name] = &traces1[i]
-	}
-
-	for i := range traces2 {
-		name := getTraceIdentifier(traces2[i], "name")
-		traces2Map[name] = &traces2[i]
-	}
+	result := Compare(traces1, traces2, "name", Options{})
 
 	// Compare traces
 	sb.WriteString("### Trace Comparison\n\n")
 
-	// Find matching traces
-	var matchingTraces []string
-	for name := range traces1Map {
-		if _, exists := traces2Map[name]; exists {
-			matchingTraces = append(matchingTraces, name)
-		}
-	}
-	sort.Strings(matchingTraces)
-
-	// Find traces only in first set
-	var onlyInFirst []string
-	for name := range traces1Map {
-		if _, exists := traces2Map[name]; !exists {
-			onlyInFirst = append(onlyInFirst, name)
-		}
-	}
-	sort.Strings(onlyInFirst)
-
-	// Find traces only in second set
-	var onlyInSecond []string
-	for name := range traces2Map {
-		if _, exists := traces1Map[name]; !exists {
-			onlyInSecond = append(onlyInSecond, name)
-		}
-	}
-	sort.Strings(onlyInSecond)
-
 	// Summary table
 	sb.WriteString("**Comparison Summary:**\n\n")
 	sb.WriteString("| Category | Count |\n")
 	sb.WriteString("|----------|-------|\n")
-	sb.WriteString(fmt.Sprintf("| Matching Traces | %d |\n", len(matchingTraces)))
-	sb.WriteString(fmt.Sprintf("| Only in First File | %d |\n", len(onlyInFirst)))
-	sb.WriteString(fmt.Sprintf("| Only in Second File | %d |\n", len(onlyInSecond)))
+	sb.WriteString(fmt.Sprintf("| Matching Traces | %d |\n", len(result.Matching)))
+	sb.WriteString(fmt.Sprintf("| Only in First File | %d |\n", len(result.OnlyInFirst)))
+	sb.WriteString(fmt.Sprintf("| Only in Second File | %d |\n", len(result.OnlyInSecond)))
 	sb.WriteString("\n")
 
 	// Matching traces comparison
-	if len(matchingTraces) > 0 {
+	if len(result.Matching) > 0 {
 		sb.WriteString("**Matching Traces:**\n\n")
-		for _, name := range matchingTraces {
-			t1 := traces1Map[name]
-			t2 := traces2Map[name]
-
-			sb.WriteString(fmt.Sprintf("<details>\n<summary>%s</summary>\n\n", name))
+		for _, c := range result.Matching {
+			sb.WriteString(fmt.Sprintf("<details>\n<summary>%s</summary>\n\n", c.Name))
 
 			// Compare durations
-			duration1 := getTraceDuration(*t1)
-			duration2 := getTraceDuration(*t2)
-			durationDiff := duration2 - duration1
-			durationChange := (durationDiff.Seconds() / duration1.Seconds()) * 100
-
 			sb.WriteString("**Duration Comparison:**\n\n")
 			sb.WriteString("| File | Duration |\n")
 			sb.WriteString("|------|----------|\n")
-			sb.WriteString(fmt.Sprintf("| First | %s |\n", formatDuration(duration1)))
-			sb.WriteString(fmt.Sprintf("| Second | %s |\n", formatDuration(duration2)))
-			sb.WriteString(fmt.Sprintf("| Difference | %s (%.1f%%) |\n", formatDuration(durationDiff), durationChange))
+			sb.WriteString(fmt.Sprintf("| First | %s |\n", formatDuration(c.FirstDuration)))
+			sb.WriteString(fmt.Sprintf("| Second | %s |\n", formatDuration(c.SecondDuration)))
+			sb.WriteString(fmt.Sprintf("| Difference | %s (%.1f%%) |\n", formatDurationDelta(c.Delta), c.Percent()))
 			sb.WriteString("\n")
 
 			// Compare spans
 			sb.WriteString("**Span Comparison:**\n\n")
-			sb.WriteString("| Span Name | First Duration | Second Duration | Difference |\n")
-			sb.WriteString("|-----------|----------------|-----------------|------------|\n")
-
-			// Create maps of spans by name
-			spans1Map := make(map[string]*Span)
-			spans2Map := make(map[string]*Span)
-
-			for i := range t1.Spans {
-				spans1Map[t1.Spans[i].Name] = &t1.Spans[i]
+			sb.WriteString("| Span Name | First Start | Second Start | Start Difference | First Duration | Second Duration | Difference |\n")
+			sb.WriteString("|-----------|-------------|--------------|------------------|----------------|-----------------|------------|\n")
+			for _, span := range c.Spans {
+				sb.WriteString(fmt.Sprintf("| %s | +%s | +%s | %s | %s | %s | %s (%.1f%%) |\n",
+					escapeMarkdownCell(span.Name),
+					formatDuration(span.FirstOffset),
+					formatDuration(span.SecondOffset),
+					formatDurationDelta(span.OffsetDelta),
+					formatDuration(span.FirstDuration),
+					formatDuration(span.SecondDuration),
+					formatDurationDelta(span.Delta),
+					span.Percent()))
 			}
+			sb.WriteString("\n")
 
-			for i := range t2.Spans {
-				spans2Map[t2.Spans[i].Name] = &t2.Spans[i]
+			// Events added, removed or changed in matching spans
+			var eventLines []string
+			for _, span := range c.Spans {
+				if len(span.EventChanges) > 0 {
+					eventLines = append(eventLines, fmt.Sprintf("- %s: %s\n", span.Name, formatEventChanges(span.EventChanges, emojiIndicators)))
+				}
+			}
+			if len(eventLines) > 0 {
+				sb.WriteString("**Event Changes:**\n\n")
+				for _, line := range eventLines {
+					sb.WriteString(line)
+				}
+				sb.WriteString("\n")
 			}
 
-			// Compare matching spans
-			for name, span1 := range spans1Map {
-				if span2, exists := spans2Map[name]; exists {
-					d1 := span1.EndTime.Sub(span1.StartTime)
-					d2 := span2.EndTime.Sub(span2.StartTime)
-					diff := d2 - d1
-					change := (diff.Seconds() / d1.Seconds()) * 100
-
-					sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s (%.1f%%) |\n",
-						name,
-						formatDuration(d1),
-						formatDuration(d2),
-						formatDuration(diff),
-						change))
+			// Spans added or removed between the two versions
+			if len(c.SpansOnlyInFirst) > 0 {
+				sb.WriteString("**Spans Only in First File:**\n\n")
+				for _, name := range c.SpansOnlyInFirst {
+					sb.WriteString(fmt.Sprintf("- %s\n", name))
+				}
+				sb.WriteString("\n")
+			}
+			if len(c.SpansOnlyInSecond) > 0 {
+				sb.WriteString("**Spans Only in Second File:**\n\n")
+				for _, name := range c.SpansOnlyInSecond {
+					sb.WriteString(fmt.Sprintf("- %s\n", name))
 				}
+				sb.WriteString("\n")
 			}
 
-			sb.WriteString("\n</details>\n\n")
+			sb.WriteString("</details>\n\n")
 		}
 	}
 
 	// Traces only in first file
-	if len(onlyInFirst) > 0 {
+	if len(result.OnlyInFirst) > 0 {
 		sb.WriteString("**Traces Only in First File:**\n\n")
-		for _, name := range onlyInFirst {
+		for _, name := range result.OnlyInFirst {
 			sb.WriteString(fmt.Sprintf("- %s\n", name))
 		}
 		sb.WriteString("\n")
 	}
 
 	// Traces only in second file
-	if len(onlyInSecond) > 0 {
+	if len(result.OnlyInSecond) > 0 {
 		sb.WriteString("**Traces Only in Second File:**\n\n")
-		for _, name := range onlyInSecond {
+		for _, name := range result.OnlyInSecond {
 			sb.WriteString(fmt.Sprintf("- %s\n", name))
 		}
 		sb.WriteString("\n")
@@ -359,312 +930,652 @@ func CompareTraces(traces1, traces2 []Trace) string {
 	return sb.String()
 }
 
-// New function to get the trace identifier based on the specified attribute
+// getTraceIdentifier returns the identifier of a trace. The attribute may
+// be a comma-separated list of attributes that are tried in order, falling
+// back to the trace ID when none of them is present.
 func getTraceIdentifier(t Trace, attribute string) string {
+	for _, name := range strings.Split(attribute, ",") {
+		if value, ok := lookupTraceIdentifier(t, strings.TrimSpace(name)); ok {
+			return value
+		}
+	}
+	return t.TraceID
+}
+
+// lookupTraceIdentifier resolves a single identifier attribute of a trace
+func lookupTraceIdentifier(t Trace, attribute string) (string, bool) {
 	// If the attribute is "trace_id", use the trace ID
 	if attribute == "trace_id" {
-		return t.TraceID
+		return t.TraceID, true
+	}
+
+	// If the attribute is "structure", use the shape of the span tree
+	if attribute == StructureAttribute {
+		return Fingerprint(t), true
 	}
 
 	// If the attribute is "name", find the root span or first span
 	if attribute == "name" {
 		if len(t.Spans) == 0 {
-			return "Unknown Trace"
+			return "Unknown Trace", true
 		}
 
 		// Try to find a root span (no parent)
 		for _, span := range t.Spans {
 			if span.ParentSpanID == "" {
-				return span.Name
+				return span.Name, true
 			}
 		}
 
 		// If no root span found, return the name of the first span
-		return t.Spans[0].Name
+		return t.Spans[0].Name, true
 	}
 
 	// Search in trace attributes
 	if value, ok := t.Attributes[attribute]; ok {
-		return value
+		return value, true
 	}
 
 	// Search in resource attributes
-	if value, ok := t.ResourceAttrs[attribute]; ok {
-		return value
+	value, ok := t.ResourceAttrs[attribute]
+	return value, ok
+}
+
+// traceIndex maps the traces of a set by their identifier attribute. Traces
+// sharing an identifier, such as requests to the same http.route, are
+// numbered by start time like repeated spans, e.g. "GET /users #2", so each
+// is compared with the trace of the same occurrence in other files.
+func traceIndex(set TraceSet, attribute string) map[string]*Trace {
+	traces := make([]*Trace, len(set.Traces))
+	for i := range set.Traces {
+		traces[i] = &set.Traces[i]
 	}
+	sort.SliceStable(traces, func(i, j int) bool {
+		return traceStart(*traces[i]).Before(traceStart(*traces[j]))
+	})
 
-	// Fallback to trace ID
-	return t.TraceID
+	index := make(map[string]*Trace, len(traces))
+	occurrences := make(map[string]int)
+	for _, t := range traces {
+		identifier := getTraceIdentifier(*t, attribute)
+		occurrences[identifier]++
+		index[spanRef{name: identifier, occurrence: occurrences[identifier]}.key()] = t
+	}
+	return index
 }
 
 // CompareMultipleTraces compares multiple sets of traces and generates a markdown report
-func CompareMultipleTraces(traceSets []TraceSet, attribute string) string {
+func CompareMultipleTraces(traceSets []TraceSet, attribute string, opts Options) string {
 	var sb strings.Builder
+	ind := opts.indicators()
 
 	sb.WriteString("### Multiple Traces Comparison\n\n")
 
-	// Create maps of traces by attribute for each set
-	traceMaps := make([]map[string]*Trace, len(traceSets))
-	for i, set := range traceSets {
-		traceMaps[i] = make(map[string]*Trace)
-		for j := range set.Traces {
-			identifier := getTraceIdentifier(set.Traces[j], attribute)
-			traceMaps[i][identifier] = &set.Traces[j]
+	// Match traces and spans the same way as the structured comparison
+	match := matchTraces(traceSets, attribute, opts)
+	traceMaps, traceNames := match.traceMaps, match.names
+
+	// Unrelated files make a valid but useless report, so say so first.
+	// Grouping by span attribute does not match traces at all.
+	if opts.GroupBy == "" {
+		sb.WriteString(matchWarning(traceMaps, match.allNames, attribute, opts.MinMatchFraction, ind))
+	}
+	if opts.Verdict != nil {
+		sb.WriteString(fmt.Sprintf("**%s**\n\n", verdictLine(traceSets, attribute, opts.AggregateBy, *opts.Verdict, ind)))
+	}
+	if len(traceNames) < len(match.allNames) {
+		sb.WriteString(topNote(len(traceNames), len(match.allNames)))
+	}
+
+	// Resource attributes of each file, to confirm the right builds are compared
+	writeResourceComparison(&sb, traceSets, ind)
+
+	// Summary table, comparing span durations per group when grouping or
+	// p90s per trace group when aggregating
+	var groups groupMatch
+	switch {
+	case opts.GroupBy != "":
+		writeGroupComparison(&sb, traceSets, opts.GroupBy, ind)
+	case opts.AggregateBy != "":
+		groups = matchTraceGroups(traceSets, opts.AggregateBy)
+		writeAggregateComparison(&sb, traceSets, groups, ind)
+	default:
+		writeComparisonSummary(&sb, traceSets, traceMaps, traceNames, ind)
+	}
+	sb.WriteString(durationLegend(traceSets[0].Name, ind))
+
+	// Traces that fan out to more services than in the first file
+	var fanOut []string
+	for _, name := range traceNames {
+		first, ok := traceMaps[0][name]
+		if !ok {
+			continue
+		}
+		baseCount := ServiceCount(*first)
+		for i := 1; i < len(traceMaps); i++ {
+			if trace, exists := traceMaps[i][name]; exists {
+				if count := ServiceCount(*trace); count > baseCount {
+					fanOut = append(fanOut, fmt.Sprintf("- %s %s: services %d → %d in %s\n",
+						ind.warning, name, baseCount, count, getFileNameWithoutExt(traceSets[i].Name)))
+				}
+			}
 		}
 	}
+	if len(fanOut) > 0 {
+		sb.WriteString("**Service Fan-out Increases:**\n\n")
+		for _, line := range fanOut {
+			sb.WriteString(line)
+		}
+		sb.WriteString("\n")
+	}
+
+	// Apdex scores per operation
+	writeApdexComparison(&sb, traceSets, opts)
+
+	// Aggregated traces compare span percentiles per trace group, since
+	// any single trace of a group is an arbitrary representative
+	if opts.AggregateBy != "" {
+		writeSpanPercentileComparison(&sb, traceSets, groups, ind)
+		writeFooter(&sb, opts.Footer, ind.compareLegend())
+		return sb.String()
+	}
 
-	// Find all unique trace names across all sets
-	allTraceNames := make(map[string]bool)
+	// Detailed comparison for matching traces
+	sb.WriteString("**Detailed Comparison:**\n\n")
+	for _, t := range match.traces {
+		name := t.name
+		sb.WriteString(fmt.Sprintf("<details>\n<summary>%s</summary>\n\n", name))
+
+		// Show the number of services involved in each file
+		var serviceCounts []string
+		for _, traceMap := range traceMaps {
+			serviceCounts = append(serviceCounts, fmt.Sprintf("%d", ServiceCount(*traceMap[name])))
+		}
+		sb.WriteString(fmt.Sprintf("**Services:** %s\n\n", strings.Join(serviceCounts, " → ")))
+
+		// Show trace attributes, as a unified diff if requested
+		if opts.AttrDiff == AttrDiffUnified {
+			writeUnifiedAttributeDiff(&sb, traceSets, traceMaps, name)
+		} else {
+			writeTraceAttributeTable(&sb, traceSets, traceMaps, name)
+		}
+
+		// Compare spans
+		sb.WriteString("**Span Comparison:**\n\n")
+		var spanHeader strings.Builder
+		spanHeader.WriteString("| Span Name |")
+		for _, set := range traceSets {
+			spanHeader.WriteString(fmt.Sprintf(" %s |", escapeMarkdownCell(getFileNameWithoutExt(set.Name))))
+		}
+		// With three or more files, name the fastest one per span
+		var fastestNames []string
+		diffHeaders := durationDiffHeaders(traceSets)
+		if len(traceSets) > 2 {
+			for _, set := range traceSets {
+				fastestNames = append(fastestNames, escapeMarkdownCell(getFileNameWithoutExt(set.Name)))
+			}
+			diffHeaders = append(diffHeaders, "Fastest")
+		}
+		spanHeader.WriteString(fmt.Sprintf(" %s | Changes |\n|-----------", strings.Join(diffHeaders, " | ")))
+		for range traceSets {
+			spanHeader.WriteString("|-----------")
+		}
+		spanHeader.WriteString(strings.Repeat("|------------", len(diffHeaders)))
+		spanHeader.WriteString("|---------|\n")
+
+		// Show span durations for each set, grouped by section if requested
+		sections := make(map[string]*strings.Builder)
+		for _, ref := range t.spans {
+			section := t.sections[ref]
+			if sections[section] == nil {
+				sections[section] = &strings.Builder{}
+			}
+			writeSpanComparisonRows(sections[section], t.spanIndexes, ref, t.aliases, fastestNames, ind)
+		}
+		writeSections(&sb, spanHeader.String(), sections)
+
+		sb.WriteString("\n</details>\n\n")
+	}
+	if match.unchanged > 0 {
+		sb.WriteString(fmt.Sprintf("_%d unchanged trace(s) hidden._\n", match.unchanged))
+	}
+
+	writeFooter(&sb, opts.Footer, ind.compareLegend())
+	return sb.String()
+}
+
+// writeTraceAttributeTable writes the trace and resource attributes of a
+// matching trace side by side, a column per trace set
+func writeTraceAttributeTable(sb *strings.Builder, traceSets []TraceSet, traceMaps []map[string]*Trace, name string) {
+	sb.WriteString("**Trace Attributes:**\n\n")
+	sb.WriteString("| Attribute |")
+	for _, set := range traceSets {
+		sb.WriteString(fmt.Sprintf(" %s |", escapeMarkdownCell(getFileNameWithoutExt(set.Name))))
+	}
+	sb.WriteString("\n|-----------")
+	for range traceSets {
+		sb.WriteString("|-----------")
+	}
+	sb.WriteString("|\n")
+
+	// Get all unique attribute keys
+	allAttrKeys := make(map[string]bool)
 	for _, traceMap := range traceMaps {
-		for name := range traceMap {
-			allTraceNames[name] = true
+		trace := traceMap[name]
+		for k := range trace.Attributes {
+			allAttrKeys[k] = true
+		}
+		for k := range trace.ResourceAttrs {
+			allAttrKeys[k] = true
 		}
 	}
 
 	// Convert to slice and sort
-	var traceNames []string
-	for name := range allTraceNames {
-		traceNames = append(traceNames, name)
+	var attrKeys []string
+	for k := range allAttrKeys {
+		attrKeys = append(attrKeys, k)
+	}
+	sort.Strings(attrKeys)
+
+	// Show attribute values for each set
+	for _, key := range attrKeys {
+		sb.WriteString(fmt.Sprintf("| %s |", escapeMarkdownCell(key)))
+		for i, _ := range traceSets {
+			trace := traceMaps[i][name]
+			var value string
+			if v, ok := trace.Attributes[key]; ok {
+				value = v
+			} else if v, ok := trace.ResourceAttrs[key]; ok {
+				value = v
+			}
+			sb.WriteString(fmt.Sprintf(" %s |", escapeMarkdownCell(value)))
+		}
+		sb.WriteString("\n")
 	}
-	sort.Strings(traceNames)
+	sb.WriteString("\n")
 
-	// Summary table
+}
+
+// writeComparisonSummary writes a table showing which trace sets contain
+// each trace and the largest duration and span count differences to the
+// first set, followed by the total and average span count of each set
+func writeComparisonSummary(sb *strings.Builder, traceSets []TraceSet, traceMaps []map[string]*Trace, traceNames []string, ind indicators) {
 	sb.WriteString("**Comparison Summary:**\n\n")
 	sb.WriteString("| Trace Name |")
 	for _, set := range traceSets {
-		sb.WriteString(fmt.Sprintf(" %s |", getFileNameWithoutExt(set.Name)))
+		sb.WriteString(fmt.Sprintf(" %s |", escapeMarkdownCell(getFileNameWithoutExt(set.Name))))
 	}
-	sb.WriteString(" Duration Diff |\n|------------")
+	diffHeaders := durationDiffHeaders(traceSets)
+	sb.WriteString(fmt.Sprintf(" %s | Span Diff |\n|------------", strings.Join(diffHeaders, " | ")))
 	for range traceSets {
 		sb.WriteString("|------------")
 	}
-	sb.WriteString("|------------|\n")
+	sb.WriteString(strings.Repeat("|------------", len(diffHeaders)))
+	sb.WriteString("|-----------|\n")
 
 	// For each trace name, show if it exists in each set and calculate duration differences
 	for _, name := range traceNames {
-		sb.WriteString(fmt.Sprintf("| %s |", name))
+		sb.WriteString(fmt.Sprintf("| %s |", escapeMarkdownCell(name)))
 
 		// Store durations for comparison
 		var durations []time.Duration
+		var present []bool
 		for _, traceMap := range traceMaps {
 			if trace, exists := traceMap[name]; exists {
-				sb.WriteString(" ✓ |")
+				sb.WriteString(fmt.Sprintf(" %s |", ind.present))
 				durations = append(durations, getTraceDuration(*trace))
 			} else {
-				sb.WriteString(" ✗ |")
+				sb.WriteString(fmt.Sprintf(" %s |", ind.missing))
 				durations = append(durations, 0)
 			}
+			present = append(present, traceMap[name] != nil)
 		}
 
-		// Calculate and show duration difference
-		if len(durations) > 1 {
-			firstDuration := durations[0]
-			isSlowerThanAny := false
-			var maxDiff time.Duration
-
-			// Compare first duration with all others
-			for i := 1; i < len(durations); i++ {
-				if durations[i] > 0 { // Only compare with existing traces
-					diff := durations[i] - firstDuration
-					if diff < 0 {
-						diff = -diff
-					}
-					if diff > maxDiff {
-						maxDiff = diff
-					}
-					if firstDuration > durations[i] {
-						isSlowerThanAny = true
-					}
-				}
-			}
+		sb.WriteString(fmt.Sprintf(" %s | %s |\n", strings.Join(durationDiffCells(durations, present, ind), " | "), spanCountDiff(traceMaps, name)))
+	}
 
-			if maxDiff > 0 {
-				indicator := "🔴"
-				if isSlowerThanAny {
-					indicator = "🟢"
-				}
-				sb.WriteString(fmt.Sprintf(" %s %s |\n", indicator, formatDuration(maxDiff)))
-			} else {
-				sb.WriteString(" - |\n")
-			}
-		} else {
-			sb.WriteString(" - |\n")
+	// Span counts across all traces of each set
+	sb.WriteString("| **Total spans** |")
+	for _, set := range traceSets {
+		sb.WriteString(fmt.Sprintf(" %d |", spanCount(set.Traces)))
+	}
+	sb.WriteString(fmt.Sprintf("%s %s |\n", strings.Repeat(" - |", len(diffHeaders)), matchingSpanCountDiff(traceMaps)))
+	sb.WriteString("| **Avg spans/trace** |")
+	for _, set := range traceSets {
+		avg := 0.0
+		if len(set.Traces) > 0 {
+			avg = float64(spanCount(set.Traces)) / float64(len(set.Traces))
 		}
+		sb.WriteString(fmt.Sprintf(" %.1f |", avg))
 	}
+	sb.WriteString(strings.Repeat(" - |", len(diffHeaders)) + " - |\n")
 	sb.WriteString("\n")
+}
 
-	// Detailed comparison for matching traces
-	sb.WriteString("**Detailed Comparison:**\n\n")
-	for _, name := range traceNames {
-		// Check if trace exists in all sets
-		existsInAll := true
-		for _, traceMap := range traceMaps {
-			if _, exists := traceMap[name]; !exists {
-				existsInAll = false
-				break
+// spanCount returns the number of spans across all traces
+func spanCount(traces []Trace) int {
+	var count int
+	for _, t := range traces {
+		count += len(t.Spans)
+	}
+	return count
+}
+
+// spanCountDiff formats the largest change in the span count of a trace
+// compared to the first set, among the sets that contain it
+func spanCountDiff(traceMaps []map[string]*Trace, name string) string {
+	base, ok := traceMaps[0][name]
+	if !ok {
+		return "-"
+	}
+	var deltas []int
+	for _, traceMap := range traceMaps[1:] {
+		if t, exists := traceMap[name]; exists {
+			deltas = append(deltas, len(t.Spans)-len(base.Spans))
+		}
+	}
+	return largestCountChange(deltas)
+}
+
+// matchingSpanCountDiff formats the largest change in the total span count
+// of the traces each set shares with the first set
+func matchingSpanCountDiff(traceMaps []map[string]*Trace) string {
+	var deltas []int
+	for _, traceMap := range traceMaps[1:] {
+		var delta int
+		for name, t := range traceMap {
+			if base, ok := traceMaps[0][name]; ok {
+				delta += len(t.Spans) - len(base.Spans)
 			}
 		}
+		deltas = append(deltas, delta)
+	}
+	return largestCountChange(deltas)
+}
 
-		if existsInAll {
-			sb.WriteString(fmt.Sprintf("<details>\n<summary>%s</summary>\n\n", name))
+// largestCountChange formats the change with the largest magnitude as a
+// signed number, or "-" when nothing changed
+func largestCountChange(deltas []int) string {
+	var largest int
+	for _, delta := range deltas {
+		if abs(float64(delta)) > abs(float64(largest)) {
+			largest = delta
+		}
+	}
+	if largest == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%+d", largest)
+}
 
-			// Show trace attributes
-			sb.WriteString("**Trace Attributes:**\n\n")
-			sb.WriteString("| Attribute |")
-			for _, set := range traceSets {
-				sb.WriteString(fmt.Sprintf(" %s |", getFileNameWithoutExt(set.Name)))
-			}
-			sb.WriteString("\n|-----------")
-			for range traceSets {
-				sb.WriteString("|-----------")
-			}
-			sb.WriteString("|\n")
+// durationDiff formats the largest signed duration difference between the
+// first duration and the others, with the change as a percentage of the
+// first duration. Present reports which durations exist; missing ones are
+// skipped, and nothing is compared when the first one is missing. It is
+// marked slower or faster with the indicators. The percentage is left out
+// when the first duration is zero.
+func durationDiff(durations []time.Duration, present []bool, ind indicators) string {
+	if len(durations) < 2 || !present[0] {
+		return "-"
+	}
 
-			// Get all unique attribute keys
-			allAttrKeys := make(map[string]bool)
-			for _, traceMap := range traceMaps {
-				trace := traceMap[name]
-				for k := range trace.Attributes {
-					allAttrKeys[k] = true
-				}
-				for k := range trace.ResourceAttrs {
-					allAttrKeys[k] = true
-				}
+	// Find the other duration that differs most from the first one
+	firstDuration := durations[0]
+	var largest time.Duration
+	for i := 1; i < len(durations); i++ {
+		if !present[i] {
+			continue
+		}
+		if diff := durations[i] - firstDuration; diff.Abs() > largest.Abs() {
+			largest = diff
+		}
+	}
+	if largest == 0 {
+		return "-"
+	}
+
+	indicator, delta := ind.slower, "+"+formatDuration(largest)
+	if largest < 0 {
+		indicator, delta = ind.faster, "-"+formatDuration(-largest)
+	}
+	if firstDuration == 0 {
+		return fmt.Sprintf("%s %s", indicator, delta)
+	}
+	percent := DurationChange{Baseline: firstDuration, Current: firstDuration + largest}.Percent()
+	return fmt.Sprintf("%s %s (%s)", indicator, delta, formatPercentChange(percent))
+}
+
+// durationDiffHeaders returns the headers of the duration diff columns,
+// one for every trace set compared with the first. Two trace sets have a
+// single Duration Diff column.
+func durationDiffHeaders(traceSets []TraceSet) []string {
+	if len(traceSets) <= 2 {
+		return []string{"Duration Diff"}
+	}
+	headers := make([]string, 0, len(traceSets)-1)
+	for _, set := range traceSets[1:] {
+		headers = append(headers, fmt.Sprintf("Δ %s", escapeMarkdownCell(getFileNameWithoutExt(set.Name))))
+	}
+	return headers
+}
+
+// metricDiffHeaders returns the headers of the diff columns of a metric such
+// as p90, like durationDiffHeaders: "p90 Diff" for two trace sets, and
+// "p90 Δ file" for every other trace set otherwise.
+func metricDiffHeaders(traceSets []TraceSet, metric string) []string {
+	if len(traceSets) <= 2 {
+		return []string{metric + " Diff"}
+	}
+	headers := make([]string, 0, len(traceSets)-1)
+	for _, set := range traceSets[1:] {
+		headers = append(headers, fmt.Sprintf("%s Δ %s", metric, escapeMarkdownCell(getFileNameWithoutExt(set.Name))))
+	}
+	return headers
+}
+
+// fastestFile names the file with the lowest of the present durations, or
+// every file sharing it on a tie. It returns "-" unless at least two
+// durations are present to compare.
+func fastestFile(names []string, durations []time.Duration, present []bool) string {
+	var fastest []string
+	var lowest time.Duration
+	count := 0
+	for i, d := range durations {
+		if !present[i] {
+			continue
+		}
+		count++
+		switch {
+		case len(fastest) == 0 || d < lowest:
+			fastest, lowest = []string{names[i]}, d
+		case d == lowest:
+			fastest = append(fastest, names[i])
+		}
+	}
+	if count < 2 {
+		return "-"
+	}
+	if len(fastest) > 1 {
+		return "tie: " + strings.Join(fastest, ", ")
+	}
+	return fastest[0]
+}
+
+// durationDiffCells formats the difference between the first duration and
+// every other one, for the columns of durationDiffHeaders. Present reports
+// which durations exist, see durationDiff.
+func durationDiffCells(durations []time.Duration, present []bool, ind indicators) []string {
+	if len(durations) <= 2 {
+		return []string{durationDiff(durations, present, ind)}
+	}
+	cells := make([]string, 0, len(durations)-1)
+	for i := 1; i < len(durations); i++ {
+		cells = append(cells, durationDiff(
+			[]time.Duration{durations[0], durations[i]},
+			[]bool{present[0], present[i]}, ind))
+	}
+	return cells
+}
+
+// durationLegend explains the indicators of the Duration Diff columns and
+// names the baseline they are relative to
+func durationLegend(baseline string, ind indicators) string {
+	return fmt.Sprintf("_%s slower than the baseline `%s` · %s faster than the baseline_\n\n", ind.slower, getFileNameWithoutExt(baseline), ind.faster)
+}
+
+// getComparisonSection returns the section of a span in a comparison,
+// resolved from the first file that contains the span
+func getComparisonSection(traceMaps []map[string]*Trace, spanIndexes []map[spanRef]*Span, traceName string, ref spanRef, attribute string) string {
+	for i, index := range spanIndexes {
+		if span, ok := index[ref]; ok {
+			return getSpanSection(traceMaps[i][traceName], span, attribute)
+		}
+	}
+	return unassignedSection
+}
+
+// writeSpanComparisonRows writes the duration and attribute rows comparing a
+// single span across all trace sets. Aliases map the span name to the name
+// of an approximately matching span in the trace set at the same index.
+// When the names of the trace sets are given, a column names the fastest.
+func writeSpanComparisonRows(sb *strings.Builder, spanIndexes []map[spanRef]*Span, ref spanRef, aliases []map[string]string, names []string, ind indicators) {
+	// Resolve the span in each trace set
+	refs := make([]spanRef, len(spanIndexes))
+	label := escapeMarkdownCell(ref.key())
+	seen := map[string]bool{ref.name: true}
+	for i := range spanIndexes {
+		refs[i] = ref
+		if alias, ok := aliases[i][ref.name]; ok {
+			refs[i].name = alias
+			if !seen[alias] {
+				label += " ≈ " + escapeMarkdownCell(refs[i].key())
+				seen[alias] = true
 			}
+		}
+	}
+	if len(seen) > 1 {
+		label += " _(approximate)_"
+	}
+	label += originalNames(spanIndexes, refs)
+
+	sb.WriteString(fmt.Sprintf("| %s |", label))
+	var spanDurations []time.Duration
+	var present []bool
+	spans := make([]*Span, len(spanIndexes))
+	for i, index := range spanIndexes {
+		span, found := index[refs[i]]
+		if found {
+			duration := spanDuration(*span)
+			spans[i] = span
+			sb.WriteString(fmt.Sprintf(" %s |", formatDuration(duration)))
+			spanDurations = append(spanDurations, duration)
+		} else {
+			sb.WriteString(fmt.Sprintf(" %s |", ind.missing))
+			spanDurations = append(spanDurations, 0)
+		}
+		present = append(present, found)
+	}
 
-			// Convert to slice and sort
-			var attrKeys []string
-			for k := range allAttrKeys {
-				attrKeys = append(attrKeys, k)
-			}
-			sort.Strings(attrKeys)
-
-			// Show attribute values for each set
-			for _, key := range attrKeys {
-				sb.WriteString(fmt.Sprintf("| %s |", key))
-				for i, _ := range traceSets {
-					trace := traceMaps[i][name]
-					var value string
-					if v, ok := trace.Attributes[key]; ok {
-						value = v
-					} else if v, ok := trace.ResourceAttrs[key]; ok {
-						value = v
-					}
-					sb.WriteString(fmt.Sprintf(" %s |", value))
-				}
-				sb.WriteString("\n")
+	// Calculate and show duration difference for spans
+	sb.WriteString(fmt.Sprintf(" %s |", strings.Join(durationDiffCells(spanDurations, present, ind), " | ")))
+	if names != nil {
+		sb.WriteString(fmt.Sprintf(" %s |", fastestFile(names, spanDurations, present)))
+	}
+	sb.WriteString(fmt.Sprintf(" %s |\n", spanChanges(spans, ind)))
+
+	// Show span attributes, marking keys that were added, removed or
+	// changed relative to the first file
+	sb.WriteString("| Attributes |")
+	for i, span := range spans {
+		var attrs []string
+		switch {
+		case span == nil:
+		case i == 0 || spans[0] == nil:
+			for k, v := range span.Attributes {
+				attrs = append(attrs, fmt.Sprintf("%s: %s", escapeMarkdownCell(k), escapeMarkdownCell(v)))
 			}
-			sb.WriteString("\n")
+			sort.Strings(attrs)
+		default:
+			for _, change := range DiffAttributes(spans[0].Attributes, span.Attributes) {
+				attrs = append(attrs, formatAttributeChange(change, ind))
+			}
+		}
+		sb.WriteString(fmt.Sprintf(" %s |", strings.Join(attrs, "<br> ")))
+	}
+	sb.WriteString("\n")
+}
 
-			// Compare spans
-			sb.WriteString("**Span Comparison:**\n\n")
-			sb.WriteString("| Span Name |")
-			for _, set := range traceSets {
-				sb.WriteString(fmt.Sprintf(" %s |", getFileNameWithoutExt(set.Name)))
+// spanChanges describes how a span changed between the first trace set and
+// the others. Missing spans are nil.
+func spanChanges(spans []*Span, ind indicators) string {
+	var changes []string
+	if base := spans[0]; base != nil {
+		for _, span := range spans[1:] {
+			if span == nil {
+				continue
 			}
-			sb.WriteString(" Duration Diff |\n|-----------")
-			for range traceSets {
-				sb.WriteString("|-----------")
+			if !isError(*base) && isError(*span) {
+				changes = append(changes, ind.failing+" OK → ERROR")
+				break
+			}
+			if isError(*base) && !isError(*span) {
+				changes = append(changes, ind.passing+" ERROR → OK")
+				break
 			}
-			sb.WriteString("|------------|\n")
+		}
 
-			// Get all unique span names
-			allSpanNames := make(map[string]bool)
-			for _, traceMap := range traceMaps {
-				trace := traceMap[name]
-				for _, span := range trace.Spans {
-					allSpanNames[span.Name] = true
-				}
+		// Attribute changes are often more telling than duration changes
+		for _, span := range spans[1:] {
+			if span != nil && hasAttributeChanges(DiffAttributes(base.Attributes, span.Attributes)) {
+				changes = append(changes, ind.attributesChanged+" attributes changed")
+				break
 			}
+		}
 
-			// Convert to slice and sort
-			var spanNames []string
-			for name := range allSpanNames {
-				spanNames = append(spanNames, name)
-			}
-			sort.Strings(spanNames)
-
-			// Show span durations for each set
-			for _, spanName := range spanNames {
-				sb.WriteString(fmt.Sprintf("| %s |", spanName))
-				var spanDurations []time.Duration
-				for i, _ := range traceSets {
-					trace := traceMaps[i][name]
-					var duration time.Duration
-					found := false
-					for _, span := range trace.Spans {
-						if span.Name == spanName {
-							duration = span.EndTime.Sub(span.StartTime)
-							found = true
-							break
-						}
-					}
-					if found {
-						sb.WriteString(fmt.Sprintf(" %s |", formatDuration(duration)))
-						spanDurations = append(spanDurations, duration)
-					} else {
-						sb.WriteString(" ✗ |")
-						spanDurations = append(spanDurations, 0)
-					}
-				}
+		// Events mark things like cache hits and retries
+		for _, span := range spans[1:] {
+			if span == nil {
+				continue
+			}
+			if eventChanges := DiffEvents(base.Events, span.Events); len(eventChanges) > 0 {
+				changes = append(changes, formatEventChanges(eventChanges, ind))
+				break
+			}
+		}
 
-				// Calculate and show duration difference for spans
-				if len(spanDurations) > 1 {
-					firstDuration := spanDurations[0]
-					isSlowerThanAny := false
-					var maxDiff time.Duration
-
-					// Compare first duration with all others
-					for i := 1; i < len(spanDurations); i++ {
-						if spanDurations[i] > 0 { // Only compare with existing spans
-							diff := spanDurations[i] - firstDuration
-							if diff < 0 {
-								diff = -diff
-							}
-							if diff > maxDiff {
-								maxDiff = diff
-							}
-							if firstDuration > spanDurations[i] {
-								isSlowerThanAny = true
-							}
-						}
-					}
-
-					if maxDiff > 0 {
-						indicator := "🔴"
-						if isSlowerThanAny {
-							indicator = "🟢"
-						}
-						sb.WriteString(fmt.Sprintf(" %s %s |\n", indicator, formatDuration(maxDiff)))
-					} else {
-						sb.WriteString(" - |\n")
-					}
-				} else {
-					sb.WriteString(" - |\n")
-				}
+		// Links connect producers and consumers of async messages
+		for _, span := range spans[1:] {
+			if span == nil {
+				continue
+			}
+			if change := linkChange(*base, *span, ind); change != "" {
+				changes = append(changes, change)
+				break
+			}
+		}
 
-				// Show span attributes
-				sb.WriteString("| Attributes |")
-				for i, _ := range traceSets {
-					trace := traceMaps[i][name]
-					var attrs []string
-					for _, span := range trace.Spans {
-						if span.Name == spanName {
-							for k, v := range span.Attributes {
-								attrs = append(attrs, fmt.Sprintf("%s: %s", k, v))
-							}
-							break
-						}
-					}
-					sort.Strings(attrs)
-					sb.WriteString(fmt.Sprintf(" %s |", strings.Join(attrs, "<br> ")))
-				}
-				sb.WriteString("\n")
+		// A span changing kind usually means broken instrumentation
+		for _, span := range spans[1:] {
+			if span != nil && span.Kind != base.Kind {
+				changes = append(changes, fmt.Sprintf("%s kind %s → %s", ind.warning, formatKind(base.Kind), formatKind(span.Kind)))
+				break
 			}
+		}
 
-			sb.WriteString("\n</details>\n\n")
+		// A new scope version points at an SDK or instrumentation upgrade
+		for _, span := range spans[1:] {
+			if span == nil {
+				continue
+			}
+			if change := scopeChange(*base, *span, ind); change != "" {
+				changes = append(changes, change)
+				break
+			}
 		}
 	}
 
-	return sb.String()
+	if len(changes) == 0 {
+		return "-"
+	}
+	return strings.Join(changes, "<br>")
 }
@@ -1,6 +1,11 @@
 package trace
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -27,6 +32,16 @@ func TestParseTraces(t *testing.T) {
 			input:   []byte(`[]`),
 			wantErr: false,
 		},
+		{
+			name:    "empty input",
+			input:   []byte(""),
+			wantErr: true,
+		},
+		{
+			name:    "whitespace only",
+			input:   []byte(" \n\t\n"),
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -36,6 +51,9 @@ func TestParseTraces(t *testing.T) {
 				t.Errorf("ParseTraces() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if len(bytes.TrimSpace(tt.input)) == 0 && !errors.Is(err, ErrEmptyInput) {
+				t.Errorf("ParseTraces() error = %v, want ErrEmptyInput", err)
+			}
 			if !tt.wantErr && len(got) == 0 && tt.name != "empty array" {
 				t.Error("ParseTraces() returned empty slice for valid input")
 			}
@@ -127,6 +145,44 @@ func TestGetTraceIdentifier(t *testing.T) {
 			attribute: "non-existent",
 			expected:  "test-trace",
 		},
+		{
+			name: "first attribute of a chain",
+			trace: Trace{
+				TraceID:    "test-trace",
+				Attributes: map[string]string{"http.route": "/users/{id}", "http.target": "/users/42"},
+				Spans:      []Span{{Name: "root"}},
+			},
+			attribute: "http.route,http.target,name",
+			expected:  "/users/{id}",
+		},
+		{
+			name: "chain falls through missing attributes",
+			trace: Trace{
+				TraceID:       "test-trace",
+				ResourceAttrs: map[string]string{"http.target": "/users/42"},
+				Spans:         []Span{{Name: "root"}},
+			},
+			attribute: "http.route, http.target,name",
+			expected:  "/users/42",
+		},
+		{
+			name: "chain falls through to name",
+			trace: Trace{
+				TraceID: "test-trace",
+				Spans:   []Span{{Name: "root"}},
+			},
+			attribute: "http.route,http.target,name",
+			expected:  "root",
+		},
+		{
+			name: "chain falls back to trace_id",
+			trace: Trace{
+				TraceID: "test-trace",
+				Spans:   []Span{{Name: "root"}},
+			},
+			attribute: "http.route,http.target",
+			expected:  "test-trace",
+		},
 	}
 
 	for _, tt := range tests {
@@ -179,6 +235,34 @@ func TestGetTraceDuration(t *testing.T) {
 			trace:    Trace{Spans: []Span{}},
 			expected: 0,
 		},
+		{
+			name: "end before start",
+			trace: Trace{
+				Spans: []Span{
+					{
+						StartTime: now.Add(time.Second),
+						EndTime:   now,
+					},
+				},
+			},
+			expected: 0,
+		},
+		{
+			name: "skewed span inside a trace",
+			trace: Trace{
+				Spans: []Span{
+					{
+						StartTime: now,
+						EndTime:   now.Add(time.Second),
+					},
+					{
+						StartTime: now.Add(2 * time.Second),
+						EndTime:   now.Add(500 * time.Millisecond),
+					},
+				},
+			},
+			expected: 2 * time.Second,
+		},
 	}
 
 	for _, tt := range tests {
@@ -191,12 +275,74 @@ func TestGetTraceDuration(t *testing.T) {
 	}
 }
 
+func TestGenerateMarkdownClockSkew(t *testing.T) {
+	now := time.Now()
+	traces := []Trace{{TraceID: "trace1", Spans: []Span{
+		{SpanID: "a", Name: "root", StartTime: now, EndTime: now.Add(time.Second)},
+		{SpanID: "b", ParentSpanID: "a", Name: "skewed", StartTime: now.Add(time.Second), EndTime: now.Add(400 * time.Millisecond)},
+	}}}
+
+	got := GenerateMarkdown(traces, Options{})
+	if strings.Contains(got, "-600") {
+		t.Errorf("GenerateMarkdown() printed a negative duration:\n%s", got)
+	}
+	for _, want := range []string{
+		"- Trace `trace1`: span \"skewed\" (b) ends 600.00ms before it starts",
+		"| `trace1` | `b` | skewed | - | 0ns | root |",
+		"- **skewed** (0ns)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("GenerateMarkdown() output does not contain %q:\n%s", want, got)
+		}
+	}
+}
+
 func TestFormatDuration(t *testing.T) {
 	tests := []struct {
 		name     string
 		duration time.Duration
 		expected string
 	}{
+		{
+			name:     "zero",
+			duration: 0,
+			expected: "0ns",
+		},
+		{
+			name:     "nanoseconds",
+			duration: 640 * time.Nanosecond,
+			expected: "640ns",
+		},
+		{
+			name:     "negative nanoseconds",
+			duration: -640 * time.Nanosecond,
+			expected: "-640ns",
+		},
+		{
+			name:     "negative microseconds",
+			duration: -500 * time.Microsecond,
+			expected: "-500.00µs",
+		},
+		{
+			name:     "negative milliseconds",
+			duration: -1500 * time.Microsecond,
+			expected: "-1.50ms",
+		},
+		{
+			name:     "negative hours",
+			duration: -(90 * time.Minute),
+			expected: "-1h30m",
+		},
+		{
+			name:     "most negative duration",
+			duration: math.MinInt64,
+			expected: "-2562047h47m",
+		},
+		{
+			name:     "one microsecond",
+			duration: time.Microsecond,
+			expected: "1.00µs",
+		},
 		{
 			name:     "microseconds",
 			duration: 500 * time.Microsecond,
@@ -207,11 +353,46 @@ func TestFormatDuration(t *testing.T) {
 			duration: 500 * time.Millisecond,
 			expected: "500.00ms",
 		},
+		{
+			name:     "fractional milliseconds",
+			duration: 1500 * time.Microsecond,
+			expected: "1.50ms",
+		},
+		{
+			name:     "fractional milliseconds rounding",
+			duration: 1750 * time.Microsecond,
+			expected: "1.75ms",
+		},
 		{
 			name:     "seconds",
 			duration: 5 * time.Second,
 			expected: "5.00s",
 		},
+		{
+			name:     "just under a minute",
+			duration: 59*time.Second + 990*time.Millisecond,
+			expected: "59.99s",
+		},
+		{
+			name:     "one minute",
+			duration: time.Minute,
+			expected: "1m0.00s",
+		},
+		{
+			name:     "minutes",
+			duration: 372 * time.Second,
+			expected: "6m12.00s",
+		},
+		{
+			name:     "one hour",
+			duration: time.Hour,
+			expected: "1h0m",
+		},
+		{
+			name:     "hours",
+			duration: time.Hour + 3*time.Minute + 30*time.Second,
+			expected: "1h3m",
+		},
 	}
 
 	for _, tt := range tests {
@@ -228,28 +409,44 @@ func TestTruncateID(t *testing.T) {
 	tests := []struct {
 		name     string
 		id       string
+		length   int
 		expected string
 	}{
 		{
 			name:     "long id",
 			id:       "1234567890",
+			length:   8,
 			expected: "12345678",
 		},
 		{
 			name:     "short id",
 			id:       "123",
+			length:   8,
 			expected: "123",
 		},
 		{
 			name:     "empty id",
 			id:       "",
+			length:   8,
 			expected: "",
 		},
+		{
+			name:     "custom length",
+			id:       "1234567890",
+			length:   4,
+			expected: "1234",
+		},
+		{
+			name:     "no truncation",
+			id:       "1234567890",
+			length:   0,
+			expected: "1234567890",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := truncateID(tt.id)
+			got := truncateID(tt.id, tt.length)
 			if got != tt.expected {
 				t.Errorf("truncateID() = %v, want %v", got, tt.expected)
 			}
@@ -283,7 +480,27 @@ func TestCompareTraces(t *testing.T) {
 					},
 				},
 			},
-			contains: []string{"Matching Traces", "Duration Comparison"},
+			contains: []string{"Matching Traces", "Duration Comparison", "| Difference | +1.00s (100.0%) |"},
+		},
+		{
+			name: "faster trace",
+			traces1: []Trace{
+				{
+					TraceID: "trace1",
+					Spans: []Span{
+						{Name: "span1", StartTime: now, EndTime: now.Add(time.Second)},
+					},
+				},
+			},
+			traces2: []Trace{
+				{
+					TraceID: "trace1",
+					Spans: []Span{
+						{Name: "span1", StartTime: now, EndTime: now.Add(500 * time.Millisecond)},
+					},
+				},
+			},
+			contains: []string{"| Difference | -500.00ms (-50.0%) |"},
 		},
 		{
 			name: "different traces",
@@ -318,3 +535,750 @@ func TestCompareTraces(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateMarkdownIDLength(t *testing.T) {
+	now := time.Now()
+	traces := []Trace{{TraceID: "trace1", Spans: []Span{
+		{SpanID: "0123456789abcdef", Name: "root", StartTime: now, EndTime: now.Add(time.Second)},
+	}}}
+
+	tests := []struct {
+		name     string
+		idLength int
+		expected string
+	}{
+		{name: "default", idLength: 0, expected: "| `trace1` | `01234567` |"},
+		{name: "custom", idLength: 12, expected: "| `trace1` | `0123456789ab` |"},
+		{name: "full", idLength: -1, expected: "| `trace1` | `0123456789abcdef` |"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GenerateMarkdown(traces, Options{IDLength: tt.idLength})
+			if !strings.Contains(got, tt.expected) {
+				t.Errorf("GenerateMarkdown() output does not contain %q:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetSpanSection(t *testing.T) {
+	trace := Trace{
+		ResourceAttrs: map[string]string{"team": "platform"},
+		Spans: []Span{
+			{SpanID: "span1", Name: "root", Attributes: map[string]string{"team": "checkout"}},
+			{SpanID: "span2", ParentSpanID: "span1", Name: "child"},
+		},
+	}
+	tests := []struct {
+		name      string
+		span      int
+		attribute string
+		expected  string
+	}{
+		{name: "span attribute", span: 0, attribute: "team", expected: "checkout"},
+		{name: "resource fallback", span: 1, attribute: "team", expected: "platform"},
+		{name: "unassigned", span: 1, attribute: "owner", expected: "unassigned"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := getSpanSection(&trace, &trace.Spans[tt.span], tt.attribute)
+			if got != tt.expected {
+				t.Errorf("getSpanSection() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGenerateMarkdownSectionBy(t *testing.T) {
+	now := time.Now()
+	traces := []Trace{
+		{
+			TraceID: "trace1",
+			Spans: []Span{
+				{SpanID: "span1", Name: "checkout", StartTime: now, EndTime: now.Add(time.Second), Attributes: map[string]string{"team": "payments"}},
+				{SpanID: "span2", ParentSpanID: "span1", Name: "lookup", StartTime: now, EndTime: now.Add(time.Millisecond)},
+			},
+		},
+	}
+
+	got := GenerateMarkdown(traces, Options{SectionBy: "team"})
+	payments := strings.Index(got, "#### payments")
+	unassigned := strings.Index(got, "#### unassigned")
+	if payments == -1 || unassigned == -1 {
+		t.Fatalf("GenerateMarkdown() output missing section headers:\n%s", got)
+	}
+	if payments > unassigned {
+		t.Errorf("GenerateMarkdown() should list unassigned section last")
+	}
+	if strings.Contains(GenerateMarkdown(traces, Options{}), "####") {
+		t.Errorf("GenerateMarkdown() without SectionBy should not write section headers")
+	}
+}
+
+func TestServiceCount(t *testing.T) {
+	tests := []struct {
+		name     string
+		trace    Trace
+		expected int
+	}{
+		{
+			name: "resource service only",
+			trace: Trace{
+				ResourceAttrs: map[string]string{"service.name": "api"},
+				Spans:         []Span{{Name: "a"}, {Name: "b"}},
+			},
+			expected: 1,
+		},
+		{
+			name: "span services",
+			trace: Trace{
+				ResourceAttrs: map[string]string{"service.name": "api"},
+				Spans: []Span{
+					{Name: "a"},
+					{Name: "b", Attributes: map[string]string{"service.name": "db"}},
+					{Name: "c", Attributes: map[string]string{"service.name": "cache"}},
+				},
+			},
+			expected: 3,
+		},
+		{
+			name:     "no services",
+			trace:    Trace{Spans: []Span{{Name: "a"}}},
+			expected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ServiceCount(tt.trace)
+			if got != tt.expected {
+				t.Errorf("ServiceCount() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCompareMultipleTracesServiceFanOut(t *testing.T) {
+	now := time.Now()
+	traceSets := []TraceSet{
+		{Name: "base.json", Traces: []Trace{{TraceID: "trace1", Spans: []Span{
+			{Name: "root", StartTime: now, EndTime: now.Add(time.Second), Attributes: map[string]string{"service.name": "api"}},
+		}}}},
+		{Name: "head.json", Traces: []Trace{{TraceID: "trace1", Spans: []Span{
+			{Name: "root", StartTime: now, EndTime: now.Add(time.Second), Attributes: map[string]string{"service.name": "api"}},
+			{Name: "lookup", StartTime: now, EndTime: now.Add(time.Millisecond), Attributes: map[string]string{"service.name": "users"}},
+		}}}},
+	}
+
+	got := CompareMultipleTraces(traceSets, "trace_id", Options{})
+	for _, want := range []string{"Service Fan-out Increases", "trace1: services 1 → 2 in head", "**Services:** 1 → 2"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("CompareMultipleTraces() output does not contain %q", want)
+		}
+	}
+}
+
+func TestShowSpanFoldRepeats(t *testing.T) {
+	now := time.Now()
+	trace := Trace{
+		TraceID: "trace1",
+		Spans: []Span{
+			{SpanID: "root", Name: "loop", StartTime: now, EndTime: now.Add(10 * time.Millisecond)},
+			{SpanID: "i1", ParentSpanID: "root", Name: "iteration", StartTime: now, EndTime: now.Add(time.Millisecond)},
+			{SpanID: "i2", ParentSpanID: "root", Name: "iteration", StartTime: now, EndTime: now.Add(2 * time.Millisecond)},
+			{SpanID: "i3", ParentSpanID: "root", Name: "iteration", StartTime: now, EndTime: now.Add(3 * time.Millisecond)},
+			{SpanID: "done", ParentSpanID: "root", Name: "flush", StartTime: now, EndTime: now.Add(time.Millisecond)},
+		},
+	}
+
+	var folded strings.Builder
+	showSpan(&folded, &trace, "", nil, Options{FoldRepeats: true})
+	if !strings.Contains(folded.String(), "- **iteration** ×3, total 6.00ms, avg 2.00ms") {
+		t.Errorf("showSpan() did not fold repeated spans:\n%s", folded.String())
+	}
+	if !strings.Contains(folded.String(), "- **flush**") {
+		t.Errorf("showSpan() dropped the span after the folded group:\n%s", folded.String())
+	}
+
+	var exact strings.Builder
+	showSpan(&exact, &trace, "", nil, Options{})
+	if got := strings.Count(exact.String(), "- **iteration**"); got != 3 {
+		t.Errorf("showSpan() without FoldRepeats rendered %d iteration spans, want 3", got)
+	}
+}
+
+func TestShowSpanMaxDepth(t *testing.T) {
+	// root → a → b → c, with a second child d of a and a sibling e of a
+	trace := Trace{
+		TraceID: "trace1",
+		Spans: []Span{
+			{SpanID: "root", Name: "root"},
+			{SpanID: "a", ParentSpanID: "root", Name: "a"},
+			{SpanID: "b", ParentSpanID: "a", Name: "b"},
+			{SpanID: "c", ParentSpanID: "b", Name: "c"},
+			{SpanID: "d", ParentSpanID: "a", Name: "d"},
+			{SpanID: "e", ParentSpanID: "root", Name: "e"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		maxDepth int
+		shown    []string
+		notes    []string
+	}{
+		{name: "unlimited", maxDepth: 0, shown: []string{"root", "a", "b", "c", "d", "e"}},
+		{name: "roots only", maxDepth: 1, shown: []string{"root"}, notes: []string{"+5 deeper span(s)"}},
+		{name: "two levels", maxDepth: 2, shown: []string{"root", "a", "e"}, notes: []string{"+3 deeper span(s)"}},
+		{name: "three levels", maxDepth: 3, shown: []string{"root", "a", "b", "d", "e"}, notes: []string{"+1 deeper span(s)"}},
+		{name: "deeper than the trace", maxDepth: 10, shown: []string{"root", "a", "b", "c", "d", "e"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sb strings.Builder
+			showSpan(&sb, &trace, "", nil, Options{MaxSpanDepth: tt.maxDepth})
+			got := sb.String()
+			if count := strings.Count(got, "- **"); count != len(tt.shown) {
+				t.Errorf("showSpan() rendered %d spans, want %d:\n%s", count, len(tt.shown), got)
+			}
+			for _, name := range tt.shown {
+				if !strings.Contains(got, fmt.Sprintf("- **%s**", name)) {
+					t.Errorf("showSpan() did not render %s:\n%s", name, got)
+				}
+			}
+			if count := strings.Count(got, "deeper span(s)"); count != len(tt.notes) {
+				t.Errorf("showSpan() wrote %d depth notes, want %d:\n%s", count, len(tt.notes), got)
+			}
+			for _, note := range tt.notes {
+				if !strings.Contains(got, note) {
+					t.Errorf("showSpan() did not summarize with %q:\n%s", note, got)
+				}
+			}
+		})
+	}
+}
+
+func TestParseTracesStatus(t *testing.T) {
+	input := []byte(`[{"trace_id": "trace1", "spans": [
+		{"span_id": "a", "name": "ok", "status": {"code": "OK"}},
+		{"span_id": "b", "name": "prefixed", "status": {"code": "STATUS_CODE_ERROR", "message": "boom"}},
+		{"span_id": "c", "name": "numeric", "status": {"code": 2}},
+		{"span_id": "d", "name": "unset"}
+	]}]`)
+
+	traces, err := ParseTraces(input)
+	if err != nil {
+		t.Fatalf("ParseTraces() error = %v", err)
+	}
+
+	spans := traces[0].Spans
+	expected := []StatusCode{StatusOK, StatusError, StatusError, ""}
+	for i, want := range expected {
+		if spans[i].Status.Code != want {
+			t.Errorf("span %s status = %q, want %q", spans[i].Name, spans[i].Status.Code, want)
+		}
+	}
+	if spans[1].Status.Message != "boom" {
+		t.Errorf("span status message = %q, want boom", spans[1].Status.Message)
+	}
+	if got := errorCount(traces[0]); got != 2 {
+		t.Errorf("errorCount() = %d, want 2", got)
+	}
+}
+
+func TestCompareMultipleTracesStatusFlip(t *testing.T) {
+	now := time.Now()
+	traceSets := []TraceSet{
+		{Name: "base.json", Traces: []Trace{{TraceID: "trace1", Spans: []Span{
+			{Name: "charge", StartTime: now, EndTime: now.Add(time.Second), Status: Status{Code: StatusOK}},
+		}}}},
+		{Name: "head.json", Traces: []Trace{{TraceID: "trace1", Spans: []Span{
+			{Name: "charge", StartTime: now, EndTime: now.Add(time.Second), Status: Status{Code: StatusError}},
+		}}}},
+	}
+
+	got := CompareMultipleTraces(traceSets, "trace_id", Options{})
+	if !strings.Contains(got, "| charge | 1.00s | 1.00s | - | ❌ OK → ERROR |") {
+		t.Errorf("CompareMultipleTraces() did not flag the status flip:\n%s", got)
+	}
+}
+
+func TestCompareMultipleTracesNamesBaseline(t *testing.T) {
+	now := time.Now()
+	newSet := func(name string, d time.Duration) TraceSet {
+		return TraceSet{Name: name, Traces: []Trace{{TraceID: "trace1", Spans: []Span{
+			{Name: "root", StartTime: now, EndTime: now.Add(d)},
+		}}}}
+	}
+	traceSets := []TraceSet{newSet("main.json", time.Second), newSet("feature.json", 2*time.Second)}
+
+	got := CompareMultipleTraces(traceSets, "trace_id", Options{})
+	for _, want := range []string{
+		"_🔴 slower than the baseline `main` · 🟢 faster than the baseline_",
+		"| trace1 | ✓ | ✓ | 🔴 +1.00s (+100.0%) | - |",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("CompareMultipleTraces() output does not contain %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestCompareMultipleTracesSharedIdentifier(t *testing.T) {
+	now := time.Now()
+	newSet := func(name string, first, second time.Duration) TraceSet {
+		// The slower request is listed first but started second
+		return TraceSet{Name: name, Traces: []Trace{
+			{TraceID: name + "-2", Attributes: map[string]string{"http.route": "/users"}, Spans: []Span{
+				{Name: "GET /users", StartTime: now.Add(time.Minute), EndTime: now.Add(time.Minute + second)},
+			}},
+			{TraceID: name + "-1", Attributes: map[string]string{"http.route": "/users"}, Spans: []Span{
+				{Name: "GET /users", StartTime: now, EndTime: now.Add(first)},
+			}},
+		}}
+	}
+	traceSets := []TraceSet{
+		newSet("main.json", time.Second, 2*time.Second),
+		newSet("feature.json", time.Second, 3*time.Second),
+	}
+
+	got := CompareMultipleTraces(traceSets, "http.route", Options{})
+	for _, want := range []string{
+		"| /users | ✓ | ✓ | - | - |",
+		"| /users #2 | ✓ | ✓ | 🔴 +1.00s (+50.0%) | - |",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("CompareMultipleTraces() output does not contain %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestParseTracesTraceState(t *testing.T) {
+	// Trace state is vendor data, so it is kept exactly as written
+	const state = "congo=t61rcWkgMzE, rojo=00f067aa0ba902b7 "
+	input := []byte(`[{"trace_id": "trace1", "spans": [
+		{"span_id": "a", "name": "root", "trace_state": "` + state + `"},
+		{"span_id": "b", "parent_span_id": "a", "name": "child"}
+	]}]`)
+
+	traces, err := ParseTraces(input)
+	if err != nil {
+		t.Fatalf("ParseTraces() error = %v", err)
+	}
+	if got := traces[0].Spans[0].TraceState; got != state {
+		t.Errorf("root trace state = %q, want %q", got, state)
+	}
+	if got := traces[0].Spans[1].TraceState; got != "" {
+		t.Errorf("child trace state = %q, want none", got)
+	}
+
+	markdown := GenerateMarkdown(traces, Options{})
+	if want := "  **Trace State:** `" + state + "`\n"; !strings.Contains(markdown, want) {
+		t.Errorf("GenerateMarkdown() output does not contain %q:\n%s", want, markdown)
+	}
+}
+
+func TestParseTracesKind(t *testing.T) {
+	input := []byte(`[{"trace_id": "trace1", "spans": [
+		{"span_id": "a", "name": "server", "kind": "SERVER"},
+		{"span_id": "b", "name": "prefixed", "kind": "SPAN_KIND_CLIENT"},
+		{"span_id": "c", "name": "numeric", "kind": 4},
+		{"span_id": "d", "name": "unspecified", "kind": "SPAN_KIND_UNSPECIFIED"},
+		{"span_id": "e", "name": "missing"}
+	]}]`)
+
+	traces, err := ParseTraces(input)
+	if err != nil {
+		t.Fatalf("ParseTraces() error = %v", err)
+	}
+
+	spans := traces[0].Spans
+	expected := []SpanKind{SpanKindServer, SpanKindClient, SpanKindProducer, "", ""}
+	for i, want := range expected {
+		if spans[i].Kind != want {
+			t.Errorf("span %s kind = %q, want %q", spans[i].Name, spans[i].Kind, want)
+		}
+	}
+}
+
+func TestCompareMultipleTracesKindChange(t *testing.T) {
+	now := time.Now()
+	traceSets := []TraceSet{
+		{Name: "base.json", Traces: []Trace{{TraceID: "trace1", Spans: []Span{
+			{Name: "GET /users", Kind: SpanKindServer, StartTime: now, EndTime: now.Add(time.Second)},
+		}}}},
+		{Name: "head.json", Traces: []Trace{{TraceID: "trace1", Spans: []Span{
+			{Name: "GET /users", Kind: SpanKindClient, StartTime: now, EndTime: now.Add(time.Second)},
+		}}}},
+	}
+
+	got := CompareMultipleTraces(traceSets, "trace_id", Options{})
+	if !strings.Contains(got, "| GET /users | 1.00s | 1.00s | - | ⚠️ kind SERVER → CLIENT |") {
+		t.Errorf("CompareMultipleTraces() did not flag the kind change:\n%s", got)
+	}
+}
+
+func TestCompareMultipleTracesDuplicateSpanNames(t *testing.T) {
+	now := time.Now()
+	newTrace := func(durations ...time.Duration) Trace {
+		t := Trace{TraceID: "trace1", Spans: []Span{
+			{SpanID: "root", Name: "GET /users", StartTime: now, EndTime: now.Add(time.Second)},
+		}}
+		for i, d := range durations {
+			start := now.Add(time.Duration(i) * 100 * time.Millisecond)
+			t.Spans = append(t.Spans, Span{SpanID: fmt.Sprintf("q%d", i), ParentSpanID: "root", Name: "db.query", StartTime: start, EndTime: start.Add(d)})
+		}
+		return t
+	}
+	traceSets := []TraceSet{
+		{Name: "base.json", Traces: []Trace{newTrace(10*time.Millisecond, 20*time.Millisecond, 30*time.Millisecond)}},
+		{Name: "head.json", Traces: []Trace{newTrace(10*time.Millisecond, 50*time.Millisecond)}},
+	}
+
+	got := CompareMultipleTraces(traceSets, "trace_id", Options{})
+
+	// Each occurrence is compared with the same occurrence in the other file
+	expected := []string{
+		"| db.query | 10.00ms | 10.00ms | - | - |",
+		"| db.query #2 | 20.00ms | 50.00ms | 🔴 +30.00ms (+150.0%) | - |",
+		"| db.query #3 | 30.00ms | ✗ | - | - |",
+	}
+	for _, want := range expected {
+		if !strings.Contains(got, want) {
+			t.Errorf("CompareMultipleTraces() output does not contain %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestGenerateMarkdownDeterministic(t *testing.T) {
+	now := time.Now()
+	attrs := make(map[string]string)
+	for i := 0; i < 20; i++ {
+		attrs[fmt.Sprintf("key%02d", i)] = fmt.Sprintf("value%d", i)
+	}
+	traces := []Trace{{
+		TraceID:    "trace1",
+		Attributes: attrs,
+		Spans: []Span{{
+			SpanID: "a", Name: "root", StartTime: now, EndTime: now.Add(time.Second),
+			Attributes: attrs,
+			Events:     []Event{{Name: "event", Attributes: attrs}},
+		}},
+	}}
+
+	first := GenerateMarkdown(traces, Options{})
+	for i := 0; i < 10; i++ {
+		if got := GenerateMarkdown(traces, Options{}); got != first {
+			t.Fatalf("GenerateMarkdown() output changed between runs:\n%s\n---\n%s", first, got)
+		}
+	}
+	if strings.Index(first, "| key00 |") > strings.Index(first, "| key19 |") {
+		t.Errorf("GenerateMarkdown() did not sort trace attributes:\n%s", first)
+	}
+}
+
+func TestEscapeMarkdownCell(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "pipe", input: "a|b", expected: `a\|b`},
+		{name: "backtick", input: "`x`", expected: "\\`x\\`"},
+		{name: "newlines", input: "line1\nline2\r\nline3", expected: "line1<br>line2<br>line3"},
+		{name: "plain", input: "GET /users", expected: "GET /users"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := escapeMarkdownCell(tt.input)
+			if got != tt.expected {
+				t.Errorf("escapeMarkdownCell() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// countCells returns the number of cells in a markdown table row, ignoring
+// escaped pipes
+func countCells(row string) int {
+	return strings.Count(strings.ReplaceAll(row, `\|`, ""), "|") - 1
+}
+
+func TestGenerateMarkdownEscapesCells(t *testing.T) {
+	now := time.Now()
+	traces := []Trace{
+		{
+			TraceID: "trace1",
+			Attributes: map[string]string{
+				"db.statement": "SELECT *\nFROM t",
+			},
+			Spans: []Span{
+				{SpanID: "span1", Name: "SELECT * FROM t WHERE a|b", StartTime: now, EndTime: now.Add(time.Second)},
+			},
+		},
+	}
+
+	got := GenerateMarkdown(traces, Options{})
+	found := false
+	for _, line := range strings.Split(got, "\n") {
+		if !strings.HasPrefix(line, "|") || !strings.Contains(line, "SELECT * FROM t WHERE a") {
+			continue
+		}
+		// Span details rows start with the trace ID; self time rows do not
+		want := 5
+		if strings.HasPrefix(line, "| `trace1` |") {
+			found = true
+			want = 6
+		}
+		if cells := countCells(line); cells != want {
+			t.Errorf("span row has %d cells, want %d: %s", cells, want, line)
+		}
+	}
+	if !found {
+		t.Fatalf("GenerateMarkdown() output does not contain the span row:\n%s", got)
+	}
+	if !strings.Contains(got, "| db.statement | SELECT *<br>FROM t |") {
+		t.Errorf("GenerateMarkdown() did not replace newlines in attribute values:\n%s", got)
+	}
+}
+
+func TestCompareMultipleTracesEscapesCells(t *testing.T) {
+	now := time.Now()
+	span := Span{Name: "SELECT * FROM t WHERE a|b", StartTime: now, EndTime: now.Add(time.Second)}
+	traceSets := []TraceSet{
+		{Name: "base.json", Traces: []Trace{{TraceID: "trace1", Spans: []Span{span}}}},
+		{Name: "head.json", Traces: []Trace{{TraceID: "trace1", Spans: []Span{span}}}},
+	}
+
+	got := CompareMultipleTraces(traceSets, "trace_id", Options{})
+	for _, line := range strings.Split(got, "\n") {
+		if strings.HasPrefix(line, "| SELECT") {
+			if cells := countCells(line); cells != 5 {
+				t.Errorf("span row has %d cells, want 5: %s", cells, line)
+			}
+			return
+		}
+	}
+	t.Errorf("CompareMultipleTraces() output does not contain the span row:\n%s", got)
+}
+
+func TestGenerateMarkdownDoesNotMutateInput(t *testing.T) {
+	now := time.Now()
+	traces := []Trace{
+		{
+			TraceID: "fast",
+			Spans: []Span{
+				{SpanID: "a", Name: "short", StartTime: now, EndTime: now.Add(time.Millisecond)},
+				{SpanID: "b", ParentSpanID: "a", Name: "long", StartTime: now, EndTime: now.Add(time.Second)},
+			},
+		},
+		{
+			TraceID: "slow",
+			Spans: []Span{
+				{SpanID: "c", Name: "root", StartTime: now, EndTime: now.Add(time.Minute)},
+			},
+		},
+	}
+
+	var traceOrder, spanOrder []string
+	for _, tr := range traces {
+		traceOrder = append(traceOrder, tr.TraceID)
+		for _, span := range tr.Spans {
+			spanOrder = append(spanOrder, span.SpanID)
+		}
+	}
+
+	GenerateMarkdown(traces, Options{})
+
+	var gotTraces, gotSpans []string
+	for _, tr := range traces {
+		gotTraces = append(gotTraces, tr.TraceID)
+		for _, span := range tr.Spans {
+			gotSpans = append(gotSpans, span.SpanID)
+		}
+	}
+	if strings.Join(gotTraces, ",") != strings.Join(traceOrder, ",") {
+		t.Errorf("GenerateMarkdown() reordered traces: got %v, want %v", gotTraces, traceOrder)
+	}
+	if strings.Join(gotSpans, ",") != strings.Join(spanOrder, ",") {
+		t.Errorf("GenerateMarkdown() reordered spans: got %v, want %v", gotSpans, spanOrder)
+	}
+}
+
+func TestCompareMultipleTracesSpanCounts(t *testing.T) {
+	now := time.Now()
+	makeTrace := func(id string, spans int) Trace {
+		tr := Trace{TraceID: id}
+		for i := 0; i < spans; i++ {
+			tr.Spans = append(tr.Spans, Span{SpanID: fmt.Sprintf("s%d", i), Name: fmt.Sprintf("op%d", i), StartTime: now, EndTime: now.Add(time.Second)})
+		}
+		return tr
+	}
+
+	tests := []struct {
+		name      string
+		traceSets []TraceSet
+		expected  []string
+	}{
+		{
+			name: "span count changes",
+			traceSets: []TraceSet{
+				{Name: "base.json", Traces: []Trace{makeTrace("trace1", 2), makeTrace("trace2", 3)}},
+				{Name: "head.json", Traces: []Trace{makeTrace("trace1", 5), makeTrace("trace2", 3), makeTrace("trace3", 1)}},
+			},
+			expected: []string{
+				"| Trace Name | base | head | Duration Diff | Span Diff |",
+				"| trace1 | ✓ | ✓ | - | +3 |",
+				"| trace2 | ✓ | ✓ | - | - |",
+				"| trace3 | ✗ | ✓ | - | - |",
+				"| **Total spans** | 5 | 9 | - | +3 |",
+				"| **Avg spans/trace** | 2.5 | 3.0 | - | - |",
+			},
+		},
+		{
+			name: "empty file",
+			traceSets: []TraceSet{
+				{Name: "base.json", Traces: []Trace{makeTrace("trace1", 2)}},
+				{Name: "head.json"},
+			},
+			expected: []string{
+				"| trace1 | ✓ | ✗ | - | - |",
+				"| **Total spans** | 2 | 0 | - | - |",
+				"| **Avg spans/trace** | 2.0 | 0.0 | - | - |",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CompareMultipleTraces(tt.traceSets, "trace_id", Options{})
+			for _, want := range tt.expected {
+				if !strings.Contains(got, want) {
+					t.Errorf("CompareMultipleTraces() output does not contain %q:\n%s", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCompareMultipleTracesPerFileDeltas(t *testing.T) {
+	now := time.Now()
+	newSet := func(name string, d time.Duration) TraceSet {
+		return TraceSet{Name: name, Traces: []Trace{{TraceID: "trace1", Spans: []Span{
+			{SpanID: "a", Name: "root", StartTime: now, EndTime: now.Add(d)},
+		}}}}
+	}
+	traceSets := []TraceSet{
+		newSet("base.json", time.Second),
+		newSet("slower.json", 1500*time.Millisecond),
+		newSet("faster.json", 800*time.Millisecond),
+	}
+
+	// The slower file is not hidden behind the largest change
+	got := CompareMultipleTraces(traceSets, "trace_id", Options{})
+	for _, want := range []string{
+		"| Trace Name | base | slower | faster | Δ slower | Δ faster | Span Diff |\n",
+		"| trace1 | ✓ | ✓ | ✓ | 🔴 +500.00ms (+50.0%) | 🟢 -200.00ms (-20.0%) | - |\n",
+		"| **Total spans** | 1 | 1 | 1 | - | - | - |\n",
+		"| Span Name | base | slower | faster | Δ slower | Δ faster | Fastest | Changes |\n",
+		"| root | 1.00s | 1.50s | 800.00ms | 🔴 +500.00ms (+50.0%) | 🟢 -200.00ms (-20.0%) | faster | - |\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("CompareMultipleTraces() output does not contain %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestFastestFile(t *testing.T) {
+	names := []string{"base", "head", "next"}
+	tests := []struct {
+		name      string
+		durations []time.Duration
+		present   []bool
+		expected  string
+	}{
+		{name: "single fastest", durations: []time.Duration{3, 1, 2}, present: []bool{true, true, true}, expected: "head"},
+		{name: "missing spans are ignored", durations: []time.Duration{3, 0, 2}, present: []bool{true, false, true}, expected: "next"},
+		{name: "tie", durations: []time.Duration{2, 1, 1}, present: []bool{true, true, true}, expected: "tie: head, next"},
+		{name: "nothing to compare", durations: []time.Duration{0, 1, 0}, present: []bool{false, true, false}, expected: "-"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fastestFile(names, tt.durations, tt.present); got != tt.expected {
+				t.Errorf("fastestFile() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDurationDiffCells(t *testing.T) {
+	tests := []struct {
+		name      string
+		durations []time.Duration
+		present   []bool
+		expected  []string
+	}{
+		{
+			name:      "two files collapse to one column",
+			durations: []time.Duration{time.Second, 2 * time.Second},
+			present:   []bool{true, true},
+			expected:  []string{"🔴 +1.00s (+100.0%)"},
+		},
+		{
+			name:      "missing in one file",
+			durations: []time.Duration{time.Second, 0, 500 * time.Millisecond},
+			present:   []bool{true, false, true},
+			expected:  []string{"-", "🟢 -500.00ms (-50.0%)"},
+		},
+		{
+			name:      "missing in the first file",
+			durations: []time.Duration{0, time.Second, time.Second},
+			present:   []bool{false, true, true},
+			expected:  []string{"-", "-"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := durationDiffCells(tt.durations, tt.present, emojiIndicators)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("durationDiffCells() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDurationDiff(t *testing.T) {
+	tests := []struct {
+		name      string
+		durations []time.Duration
+		present   []bool
+		expected  string
+	}{
+		{name: "slower", durations: []time.Duration{time.Second, 2 * time.Second}, present: []bool{true, true}, expected: "🔴 +1.00s (+100.0%)"},
+		{name: "faster", durations: []time.Duration{2 * time.Second, time.Second}, present: []bool{true, true}, expected: "🟢 -1.00s (-50.0%)"},
+		{name: "unchanged", durations: []time.Duration{time.Second, time.Second}, present: []bool{true, true}, expected: "-"},
+		{name: "missing other", durations: []time.Duration{time.Second, 0}, present: []bool{true, false}, expected: "-"},
+		{name: "missing first", durations: []time.Duration{0, time.Second}, present: []bool{false, true}, expected: "-"},
+		{name: "zero first", durations: []time.Duration{0, 120 * time.Millisecond}, present: []bool{true, true}, expected: "🔴 +120.00ms"},
+		{name: "single file", durations: []time.Duration{time.Second}, present: []bool{true}, expected: "-"},
+		{
+			name:      "largest change decides",
+			durations: []time.Duration{2 * time.Second, 1900 * time.Millisecond, 5 * time.Second},
+			present:   []bool{true, true, true},
+			expected:  "🔴 +3.00s (+150.0%)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := durationDiff(tt.durations, tt.present, emojiIndicators); got != tt.expected {
+				t.Errorf("durationDiff(%v) = %q, want %q", tt.durations, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package trace
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Attribute diff styles of comparisons
+const (
+	// AttrDiffTable shows trace attributes side by side, a column per file
+	AttrDiffTable = "table"
+
+	// AttrDiffUnified shows changed trace attributes as a unified diff
+	// against the first file
+	AttrDiffUnified = "unified"
+)
+
+// writeUnifiedAttributeDiff writes the trace and resource attributes of a
+// matching trace that changed relative to the first trace set, as a
+// git-style diff per other trace set
+func writeUnifiedAttributeDiff(sb *strings.Builder, traceSets []TraceSet, traceMaps []map[string]*Trace, name string) {
+	base := traceAttributes(traceMaps[0][name])
+	var diffs strings.Builder
+	for i := 1; i < len(traceMaps); i++ {
+		diff := CompareAttributes(base, traceAttributes(traceMaps[i][name]))
+		if diff.Empty() {
+			continue
+		}
+		diffs.WriteString(fmt.Sprintf("```diff\n--- %s\n+++ %s\n", getFileNameWithoutExt(traceSets[0].Name), getFileNameWithoutExt(traceSets[i].Name)))
+		diffs.WriteString(formatUnifiedDiff(diff))
+		diffs.WriteString("```\n\n")
+	}
+
+	if diffs.Len() == 0 {
+		sb.WriteString("**Trace Attributes:** unchanged\n\n")
+		return
+	}
+	sb.WriteString("**Trace Attributes:**\n\n")
+	sb.WriteString(diffs.String())
+}
+
+// formatUnifiedDiff formats an attribute diff as unified diff lines sorted
+// by key, a changed key showing its old value before its new one
+func formatUnifiedDiff(diff AttributeDiff) string {
+	var keys []string
+	for _, m := range []map[string]string{diff.Added, diff.Removed} {
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	for k := range diff.Changed {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		if v, ok := diff.Removed[k]; ok {
+			sb.WriteString(fmt.Sprintf("- %s: %s\n", diffLineValue(k), diffLineValue(v)))
+		}
+		if v, ok := diff.Changed[k]; ok {
+			sb.WriteString(fmt.Sprintf("- %s: %s\n", diffLineValue(k), diffLineValue(v[0])))
+			sb.WriteString(fmt.Sprintf("+ %s: %s\n", diffLineValue(k), diffLineValue(v[1])))
+		}
+		if v, ok := diff.Added[k]; ok {
+			sb.WriteString(fmt.Sprintf("+ %s: %s\n", diffLineValue(k), diffLineValue(v)))
+		}
+	}
+	return sb.String()
+}
+
+// diffLineValue keeps a key or value on a single diff line
+func diffLineValue(s string) string {
+	return strings.NewReplacer("\r", `\r`, "\n", `\n`).Replace(s)
+}
+
+// traceAttributes merges the resource and trace attributes of a trace,
+// trace attributes taking precedence like in the attribute table
+func traceAttributes(t *Trace) map[string]string {
+	attrs := make(map[string]string, len(t.Attributes)+len(t.ResourceAttrs))
+	for k, v := range t.ResourceAttrs {
+		attrs[k] = v
+	}
+	for k, v := range t.Attributes {
+		attrs[k] = v
+	}
+	return attrs
+}
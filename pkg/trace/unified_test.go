@@ -0,0 +1,61 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatUnifiedDiff(t *testing.T) {
+	diff := AttributeDiff{
+		Added:   map[string]string{"request.id": "req-123", "note": "a\nb"},
+		Removed: map[string]string{"cache": "warm"},
+		Changed: map[string][2]string{"service.version": {"1.0.0", "1.2.0"}},
+	}
+	want := "- cache: warm\n" +
+		"+ note: a\\nb\n" +
+		"+ request.id: req-123\n" +
+		"- service.version: 1.0.0\n" +
+		"+ service.version: 1.2.0\n"
+
+	// Map iteration order must not change the output
+	for i := 0; i < 5; i++ {
+		if got := formatUnifiedDiff(diff); got != want {
+			t.Fatalf("formatUnifiedDiff() =\n%s\nwant\n%s", got, want)
+		}
+	}
+}
+
+func TestCompareMultipleTracesUnifiedAttributeDiff(t *testing.T) {
+	now := time.Now()
+	newSet := func(name string, attrs, resource map[string]string) TraceSet {
+		return TraceSet{Name: name, Traces: []Trace{{
+			TraceID:       "t1",
+			Attributes:    attrs,
+			ResourceAttrs: resource,
+			Spans:         []Span{{SpanID: "a", Name: "root", StartTime: now, EndTime: now.Add(time.Second)}},
+		}}}
+	}
+	base := newSet("base.json", map[string]string{"http.status_code": "200"}, map[string]string{"service.version": "1.0.0"})
+	head := newSet("head.json", map[string]string{"http.status_code": "500"}, map[string]string{"service.version": "1.0.0"})
+	same := newSet("same.json", map[string]string{"http.status_code": "200"}, map[string]string{"service.version": "1.0.0"})
+
+	markdown := CompareMultipleTraces([]TraceSet{base, head, same}, "trace_id", Options{AttrDiff: AttrDiffUnified})
+	want := "```diff\n--- base\n+++ head\n- http.status_code: 200\n+ http.status_code: 500\n```"
+	if !strings.Contains(markdown, want) {
+		t.Errorf("CompareMultipleTraces() did not show a unified attribute diff:\n%s", markdown)
+	}
+	if strings.Contains(markdown, "+++ same") || strings.Contains(markdown, "**Trace Attributes:**\n\n| Attribute |") {
+		t.Errorf("CompareMultipleTraces() should only diff changed files, without the attribute table:\n%s", markdown)
+	}
+
+	unchanged := CompareMultipleTraces([]TraceSet{base, same}, "trace_id", Options{AttrDiff: AttrDiffUnified})
+	if !strings.Contains(unchanged, "**Trace Attributes:** unchanged") {
+		t.Errorf("CompareMultipleTraces() should say when no attribute changed:\n%s", unchanged)
+	}
+
+	table := CompareMultipleTraces([]TraceSet{base, head}, "trace_id", Options{})
+	if !strings.Contains(table, "| http.status_code | 200 | 500 |") {
+		t.Errorf("CompareMultipleTraces() should keep the attribute table by default:\n%s", table)
+	}
+}
@@ -0,0 +1,52 @@
+package trace
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Validate checks the span tree of a trace and returns a finding for every
+// orphan span (parent not found), parent cycle, span that ends before it
+// starts and span whose time window falls outside its parent's, most
+// serious first
+func Validate(t Trace) []Finding {
+	return RunChecks([]Trace{t}, validateChecks()...)
+}
+
+// parentCycle follows the parent chain of a span and returns the IDs of the
+// spans forming a cycle, starting and ending with the span, or nil if the
+// span is not part of a cycle
+func parentCycle(span *Span, spans map[string]*Span) []string {
+	chain := []string{span.SpanID}
+	seen := map[string]bool{span.SpanID: true}
+	for parentID := span.ParentSpanID; parentID != ""; {
+		if parentID == span.SpanID {
+			return append(chain, span.SpanID)
+		}
+		if seen[parentID] {
+			// A cycle further up the chain that this span merely leads to
+			return nil
+		}
+		parent, ok := spans[parentID]
+		if !ok {
+			return nil
+		}
+		seen[parentID] = true
+		chain = append(chain, parentID)
+		parentID = parent.ParentSpanID
+	}
+	return nil
+}
+
+// cycleKey identifies a cycle regardless of the span it was found from
+func cycleKey(cycle []string) string {
+	ids := append([]string(nil), cycle[:len(cycle)-1]...)
+	sort.Strings(ids)
+	return strings.Join(ids, ",")
+}
+
+// describeSpan names a span in validation warnings
+func describeSpan(span *Span) string {
+	return fmt.Sprintf("%q (%s)", span.Name, span.SpanID)
+}
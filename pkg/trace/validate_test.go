@@ -0,0 +1,118 @@
+package trace
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		spans    []Span
+		expected []string
+	}{
+		{
+			name: "valid",
+			spans: []Span{
+				{SpanID: "a", Name: "root", StartTime: now, EndTime: now.Add(time.Second)},
+				{SpanID: "b", ParentSpanID: "a", Name: "child", StartTime: now, EndTime: now.Add(time.Second)},
+			},
+		},
+		{
+			name: "orphan",
+			spans: []Span{
+				{SpanID: "b", ParentSpanID: "x", Name: "child"},
+			},
+			expected: []string{"warning orphan-parent b: has parent x, which is not in the trace"},
+		},
+		{
+			name: "cycle",
+			spans: []Span{
+				{SpanID: "a", ParentSpanID: "b", Name: "first"},
+				{SpanID: "b", ParentSpanID: "a", Name: "second"},
+				{SpanID: "c", ParentSpanID: "c", Name: "self"},
+			},
+			expected: []string{
+				"error parent-cycle a: forms a parent cycle a → b → a",
+				"error parent-cycle c: forms a parent cycle c → c",
+			},
+		},
+		{
+			name: "outside parent window",
+			spans: []Span{
+				{SpanID: "a", Name: "root", StartTime: now, EndTime: now.Add(time.Second)},
+				{SpanID: "b", ParentSpanID: "a", Name: "early", StartTime: now.Add(-time.Millisecond), EndTime: now.Add(time.Millisecond)},
+				{SpanID: "c", ParentSpanID: "a", Name: "late", StartTime: now, EndTime: now.Add(2 * time.Second)},
+			},
+			expected: []string{
+				`error starts-before-parent b: starts 1.00ms before its parent "root" (a)`,
+				`warning ends-after-parent c: ends 1.00s after its parent "root" (a)`,
+			},
+		},
+		{
+			name: "ends before it starts",
+			spans: []Span{
+				{SpanID: "a", Name: "root", StartTime: now, EndTime: now.Add(time.Second)},
+				{SpanID: "b", ParentSpanID: "a", Name: "skewed", StartTime: now.Add(500 * time.Millisecond), EndTime: now.Add(300 * time.Millisecond)},
+			},
+			expected: []string{
+				"error ends-before-start b: ends 200.00ms before it starts, likely because of clock skew; its duration is counted as 0",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, f := range Validate(Trace{TraceID: "trace1", Spans: tt.spans}) {
+				got = append(got, string(f.Severity)+" "+f.Check+" "+f.SpanID+": "+f.Message)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Validate() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGenerateMarkdownWarnings(t *testing.T) {
+	now := time.Now()
+	traces := []Trace{{TraceID: "trace1", Spans: []Span{
+		// Duplicate IDs that would otherwise make showSpan recurse forever
+		{SpanID: "a", Name: "root", StartTime: now, EndTime: now.Add(time.Second)},
+		{SpanID: "b", ParentSpanID: "a", Name: "child", StartTime: now, EndTime: now.Add(time.Second)},
+		{SpanID: "a", ParentSpanID: "b", Name: "loop", StartTime: now, EndTime: now.Add(time.Second)},
+		{SpanID: "", Name: "unnamed", StartTime: now, EndTime: now.Add(time.Second)},
+		{SpanID: "c", ParentSpanID: "missing", Name: "orphan", StartTime: now, EndTime: now.Add(time.Second)},
+	}}}
+
+	got := GenerateMarkdown(traces, Options{})
+	want := "**⚠️ Warnings:**\n\n" +
+		"- Trace `trace1`: span \"child\" (b) forms a parent cycle b → a → b\n" +
+		"- Trace `trace1`: span \"orphan\" (c) has parent missing, which is not in the trace\n"
+	if !strings.Contains(got, want) {
+		t.Errorf("GenerateMarkdown() output does not contain %q:\n%s", want, got)
+	}
+}
+
+func TestShowSpanCycle(t *testing.T) {
+	now := time.Now()
+	trace := Trace{TraceID: "trace1", Spans: []Span{
+		{SpanID: "a", ParentSpanID: "b", Name: "first", StartTime: now, EndTime: now.Add(time.Second)},
+		{SpanID: "b", ParentSpanID: "a", Name: "second", StartTime: now, EndTime: now.Add(time.Second)},
+	}}
+
+	// Must terminate and still show both spans with a note about the cycle
+	var sb strings.Builder
+	showSpan(&sb, &trace, "", nil, Options{})
+
+	expected := "- _↻ parent cycle detected: a → b → a_\n" +
+		"- **first** (1.00s)\n" +
+		"- **second** (1.00s)\n" +
+		"- _↻ first already shown above (cyclic or duplicate parent reference)_\n"
+	if got := sb.String(); got != expected {
+		t.Errorf("showSpan() = %q, want %q", got, expected)
+	}
+}
@@ -0,0 +1,136 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// zipkinSpan is a span in the Zipkin v2 JSON format
+type zipkinSpan struct {
+	TraceID       string             `json:"traceId"`
+	ID            string             `json:"id"`
+	ParentID      string             `json:"parentId"`
+	Name          string             `json:"name"`
+	Timestamp     int64              `json:"timestamp"`
+	Duration      int64              `json:"duration"`
+	Kind          string             `json:"kind"`
+	LocalEndpoint *zipkinEndpoint    `json:"localEndpoint"`
+	Tags          map[string]string  `json:"tags"`
+	Annotations   []zipkinAnnotation `json:"annotations"`
+}
+
+// zipkinEndpoint is the network context of a Zipkin span
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+}
+
+// zipkinAnnotation is a timestamped event in a Zipkin span
+type zipkinAnnotation struct {
+	Timestamp int64  `json:"timestamp"`
+	Value     string `json:"value"`
+}
+
+// ParseZipkin reads Zipkin v2 JSON spans and groups them into traces by
+// trace ID, in order of first appearance. Timestamps and durations are in
+// microseconds. The service of the trace's root span (or first span when
+// there is no root) becomes the service.name resource attribute, and spans
+// from other services carry their own service.name attribute.
+func ParseZipkin(data []byte) ([]Trace, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, ErrEmptyInput
+	}
+	var zipkinSpans []zipkinSpan
+	if err := json.Unmarshal(data, &zipkinSpans); err != nil {
+		return nil, fmt.Errorf("error unmarshaling zipkin spans: %w", err)
+	}
+
+	var traces []Trace
+	traceIndex := make(map[string]int)
+	for _, zs := range zipkinSpans {
+		i, ok := traceIndex[zs.TraceID]
+		if !ok {
+			i = len(traces)
+			traceIndex[zs.TraceID] = i
+			traces = append(traces, Trace{TraceID: zs.TraceID})
+		}
+		traces[i].Spans = append(traces[i].Spans, convertZipkinSpan(zs))
+	}
+
+	// Use the root service as the resource and keep other services per span
+	for i := range traces {
+		services := make(map[string]string)
+		for _, zs := range zipkinSpans {
+			if zs.TraceID == traces[i].TraceID && zs.LocalEndpoint != nil {
+				services[zs.ID] = zs.LocalEndpoint.ServiceName
+			}
+		}
+
+		spans := traces[i].Spans
+		root := spans[0]
+		for _, span := range spans {
+			if span.ParentSpanID == "" {
+				root = span
+				break
+			}
+		}
+
+		rootService := services[root.SpanID]
+		if rootService != "" {
+			traces[i].ResourceAttrs = map[string]string{"service.name": rootService}
+		}
+		for j := range spans {
+			service := services[spans[j].SpanID]
+			if service == "" || service == rootService {
+				continue
+			}
+			if spans[j].Attributes == nil {
+				spans[j].Attributes = make(map[string]string)
+			}
+			spans[j].Attributes["service.name"] = service
+		}
+	}
+
+	return traces, nil
+}
+
+// convertZipkinSpan converts a Zipkin span, without its service, into a Span
+func convertZipkinSpan(zs zipkinSpan) Span {
+	start := fromMicros(zs.Timestamp)
+	span := Span{
+		SpanID:       zs.ID,
+		ParentSpanID: zs.ParentID,
+		Name:         zs.Name,
+		StartTime:    start,
+		EndTime:      start.Add(time.Duration(zs.Duration) * time.Microsecond),
+		Kind:         SpanKind(strings.ToUpper(zs.Kind)),
+	}
+
+	// Zipkin marks failed spans with an error tag holding the message
+	if message, ok := zs.Tags["error"]; ok {
+		span.Status = Status{Code: StatusError, Message: message}
+	}
+
+	if len(zs.Tags) > 0 {
+		span.Attributes = make(map[string]string, len(zs.Tags))
+		for k, v := range zs.Tags {
+			span.Attributes[k] = v
+		}
+	}
+
+	for _, annotation := range zs.Annotations {
+		span.Events = append(span.Events, Event{
+			Time: fromMicros(annotation.Timestamp),
+			Name: annotation.Value,
+		})
+	}
+
+	return span
+}
+
+// fromMicros converts microseconds since the Unix epoch to a UTC time
+func fromMicros(us int64) time.Time {
+	return time.UnixMicro(us).UTC()
+}
@@ -0,0 +1,63 @@
+package trace
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseZipkin(t *testing.T) {
+	input := []byte(`[
+		{"traceId": "t1", "id": "a", "name": "get /users", "kind": "SERVER", "timestamp": 1709719200000000, "duration": 150000,
+		 "localEndpoint": {"serviceName": "frontend"}, "tags": {"http.method": "GET"},
+		 "annotations": [{"timestamp": 1709719200010000, "value": "ws"}]},
+		{"traceId": "t1", "id": "b", "parentId": "a", "name": "select", "timestamp": 1709719200020000, "duration": 50000,
+		 "localEndpoint": {"serviceName": "users-db"}},
+		{"traceId": "t2", "id": "c", "parentId": "x", "name": "orphan", "timestamp": 1709719200000000, "duration": 1000,
+		 "localEndpoint": {"serviceName": "worker"}}
+	]`)
+
+	traces, err := ParseZipkin(input)
+	if err != nil {
+		t.Fatalf("ParseZipkin() error = %v", err)
+	}
+	if len(traces) != 2 {
+		t.Fatalf("ParseZipkin() returned %d traces, want 2", len(traces))
+	}
+
+	t1 := traces[0]
+	if t1.TraceID != "t1" || len(t1.Spans) != 2 {
+		t.Fatalf("ParseZipkin() first trace = %s with %d spans, want t1 with 2", t1.TraceID, len(t1.Spans))
+	}
+	if t1.ResourceAttrs["service.name"] != "frontend" {
+		t.Errorf("resource service.name = %q, want frontend", t1.ResourceAttrs["service.name"])
+	}
+	if t1.Spans[1].Attributes["service.name"] != "users-db" {
+		t.Errorf("child span service.name = %q, want users-db", t1.Spans[1].Attributes["service.name"])
+	}
+	if t1.Spans[0].Kind != SpanKindServer {
+		t.Errorf("root span kind = %q, want SERVER", t1.Spans[0].Kind)
+	}
+	if got := t1.Spans[0].EndTime.Sub(t1.Spans[0].StartTime); got != 150*time.Millisecond {
+		t.Errorf("root span duration = %v, want 150ms", got)
+	}
+	if len(t1.Spans[0].Events) != 1 || t1.Spans[0].Events[0].Name != "ws" {
+		t.Errorf("root span events = %v, want a single ws event", t1.Spans[0].Events)
+	}
+	if getTraceIdentifier(t1, "name") != "get /users" {
+		t.Errorf("getTraceIdentifier() = %q, want get /users", getTraceIdentifier(t1, "name"))
+	}
+
+	// Traces without a root span fall back to the first span
+	if got := getTraceIdentifier(traces[1], "name"); got != "orphan" {
+		t.Errorf("getTraceIdentifier() = %q, want orphan", got)
+	}
+	if traces[1].ResourceAttrs["service.name"] != "worker" {
+		t.Errorf("resource service.name = %q, want worker", traces[1].ResourceAttrs["service.name"])
+	}
+}
+
+func TestParseZipkinInvalid(t *testing.T) {
+	if _, err := ParseZipkin([]byte(`{"traceId": "t1"}`)); err == nil {
+		t.Error("ParseZipkin() expected an error for a non-array input")
+	}
+}